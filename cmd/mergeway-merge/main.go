@@ -0,0 +1,14 @@
+package main
+
+import (
+	"os"
+
+	"github.com/mergewayhq/mergeway-cli/internal/mergecmd"
+)
+
+func main() {
+	code := mergecmd.Run(os.Args[1:], os.Stdout, os.Stderr)
+	if code != 0 {
+		os.Exit(code)
+	}
+}
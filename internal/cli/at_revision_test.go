@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mergewayhq/mergeway-cli/internal/testutil"
+)
+
+// historyRepo commits the fixture, then commits a renamed Alice, a new user,
+// and a new User field. It returns the root and the first revision.
+func historyRepo(t *testing.T) (string, string) {
+	t.Helper()
+	repo := testutil.NewGitRepo(t)
+	first := repo.Revision(t, "HEAD")
+
+	repo.WriteDataChange(t, "data/users/user-alice.yaml", "id: User-Alice\nname: Alice Renamed\nemail: alice@example.com\nrole: admin\n")
+	repo.WriteDataChange(t, "data/users/user-carol.yaml", "id: User-Carol\nname: Carol Example\nemail: carol@example.com\nrole: editor\n")
+	userType := testutil.ReadFile(t, repo.Root, "types/User.yaml")
+	repo.WriteDataChange(t, "types/User.yaml", userType+"      nickname:\n        type: string\n")
+	repo.Git(t, "add", ".")
+	repo.Git(t, "commit", "-m", "second")
+
+	return repo.Root, first
+}
+
+func TestAtRevisionReadCommands(t *testing.T) {
+	root, first := historyRepo(t)
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		notWant []string
+	}{
+		{name: "get", args: []string{"get", "--type", "User", "User-Alice"}, want: []string{"Alice Example"}, notWant: []string{"Alice Renamed"}},
+		{name: "list", args: []string{"list", "--type", "User"}, want: []string{"User-Alice", "User-Bob"}, notWant: []string{"User-Carol"}},
+		{name: "export", args: []string{"export", "User"}, want: []string{"Alice Example"}, notWant: []string{"Carol"}},
+		{name: "validate", args: []string{"validate"}, want: []string{"validation succeeded"}},
+		{name: "entity show", args: []string{"entity", "show", "User"}, want: []string{"email"}, notWant: []string{"nickname"}},
+		{name: "files", args: []string{"files", "--type", "User"}, want: []string{"data/users/user-alice.yaml"}, notWant: []string{"user-carol"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+			args := append([]string{"--root", root, "--at", first}, tc.args...)
+			if code := Run(args, stdout, stderr); code != 0 {
+				t.Fatalf("exit code %d, stderr %s", code, stderr.String())
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(stdout.String(), want) {
+					t.Fatalf("expected %q in output:\n%s", want, stdout.String())
+				}
+			}
+			for _, notWant := range tc.notWant {
+				if strings.Contains(stdout.String(), notWant) {
+					t.Fatalf("expected %q to be absent at %s:\n%s", notWant, first, stdout.String())
+				}
+			}
+		})
+	}
+}
+
+func TestAtRevisionIgnoresUncommittedChanges(t *testing.T) {
+	root, _ := historyRepo(t)
+	testutil.WriteFile(t, root, "data/users/user-alice.yaml", "id: User-Alice\nname: Alice Uncommitted\nemail: alice@example.com\nrole: admin\n")
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	code := Run([]string{"--root", root, "--at", "HEAD", "get", "--type", "User", "User-Alice"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("exit code %d, stderr %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Alice Renamed") {
+		t.Fatalf("expected committed value, got %s", stdout.String())
+	}
+}
+
+func TestAtRevisionRejectsMutatingCommands(t *testing.T) {
+	root, first := historyRepo(t)
+	before, err := os.ReadFile(filepath.Join(root, "data", "users", "user-alice.yaml"))
+	if err != nil {
+		t.Fatalf("read user: %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"create", "--type", "Tag", "--id", "tag-new"},
+		{"update", "--type", "User", "--id", "User-Alice"},
+		{"delete", "--type", "User", "--yes", "User-Alice"},
+		{"fmt", "--in-place", "data/users/user-alice.yaml"},
+		{"init"},
+	} {
+		stdout := &bytes.Buffer{}
+		stderr := &bytes.Buffer{}
+		code := Run(append([]string{"--root", root, "--at", first}, args...), stdout, stderr)
+		if code == 0 {
+			t.Fatalf("expected %s to fail with --at", args[0])
+		}
+		if !strings.Contains(stderr.String(), args[0]+": --at is only supported by read-only commands") {
+			t.Fatalf("expected read-only error for %s, got %s", args[0], stderr.String())
+		}
+	}
+
+	after, err := os.ReadFile(filepath.Join(root, "data", "users", "user-alice.yaml"))
+	if err != nil {
+		t.Fatalf("read user: %v", err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Fatalf("expected data to be untouched, got %s", after)
+	}
+}
+
+func TestAtRevisionRejectsUnknownRevision(t *testing.T) {
+	root, _ := historyRepo(t)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	code := Run([]string{"--root", root, "--at", "no-such-rev", "list", "--type", "User"}, stdout, stderr)
+	if code == 0 {
+		t.Fatalf("expected unknown revision to fail")
+	}
+	if !strings.Contains(stderr.String(), "--at no-such-rev: invalid revision") {
+		t.Fatalf("unexpected stderr: %s", stderr.String())
+	}
+}
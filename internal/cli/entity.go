@@ -22,8 +22,8 @@ func newEntityCommand() *cobra.Command {
 	}
 
 	cmd.AddCommand(
-		newEntityListCommand(),
-		newEntityShowCommand(),
+		allowAtRevision(newEntityListCommand()),
+		allowAtRevision(newEntityShowCommand()),
 	)
 
 	return cmd
@@ -38,6 +38,9 @@ func newEntityListCommand() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			defer func() {
+				_ = ctx.Close()
+			}()
 
 			cfg, err := loadConfig(ctx)
 			if err != nil {
@@ -69,6 +72,9 @@ func newEntityShowCommand() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			defer func() {
+				_ = ctx.Close()
+			}()
 
 			if len(args) == 0 {
 				_, _ = fmt.Fprintln(ctx.Stderr, "entity show requires an entity name")
@@ -19,6 +19,9 @@ func newExportCommand() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			defer func() {
+				_ = ctx.Close()
+			}()
 
 			include := args
 
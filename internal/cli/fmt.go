@@ -96,7 +96,7 @@ func newFmtCommand() *cobra.Command {
 				if cached, ok := schemaCache[typeDef.Name]; ok {
 					return cached
 				}
-				schema := buildFormatSchema(typeDef)
+				schema := format.SchemaForType(typeDef)
 				schemaCache[typeDef.Name] = schema
 				return schema
 			}
@@ -229,49 +229,6 @@ func collectConfiguredFiles(root string, cfg *config.Config) (*configuredFileSet
 	return set, nil
 }
 
-func buildFormatSchema(typeDef *config.TypeDefinition) *format.Schema {
-	if typeDef == nil || len(typeDef.FieldOrder) == 0 {
-		return nil
-	}
-
-	fields := make([]*format.SchemaField, 0, len(typeDef.FieldOrder))
-	for _, name := range typeDef.FieldOrder {
-		field := typeDef.Fields[name]
-		if field == nil {
-			continue
-		}
-		if schemaField := buildSchemaField(field); schemaField != nil {
-			fields = append(fields, schemaField)
-		}
-	}
-
-	return format.NewSchema(fields)
-}
-
-func buildSchemaField(field *config.FieldDefinition) *format.SchemaField {
-	if field == nil || field.Name == "" {
-		return nil
-	}
-	schemaField := &format.SchemaField{
-		Name:     field.Name,
-		Repeated: field.Repeated,
-	}
-	if field.Type == "object" && len(field.PropertyOrder) > 0 {
-		children := make([]*format.SchemaField, 0, len(field.PropertyOrder))
-		for _, propName := range field.PropertyOrder {
-			child := field.Properties[propName]
-			if child == nil {
-				continue
-			}
-			if nested := buildSchemaField(child); nested != nil {
-				children = append(children, nested)
-			}
-		}
-		schemaField.Nested = format.NewSchema(children)
-	}
-	return schemaField
-}
-
 func expandFmtTargets(root string, inputs []string) ([]string, error) {
 	var targets []string
 	seen := make(map[string]struct{})
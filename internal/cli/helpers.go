@@ -18,11 +18,11 @@ import (
 )
 
 func loadConfig(ctx *Context) (*config.Config, error) {
-	return config.Load(ctx.Config)
+	return config.LoadWithOps(ctx.Config, ctx.FileOps())
 }
 
 func loadStore(ctx *Context, cfg *config.Config) (*data.Store, error) {
-	return data.NewStore(ctx.Root, cfg)
+	return data.NewStoreWithOps(ctx.Root, cfg, ctx.FileOps())
 }
 
 func readPayload(path string) (map[string]any, error) {
@@ -155,6 +155,12 @@ func confirm(in io.Reader, out io.Writer, prompt string) (bool, error) {
 	return answer == "y" || answer == "yes", nil
 }
 
+// diffErrorMessage drops the "diff: " prefix from errors returned by the diff
+// package, since commands print their own name first.
+func diffErrorMessage(err error) string {
+	return strings.TrimPrefix(err.Error(), "diff: ")
+}
+
 func (ctx *Context) Stdin() io.Reader {
 	return os.Stdin
 }
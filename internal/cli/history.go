@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/mergewayhq/mergeway-cli/internal/diff"
+	"github.com/spf13/cobra"
+)
+
+type historyEntry struct {
+	Commit    string         `json:"commit" yaml:"commit"`
+	Author    string         `json:"author" yaml:"author"`
+	Date      string         `json:"date" yaml:"date"`
+	Subject   string         `json:"subject" yaml:"subject"`
+	Change    string         `json:"change" yaml:"change"`
+	At        string         `json:"at,omitempty" yaml:"at,omitempty"`
+	MovedFrom string         `json:"moved_from,omitempty" yaml:"moved_from,omitempty"`
+	Fields    []historyField `json:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
+type historyField struct {
+	Path   string `json:"path" yaml:"path"`
+	Before any    `json:"before,omitempty" yaml:"before,omitempty"`
+	After  any    `json:"after,omitempty" yaml:"after,omitempty"`
+}
+
+type blameEntry struct {
+	Field   string `json:"field" yaml:"field"`
+	Value   any    `json:"value" yaml:"value"`
+	Commit  string `json:"commit,omitempty" yaml:"commit,omitempty"`
+	Author  string `json:"author,omitempty" yaml:"author,omitempty"`
+	Date    string `json:"date,omitempty" yaml:"date,omitempty"`
+	Subject string `json:"subject,omitempty" yaml:"subject,omitempty"`
+}
+
+func newHistoryCommand() *cobra.Command {
+	var typeName string
+
+	cmd := &cobra.Command{
+		Use:   "history <id>",
+		Short: "List the commits that changed an object",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := contextFromCommand(cmd)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = ctx.Close()
+			}()
+
+			opts, ok := historyOptions(ctx, "history", typeName, args)
+			if !ok {
+				return newExitError(1)
+			}
+
+			commits, err := diff.History(opts)
+			if err != nil {
+				_, _ = fmt.Fprintf(ctx.Stderr, "history: %s\n", diffErrorMessage(err))
+				return newExitError(1)
+			}
+
+			entries := make([]historyEntry, 0, len(commits))
+			for _, commit := range commits {
+				entry := historyEntry{
+					Commit:    commit.Commit,
+					Author:    commit.Author,
+					Date:      commit.Date,
+					Subject:   commit.Subject,
+					Change:    string(commit.Kind),
+					At:        commit.At,
+					MovedFrom: commit.MovedFrom,
+				}
+				for _, field := range commit.Fields {
+					entry.Fields = append(entry.Fields, historyField{
+						Path:   field.Path,
+						Before: field.OldValue,
+						After:  field.NewValue,
+					})
+				}
+				entries = append(entries, entry)
+			}
+
+			if code := writeFormatted(ctx, entries); code != 0 {
+				return newExitError(code)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&typeName, "type", "", "Type identifier")
+
+	return cmd
+}
+
+func newBlameCommand() *cobra.Command {
+	var typeName string
+
+	cmd := &cobra.Command{
+		Use:   "blame <id>",
+		Short: "Show the commit that last changed each field of an object",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := contextFromCommand(cmd)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = ctx.Close()
+			}()
+
+			opts, ok := historyOptions(ctx, "blame", typeName, args)
+			if !ok {
+				return newExitError(1)
+			}
+
+			fields, err := diff.Blame(opts)
+			if err != nil {
+				_, _ = fmt.Fprintf(ctx.Stderr, "blame: %s\n", diffErrorMessage(err))
+				return newExitError(1)
+			}
+
+			entries := make([]blameEntry, 0, len(fields))
+			for _, field := range fields {
+				entry := blameEntry{Field: field.Field, Value: field.Value}
+				if field.Commit != nil {
+					entry.Commit = field.Commit.Commit
+					entry.Author = field.Commit.Author
+					entry.Date = field.Commit.Date
+					entry.Subject = field.Commit.Subject
+				}
+				entries = append(entries, entry)
+			}
+
+			if code := writeFormatted(ctx, entries); code != 0 {
+				return newExitError(code)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&typeName, "type", "", "Type identifier")
+
+	return cmd
+}
+
+// historyOptions validates the shared history/blame arguments, reporting
+// problems on stderr. The walk starts at --at when it is set.
+func historyOptions(ctx *Context, command, typeName string, args []string) (diff.HistoryOptions, bool) {
+	if typeName == "" {
+		_, _ = fmt.Fprintf(ctx.Stderr, "%s requires --type\n", command)
+		return diff.HistoryOptions{}, false
+	}
+	if len(args) != 1 {
+		_, _ = fmt.Fprintf(ctx.Stderr, "%s requires an identifier\n", command)
+		return diff.HistoryOptions{}, false
+	}
+	return diff.HistoryOptions{
+		Root:     ctx.Root,
+		Config:   ctx.Config,
+		Type:     typeName,
+		ID:       args[0],
+		Revision: ctx.At,
+	}, true
+}
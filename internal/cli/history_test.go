@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mergewayhq/mergeway-cli/internal/testutil"
+)
+
+func TestHistoryCommand(t *testing.T) {
+	root, first := historyRepo(t)
+	second := strings.TrimSpace(testutil.RunGit(t, root, "rev-parse", "HEAD"))
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	code := Run([]string{"--root", root, "--format", "json", "history", "--type", "User", "User-Alice"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("exit code %d, stderr %s", code, stderr.String())
+	}
+
+	var entries []historyEntry
+	if err := json.Unmarshal(stdout.Bytes(), &entries); err != nil {
+		t.Fatalf("decode history: %v\n%s", err, stdout.String())
+	}
+	if len(entries) != 2 || entries[0].Commit != second || entries[1].Commit != first {
+		t.Fatalf("expected second and first commits, got %+v", entries)
+	}
+	if entries[0].Change != "modified" || len(entries[0].Fields) != 1 || entries[0].Fields[0].Path != "name" {
+		t.Fatalf("expected name modification, got %+v", entries[0])
+	}
+	if entries[0].Fields[0].Before != "Alice Example" || entries[0].Fields[0].After != "Alice Renamed" {
+		t.Fatalf("unexpected name change: %+v", entries[0].Fields[0])
+	}
+	if entries[1].Change != "added" || entries[1].Subject != "initial fixture" {
+		t.Fatalf("expected initial addition, got %+v", entries[1])
+	}
+}
+
+func TestBlameCommand(t *testing.T) {
+	root, first := historyRepo(t)
+	second := strings.TrimSpace(testutil.RunGit(t, root, "rev-parse", "HEAD"))
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	code := Run([]string{"--root", root, "--format", "json", "blame", "--type", "User", "User-Alice"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("exit code %d, stderr %s", code, stderr.String())
+	}
+
+	var entries []blameEntry
+	if err := json.Unmarshal(stdout.Bytes(), &entries); err != nil {
+		t.Fatalf("decode blame: %v\n%s", err, stdout.String())
+	}
+	got := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		got[entry.Field] = entry.Commit
+	}
+	if got["name"] != second || got["email"] != first || got["id"] != first {
+		t.Fatalf("unexpected blame: %+v", entries)
+	}
+}
+
+func TestHistoryCommandRequiresType(t *testing.T) {
+	root, _ := historyRepo(t)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	code := Run([]string{"--root", root, "history", "User-Alice"}, stdout, stderr)
+	if code == 0 {
+		t.Fatalf("expected history without --type to fail")
+	}
+	if !strings.Contains(stderr.String(), "history requires --type") {
+		t.Fatalf("unexpected stderr: %s", stderr.String())
+	}
+}
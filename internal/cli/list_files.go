@@ -4,12 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
-	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/mergewayhq/mergeway-cli/internal/config"
+	"github.com/mergewayhq/mergeway-cli/internal/fileutil"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 )
@@ -31,6 +31,9 @@ func newFilesCommand() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			defer func() {
+				_ = ctx.Close()
+			}()
 
 			cfg, err := loadConfig(ctx)
 			if err != nil {
@@ -44,7 +47,7 @@ func newFilesCommand() *cobra.Command {
 				}
 			}
 
-			entries, err := collectListFileEntries(ctx.Root, cfg, typeName, group)
+			entries, err := collectListFileEntries(ctx.Root, cfg, typeName, group, ctx.FileOps())
 			if err != nil {
 				_, _ = fmt.Fprintf(ctx.Stderr, "files: %v\n", err)
 				return newExitError(1)
@@ -70,7 +73,7 @@ func newFilesCommand() *cobra.Command {
 	return cmd
 }
 
-func collectListFileEntries(root string, cfg *config.Config, typeName string, group bool) ([]listFileEntry, error) {
+func collectListFileEntries(root string, cfg *config.Config, typeName string, group bool, ops fileutil.Ops) ([]listFileEntry, error) {
 	absRoot, err := filepath.Abs(root)
 	if err != nil {
 		return nil, fmt.Errorf("resolve root %s: %w", root, err)
@@ -103,7 +106,7 @@ func collectListFileEntries(root string, cfg *config.Config, typeName string, gr
 				pattern = filepath.Join(absRoot, filepath.Clean(pattern))
 			}
 
-			matches, err := filepath.Glob(pattern)
+			matches, err := ops.Glob(pattern)
 			if err != nil {
 				return nil, fmt.Errorf("glob %s: %w", include.Path, err)
 			}
@@ -112,7 +115,7 @@ func collectListFileEntries(root string, cfg *config.Config, typeName string, gr
 			}
 
 			for _, match := range matches {
-				info, err := os.Stat(match)
+				info, err := ops.Stat(match)
 				if err != nil {
 					if errors.Is(err, fs.ErrNotExist) {
 						continue
@@ -141,7 +144,7 @@ func collectListFileEntries(root string, cfg *config.Config, typeName string, gr
 
 				entryPath := displayWorkspacePath(absRoot, absMatch)
 				if group && include.Selector == "" && includeHasGlob(include.Path) {
-					if multi, ok := yamlFileContainsItems(absMatch); ok && !multi {
+					if multi, ok := yamlFileContainsItems(ops, absMatch); ok && !multi {
 						entryPath = displayWorkspacePath(absRoot, pattern)
 					}
 				}
@@ -171,8 +174,8 @@ func includeHasGlob(path string) bool {
 	return strings.ContainsAny(path, "*?[")
 }
 
-func yamlFileContainsItems(path string) (multi bool, ok bool) {
-	body, err := os.ReadFile(path)
+func yamlFileContainsItems(ops fileutil.Ops, path string) (multi bool, ok bool) {
+	body, err := ops.ReadFile(path)
 	if err != nil {
 		return false, false
 	}
@@ -18,6 +18,9 @@ func newListCommand() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			defer func() {
+				_ = ctx.Close()
+			}()
 
 			if typeName == "" {
 				_, _ = fmt.Fprintln(ctx.Stderr, "list requires --type")
@@ -62,6 +65,9 @@ func newGetCommand() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			defer func() {
+				_ = ctx.Close()
+			}()
 
 			if len(args) == 0 {
 				_, _ = fmt.Fprintln(ctx.Stderr, "get requires an identifier")
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mergewayhq/mergeway-cli/internal/diff"
+	"github.com/spf13/cobra"
+)
+
+type patchEntry struct {
+	Change    string          `json:"change" yaml:"change"`
+	Type      string          `json:"type" yaml:"type"`
+	ID        string          `json:"id" yaml:"id"`
+	OldID     string          `json:"old_id,omitempty" yaml:"old_id,omitempty"`
+	Status    string          `json:"status" yaml:"status"`
+	Conflicts []patchConflict `json:"conflicts,omitempty" yaml:"conflicts,omitempty"`
+}
+
+type patchConflict struct {
+	Kind   string `json:"kind" yaml:"kind"`
+	Field  string `json:"field,omitempty" yaml:"field,omitempty"`
+	Base   any    `json:"base" yaml:"base"`
+	Ours   any    `json:"ours" yaml:"ours"`
+	Theirs any    `json:"theirs" yaml:"theirs"`
+}
+
+func newPatchCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "patch",
+		Short: "Apply semantic data diffs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := contextFromCommand(cmd)
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(ctx.Stderr, "patch subcommand required (apply)")
+			return newExitError(1)
+		},
+	}
+
+	cmd.AddCommand(
+		newPatchApplyCommand(),
+	)
+
+	return cmd
+}
+
+func newPatchApplyCommand() *cobra.Command {
+	var threeWay bool
+
+	cmd := &cobra.Command{
+		Use:   "apply <diff.json>",
+		Short: "Replay a mergeway-diff JSON diff against the workspace",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := contextFromCommand(cmd)
+			if err != nil {
+				return err
+			}
+
+			if len(args) != 1 {
+				_, _ = fmt.Fprintln(ctx.Stderr, "patch apply requires a diff file (use - for STDIN)")
+				return newExitError(1)
+			}
+
+			payload, err := readPatch(ctx, args[0])
+			if err != nil {
+				_, _ = fmt.Fprintf(ctx.Stderr, "patch apply: %v\n", err)
+				return newExitError(1)
+			}
+
+			result, applyErr := diff.ApplyPatch(diff.PatchOptions{
+				Root:     ctx.Root,
+				Config:   ctx.Config,
+				Patch:    payload,
+				ThreeWay: threeWay,
+			})
+			if applyErr != nil && len(result.Entries) == 0 {
+				_, _ = fmt.Fprintf(ctx.Stderr, "patch apply: %s\n", diffErrorMessage(applyErr))
+				return newExitError(1)
+			}
+
+			entries := make([]patchEntry, 0, len(result.Entries))
+			failed := 0
+			for _, outcome := range result.Entries {
+				entry := patchEntry{
+					Change: string(outcome.Kind),
+					Type:   outcome.Type,
+					ID:     outcome.ObjectID,
+					OldID:  outcome.OldObjectID,
+					Status: string(outcome.Status),
+				}
+				for _, conflict := range outcome.Conflicts {
+					entry.Conflicts = append(entry.Conflicts, patchConflict{
+						Kind:   string(conflict.Kind),
+						Field:  conflict.Field,
+						Base:   conflict.Base,
+						Ours:   conflict.Ours,
+						Theirs: conflict.Theirs,
+					})
+				}
+				switch outcome.Status {
+				case diff.PatchStatusDrifted, diff.PatchStatusConflict, diff.PatchStatusReadOnly:
+					failed++
+				}
+				entries = append(entries, entry)
+			}
+
+			if code := writeFormatted(ctx, entries); code != 0 {
+				return newExitError(code)
+			}
+			if applyErr != nil {
+				_, _ = fmt.Fprintf(ctx.Stderr, "patch apply: %s\n", diffErrorMessage(applyErr))
+				return newExitError(1)
+			}
+			if !result.Failed() {
+				return nil
+			}
+			if threeWay {
+				_, _ = fmt.Fprintf(ctx.Stderr, "patch apply: %d entries could not be applied and were left unchanged\n", failed)
+			} else {
+				_, _ = fmt.Fprintf(ctx.Stderr, "patch apply: %d entries no longer match the workspace; nothing was applied (retry with --3way to merge them)\n", failed)
+			}
+			return newExitError(1)
+		},
+	}
+
+	cmd.Flags().BoolVar(&threeWay, "3way", false, "Merge drifted objects three ways and apply every entry that merges cleanly")
+
+	return cmd
+}
+
+func readPatch(ctx *Context, path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(ctx.Stdin())
+	}
+	return os.ReadFile(path)
+}
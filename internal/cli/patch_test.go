@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mergewayhq/mergeway-cli/internal/diffcmd"
+	"github.com/mergewayhq/mergeway-cli/internal/testutil"
+)
+
+const bobRenamePatch = `{
+  "version": 1,
+  "entries": [
+    {
+      "kind": "modified",
+      "type": "User",
+      "object_id": "User-Bob",
+      "old_value": {"id": "User-Bob", "name": "Bob Example", "email": "bob@example.com", "role": "editor"},
+      "new_value": {"id": "User-Bob", "name": "Robert Example", "email": "bob@example.com", "role": "editor"},
+      "changes": [{"path": "name", "before": "Bob Example", "after": "Robert Example"}]
+    }
+  ]
+}`
+
+func writePatch(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "diff.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write patch: %v", err)
+	}
+	return path
+}
+
+func TestPatchApplyCommand(t *testing.T) {
+	root := testutil.CopyFixture(t)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	code := Run([]string{"--root", root, "--format", "json", "patch", "apply", writePatch(t, bobRenamePatch)}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("exit code %d, stderr %s", code, stderr.String())
+	}
+
+	var entries []patchEntry
+	if err := json.Unmarshal(stdout.Bytes(), &entries); err != nil {
+		t.Fatalf("decode patch result: %v\n%s", err, stdout.String())
+	}
+	if len(entries) != 1 || entries[0].ID != "User-Bob" || entries[0].Status != "applied" {
+		t.Fatalf("unexpected patch result %+v", entries)
+	}
+	content, err := os.ReadFile(filepath.Join(root, "data", "users", "user-bob.yaml"))
+	if err != nil {
+		t.Fatalf("read bob: %v", err)
+	}
+	if !strings.Contains(string(content), "name: Robert Example") {
+		t.Fatalf("expected Bob to be renamed:\n%s", content)
+	}
+}
+
+func TestPatchApplyCommandReportsConflicts(t *testing.T) {
+	root := testutil.CopyFixture(t)
+	bobPath := filepath.Join(root, "data", "users", "user-bob.yaml")
+	if err := os.WriteFile(bobPath, []byte("id: User-Bob\nname: Bobby Example\nemail: bob@example.com\nrole: editor\n"), 0o644); err != nil {
+		t.Fatalf("write bob: %v", err)
+	}
+	patch := writePatch(t, bobRenamePatch)
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	code := Run([]string{"--root", root, "patch", "apply", patch}, stdout, stderr)
+	if code != 1 || !strings.Contains(stderr.String(), "retry with --3way") || !strings.Contains(stdout.String(), "status: drifted") {
+		t.Fatalf("expected drift, got exit %d\nstdout: %s\nstderr: %s", code, stdout.String(), stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	code = Run([]string{"--root", root, "--format", "json", "patch", "apply", "--3way", patch}, stdout, stderr)
+	if code != 1 {
+		t.Fatalf("expected a conflict, got exit %d, stderr %s", code, stderr.String())
+	}
+	var entries []patchEntry
+	if err := json.Unmarshal(stdout.Bytes(), &entries); err != nil {
+		t.Fatalf("decode patch result: %v\n%s", err, stdout.String())
+	}
+	if len(entries) != 1 || entries[0].Status != "conflict" || len(entries[0].Conflicts) != 1 || entries[0].Conflicts[0].Field != "name" {
+		t.Fatalf("unexpected patch result %+v", entries)
+	}
+}
+
+func TestPatchApplyCommandReplaysFieldFilteredDiff(t *testing.T) {
+	left := testutil.CopyFixture(t)
+	right := testutil.CopyFixture(t)
+	testutil.WriteFile(t, right, "data/users/user-bob.yaml", "id: User-Bob\nname: Robert Example\nemail: robert@example.com\nrole: editor\n")
+
+	diffOut := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	code := diffcmd.Run([]string{"--root", left, "--field", "name", "--format", "json", "dir:" + left, "dir:" + right}, diffOut, stderr)
+	if code != 0 || !strings.Contains(diffOut.String(), "User-Bob") {
+		t.Fatalf("expected a diff of Bob, got exit %d\nstdout: %s\nstderr: %s", code, diffOut.String(), stderr.String())
+	}
+
+	target := testutil.CopyFixture(t)
+	stdout := &bytes.Buffer{}
+	stderr.Reset()
+	code = Run([]string{"--root", target, "patch", "apply", writePatch(t, diffOut.String())}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("exit code %d, stderr %s", code, stderr.String())
+	}
+
+	bob := testutil.ReadFile(t, target, "data/users/user-bob.yaml")
+	if !strings.Contains(bob, "name: Robert Example") || !strings.Contains(bob, "email: bob@example.com") {
+		t.Fatalf("expected only the filtered name change to be applied:\n%s", bob)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/mergewayhq/mergeway-cli/internal/diff"
+	"github.com/mergewayhq/mergeway-cli/internal/fileutil"
 	"github.com/spf13/cobra"
 )
 
@@ -17,8 +19,54 @@ type Context struct {
 	FailFast bool
 	Yes      bool
 	Verbose  bool
-	Stdout   io.Writer
-	Stderr   io.Writer
+	// At is the git revision read by --at; empty means the working tree.
+	At     string
+	Stdout io.Writer
+	Stderr io.Writer
+
+	revision *fileutil.GitTree
+}
+
+// atRevisionAnnotation marks read-only commands that honour --at.
+const atRevisionAnnotation = "mergeway.io/at-revision"
+
+// allowAtRevision marks cmd as able to read from a revision given by --at.
+// Callers must close the context returned by contextFromCommand.
+func allowAtRevision(cmd *cobra.Command) *cobra.Command {
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+	cmd.Annotations[atRevisionAnnotation] = "true"
+	return cmd
+}
+
+// checkAtRevision rejects --at for commands that may write to the repository
+// or otherwise only work against the working tree.
+func checkAtRevision(cmd *cobra.Command) error {
+	at, err := cmd.Flags().GetString("at")
+	if err != nil || at == "" || cmd.Annotations[atRevisionAnnotation] == "true" {
+		return err
+	}
+	name := strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+	_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s: --at is only supported by read-only commands (list, get, export, validate, entity list, entity show, files, history, blame)\n", name)
+	return newExitError(1)
+}
+
+// FileOps returns the file operations commands load config and data with:
+// the --at revision when set, otherwise the local filesystem.
+func (ctx *Context) FileOps() fileutil.Ops {
+	if ctx.revision != nil {
+		return ctx.revision.Ops()
+	}
+	return fileutil.OS
+}
+
+// Close releases the --at revision, if one was opened.
+func (ctx *Context) Close() error {
+	if ctx.revision == nil {
+		return nil
+	}
+	return ctx.revision.Close()
 }
 
 // Run executes the CLI. It returns an exit code.
@@ -62,6 +110,9 @@ func newRootCommand(stdout, stderr io.Writer) *cobra.Command {
 		Short:         "Manage mergeway repositories",
 		SilenceUsage:  true,
 		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return checkAtRevision(cmd)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			_ = cmd.Help()
 			return newExitError(1)
@@ -77,18 +128,22 @@ func newRootCommand(stdout, stderr io.Writer) *cobra.Command {
 	flags.Bool("fail-fast", false, "Stop validation on first error")
 	flags.Bool("yes", false, "Auto-confirm prompts")
 	flags.Bool("verbose", false, "Enable verbose logging")
+	flags.String("at", "", "Read config and data from a git revision instead of the working tree (read-only commands)")
 
 	cmd.AddCommand(
 		newInitCommand(),
 		newEntityCommand(),
-		newListCommand(),
-		newFilesCommand(),
-		newGetCommand(),
+		allowAtRevision(newListCommand()),
+		allowAtRevision(newFilesCommand()),
+		allowAtRevision(newGetCommand()),
 		newCreateCommand(),
 		newUpdateCommand(),
 		newDeleteCommand(),
-		newExportCommand(),
-		newValidateCommand(),
+		newPatchCommand(),
+		allowAtRevision(newExportCommand()),
+		allowAtRevision(newValidateCommand()),
+		allowAtRevision(newHistoryCommand()),
+		allowAtRevision(newBlameCommand()),
 		newFmtCommand(),
 		newConfigCommand(),
 		newVersionCommand(),
@@ -123,6 +178,10 @@ func contextFromCommand(cmd *cobra.Command) (*Context, error) {
 	if err != nil {
 		return nil, err
 	}
+	at, err := cmd.Flags().GetString("at")
+	if err != nil {
+		return nil, err
+	}
 
 	ctx := &Context{
 		Root:     root,
@@ -131,6 +190,7 @@ func contextFromCommand(cmd *cobra.Command) (*Context, error) {
 		FailFast: failFast,
 		Yes:      yes,
 		Verbose:  verbose,
+		At:       at,
 		Stdout:   cmd.OutOrStdout(),
 		Stderr:   cmd.ErrOrStderr(),
 	}
@@ -139,5 +199,12 @@ func contextFromCommand(cmd *cobra.Command) (*Context, error) {
 		ctx.Config = filepath.Join(ctx.Root, "mergeway.yaml")
 	}
 
+	if ctx.At != "" {
+		ctx.revision, err = diff.OpenRevision(ctx.Root, ctx.At)
+		if err != nil {
+			return nil, fmt.Errorf("--at %s: %w", ctx.At, err)
+		}
+	}
+
 	return ctx, nil
 }
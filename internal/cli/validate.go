@@ -19,13 +19,16 @@ func newValidateCommand() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			defer func() {
+				_ = ctx.Close()
+			}()
 
 			opts := validation.Options{
 				FailFast: ctx.FailFast,
 				Phases:   phaseFlags.Values,
 			}
 
-			report, err := workspace.Validate(ctx.Root, ctx.Config, opts)
+			report, err := workspace.ValidateWithOps(ctx.Root, ctx.Config, opts, ctx.FileOps())
 			if err != nil {
 				_, _ = fmt.Fprintf(ctx.Stderr, "validate: %v\n", err)
 				return newExitError(1)
@@ -4,14 +4,15 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"os"
 	"sort"
 	"strconv"
 	"strings"
+
+	"github.com/mergewayhq/mergeway-cli/internal/fileutil"
 )
 
-func loadJSONSchemaFields(typeName, schemaPath, displayPath string) (map[string]*FieldDefinition, []string, error) {
-	data, err := os.ReadFile(schemaPath)
+func loadJSONSchemaFields(typeName, schemaPath, displayPath string, ops fileutil.Ops) (map[string]*FieldDefinition, []string, error) {
+	data, err := ops.ReadFile(schemaPath)
 	if err != nil {
 		return nil, nil, fmt.Errorf("config: type %q read json_schema %s: %w", typeName, displayPath, err)
 	}
@@ -36,7 +36,7 @@ func LoadWithOps(path string, ops fileutil.Ops) (*Config, error) {
 		return nil, err
 	}
 
-	return normalizeAggregate(agg)
+	return normalizeAggregate(agg, ops)
 }
 
 func loadRecursive(path string, cache map[string]*aggregateConfig, stack map[string]bool, ops fileutil.Ops) (*aggregateConfig, error) {
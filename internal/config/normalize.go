@@ -6,9 +6,11 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+
+	"github.com/mergewayhq/mergeway-cli/internal/fileutil"
 )
 
-func normalizeAggregate(agg *aggregateConfig) (*Config, error) {
+func normalizeAggregate(agg *aggregateConfig, ops fileutil.Ops) (*Config, error) {
 	if agg == nil {
 		return nil, errors.New("config: missing aggregate configuration")
 	}
@@ -65,7 +67,7 @@ func normalizeAggregate(agg *aggregateConfig) (*Config, error) {
 			}
 		}
 
-		typeDef, err := normalizeTypeDefinition(rawType, parentDef, len(graph.children[name]) > 0)
+		typeDef, err := normalizeTypeDefinition(rawType, parentDef, len(graph.children[name]) > 0, ops)
 		if err != nil {
 			return nil, err
 		}
@@ -194,7 +196,7 @@ func validateInheritanceCycles(graph *inheritanceGraph) error {
 	return nil
 }
 
-func normalizeTypeDefinition(rawType rawTypeWithSource, parentDef *TypeDefinition, hasChildren bool) (*TypeDefinition, error) {
+func normalizeTypeDefinition(rawType rawTypeWithSource, parentDef *TypeDefinition, hasChildren bool, ops fileutil.Ops) (*TypeDefinition, error) {
 	spec := rawType.Spec
 	extends := strings.TrimSpace(spec.Extends)
 
@@ -235,7 +237,7 @@ func normalizeTypeDefinition(rawType rawTypeWithSource, parentDef *TypeDefinitio
 			schemaPath = filepath.Join(baseDir, schemaPath)
 		}
 		var err error
-		fields, fieldOrder, err = loadJSONSchemaFields(rawType.Name, schemaPath, jsonSchemaPath)
+		fields, fieldOrder, err = loadJSONSchemaFields(rawType.Name, schemaPath, jsonSchemaPath, ops)
 		if err != nil {
 			return nil, err
 		}
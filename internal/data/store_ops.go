@@ -149,6 +149,21 @@ func (s *Store) LoadExactAll(typeName string) ([]*Object, error) {
 	return result, nil
 }
 
+// CreateTarget reports the file Create would write a new object with the given
+// identifier to, and whether that file holds multiple objects.
+func (s *Store) CreateTarget(typeName, id string) (string, bool, error) {
+	typeDef, err := s.requireType(typeName)
+	if err != nil {
+		return "", false, err
+	}
+
+	target, err := s.chooseCreateTarget(typeDef, id)
+	if err != nil {
+		return "", false, err
+	}
+	return target.Path, target.Multi, nil
+}
+
 // Create writes a new object to disk.
 func (s *Store) Create(typeName string, fields map[string]any) (*Object, error) {
 	typeDef, err := s.requireType(typeName)
@@ -131,6 +131,34 @@ func TestStoreCreateUpdateDelete(t *testing.T) {
 	}
 }
 
+func TestStoreCreateTarget(t *testing.T) {
+	store, repo := setupStore(t, "repo")
+
+	path, multi, err := store.CreateTarget("Tag", "Tag New")
+	if err != nil {
+		t.Fatalf("CreateTarget returned error: %v", err)
+	}
+	if path != filepath.Join(repo, "data", "tags", "Tag-New.yaml") || multi {
+		t.Fatalf("expected single-object tag file, got %s (multi=%v)", path, multi)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected CreateTarget not to write %s, err=%v", path, err)
+	}
+
+	pathStore, pathRepo := setupStore(t, "path_identifier")
+	path, _, err = pathStore.CreateTarget("Note", "data/notes/beta.yaml")
+	if err != nil {
+		t.Fatalf("CreateTarget returned error: %v", err)
+	}
+	if path != filepath.Join(pathRepo, "data", "notes", "beta.yaml") {
+		t.Fatalf("expected path identifier target, got %s", path)
+	}
+
+	if _, _, err := pathStore.CreateTarget("Note", "../outside.yaml"); err == nil {
+		t.Fatalf("expected error for path identifier outside the root")
+	}
+}
+
 func TestStoreInheritanceParentReads(t *testing.T) {
 	store, _ := setupInheritanceStore(t, false)
 
@@ -1,28 +1,58 @@
 package diff
 
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported output format")
+	ErrInvalidFilter     = errors.New("invalid filter")
+)
+
+// OutputFormat selects how Run renders a diff. The zero value renders text.
+type OutputFormat string
+
+const (
+	OutputFormatText     OutputFormat = "yaml"
+	OutputFormatJSON     OutputFormat = "json"
+	OutputFormatMarkdown OutputFormat = "markdown"
+	OutputFormatHTML     OutputFormat = "html"
+)
+
 type Options struct {
 	Root   string
 	Config string
 	Args   []string
-	JSON   bool
+	Mode   SnapshotMode
+	Format OutputFormat
+	Filter DiffFilter
+	// RenameThreshold enables rename detection: a removed and an added
+	// object of the same type are reported as one renamed object when at
+	// least this percentage of their fields match. Zero disables it.
+	RenameThreshold int
+	// Impact lists the references in the right-hand snapshot that point at
+	// each removed, renamed, or modified object.
+	Impact bool
 }
 
 func Run(opts Options) (string, error) {
-	snapshots, err := resolveDiffSnapshots(opts.Root, opts.Args)
-	if err != nil {
+	if err := validateOutputFormat(opts.Format, OutputFormatText, OutputFormatJSON, OutputFormatMarkdown, OutputFormatHTML); err != nil {
 		return "", err
 	}
-
-	corpora, err := loadDiffDataCorpora(opts.Root, opts.Config, snapshots.Left, snapshots.Right)
-	if err != nil {
+	if err := opts.Filter.validate(); err != nil {
+		return "", err
+	}
+	if err := validateRenameThreshold(opts.RenameThreshold); err != nil {
 		return "", err
 	}
 
-	leftDB, err := buildLogicalDatabase(corpora.Left)
+	snapshots, err := resolveDiffSnapshots(opts.Root, opts.Args, opts.Mode)
 	if err != nil {
 		return "", err
 	}
-	rightDB, err := buildLogicalDatabase(corpora.Right)
+
+	leftDB, rightDB, err := loadDiffLogicalDatabases(opts.Root, opts.Config, snapshots.Left, snapshots.Right)
 	if err != nil {
 		return "", err
 	}
@@ -31,14 +61,40 @@ func Run(opts Options) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if opts.RenameThreshold > 0 {
+		if result, err = detectRenames(result, rightDB, opts.RenameThreshold); err != nil {
+			return "", err
+		}
+	}
+	result = applyDiffFilter(result, opts.Filter)
+	if opts.Impact {
+		result = analyzeImpact(result, rightDB)
+	}
 
-	if opts.JSON {
+	switch opts.Format {
+	case OutputFormatJSON:
 		payload, err := marshalDiffResultJSON(result)
 		if err != nil {
 			return "", err
 		}
 		return string(payload) + "\n", nil
+	case OutputFormatMarkdown:
+		return renderDiffResultMarkdown(result), nil
+	case OutputFormatHTML:
+		return renderDiffResultHTML(result)
+	default:
+		return renderDiffResult(result), nil
 	}
+}
 
-	return renderDiffResult(result), nil
+func validateOutputFormat(format OutputFormat, supported ...OutputFormat) error {
+	if format == "" {
+		return nil
+	}
+	for _, candidate := range supported {
+		if format == candidate {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w %q", ErrUnsupportedFormat, format)
 }
@@ -10,8 +10,9 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
-	"gopkg.in/yaml.v3"
+	"github.com/mergewayhq/mergeway-cli/internal/fileutil"
 )
 
 type SnapshotDataFile struct {
@@ -43,25 +44,24 @@ func loadDiffDataCorpora(root, configPath string, left, right SnapshotRef) (Diff
 		return DiffDataCorpora{}, fmt.Errorf("diff: resolve config path: %w", err)
 	}
 
-	leftSchema, err := loadSnapshotDiffSchema(absRoot, absConfig, left)
-	if err != nil {
-		return DiffDataCorpora{}, err
-	}
-	rightSchema, err := loadSnapshotDiffSchema(absRoot, absConfig, right)
+	readers := newSnapshotReaders(absRoot)
+	defer readers.Close()
+
+	sides, err := readers.openAll(absConfig, left, right)
 	if err != nil {
 		return DiffDataCorpora{}, err
 	}
 
-	paths, err := discoverDiffDataPaths(absRoot, absConfig, left, right)
+	paths, err := discoverDiffDataPaths(sides)
 	if err != nil {
 		return DiffDataCorpora{}, err
 	}
 
-	leftCorpus, err := loadSnapshotDataCorpus(absRoot, left, leftSchema, paths)
+	leftCorpus, err := loadSnapshotDataCorpus(sides[0], paths)
 	if err != nil {
 		return DiffDataCorpora{}, err
 	}
-	rightCorpus, err := loadSnapshotDataCorpus(absRoot, right, rightSchema, paths)
+	rightCorpus, err := loadSnapshotDataCorpus(sides[1], paths)
 	if err != nil {
 		return DiffDataCorpora{}, err
 	}
@@ -73,56 +73,44 @@ func loadDiffDataCorpora(root, configPath string, left, right SnapshotRef) (Diff
 	}, nil
 }
 
-func discoverDiffDataPaths(root, configPath string, left, right SnapshotRef) ([]string, error) {
-	leftPatterns, err := loadSnapshotDataIncludePatterns(root, configPath, left)
-	if err != nil {
-		return nil, err
-	}
-	rightPatterns, err := loadSnapshotDataIncludePatterns(root, configPath, right)
-	if err != nil {
-		return nil, err
-	}
+// snapshotSide pairs a snapshot's reader with the schema loaded from it.
+type snapshotSide struct {
+	reader *snapshotReader
+	schema *diffSnapshotSchema
+}
 
-	patternSet := make(map[string]struct{}, len(leftPatterns)+len(rightPatterns))
-	for _, pattern := range leftPatterns {
-		patternSet[pattern] = struct{}{}
-	}
-	for _, pattern := range rightPatterns {
-		patternSet[pattern] = struct{}{}
+// discoverDiffDataPaths returns every path matched by any side's include
+// patterns on any side, so a file that exists in one snapshot is known in
+// all of them.
+func discoverDiffDataPaths(sides []snapshotSide) ([]string, error) {
+	patternSet := make(map[string]struct{})
+	for _, side := range sides {
+		for _, pattern := range side.schema.includePatterns() {
+			patternSet[pattern] = struct{}{}
+		}
 	}
 
 	patterns := sortedKeys(patternSet)
 	paths := make(map[string]struct{})
 	for _, pattern := range patterns {
-		leftMatches, err := matchSnapshotPattern(root, left, pattern)
-		if err != nil {
-			return nil, err
-		}
-		for _, path := range leftMatches {
-			paths[path] = struct{}{}
-		}
-
-		rightMatches, err := matchSnapshotPattern(root, right, pattern)
-		if err != nil {
-			return nil, err
-		}
-		for _, path := range rightMatches {
-			paths[path] = struct{}{}
+		for _, side := range sides {
+			matches, err := side.reader.Match(pattern)
+			if err != nil {
+				return nil, err
+			}
+			for _, path := range matches {
+				paths[path] = struct{}{}
+			}
 		}
 	}
 
 	return sortedKeys(paths), nil
 }
 
-func loadSnapshotDataCorpus(root string, snapshot SnapshotRef, schema *diffSnapshotSchema, paths []string) (SnapshotDataCorpus, error) {
-	reader, err := newSnapshotReader(root, snapshot)
-	if err != nil {
-		return SnapshotDataCorpus{}, err
-	}
-
+func loadSnapshotDataCorpus(side snapshotSide, paths []string) (SnapshotDataCorpus, error) {
 	files := make([]SnapshotDataFile, 0, len(paths))
 	for _, path := range paths {
-		content, exists, err := reader.Read(path)
+		content, exists, err := side.reader.Read(path)
 		if err != nil {
 			return SnapshotDataCorpus{}, err
 		}
@@ -134,18 +122,77 @@ func loadSnapshotDataCorpus(root string, snapshot SnapshotRef, schema *diffSnaps
 	}
 
 	return SnapshotDataCorpus{
-		Snapshot: snapshot,
-		Schema:   schema,
+		Snapshot: side.reader.snapshot,
+		Schema:   side.schema,
 		Files:    files,
 	}, nil
 }
 
+// snapshotReaders opens each snapshot once per command, so every loader
+// shares one tree listing and one cat-file process per revision.
+type snapshotReaders struct {
+	root    string
+	readers map[SnapshotRef]*snapshotReader
+}
+
+func newSnapshotReaders(root string) *snapshotReaders {
+	return &snapshotReaders{
+		root:    root,
+		readers: make(map[SnapshotRef]*snapshotReader),
+	}
+}
+
+func (s *snapshotReaders) Get(snapshot SnapshotRef) (*snapshotReader, error) {
+	if reader, ok := s.readers[snapshot]; ok {
+		return reader, nil
+	}
+	reader, err := newSnapshotReader(s.root, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	s.readers[snapshot] = reader
+	return reader, nil
+}
+
+// openAll opens each snapshot and loads its schema.
+func (s *snapshotReaders) openAll(configPath string, snapshots ...SnapshotRef) ([]snapshotSide, error) {
+	sides := make([]snapshotSide, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		reader, err := s.Get(snapshot)
+		if err != nil {
+			return nil, err
+		}
+		readerConfig, err := reader.configPath(s.root, configPath)
+		if err != nil {
+			return nil, err
+		}
+		schema, err := loadSnapshotDiffSchema(reader, readerConfig)
+		if err != nil {
+			return nil, err
+		}
+		sides = append(sides, snapshotSide{reader: reader, schema: schema})
+	}
+	return sides, nil
+}
+
+func (s *snapshotReaders) Close() error {
+	var errs []error
+	for _, reader := range s.readers {
+		errs = append(errs, reader.Close())
+	}
+	s.readers = make(map[SnapshotRef]*snapshotReader)
+	return errors.Join(errs...)
+}
+
 type snapshotReader struct {
 	root     string
 	snapshot SnapshotRef
 
+	// tree serves revision snapshots, and unchanged files of the unstaged
+	// working tree view.
+	tree *fileutil.GitTree
+
 	files     []string
-	fileSet   map[string]struct{}
 	unstaged  map[string]struct{}
 	untracked map[string]struct{}
 }
@@ -156,7 +203,21 @@ func newSnapshotReader(root string, snapshot SnapshotRef) (*snapshotReader, erro
 		snapshot: snapshot,
 	}
 
-	if snapshot.Kind == SnapshotKindWorkingTree && snapshot.WorkingTreeView == WorkingTreeViewUnstaged {
+	switch snapshot.Kind {
+	case SnapshotKindHead, SnapshotKindRevision:
+		revision := snapshot.Revision
+		if snapshot.Kind == SnapshotKindHead {
+			revision = "HEAD"
+		}
+		tree, err := fileutil.OpenGitTree(root, revision)
+		if err != nil {
+			return nil, fmt.Errorf("diff: %w", err)
+		}
+		r.tree = tree
+	case SnapshotKindWorkingTree:
+		if snapshot.WorkingTreeView != WorkingTreeViewUnstaged {
+			break
+		}
 		unstaged, err := listGitNames(root, "diff", "--name-only", "--no-renames")
 		if err != nil {
 			return nil, err
@@ -168,31 +229,49 @@ func newSnapshotReader(root string, snapshot SnapshotRef) (*snapshotReader, erro
 			return nil, err
 		}
 		r.untracked = sliceToSet(untracked)
+
+		tree, err := fileutil.OpenGitTree(root, "HEAD")
+		if err != nil {
+			return nil, fmt.Errorf("diff: %w", err)
+		}
+		r.tree = tree
+	case SnapshotKindIndex:
+		tree, err := fileutil.OpenGitIndex(root)
+		if err != nil {
+			return nil, fmt.Errorf("diff: %w", err)
+		}
+		r.tree = tree
+	case SnapshotKindDirectory:
+		r.root = snapshot.Path
+	case SnapshotKindExport:
+		return nil, fmt.Errorf("diff: %w: %s has no config or data files", ErrInvalidSnapshot, snapshot)
+	default:
+		return nil, fmt.Errorf("diff: unsupported snapshot kind %q", snapshot.Kind)
 	}
 
 	return r, nil
 }
 
 func (r *snapshotReader) Read(path string) ([]byte, bool, error) {
-	switch r.snapshot.Kind {
-	case SnapshotKindHead:
-		return readGitRevisionFile(r.root, "HEAD", path)
-	case SnapshotKindRevision:
-		return readGitRevisionFile(r.root, r.snapshot.Revision, path)
-	case SnapshotKindWorkingTree:
+	if r.onDisk() {
 		if r.snapshot.WorkingTreeView == WorkingTreeViewUnstaged {
-			if _, ok := r.untracked[path]; ok {
-				return readWorkingTreeFile(r.root, path)
+			_, untracked := r.untracked[path]
+			_, unstaged := r.unstaged[path]
+			if !untracked && !unstaged {
+				return r.readTree(path)
 			}
-			if _, ok := r.unstaged[path]; ok {
-				return readWorkingTreeFile(r.root, path)
-			}
-			return readGitRevisionFile(r.root, "HEAD", path)
 		}
 		return readWorkingTreeFile(r.root, path)
-	default:
-		return nil, false, fmt.Errorf("diff: unsupported snapshot kind %q", r.snapshot.Kind)
 	}
+	return r.readTree(path)
+}
+
+func (r *snapshotReader) readTree(path string) ([]byte, bool, error) {
+	content, exists, err := r.tree.Read(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("diff: %w", err)
+	}
+	return content, exists, nil
 }
 
 func (r *snapshotReader) ListFiles() ([]string, error) {
@@ -200,126 +279,131 @@ func (r *snapshotReader) ListFiles() ([]string, error) {
 		return append([]string(nil), r.files...), nil
 	}
 
-	var files []string
-	switch r.snapshot.Kind {
-	case SnapshotKindHead:
-		var err error
-		files, err = listGitTreeFiles(r.root, "HEAD")
+	if r.onDisk() {
+		files, err := listWorkingTreeFiles(r.root)
 		if err != nil {
 			return nil, err
 		}
-	case SnapshotKindRevision:
-		var err error
-		files, err = listGitTreeFiles(r.root, r.snapshot.Revision)
-		if err != nil {
-			return nil, err
-		}
-	case SnapshotKindWorkingTree:
-		var err error
-		files, err = listWorkingTreeFiles(r.root)
-		if err != nil {
-			return nil, err
-		}
-	default:
-		return nil, fmt.Errorf("diff: unsupported snapshot kind %q", r.snapshot.Kind)
+		r.files = files
+	} else {
+		r.files = r.tree.Files()
 	}
-
-	r.files = files
-	r.fileSet = sliceToSet(files)
-	return append([]string(nil), files...), nil
+	return append([]string(nil), r.files...), nil
 }
 
-func loadSnapshotDataIncludePatterns(root, configPath string, snapshot SnapshotRef) ([]string, error) {
-	schema, err := loadSnapshotDiffSchema(root, configPath, snapshot)
+// Match returns the snapshot's files matching a root-relative pattern.
+func (r *snapshotReader) Match(pattern string) ([]string, error) {
+	files, err := r.ListFiles()
 	if err != nil {
 		return nil, err
 	}
-	return schema.includePatterns(), nil
-}
-
-type diffConfigInclude struct {
-	Path     string `yaml:"path"`
-	Selector string `yaml:"selector"`
-}
 
-func (d *diffConfigInclude) UnmarshalYAML(node *yaml.Node) error {
-	if node == nil {
-		return nil
-	}
-
-	switch node.Kind {
-	case yaml.ScalarNode:
-		var path string
-		if err := node.Decode(&path); err != nil {
-			return err
-		}
-		path = strings.TrimSpace(path)
-		if path == "" {
-			return fmt.Errorf("include path must be a non-empty string")
-		}
-		d.Path = path
-		d.Selector = ""
-		return nil
-	case yaml.MappingNode, yaml.AliasNode:
-		type alias diffConfigInclude
-		var tmp alias
-		if err := node.Decode(&tmp); err != nil {
-			return err
+	var matches []string
+	for _, path := range files {
+		ok, err := filepath.Match(pattern, path)
+		if err != nil {
+			return nil, fmt.Errorf("diff: match pattern %s: %w", pattern, err)
 		}
-		tmp.Path = strings.TrimSpace(tmp.Path)
-		tmp.Selector = strings.TrimSpace(tmp.Selector)
-		if tmp.Path == "" {
-			return fmt.Errorf("include path must be a non-empty string")
+		if ok {
+			matches = append(matches, path)
 		}
-		*d = diffConfigInclude(tmp)
-		return nil
-	default:
-		return fmt.Errorf("include entry must be a string or mapping, got %s", node.ShortTag())
 	}
+
+	return matches, nil
 }
 
-func normalizeSnapshotPattern(baseDir, pattern string) (string, error) {
-	pattern = strings.TrimSpace(pattern)
-	if pattern == "" {
-		return "", errors.New("empty include path")
-	}
+// onDisk reports whether the snapshot is read from the filesystem rather
+// than a git tree. The unstaged view mixes both and is handled by Read.
+func (r *snapshotReader) onDisk() bool {
+	return r.snapshot.Kind == SnapshotKindWorkingTree || r.snapshot.Kind == SnapshotKindDirectory
+}
 
-	if filepath.IsAbs(pattern) {
-		return "", fmt.Errorf("absolute include paths are not supported for snapshot loading: %s", pattern)
+// configPath maps the config path under the command's root to the same
+// location in this snapshot, which differs only for directory snapshots.
+func (r *snapshotReader) configPath(root, configPath string) (string, error) {
+	if r.root == root {
+		return configPath, nil
 	}
-
-	joined := filepath.Clean(filepath.Join(baseDir, pattern))
-	if joined == ".." || strings.HasPrefix(joined, ".."+string(filepath.Separator)) {
-		return "", fmt.Errorf("include path resolves outside repository root: %s", pattern)
+	rel, err := rootRelativePath(root, configPath)
+	if err != nil {
+		return "", fmt.Errorf("diff: config path %s: %w", configPath, err)
 	}
-
-	return joined, nil
+	return filepath.Join(r.root, rel), nil
 }
 
-func matchSnapshotPattern(root string, snapshot SnapshotRef, pattern string) ([]string, error) {
-	reader, err := newSnapshotReader(root, snapshot)
-	if err != nil {
-		return nil, err
-	}
-	files, err := reader.ListFiles()
-	if err != nil {
-		return nil, err
+func (r *snapshotReader) Close() error {
+	if r.tree == nil {
+		return nil
 	}
+	return r.tree.Close()
+}
 
-	var matches []string
-	for _, path := range files {
-		ok, err := filepath.Match(pattern, path)
+// FileOps exposes the snapshot through fileutil.Ops so loaders written
+// against the filesystem, such as config.LoadWithOps and data.Store, can read
+// it. Paths are absolute and must resolve inside root.
+func (r *snapshotReader) FileOps() fileutil.Ops {
+	switch {
+	case r.snapshot.Kind == SnapshotKindDirectory:
+		return fileutil.OS
+	case r.snapshot.Kind != SnapshotKindWorkingTree:
+		return r.tree.Ops()
+	case r.snapshot.WorkingTreeView != WorkingTreeViewUnstaged:
+		return fileutil.OS
+	}
+
+	read := func(path string) ([]byte, error) {
+		rel, err := rootRelativePath(r.root, path)
 		if err != nil {
-			return nil, fmt.Errorf("diff: match pattern %s: %w", pattern, err)
+			return nil, &fs.PathError{Op: "open", Path: path, Err: err}
 		}
-		if ok {
-			matches = append(matches, path)
+		content, exists, err := r.Read(rel)
+		if err != nil {
+			return nil, err
 		}
+		if !exists {
+			return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+		}
+		return content, nil
 	}
 
-	return matches, nil
+	return fileutil.Ops{
+		ReadFile: read,
+		Stat: func(path string) (os.FileInfo, error) {
+			content, err := read(path)
+			if err != nil {
+				return nil, err
+			}
+			return snapshotFileInfo{name: filepath.Base(path), size: int64(len(content))}, nil
+		},
+		Glob: func(pattern string) ([]string, error) {
+			rel, err := rootRelativePath(r.root, pattern)
+			if err != nil {
+				return nil, nil
+			}
+			matches, err := r.Match(rel)
+			if err != nil {
+				return nil, err
+			}
+			for idx, match := range matches {
+				matches[idx] = filepath.Join(r.root, match)
+			}
+			return matches, nil
+		},
+	}
+}
+
+type snapshotFileInfo struct {
+	name string
+	size int64
 }
 
+func (i snapshotFileInfo) Name() string       { return i.name }
+func (i snapshotFileInfo) Size() int64        { return i.size }
+func (i snapshotFileInfo) Mode() fs.FileMode  { return 0o644 }
+func (i snapshotFileInfo) ModTime() time.Time { return time.Time{} }
+func (i snapshotFileInfo) IsDir() bool        { return false }
+func (i snapshotFileInfo) Sys() any           { return nil }
+
 func listWorkingTreeFiles(root string) ([]string, error) {
 	var files []string
 	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
@@ -346,15 +430,6 @@ func listWorkingTreeFiles(root string) ([]string, error) {
 	return files, nil
 }
 
-func listGitTreeFiles(root, revision string) ([]string, error) {
-	lines, err := listGitNames(root, "ls-tree", "-r", "--name-only", revision)
-	if err != nil {
-		return nil, err
-	}
-	sort.Strings(lines)
-	return lines, nil
-}
-
 func listGitNames(root string, args ...string) ([]string, error) {
 	output, err := runGit(root, args...)
 	if err != nil {
@@ -382,24 +457,6 @@ func readWorkingTreeFile(root, path string) ([]byte, bool, error) {
 	return nil, false, fmt.Errorf("diff: read working tree file %s: %w", path, err)
 }
 
-func readGitRevisionFile(root, revision, path string) ([]byte, bool, error) {
-	spec := revision + ":" + path
-	cmd := exec.Command("git", "-C", root, "cat-file", "-e", spec)
-	if err := cmd.Run(); err != nil {
-		var exitErr *exec.ExitError
-		if errors.As(err, &exitErr) {
-			return nil, false, nil
-		}
-		return nil, false, fmt.Errorf("diff: inspect %s: %w", spec, err)
-	}
-
-	content, err := runGit(root, "show", spec)
-	if err != nil {
-		return nil, false, err
-	}
-	return content, true, nil
-}
-
 func runGit(root string, args ...string) ([]byte, error) {
 	cmd := exec.Command("git", append([]string{"-C", root}, args...)...)
 	output, err := cmd.CombinedOutput()
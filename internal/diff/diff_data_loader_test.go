@@ -29,10 +29,17 @@ func TestLoadDiffDataCorporaIgnoresConfigurationOnlyChanges(t *testing.T) {
 func TestLoadSnapshotDataIncludePatterns(t *testing.T) {
 	repo := newGitRepoFixture(t)
 
-	patterns, err := loadSnapshotDataIncludePatterns(repo.Root, filepath.Join(repo.Root, "mergeway.yaml"), headSnapshot())
+	reader, err := newSnapshotReader(repo.Root, headSnapshot())
 	if err != nil {
-		t.Fatalf("load snapshot data include patterns: %v", err)
+		t.Fatalf("open snapshot reader: %v", err)
 	}
+	defer reader.Close()
+
+	schema, err := loadSnapshotDiffSchema(reader, filepath.Join(repo.Root, "mergeway.yaml"))
+	if err != nil {
+		t.Fatalf("load snapshot diff schema: %v", err)
+	}
+	patterns := schema.includePatterns()
 
 	expected := []string{
 		"data/posts/*.yaml",
@@ -182,6 +189,29 @@ func TestLoadSnapshotDataCorpusSupportsWorkingTreeFullView(t *testing.T) {
 	}
 }
 
+func TestLoadDiffDataCorporaSupportsIndexSnapshot(t *testing.T) {
+	repo := newGitRepoFixture(t)
+	repo.StageDataChange(t, "data/users/user-bob.yaml", "id: User-Bob\nname: Bob Staged\nemail: bob@example.com\n")
+	repo.WriteDataChange(t, "data/users/user-bob.yaml", "id: User-Bob\nname: Bob Unstaged\nemail: bob@example.com\n")
+	repo.WriteDataChange(t, "data/tags/tag-product.yaml", "id: Tag-Product\nlabel: Product Unstaged\n")
+
+	staged := mustLoadDiffDataCorpora(t, repo.Root, headSnapshot(), indexSnapshot())
+	expected := []string{"data/users/user-bob.yaml"}
+	if changed := changedCorpusPaths(staged); !reflect.DeepEqual(changed, expected) {
+		t.Fatalf("expected only staged change %v, got %v", expected, changed)
+	}
+	bob := corpusFile(t, staged.Right, "data/users/user-bob.yaml")
+	if !bytes.Contains(bob.Content, []byte("Bob Staged")) {
+		t.Fatalf("expected the index to hold the staged Bob, got %s", bob.Content)
+	}
+
+	unstaged := mustLoadDiffDataCorpora(t, repo.Root, indexSnapshot(), workingTreeSnapshot(WorkingTreeViewFull))
+	expected = []string{"data/tags/tag-product.yaml", "data/users/user-bob.yaml"}
+	if changed := changedCorpusPaths(unstaged); !reflect.DeepEqual(changed, expected) {
+		t.Fatalf("expected unstaged changes %v, got %v", expected, changed)
+	}
+}
+
 func mustLoadDiffDataCorpora(t *testing.T, root string, left, right SnapshotRef) DiffDataCorpora {
 	t.Helper()
 	corpora, err := loadDiffDataCorpora(root, filepath.Join(root, "mergeway.yaml"), left, right)
@@ -251,6 +281,10 @@ func revisionSnapshot(revision string) SnapshotRef {
 	}
 }
 
+func indexSnapshot() SnapshotRef {
+	return SnapshotRef{Kind: SnapshotKindIndex}
+}
+
 func workingTreeSnapshot(view WorkingTreeView) SnapshotRef {
 	return SnapshotRef{
 		Kind:            SnapshotKindWorkingTree,
@@ -15,13 +15,23 @@ const (
 )
 
 func FormatCommandError(err error) string {
+	return formatCommandError("diff", err)
+}
+
+// FormatMergeCommandError formats errors from Merge with the same categories
+// as diff errors.
+func FormatMergeCommandError(err error) string {
+	return formatCommandError("merge", err)
+}
+
+func formatCommandError(command string, err error) string {
 	if err == nil {
 		return ""
 	}
 
 	category := classifyDiffCommandError(err)
 	message := normalizeDiffErrorMessage(err.Error())
-	return "diff: " + string(category) + ": " + message
+	return command + ": " + string(category) + ": " + message
 }
 
 func classifyDiffCommandError(err error) diffErrorCategory {
@@ -29,7 +39,10 @@ func classifyDiffCommandError(err error) diffErrorCategory {
 		return diffErrorCategoryInternal
 	}
 
-	if errors.Is(err, ErrTooManyArgs) {
+	if errors.Is(err, ErrTooManyArgs) || errors.Is(err, ErrMergeArgs) || errors.Is(err, ErrMergeDriverArgs) ||
+		errors.Is(err, ErrUnsupportedFormat) || errors.Is(err, ErrInvalidFilter) || errors.Is(err, ErrLogArgs) ||
+		errors.Is(err, ErrInvalidSnapshot) || errors.Is(err, ErrInvalidPatch) ||
+		errors.Is(err, ErrInvalidRenameThreshold) {
 		return diffErrorCategoryInput
 	}
 
@@ -47,9 +60,11 @@ func classifyDiffCommandError(err error) diffErrorCategory {
 			return diffErrorCategoryRepository
 		}
 		return diffErrorCategoryInput
-	case strings.Contains(message, "not a git repository"):
+	case strings.Contains(message, "not a git repository"),
+		strings.Contains(message, "would be overwritten"):
 		return diffErrorCategoryRepository
 	case strings.Contains(message, "config file "),
+		strings.Contains(message, "load config"),
 		strings.Contains(message, "mergeway block is required"),
 		strings.Contains(message, "mergeway.version"),
 		strings.Contains(message, "entity "),
@@ -67,5 +82,6 @@ func classifyDiffCommandError(err error) diffErrorCategory {
 func normalizeDiffErrorMessage(message string) string {
 	message = strings.TrimSpace(message)
 	message = strings.TrimPrefix(message, "diff: ")
+	message = strings.TrimPrefix(message, "merge: ")
 	return message
 }
@@ -0,0 +1,171 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadDiffLogicalDatabases builds the logical database for both sides of a
+// diff. Git and directory snapshots go through the shared data corpus; export
+// snapshots are read directly, using the config of another side, or of the
+// working tree, to identify their objects.
+func loadDiffLogicalDatabases(root, configPath string, left, right SnapshotRef) (LogicalDatabase, LogicalDatabase, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return LogicalDatabase{}, LogicalDatabase{}, fmt.Errorf("diff: resolve root: %w", err)
+	}
+	absConfig, err := filepath.Abs(configPath)
+	if err != nil {
+		return LogicalDatabase{}, LogicalDatabase{}, fmt.Errorf("diff: resolve config path: %w", err)
+	}
+
+	readers := newSnapshotReaders(absRoot)
+	defer readers.Close()
+
+	snapshots := []SnapshotRef{left, right}
+	var stored []SnapshotRef
+	for _, snapshot := range snapshots {
+		if snapshot.Kind != SnapshotKindExport {
+			stored = append(stored, snapshot)
+		}
+	}
+	sides, err := readers.openAll(absConfig, stored...)
+	if err != nil {
+		return LogicalDatabase{}, LogicalDatabase{}, err
+	}
+	paths, err := discoverDiffDataPaths(sides)
+	if err != nil {
+		return LogicalDatabase{}, LogicalDatabase{}, err
+	}
+
+	var exportSchema *diffSnapshotSchema
+	if len(sides) > 0 {
+		exportSchema = sides[0].schema
+	} else if exportSchema, err = loadWorkingTreeDiffSchema(readers, absConfig); err != nil {
+		return LogicalDatabase{}, LogicalDatabase{}, err
+	}
+
+	var dbs [2]LogicalDatabase
+	next := 0
+	for idx, snapshot := range snapshots {
+		if snapshot.Kind == SnapshotKindExport {
+			if dbs[idx], err = loadExportLogicalDatabase(snapshot, exportSchema); err != nil {
+				return LogicalDatabase{}, LogicalDatabase{}, err
+			}
+			continue
+		}
+		corpus, err := loadSnapshotDataCorpus(sides[next], paths)
+		if err != nil {
+			return LogicalDatabase{}, LogicalDatabase{}, err
+		}
+		next++
+		if dbs[idx], err = buildLogicalDatabase(corpus); err != nil {
+			return LogicalDatabase{}, LogicalDatabase{}, err
+		}
+	}
+	return dbs[0], dbs[1], nil
+}
+
+// loadWorkingTreeDiffSchema loads the schema from the working tree config,
+// or returns nil when there is none.
+func loadWorkingTreeDiffSchema(readers *snapshotReaders, configPath string) (*diffSnapshotSchema, error) {
+	if _, err := os.Stat(configPath); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	reader, err := readers.Get(SnapshotRef{Kind: SnapshotKindWorkingTree, WorkingTreeView: WorkingTreeViewFull})
+	if err != nil {
+		return nil, err
+	}
+	return loadSnapshotDiffSchema(reader, configPath)
+}
+
+// loadExportLogicalDatabase reads a `mergeway-cli export` file in YAML or
+// JSON: a map from type name to that type's objects. Identifiers follow
+// schema; a type it does not declare is identified by its "id" field. Each
+// object's source is the export file, so objects never count as relocated.
+func loadExportLogicalDatabase(snapshot SnapshotRef, schema *diffSnapshotSchema) (LogicalDatabase, error) {
+	content, err := os.ReadFile(snapshot.Path)
+	if err != nil {
+		return LogicalDatabase{}, fmt.Errorf("diff: read export %s: %w", snapshot.Path, err)
+	}
+
+	var doc map[string][]map[string]any
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return LogicalDatabase{}, &LogicalDatabaseBuildError{
+			Kind:     LogicalDatabaseErrorParse,
+			Snapshot: snapshot,
+			Path:     snapshot.Path,
+			Err:      fmt.Errorf("parse export: %w", err),
+		}
+	}
+
+	names := make([]string, 0, len(doc))
+	for name := range doc {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	db := LogicalDatabase{Snapshot: snapshot, Schema: schema}
+	seen := make(map[string]struct{})
+	for _, typeName := range names {
+		typeDef := &diffSnapshotType{Name: typeName, IdentifierField: "id"}
+		if schema != nil && schema.Types[typeName] != nil {
+			typeDef = schema.Types[typeName]
+		}
+		buildErr := func(kind LogicalDatabaseErrorKind, id string, err error) error {
+			return &LogicalDatabaseBuildError{
+				Kind:     kind,
+				Snapshot: snapshot,
+				TypeName: typeName,
+				ObjectID: id,
+				Path:     snapshot.Path,
+				Err:      err,
+			}
+		}
+		if typeDef.identifierIsPath() {
+			return LogicalDatabase{}, buildErr(LogicalDatabaseErrorInvalidObject, "", fmt.Errorf("type is identified by file path, which an export does not record"))
+		}
+
+		for idx, fields := range doc[typeName] {
+			id, err := deriveLogicalObjectID(typeDef, fields, "")
+			if err != nil {
+				return LogicalDatabase{}, buildErr(LogicalDatabaseErrorInvalidObject, "", fmt.Errorf("item %d: %w", idx+1, err))
+			}
+			key := logicalObjectMapKey(typeName, id)
+			if _, ok := seen[key]; ok {
+				return LogicalDatabase{}, buildErr(LogicalDatabaseErrorIdentityCollision, id, fmt.Errorf("object listed more than once"))
+			}
+			seen[key] = struct{}{}
+
+			canonical, err := canonicalizeLogicalFields(fields)
+			if err != nil {
+				return LogicalDatabase{}, buildErr(LogicalDatabaseErrorInvalidObject, id, err)
+			}
+			db.Objects = append(db.Objects, LogicalObject{
+				Type:      typeName,
+				ID:        id,
+				Fields:    fields,
+				Canonical: canonical,
+				Sources: []LogicalObjectSource{{
+					Path:     filepath.ToSlash(snapshot.Path),
+					Selector: "$." + typeName,
+					ReadOnly: true,
+				}},
+			})
+		}
+	}
+
+	sort.Slice(db.Objects, func(i, j int) bool {
+		if db.Objects[i].Type != db.Objects[j].Type {
+			return db.Objects[i].Type < db.Objects[j].Type
+		}
+		return db.Objects[i].ID < db.Objects[j].ID
+	})
+	return db, nil
+}
@@ -0,0 +1,139 @@
+package diff
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mergewayhq/mergeway-cli/internal/config"
+	"github.com/mergewayhq/mergeway-cli/internal/data"
+	"github.com/mergewayhq/mergeway-cli/internal/testutil"
+)
+
+// writeExport writes what `mergeway-cli export --format json` writes for the
+// repository at root.
+func writeExport(t *testing.T, root, path string) {
+	t.Helper()
+	cfg, err := config.Load(filepath.Join(root, "mergeway.yaml"))
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	store, err := data.NewStore(root, cfg)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	export := make(map[string]any)
+	for typeName := range cfg.Types {
+		objects, err := store.LoadAll(typeName)
+		if err != nil {
+			t.Fatalf("LoadAll(%s): %v", typeName, err)
+		}
+		records := make([]map[string]any, len(objects))
+		for idx, obj := range objects {
+			records[idx] = obj.Fields
+		}
+		export[typeName] = records
+	}
+	payload, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal export: %v", err)
+	}
+	if err := os.WriteFile(path, payload, 0o644); err != nil {
+		t.Fatalf("write export: %v", err)
+	}
+}
+
+func TestRunComparesDirectoriesWithoutGit(t *testing.T) {
+	left := testutil.CopyFixture(t)
+	right := testutil.CopyFixture(t)
+	if err := os.WriteFile(filepath.Join(right, "data", "users", "user-bob.yaml"), []byte("id: User-Bob\nname: Robert Example\nemail: bob@example.com\nrole: editor\n"), 0o644); err != nil {
+		t.Fatalf("write bob: %v", err)
+	}
+	if err := os.Remove(filepath.Join(right, "data", "tags", "tag-writing.yaml")); err != nil {
+		t.Fatalf("remove tag: %v", err)
+	}
+
+	output, err := Run(Options{
+		Root:   left,
+		Config: filepath.Join(left, "mergeway.yaml"),
+		Args:   []string{"dir:" + left, "dir:" + right},
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := "MODIFIED User[User-Bob]\n  name: \"Bob Example\" -> \"Robert Example\"\n"
+	if !strings.Contains(output, want) || !strings.Contains(output, "REMOVED Tag[Tag-Writing]\n") {
+		t.Fatalf("unexpected directory diff:\n%s", output)
+	}
+}
+
+func TestRunComparesRevisionWithItsExport(t *testing.T) {
+	repo := newParityRepoFixture(t)
+	exportPath := filepath.Join(t.TempDir(), "dump.json")
+	writeExport(t, repo.Root, exportPath)
+	opts := Options{
+		Root:   repo.Root,
+		Config: filepath.Join(repo.Root, "mergeway.yaml"),
+		Args:   []string{"HEAD", "export:" + exportPath},
+	}
+
+	output, err := Run(opts)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if output != "No changes.\n" {
+		t.Fatalf("expected the export to match HEAD, got:\n%s", output)
+	}
+
+	repo.WriteDataChange(t, "data/users/user-alice.yaml", "id: User-Alice\nname: Alice Exported\nemail: alice@example.com\nrole: admin\n")
+	writeExport(t, repo.Root, exportPath)
+	output, err = Run(opts)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := "MODIFIED User[User-Alice]\n  from: data/users/user-alice.yaml\n  to: " + filepath.ToSlash(exportPath) + " @ $.User\n  name: \"Alice Example\" -> \"Alice Exported\"\n"
+	if output != want {
+		t.Fatalf("unexpected export diff\nwant:\n%s\ngot:\n%s", want, output)
+	}
+}
+
+func TestRunComparesExportsByIDWithoutConfig(t *testing.T) {
+	dir := t.TempDir()
+	left := filepath.Join(dir, "left.yaml")
+	right := filepath.Join(dir, "right.json")
+	if err := os.WriteFile(left, []byte("Widget:\n  - id: w1\n    size: 1\n  - id: w2\n    size: 2\n"), 0o644); err != nil {
+		t.Fatalf("write left: %v", err)
+	}
+	if err := os.WriteFile(right, []byte(`{"Widget": [{"id": "w1", "size": 3}]}`), 0o644); err != nil {
+		t.Fatalf("write right: %v", err)
+	}
+
+	output, err := Run(Options{
+		Root:   dir,
+		Config: filepath.Join(dir, "mergeway.yaml"),
+		Args:   []string{"export:" + left, "export:" + right},
+		Format: OutputFormatJSON,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	var doc diffJSONDocument
+	if err := json.Unmarshal([]byte(output), &doc); err != nil {
+		t.Fatalf("decode: %v\n%s", err, output)
+	}
+	if len(doc.Entries) != 2 || doc.Entries[0].ObjectID != "w1" || doc.Entries[0].Kind != DiffEntryKindModified || doc.Entries[1].Kind != DiffEntryKindRemoved {
+		t.Fatalf("unexpected export diff %+v", doc.Entries)
+	}
+}
+
+func TestParseSnapshotArgRejectsMissingPaths(t *testing.T) {
+	root := t.TempDir()
+	for _, arg := range []string{"dir:" + filepath.Join(root, "missing"), "export:" + root} {
+		if _, err := parseSnapshotArg(root, arg); !errors.Is(err, ErrInvalidSnapshot) {
+			t.Fatalf("expected ErrInvalidSnapshot for %s, got %v", arg, err)
+		}
+	}
+}
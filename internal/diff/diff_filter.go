@@ -0,0 +1,218 @@
+package diff
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// DiffFilter scopes a semantic diff. Empty lists do not filter. IDs, Fields,
+// and IgnoreFields are globs (path.Match syntax); a field glob also matches
+// every path nested below a matching path, so "profile" covers
+// "profile.name".
+type DiffFilter struct {
+	Types        []string
+	IDs          []string
+	Fields       []string
+	Kinds        []DiffEntryKind
+	IgnoreFields []string
+}
+
+func (f DiffFilter) empty() bool {
+	return len(f.Types) == 0 && len(f.IDs) == 0 && len(f.Fields) == 0 && len(f.Kinds) == 0 && len(f.IgnoreFields) == 0
+}
+
+func (f DiffFilter) validate() error {
+	for _, kind := range f.Kinds {
+		switch kind {
+		case DiffEntryKindAdded, DiffEntryKindRemoved, DiffEntryKindModified, DiffEntryKindRelocated, DiffEntryKindRenamed:
+		default:
+			return fmt.Errorf("%w: unknown kind %q (expected added, removed, modified, relocated, or renamed)", ErrInvalidFilter, kind)
+		}
+	}
+	for _, patterns := range [][]string{f.IDs, f.Fields, f.IgnoreFields} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%w: bad pattern %q", ErrInvalidFilter, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// applyDiffFilter narrows a diff result. Ignored fields are removed first, so
+// a modification that only touched ignored fields disappears (or becomes a
+// relocation when the object also moved) before the kind filter runs.
+func applyDiffFilter(result DiffResult, filter DiffFilter) DiffResult {
+	if filter.empty() {
+		return result
+	}
+
+	filtered := DiffResult{Entries: make([]DiffEntry, 0, len(result.Entries))}
+	for _, entry := range result.Entries {
+		if len(filter.IgnoreFields) > 0 {
+			var ok bool
+			if entry, ok = ignoreDiffFields(entry, filter.IgnoreFields); !ok {
+				continue
+			}
+		}
+		if len(filter.Kinds) > 0 && !containsKind(filter.Kinds, entry.Kind) {
+			continue
+		}
+		if len(filter.Types) > 0 && !containsString(filter.Types, entry.Type) {
+			continue
+		}
+		if len(filter.IDs) > 0 && !matchesAnyGlob(filter.IDs, entry.ObjectID) &&
+			(entry.OldObjectID == "" || !matchesAnyGlob(filter.IDs, entry.OldObjectID)) {
+			continue
+		}
+		if len(filter.Fields) > 0 {
+			var ok bool
+			if entry, ok = keepDiffFields(entry, filter.Fields); !ok {
+				continue
+			}
+		}
+		filtered.Entries = append(filtered.Entries, entry)
+	}
+	return filtered
+}
+
+// ignoreDiffFields drops ignored fields from an entry's values and changes.
+// It reports false when nothing is left to show.
+func ignoreDiffFields(entry DiffEntry, patterns []string) (DiffEntry, bool) {
+	entry.OldValue = withoutFieldPaths(entry.OldValue, "", patterns)
+	entry.NewValue = withoutFieldPaths(entry.NewValue, "", patterns)
+	if entry.Kind != DiffEntryKindModified && entry.Kind != DiffEntryKindRenamed {
+		return entry, true
+	}
+
+	entry.FieldChanges = filterFieldChanges(entry.FieldChanges, func(change DiffFieldChange) bool {
+		return !fieldPathMatches(patterns, change.Path)
+	})
+	if len(entry.FieldChanges) > 0 || entry.Kind == DiffEntryKindRenamed {
+		return entry, true
+	}
+	if semanticSourcesEqual(entry.OldSources, entry.NewSources) {
+		return entry, false
+	}
+	entry.Kind = DiffEntryKindRelocated
+	return entry, true
+}
+
+// keepDiffFields narrows an entry to the fields matching patterns. Modified
+// and renamed entries keep their matching field changes; added, removed, and
+// relocated entries keep the matching fields of their values. It reports false
+// when nothing matches.
+func keepDiffFields(entry DiffEntry, patterns []string) (DiffEntry, bool) {
+	if entry.Kind == DiffEntryKindModified || entry.Kind == DiffEntryKindRenamed {
+		entry.FieldChanges = filterFieldChanges(entry.FieldChanges, func(change DiffFieldChange) bool {
+			return fieldPathMatches(patterns, change.Path)
+		})
+		return entry, len(entry.FieldChanges) > 0
+	}
+
+	entry.OldValue = onlyFieldPaths(entry.OldValue, "", patterns)
+	entry.NewValue = onlyFieldPaths(entry.NewValue, "", patterns)
+	return entry, len(entry.OldValue) > 0 || len(entry.NewValue) > 0
+}
+
+// onlyFieldPaths keeps the fields whose dotted path, or a parent of it,
+// matches patterns. Nested objects are kept with their matching fields.
+func onlyFieldPaths(value map[string]any, prefix string, patterns []string) map[string]any {
+	if value == nil {
+		return nil
+	}
+
+	out := make(map[string]any)
+	for key, field := range value {
+		fieldPath := key
+		if prefix != "" {
+			fieldPath = prefix + "." + key
+		}
+		if fieldPathMatches(patterns, fieldPath) {
+			out[key] = field
+			continue
+		}
+		if nested, ok := field.(map[string]any); ok {
+			if kept := onlyFieldPaths(nested, fieldPath, patterns); len(kept) > 0 {
+				out[key] = kept
+			}
+		}
+	}
+	return out
+}
+
+func withoutFieldPaths(value map[string]any, prefix string, patterns []string) map[string]any {
+	if value == nil {
+		return nil
+	}
+
+	out := make(map[string]any, len(value))
+	for key, field := range value {
+		fieldPath := key
+		if prefix != "" {
+			fieldPath = prefix + "." + key
+		}
+		if matchesAnyGlob(patterns, fieldPath) {
+			continue
+		}
+		if nested, ok := field.(map[string]any); ok {
+			out[key] = withoutFieldPaths(nested, fieldPath, patterns)
+			continue
+		}
+		out[key] = field
+	}
+	return out
+}
+
+func filterFieldChanges(changes []DiffFieldChange, keep func(DiffFieldChange) bool) []DiffFieldChange {
+	var out []DiffFieldChange
+	for _, change := range changes {
+		if keep(change) {
+			out = append(out, change)
+		}
+	}
+	return out
+}
+
+// fieldPathMatches reports whether any pattern matches fieldPath or one of
+// its dotted parents.
+func fieldPathMatches(patterns []string, fieldPath string) bool {
+	for candidate := fieldPath; ; {
+		if matchesAnyGlob(patterns, candidate) {
+			return true
+		}
+		idx := strings.LastIndex(candidate, ".")
+		if idx < 0 {
+			return false
+		}
+		candidate = candidate[:idx]
+	}
+}
+
+func matchesAnyGlob(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsKind(kinds []DiffEntryKind, kind DiffEntryKind) bool {
+	for _, candidate := range kinds {
+		if candidate == kind {
+			return true
+		}
+	}
+	return false
+}
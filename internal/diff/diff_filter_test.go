@@ -0,0 +1,113 @@
+package diff
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func filterTestResult() DiffResult {
+	return DiffResult{Entries: []DiffEntry{
+		{
+			Kind:       DiffEntryKindAdded,
+			Type:       "Tag",
+			ObjectID:   "Tag-New",
+			NewValue:   map[string]any{"id": "Tag-New", "updated_at": "2024-02-01"},
+			NewSources: []LogicalObjectSource{{Path: "data/tags/tag-new.yaml"}},
+		},
+		{
+			Kind:       DiffEntryKindModified,
+			Type:       "User",
+			ObjectID:   "User-Alice",
+			OldSources: []LogicalObjectSource{{Path: "data/users/user-alice.yaml"}},
+			NewSources: []LogicalObjectSource{{Path: "data/users/user-alice.yaml"}},
+			FieldChanges: []DiffFieldChange{
+				{Path: "name", OldValue: "Alice", NewValue: "Alice Updated"},
+				{Path: "profile.bio", OldValue: "old", NewValue: "new"},
+				{Path: "updated_at", OldValue: "2024-01-01", NewValue: "2024-02-01"},
+			},
+		},
+		{
+			Kind:       DiffEntryKindModified,
+			Type:       "User",
+			ObjectID:   "User-Bob",
+			OldSources: []LogicalObjectSource{{Path: "data/users/user-bob.yaml"}},
+			NewSources: []LogicalObjectSource{{Path: "data/users/user-bob.yaml"}},
+			FieldChanges: []DiffFieldChange{
+				{Path: "updated_at", OldValue: "2024-01-01", NewValue: "2024-02-01"},
+			},
+		},
+		{
+			Kind:       DiffEntryKindModified,
+			Type:       "User",
+			ObjectID:   "User-Carol",
+			OldSources: []LogicalObjectSource{{Path: "data/users/user-carol.yaml"}},
+			NewSources: []LogicalObjectSource{{Path: "data/users/all.yaml", Selector: "$.items[*]"}},
+			FieldChanges: []DiffFieldChange{
+				{Path: "updated_at", OldValue: "2024-01-01", NewValue: "2024-02-01"},
+			},
+		},
+	}}
+}
+
+func TestApplyDiffFilterByTypeIDAndKind(t *testing.T) {
+	result := applyDiffFilter(filterTestResult(), DiffFilter{
+		Types: []string{"User"},
+		IDs:   []string{"User-A*", "User-Carol"},
+		Kinds: []DiffEntryKind{DiffEntryKindModified},
+	})
+
+	if got, want := diffEntryKeys(result), []string{"modified:User:User-Alice", "modified:User:User-Carol"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected entries: got %q want %q", got, want)
+	}
+}
+
+func TestApplyDiffFilterByFieldKeepsMatchingChangesOnly(t *testing.T) {
+	result := applyDiffFilter(filterTestResult(), DiffFilter{Fields: []string{"profile"}})
+
+	if len(result.Entries) != 1 || result.Entries[0].ObjectID != "User-Alice" {
+		t.Fatalf("expected only User-Alice, got %#v", result.Entries)
+	}
+	want := []DiffFieldChange{{Path: "profile.bio", OldValue: "old", NewValue: "new"}}
+	if !reflect.DeepEqual(result.Entries[0].FieldChanges, want) {
+		t.Fatalf("unexpected field changes: %#v", result.Entries[0].FieldChanges)
+	}
+}
+
+func TestApplyDiffFilterByFieldKeepsAddedObjectsWithMatchingFields(t *testing.T) {
+	result := applyDiffFilter(filterTestResult(), DiffFilter{Fields: []string{"updated_at"}})
+
+	want := []string{"added:Tag:Tag-New", "modified:User:User-Alice", "modified:User:User-Bob", "modified:User:User-Carol"}
+	if got := diffEntryKeys(result); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected entries: got %q want %q", got, want)
+	}
+	if got, want := result.Entries[0].NewValue, map[string]any{"updated_at": "2024-02-01"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected the added value to be trimmed to the matching field, got %#v", got)
+	}
+}
+
+func TestApplyDiffFilterIgnoreFieldDropsNoiseOnlyChanges(t *testing.T) {
+	result := applyDiffFilter(filterTestResult(), DiffFilter{IgnoreFields: []string{"updated_at"}})
+
+	var kinds []string
+	for _, entry := range result.Entries {
+		kinds = append(kinds, entry.ObjectID+" "+string(entry.Kind))
+	}
+	want := []string{"Tag-New added", "User-Alice modified", "User-Carol relocated"}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("unexpected entries: got %v want %v", kinds, want)
+	}
+	if _, ok := result.Entries[0].NewValue["updated_at"]; ok {
+		t.Fatalf("expected ignored field to be removed from added value: %#v", result.Entries[0].NewValue)
+	}
+	if len(result.Entries[1].FieldChanges) != 2 {
+		t.Fatalf("expected ignored change to be dropped: %#v", result.Entries[1].FieldChanges)
+	}
+}
+
+func TestDiffFilterRejectsUnknownKind(t *testing.T) {
+	err := DiffFilter{Kinds: []DiffEntryKind{"moved"}}.validate()
+	if !errors.Is(err, ErrInvalidFilter) {
+		t.Fatalf("expected ErrInvalidFilter, got %v", err)
+	}
+}
@@ -0,0 +1,470 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	internalconfig "github.com/mergewayhq/mergeway-cli/internal/config"
+)
+
+var ErrObjectNotFound = errors.New("object not found")
+
+// HistoryOptions selects one object and the revision its history is read
+// back from. Revision defaults to HEAD.
+type HistoryOptions struct {
+	Root     string
+	Config   string
+	Type     string
+	ID       string
+	Revision string
+}
+
+// ObjectCommit is a commit that semantically changed one object. Fields holds
+// before/after pairs for modifications and the whole object for additions and
+// removals; At and MovedFrom locate the object after the commit.
+type ObjectCommit struct {
+	Commit    string
+	Author    string
+	Date      string
+	Subject   string
+	Kind      DiffEntryKind
+	Fields    []DiffFieldChange
+	At        string
+	MovedFrom string
+}
+
+// FieldBlame attributes a field's current value to the commit that last
+// changed it. Commit is nil when that commit is outside the walked history.
+type FieldBlame struct {
+	Field  string
+	Value  any
+	Commit *ObjectCommit
+}
+
+// History lists the commits that changed an object, newest first.
+//
+// Commits are found with `git log` limited to the type's include globs and
+// the config files at the starting revision, so an object is followed across
+// files, but data that lived elsewhere before an include change is not. Each
+// commit is compared with its parents through the logical database, so
+// formatting-only edits and unrelated objects in the same file never show
+// up, and a merge counts only when the object differs from every parent.
+// Commits whose config or data does not load are skipped, and the next commit
+// is compared with the nearest ancestor that loads.
+func History(opts HistoryOptions) ([]ObjectCommit, error) {
+	walker, err := newObjectHistoryWalker(opts)
+	if err != nil {
+		return nil, err
+	}
+	return walker.history()
+}
+
+func (w *objectHistoryWalker) history() ([]ObjectCommit, error) {
+	commits, err := w.commits()
+	if err != nil {
+		return nil, err
+	}
+
+	var history []ObjectCommit
+	for _, commit := range commits {
+		entry, changed, err := w.change(commit)
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			continue
+		}
+		rows := diffEntryRows(entry)
+		at, movedFrom := diffEntryLocation(entry)
+		history = append(history, ObjectCommit{
+			Commit:    commit.Hash,
+			Author:    commit.Author,
+			Date:      commit.Date,
+			Subject:   commit.Subject,
+			Kind:      entry.Kind,
+			Fields:    rows,
+			At:        at,
+			MovedFrom: movedFrom,
+		})
+	}
+
+	if len(history) == 0 {
+		return nil, fmt.Errorf("diff: %w: %s %q has no history at %s", ErrObjectNotFound, w.typeName, w.id, w.revision)
+	}
+	return history, nil
+}
+
+// Blame attributes each top-level field of the object at the starting
+// revision to the newest commit in its history that changed it.
+func Blame(opts HistoryOptions) ([]FieldBlame, error) {
+	walker, err := newObjectHistoryWalker(opts)
+	if err != nil {
+		return nil, err
+	}
+	current, err := walker.objectAt(walker.revision)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, fmt.Errorf("diff: %w: %s %q does not exist at %s", ErrObjectNotFound, opts.Type, opts.ID, walker.revision)
+	}
+
+	history, err := walker.history()
+	if err != nil {
+		return nil, err
+	}
+
+	changedBy := make(map[string]*ObjectCommit)
+	for idx := len(history) - 1; idx >= 0; idx-- {
+		commit := &history[idx]
+		switch commit.Kind {
+		case DiffEntryKindAdded:
+			changedBy = make(map[string]*ObjectCommit)
+			for _, row := range commit.Fields {
+				changedBy[row.Path] = commit
+			}
+		case DiffEntryKindRemoved:
+			changedBy = make(map[string]*ObjectCommit)
+		case DiffEntryKindModified:
+			for _, row := range commit.Fields {
+				field, _, _ := strings.Cut(row.Path, ".")
+				changedBy[field] = commit
+			}
+		}
+	}
+
+	blame := make([]FieldBlame, 0, len(current.Fields))
+	for _, row := range diffEntryRows(DiffEntry{Kind: DiffEntryKindAdded, NewValue: current.Fields}) {
+		blame = append(blame, FieldBlame{
+			Field:  row.Path,
+			Value:  row.NewValue,
+			Commit: changedBy[row.Path],
+		})
+	}
+	return blame, nil
+}
+
+type historyCommit struct {
+	Hash    string
+	Parents []string
+	Author  string
+	Date    string
+	Subject string
+}
+
+type objectHistoryWalker struct {
+	root       string
+	configPath string
+	configRel  string
+	typeName   string
+	id         string
+	revision   string
+	pathspecs  []string
+
+	// objects caches the object at each commit; nil means absent.
+	objects map[string]*LogicalObject
+	// unreadable caches the revisions whose config or data does not load.
+	unreadable map[string]error
+}
+
+func newObjectHistoryWalker(opts HistoryOptions) (*objectHistoryWalker, error) {
+	absRoot, err := filepath.Abs(opts.Root)
+	if err != nil {
+		return nil, fmt.Errorf("diff: resolve root: %w", err)
+	}
+	absConfig, err := filepath.Abs(opts.Config)
+	if err != nil {
+		return nil, fmt.Errorf("diff: resolve config path: %w", err)
+	}
+	configRel, err := rootRelativePath(absRoot, absConfig)
+	if err != nil {
+		return nil, fmt.Errorf("diff: config path %s: %w", absConfig, err)
+	}
+	revision := opts.Revision
+	if revision == "" {
+		revision = "HEAD"
+	}
+	if err := validateGitRevision(absRoot, revision); err != nil {
+		return nil, err
+	}
+
+	w := &objectHistoryWalker{
+		root:       absRoot,
+		configPath: absConfig,
+		configRel:  filepath.ToSlash(configRel),
+		typeName:   opts.Type,
+		id:         opts.ID,
+		revision:   revision,
+		objects:    make(map[string]*LogicalObject),
+		unreadable: make(map[string]error),
+	}
+	if err := w.loadPathspecs(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// loadPathspecs limits the walk to the type's data files and every config
+// file, since inline records, derived fields, and identifiers live there.
+func (w *objectHistoryWalker) loadPathspecs() error {
+	reader, err := newSnapshotReader(w.root, SnapshotRef{Kind: SnapshotKindRevision, Revision: w.revision})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	cfg, err := loadSnapshotConfig(reader, w.configPath)
+	if err != nil {
+		return err
+	}
+	typeDef := cfg.Types[w.typeName]
+	if typeDef == nil {
+		return fmt.Errorf("diff: unknown type %s at %s", w.typeName, w.revision)
+	}
+
+	var specs pathspecSet
+	specs.addConfig(w.root, w.configRel, cfg, w.typeName)
+	w.pathspecs = specs.specs
+	return nil
+}
+
+// pathspecSet collects deduplicated git pathspecs in insertion order.
+type pathspecSet struct {
+	seen  map[string]struct{}
+	specs []string
+}
+
+func (s *pathspecSet) add(path string) {
+	if s.seen == nil {
+		s.seen = make(map[string]struct{})
+	}
+	if _, ok := s.seen[path]; ok {
+		return
+	}
+	s.seen[path] = struct{}{}
+	s.specs = append(s.specs, ":(glob)"+filepath.ToSlash(path))
+}
+
+// addConfig adds the config entry file, every type's source file, and the
+// include globs of the named types.
+func (s *pathspecSet) addConfig(root, configRel string, cfg *internalconfig.Config, typeNames ...string) {
+	s.add(configRel)
+	for _, name := range slices.Sorted(maps.Keys(cfg.Types)) {
+		if source, err := rootRelativePath(root, cfg.Types[name].Source); err == nil {
+			s.add(source)
+		}
+	}
+	for _, name := range typeNames {
+		typeDef := cfg.Types[name]
+		if typeDef == nil {
+			continue
+		}
+		for _, include := range typeDef.Include {
+			if pattern, err := normalizeSnapshotPattern(".", include.Path); err == nil {
+				s.add(pattern)
+			}
+		}
+	}
+}
+
+func (w *objectHistoryWalker) commits() ([]historyCommit, error) {
+	args := append([]string{"log", "--topo-order", "--format=%H%x1f%P%x1f%an%x1f%aI%x1f%s", w.revision, "--"}, w.pathspecs...)
+	output, err := runGit(w.root, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []historyCommit
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "\x1f", 5)
+		if len(parts) != 5 {
+			return nil, fmt.Errorf("diff: unexpected git log line %q", line)
+		}
+		commits = append(commits, historyCommit{
+			Hash:    parts[0],
+			Parents: strings.Fields(parts[1]),
+			Author:  parts[2],
+			Date:    parts[3],
+			Subject: parts[4],
+		})
+	}
+	return commits, nil
+}
+
+// change compares the object at a commit with its first parent. It reports
+// false when nothing changed, or when a merge kept one parent's object.
+func (w *objectHistoryWalker) change(commit historyCommit) (DiffEntry, bool, error) {
+	after, err := w.objectAt(commit.Hash)
+	if errors.As(err, new(*unreadableRevisionError)) {
+		return DiffEntry{}, false, nil
+	}
+	if err != nil {
+		return DiffEntry{}, false, err
+	}
+
+	parents := commit.Parents
+	if len(parents) == 0 {
+		parents = []string{""}
+	}
+	var first DiffEntry
+	for idx, parent := range parents {
+		var before *LogicalObject
+		if parent != "" {
+			if before, err = w.readableObjectAt(parent); err != nil {
+				return DiffEntry{}, false, err
+			}
+		}
+		entry, changed, err := diffLogicalObjects(before, after)
+		if err != nil {
+			return DiffEntry{}, false, err
+		}
+		if !changed {
+			return DiffEntry{}, false, nil
+		}
+		if idx == 0 {
+			first = entry
+		}
+	}
+	return first, true, nil
+}
+
+// readableObjectAt returns the object at revision or, when revision does not
+// load, at its nearest first-parent ancestor that does.
+func (w *objectHistoryWalker) readableObjectAt(revision string) (*LogicalObject, error) {
+	for revision != "" {
+		obj, err := w.objectAt(revision)
+		if !errors.As(err, new(*unreadableRevisionError)) {
+			return obj, err
+		}
+		if revision, err = firstParent(w.root, revision); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// objectAt builds the object at a revision from the type's data files and
+// inline records, exactly as the diff does. It returns nil when the object,
+// its type, or the config does not exist there, and an
+// *unreadableRevisionError when the config or data does not load.
+func (w *objectHistoryWalker) objectAt(revision string) (*LogicalObject, error) {
+	if obj, ok := w.objects[revision]; ok {
+		return obj, nil
+	}
+	if err, ok := w.unreadable[revision]; ok {
+		return nil, err
+	}
+
+	reader, err := newSnapshotReader(w.root, SnapshotRef{Kind: SnapshotKindRevision, Revision: revision})
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	if _, exists, err := reader.Read(w.configRel); err != nil || !exists {
+		w.objects[revision] = nil
+		return nil, err
+	}
+	schema, err := loadSnapshotDiffSchema(reader, w.configPath)
+	if err != nil {
+		w.unreadable[revision] = &unreadableRevisionError{Revision: revision, Err: err}
+		return nil, w.unreadable[revision]
+	}
+	typeDef := schema.Types[w.typeName]
+	if typeDef == nil {
+		w.objects[revision] = nil
+		return nil, nil
+	}
+
+	pathSet := make(map[string]struct{})
+	for _, include := range typeDef.Includes {
+		matches, err := reader.Match(include.Path)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range matches {
+			pathSet[path] = struct{}{}
+		}
+	}
+	corpus, err := loadSnapshotDataCorpus(snapshotSide{reader: reader, schema: schema}, sortedKeys(pathSet))
+	if err != nil {
+		return nil, err
+	}
+	db, err := buildLogicalDatabase(corpus)
+	if errors.As(err, new(*LogicalDatabaseBuildError)) {
+		w.unreadable[revision] = &unreadableRevisionError{Revision: revision, Err: err}
+		return nil, w.unreadable[revision]
+	}
+	if err != nil {
+		return nil, fmt.Errorf("diff: at %s: %w", shortRevision(revision), err)
+	}
+
+	var found *LogicalObject
+	for idx := range db.Objects {
+		if db.Objects[idx].Type == w.typeName && db.Objects[idx].ID == w.id {
+			found = &db.Objects[idx]
+			break
+		}
+	}
+	w.objects[revision] = found
+	return found, nil
+}
+
+// diffLogicalObjects diffs one object between two revisions, where nil means
+// absent, and reports whether anything changed.
+func diffLogicalObjects(before, after *LogicalObject) (DiffEntry, bool, error) {
+	var left, right LogicalDatabase
+	if before != nil {
+		left.Objects = []LogicalObject{*before}
+	}
+	if after != nil {
+		right.Objects = []LogicalObject{*after}
+	}
+	result, err := diffLogicalDatabases(left, right)
+	if err != nil || len(result.Entries) == 0 {
+		return DiffEntry{}, false, err
+	}
+	return result.Entries[0], true, nil
+}
+
+// unreadableRevisionError reports a revision whose config or data does not
+// load. History and log walks skip such revisions.
+type unreadableRevisionError struct {
+	Revision string
+	Err      error
+}
+
+func (e *unreadableRevisionError) Error() string {
+	return fmt.Sprintf("diff: at %s: %v", shortRevision(e.Revision), e.Err)
+}
+
+func (e *unreadableRevisionError) Unwrap() error {
+	return e.Err
+}
+
+// firstParent returns the first parent of revision, or "" for a root commit.
+func firstParent(root, revision string) (string, error) {
+	output, err := runGit(root, "rev-list", "--parents", "--max-count=1", revision)
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) < 2 {
+		return "", nil
+	}
+	return fields[1], nil
+}
+
+func shortRevision(revision string) string {
+	if len(revision) > 12 {
+		return revision[:12]
+	}
+	return revision
+}
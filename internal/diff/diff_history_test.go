@@ -0,0 +1,193 @@
+package diff
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mergewayhq/mergeway-cli/internal/testutil"
+)
+
+type objectHistoryFixture struct {
+	repo    testutil.GitRepo
+	initial string
+	renamed string
+	moved   string
+	role    string
+}
+
+// newObjectHistoryFixture gives User-Alice a history with a rename, a
+// reformat, an unrelated Bob edit, a move into a multi-item file, and a
+// role change.
+func newObjectHistoryFixture(t *testing.T) objectHistoryFixture {
+	t.Helper()
+	repo := testutil.NewGitRepo(t)
+	f := objectHistoryFixture{repo: repo, initial: repo.Revision(t, "HEAD")}
+
+	f.renamed = repo.CommitDataChange(t, "data/users/user-alice.yaml", "id: User-Alice\nname: Alice Renamed\nemail: alice@example.com\nrole: admin\n")
+	repo.CommitDataChange(t, "data/users/user-alice.yaml", "role: admin\nemail: alice@example.com\nname: Alice Renamed\nid: User-Alice\n")
+	repo.CommitDataChange(t, "data/users/user-bob.yaml", "id: User-Bob\nname: Bob Changed\nemail: bob@example.com\nrole: editor\n")
+
+	if err := os.Remove(filepath.Join(repo.Root, "data", "users", "user-alice.yaml")); err != nil {
+		t.Fatalf("remove alice: %v", err)
+	}
+	repo.WriteDataChange(t, "data/users/team.yaml", "items:\n  - id: User-Alice\n    name: Alice Renamed\n    email: alice@example.com\n    role: admin\n")
+	repo.Git(t, "add", "-A")
+	repo.Git(t, "commit", "-m", "move alice")
+	f.moved = repo.Revision(t, "HEAD")
+
+	f.role = repo.CommitDataChange(t, "data/users/team.yaml", "items:\n  - id: User-Alice\n    name: Alice Renamed\n    email: alice@example.com\n    role: owner\n")
+	return f
+}
+
+func (f objectHistoryFixture) options(id string) HistoryOptions {
+	return HistoryOptions{
+		Root:   f.repo.Root,
+		Config: filepath.Join(f.repo.Root, "mergeway.yaml"),
+		Type:   "User",
+		ID:     id,
+	}
+}
+
+func TestHistoryReportsOnlySemanticChangesToTheObject(t *testing.T) {
+	f := newObjectHistoryFixture(t)
+
+	history, err := History(f.options("User-Alice"))
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+
+	var got []string
+	for _, commit := range history {
+		got = append(got, commit.Commit+":"+string(commit.Kind))
+	}
+	expected := []string{
+		f.role + ":modified",
+		f.moved + ":relocated",
+		f.renamed + ":modified",
+		f.initial + ":added",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected history %v, got %v", expected, got)
+	}
+
+	role := history[0]
+	if len(role.Fields) != 1 || role.Fields[0].Path != "role" || role.Fields[0].OldValue != "admin" || role.Fields[0].NewValue != "owner" {
+		t.Fatalf("expected a single role change, got %+v", role.Fields)
+	}
+	if role.At != "data/users/team.yaml" {
+		t.Fatalf("expected role change at team.yaml, got %q", role.At)
+	}
+	if history[1].MovedFrom != "data/users/user-alice.yaml" {
+		t.Fatalf("expected move from user-alice.yaml, got %q", history[1].MovedFrom)
+	}
+	if history[1].Subject != "move alice" {
+		t.Fatalf("expected commit subject, got %q", history[1].Subject)
+	}
+}
+
+func TestHistoryStartsAtRevision(t *testing.T) {
+	f := newObjectHistoryFixture(t)
+	opts := f.options("User-Alice")
+	opts.Revision = f.renamed
+
+	history, err := History(opts)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(history) != 2 || history[0].Commit != f.renamed {
+		t.Fatalf("expected history to stop at %s, got %+v", f.renamed, history)
+	}
+}
+
+func TestHistorySkipsMergesThatKeepOneParentsObject(t *testing.T) {
+	repo := testutil.NewGitRepo(t)
+	start := repo.Revision(t, "HEAD")
+	branch := repo.CommitOnBranch(t, start, "alice-email", map[string]string{
+		"data/users/user-alice.yaml": "id: User-Alice\nname: Alice Example\nemail: alice@example.org\nrole: admin\n",
+	})
+	repo.CommitDataChange(t, "data/users/user-bob.yaml", "id: User-Bob\nname: Bob Changed\nemail: bob@example.com\nrole: editor\n")
+	repo.Git(t, "merge", "--no-ff", "-q", "-m", "merge alice-email", "alice-email")
+
+	history, err := History(HistoryOptions{
+		Root:   repo.Root,
+		Config: filepath.Join(repo.Root, "mergeway.yaml"),
+		Type:   "User",
+		ID:     "User-Alice",
+	})
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(history) != 2 || history[0].Commit != branch || history[1].Commit != start {
+		t.Fatalf("expected branch commit and initial commit only, got %+v", history)
+	}
+}
+
+func TestHistoryUnknownObject(t *testing.T) {
+	f := newObjectHistoryFixture(t)
+
+	if _, err := History(f.options("User-Nobody")); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("expected ErrObjectNotFound, got %v", err)
+	}
+}
+
+func TestBlameAttributesFieldsToLastChange(t *testing.T) {
+	f := newObjectHistoryFixture(t)
+
+	blame, err := Blame(f.options("User-Alice"))
+	if err != nil {
+		t.Fatalf("Blame: %v", err)
+	}
+
+	got := make(map[string]string, len(blame))
+	for _, field := range blame {
+		if field.Commit == nil {
+			t.Fatalf("expected field %s to be attributed", field.Field)
+		}
+		got[field.Field] = field.Commit.Commit
+	}
+	expected := map[string]string{
+		"id":    f.initial,
+		"email": f.initial,
+		"name":  f.renamed,
+		"role":  f.role,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected blame %v, got %v", expected, got)
+	}
+	if blame[2].Field != "name" || blame[2].Value != "Alice Renamed" {
+		t.Fatalf("expected sorted fields with current values, got %+v", blame)
+	}
+}
+
+func TestHistorySkipsRevisionsThatDoNotLoad(t *testing.T) {
+	repo := testutil.NewGitRepo(t)
+	initial := repo.Revision(t, "HEAD")
+	renamed := repo.CommitDataChange(t, "data/users/user-bob.yaml", "id: User-Bob\nname: Bob Renamed\nemail: bob@example.com\nrole: editor\n")
+	repo.CommitDataChange(t, "data/users/user-bob.yaml", "id: User-Bob\nname: [unterminated\n")
+	emailed := repo.CommitDataChange(t, "data/users/user-bob.yaml", "id: User-Bob\nname: Bob Renamed\nemail: bob@example.org\nrole: editor\n")
+
+	history, err := History(HistoryOptions{
+		Root:   repo.Root,
+		Config: filepath.Join(repo.Root, "mergeway.yaml"),
+		Type:   "User",
+		ID:     "User-Bob",
+	})
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+
+	var got []string
+	for _, commit := range history {
+		got = append(got, commit.Commit+":"+string(commit.Kind))
+	}
+	expected := []string{emailed + ":modified", renamed + ":modified", initial + ":added"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected history %v, got %v", expected, got)
+	}
+	if len(history[0].Fields) != 1 || history[0].Fields[0].Path != "email" {
+		t.Fatalf("expected the email change against the last readable commit, got %+v", history[0].Fields)
+	}
+}
@@ -0,0 +1,94 @@
+package diff
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// referenceIndex maps each object, by type and identifier, to the reference
+// fields in a logical database that hold its identifier.
+type referenceIndex map[string][]DiffReference
+
+// newReferenceIndex indexes every reference field in db. A reference is
+// dangling when no object of a type the field may refer to has the referenced
+// identifier in db. The index is empty when db has no schema.
+func newReferenceIndex(db LogicalDatabase) referenceIndex {
+	index := make(referenceIndex)
+	if db.Schema == nil {
+		return index
+	}
+
+	objects := logicalObjectsByKey(db)
+	for _, obj := range db.Objects {
+		typeDef := db.Schema.Types[obj.Type]
+		if typeDef == nil {
+			continue
+		}
+		for _, field := range slices.Sorted(maps.Keys(typeDef.References)) {
+			targets := typeDef.References[field]
+			for _, id := range referencedIDs(obj.Fields[field]) {
+				dangling := true
+				for _, target := range targets {
+					if _, ok := objects[logicalObjectMapKey(target, id)]; ok {
+						dangling = false
+						break
+					}
+				}
+				reference := DiffReference{Type: obj.Type, ObjectID: obj.ID, Field: field, Dangling: dangling}
+				for _, target := range targets {
+					key := logicalObjectMapKey(target, id)
+					index[key] = append(index[key], reference)
+				}
+			}
+		}
+	}
+	return index
+}
+
+// to returns the references that hold id and may refer to typeName.
+func (idx referenceIndex) to(typeName, id string) []DiffReference {
+	refs := idx[logicalObjectMapKey(typeName, id)]
+	if len(refs) == 0 {
+		return nil
+	}
+	return append([]DiffReference(nil), refs...)
+}
+
+// referencedIDs lists the identifiers held by a reference field, which is
+// either a scalar or a list of scalars.
+func referencedIDs(value any) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []any:
+		var ids []string
+		for _, item := range v {
+			ids = append(ids, referencedIDs(item)...)
+		}
+		slices.Sort(ids)
+		return slices.Compact(ids)
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+// analyzeImpact lists, for every removed, renamed, and modified entry, the
+// references in right that point at the object: for removals and renames
+// these hold an identifier that is gone and are flagged as dangling unless
+// another object of an accepted type now has it; for modifications they are
+// the object's dependents.
+func analyzeImpact(result DiffResult, right LogicalDatabase) DiffResult {
+	index := newReferenceIndex(right)
+	out := DiffResult{Entries: make([]DiffEntry, 0, len(result.Entries))}
+	for _, entry := range result.Entries {
+		switch entry.Kind {
+		case DiffEntryKindRemoved, DiffEntryKindModified:
+			entry.References = index.to(entry.Type, entry.ObjectID)
+		case DiffEntryKindRenamed:
+			entry.References = index.to(entry.Type, entry.OldObjectID)
+		}
+		out.Entries = append(out.Entries, entry)
+	}
+	return out
+}
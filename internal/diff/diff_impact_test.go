@@ -0,0 +1,110 @@
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newImpactFixture returns two copies of the fixture where User-Alice, the
+// author of both posts, was removed and Tag-Writing, which both posts carry,
+// was relabelled.
+func newImpactFixture(t *testing.T) (string, string) {
+	t.Helper()
+	return changedFixture(t, []string{"data/users/user-alice.yaml"}, map[string]string{
+		"data/tags/tag-writing.yaml": "id: Tag-Writing\nlabel: Essays\n",
+	})
+}
+
+func TestDiffImpactListsInboundReferences(t *testing.T) {
+	left, right := newImpactFixture(t)
+
+	tests := []struct {
+		name     string
+		impact   bool
+		expected []string
+	}{
+		{
+			name:   "with impact",
+			impact: true,
+			expected: []string{
+				"MODIFIED Tag[Tag-Writing]",
+				`  label: "Writing" -> "Essays"`,
+				"  referenced by: Post[Post-001].tags",
+				"  referenced by: Post[Post-002].tags",
+				"",
+				"REMOVED User[User-Alice]",
+				"  from: data/users/user-alice.yaml",
+				"  still referenced by: Post[Post-001].author (dangling)",
+				"  still referenced by: Post[Post-002].author (dangling)",
+				"",
+			},
+		},
+		{
+			name:   "without impact",
+			impact: false,
+			expected: []string{
+				"MODIFIED Tag[Tag-Writing]",
+				`  label: "Writing" -> "Essays"`,
+				"",
+				"REMOVED User[User-Alice]",
+				"  from: data/users/user-alice.yaml",
+				"",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			output := runDirDiff(t, left, right, Options{Impact: tc.impact})
+			if expected := strings.Join(tc.expected, "\n"); output != expected {
+				t.Fatalf("unexpected output:\n%s", output)
+			}
+		})
+	}
+}
+
+func TestDiffImpactJSON(t *testing.T) {
+	left, right := newImpactFixture(t)
+
+	var doc diffJSONDocument
+	if err := json.Unmarshal([]byte(runDirDiff(t, left, right, Options{Format: OutputFormatJSON, Impact: true})), &doc); err != nil {
+		t.Fatalf("decode json: %v", err)
+	}
+	expected := [][]diffJSONReference{
+		{
+			{Type: "Post", ObjectID: "Post-001", Field: "tags"},
+			{Type: "Post", ObjectID: "Post-002", Field: "tags"},
+		},
+		{
+			{Type: "Post", ObjectID: "Post-001", Field: "author", Dangling: true},
+			{Type: "Post", ObjectID: "Post-002", Field: "author", Dangling: true},
+		},
+	}
+	for idx, entry := range doc.Entries {
+		if !reflect.DeepEqual(entry.Refs, expected[idx]) {
+			t.Fatalf("entry %s: expected references %v, got %v", entry.ObjectID, expected[idx], entry.Refs)
+		}
+	}
+}
+
+func TestReferenceIndexFindsReplacementTargets(t *testing.T) {
+	schema := &diffSnapshotSchema{Types: map[string]*diffSnapshotType{
+		"Member": {Name: "Member", IdentifierField: "id", References: map[string][]string{"team": {"Group", "Team"}}},
+		"Group":  {Name: "Group", IdentifierField: "id"},
+		"Team":   {Name: "Team", IdentifierField: "id"},
+	}}
+	db := LogicalDatabase{Schema: schema, Objects: []LogicalObject{
+		{Type: "Group", ID: "infra", Fields: map[string]any{"id": "infra"}},
+		{Type: "Member", ID: "m1", Fields: map[string]any{"id": "m1", "team": "infra"}},
+		{Type: "Member", ID: "m2", Fields: map[string]any{"id": "m2", "team": "platform"}},
+	}}
+
+	index := newReferenceIndex(db)
+	if got := index.to("Team", "infra"); len(got) != 1 || got[0].Dangling {
+		t.Fatalf("expected m1 to resolve to Group[infra], got %+v", got)
+	}
+	if got := index.to("Team", "platform"); len(got) != 1 || !got[0].Dangling || got[0].ObjectID != "m2" {
+		t.Fatalf("expected m2 to dangle, got %+v", got)
+	}
+}
@@ -14,6 +14,7 @@ type diffJSONEntry struct {
 	Kind      DiffEntryKind         `json:"kind"`
 	Type      string                `json:"type"`
 	ObjectID  string                `json:"object_id"`
+	OldID     string                `json:"old_object_id,omitempty"`
 	Value     map[string]any        `json:"value,omitempty"`
 	OldValue  map[string]any        `json:"old_value,omitempty"`
 	NewValue  map[string]any        `json:"new_value,omitempty"`
@@ -21,6 +22,14 @@ type diffJSONEntry struct {
 	Sources   []diffJSONSource      `json:"sources,omitempty"`
 	OldSource []diffJSONSource      `json:"old_sources,omitempty"`
 	NewSource []diffJSONSource      `json:"new_sources,omitempty"`
+	Refs      []diffJSONReference   `json:"references,omitempty"`
+}
+
+type diffJSONReference struct {
+	Type     string `json:"type"`
+	ObjectID string `json:"object_id"`
+	Field    string `json:"field"`
+	Dangling bool   `json:"dangling"`
 }
 
 type diffJSONFieldChange struct {
@@ -33,6 +42,7 @@ type diffJSONSource struct {
 	Path     string `json:"path"`
 	Selector string `json:"selector,omitempty"`
 	ReadOnly bool   `json:"read_only,omitempty"`
+	Inline   bool   `json:"inline,omitempty"`
 }
 
 func marshalDiffResultJSON(result DiffResult) ([]byte, error) {
@@ -75,6 +85,13 @@ func diffJSONEntryFromDiffEntry(entry DiffEntry) diffJSONEntry {
 		}
 		out.OldSource = jsonSources(entry.OldSources)
 		out.NewSource = jsonSources(entry.NewSources)
+	case DiffEntryKindRenamed:
+		out.OldID = entry.OldObjectID
+		out.OldValue = cloneMap(entry.OldValue)
+		out.NewValue = cloneMap(entry.NewValue)
+		out.Changes = jsonFieldChanges(entry.FieldChanges)
+		out.OldSource = jsonSources(entry.OldSources)
+		out.NewSource = jsonSources(entry.NewSources)
 	default:
 		out.OldValue = cloneMap(entry.OldValue)
 		out.NewValue = cloneMap(entry.NewValue)
@@ -82,6 +99,14 @@ func diffJSONEntryFromDiffEntry(entry DiffEntry) diffJSONEntry {
 		out.OldSource = jsonSources(entry.OldSources)
 		out.NewSource = jsonSources(entry.NewSources)
 	}
+	for _, reference := range entry.References {
+		out.Refs = append(out.Refs, diffJSONReference{
+			Type:     reference.Type,
+			ObjectID: reference.ObjectID,
+			Field:    reference.Field,
+			Dangling: reference.Dangling,
+		})
+	}
 
 	return out
 }
@@ -128,3 +153,31 @@ func jsonSources(sources []LogicalObjectSource) []diffJSONSource {
 	}
 	return out
 }
+
+type schemaJSONDocument struct {
+	Version  int                `json:"version"`
+	Breaking bool               `json:"breaking"`
+	Changes  []schemaJSONChange `json:"changes"`
+}
+
+type schemaJSONChange struct {
+	Kind      SchemaChangeKind     `json:"kind"`
+	Severity  SchemaChangeSeverity `json:"severity"`
+	Type      string               `json:"type"`
+	Field     string               `json:"field,omitempty"`
+	Attribute string               `json:"attribute,omitempty"`
+	Before    any                  `json:"before"`
+	After     any                  `json:"after"`
+}
+
+func marshalSchemaDiffJSON(result SchemaDiffResult) ([]byte, error) {
+	doc := schemaJSONDocument{
+		Version:  1,
+		Breaking: result.Breaking(),
+		Changes:  make([]schemaJSONChange, 0, len(result.Changes)),
+	}
+	for _, change := range result.Changes {
+		doc.Changes = append(doc.Changes, schemaJSONChange(change))
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
@@ -0,0 +1,560 @@
+package diff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var ErrLogArgs = errors.New("log requires one revision range <from>..<to>")
+
+// LogOptions selects a revision range for Log. Range is "<from>..<to>"; an
+// empty side means HEAD, as in git.
+type LogOptions struct {
+	Root    string
+	Config  string
+	Range   string
+	Format  OutputFormat
+	Summary bool
+}
+
+// LogCommit is one commit in the range together with the semantic data
+// changes it made.
+type LogCommit struct {
+	Commit  string
+	Author  string
+	Date    string
+	Message string
+	Result  DiffResult
+}
+
+// LogResult is the semantic changelog of a revision range, oldest commit
+// first. Net is the diff between the range's endpoints.
+type LogResult struct {
+	From    string
+	To      string
+	Commits []LogCommit
+	Net     DiffResult
+}
+
+// Authors returns the distinct authors of the commits, sorted.
+func (r LogResult) Authors() []string {
+	set := make(map[string]struct{}, len(r.Commits))
+	for _, commit := range r.Commits {
+		set[commit.Author] = struct{}{}
+	}
+	return sortedKeys(set)
+}
+
+// RunLog renders the semantic changelog of a revision range: every commit
+// that changed Mergeway data with the entries it changed, or with Summary,
+// the range's net changes and who made them.
+func RunLog(opts LogOptions) (string, error) {
+	if err := validateOutputFormat(opts.Format, OutputFormatText, OutputFormatJSON, OutputFormatMarkdown); err != nil {
+		return "", err
+	}
+	result, err := Log(opts)
+	if err != nil {
+		return "", err
+	}
+
+	switch opts.Format {
+	case OutputFormatJSON:
+		payload, err := marshalLogResultJSON(result, opts.Summary)
+		if err != nil {
+			return "", err
+		}
+		return string(payload) + "\n", nil
+	case OutputFormatMarkdown:
+		if opts.Summary {
+			return renderLogSummaryMarkdown(result), nil
+		}
+		return renderLogResultMarkdown(result), nil
+	default:
+		if opts.Summary {
+			return renderLogSummary(result), nil
+		}
+		return renderLogResult(result), nil
+	}
+}
+
+// Log walks the commits in a range that touch the config or data files at
+// either endpoint and diffs each against its parents through the logical
+// database. Commits without semantic data changes are dropped, and a merge
+// keeps only the objects that differ from every parent. Commits whose config
+// or data does not load are skipped, and the next commit is diffed against
+// the nearest ancestor that loads.
+func Log(opts LogOptions) (LogResult, error) {
+	from, to, err := parseLogRange(opts.Range)
+	if err != nil {
+		return LogResult{}, err
+	}
+
+	absRoot, err := filepath.Abs(opts.Root)
+	if err != nil {
+		return LogResult{}, fmt.Errorf("diff: resolve root: %w", err)
+	}
+	absConfig, err := filepath.Abs(opts.Config)
+	if err != nil {
+		return LogResult{}, fmt.Errorf("diff: resolve config path: %w", err)
+	}
+	configRel, err := rootRelativePath(absRoot, absConfig)
+	if err != nil {
+		return LogResult{}, fmt.Errorf("diff: config path %s: %w", absConfig, err)
+	}
+	for _, revision := range []string{from, to} {
+		if err := validateGitRevision(absRoot, revision); err != nil {
+			return LogResult{}, err
+		}
+	}
+
+	w := &logWalker{
+		root:       absRoot,
+		configPath: absConfig,
+		configRel:  filepath.ToSlash(configRel),
+		databases:  make(map[string]LogicalDatabase),
+		pending:    make(map[string]int),
+		unreadable: make(map[string]error),
+	}
+
+	var specs pathspecSet
+	for _, revision := range []string{from, to} {
+		if err := w.addPathspecs(&specs, revision); err != nil {
+			return LogResult{}, err
+		}
+	}
+
+	commits, err := w.commits(from, to, specs.specs)
+	if err != nil {
+		return LogResult{}, err
+	}
+
+	result := LogResult{From: from, To: to}
+	for _, commit := range commits {
+		for _, parent := range commit.Parents {
+			w.pending[parent]++
+		}
+	}
+	for _, commit := range commits {
+		changes, err := w.change(commit)
+		if err != nil {
+			return LogResult{}, err
+		}
+		if len(changes.Entries) == 0 {
+			continue
+		}
+		result.Commits = append(result.Commits, LogCommit{
+			Commit:  commit.Hash,
+			Author:  commit.Author,
+			Date:    commit.Date,
+			Message: commit.Subject,
+			Result:  changes,
+		})
+	}
+
+	left, err := w.readableDatabaseAt(from)
+	if err != nil {
+		return LogResult{}, err
+	}
+	right, err := w.readableDatabaseAt(to)
+	if err != nil {
+		return LogResult{}, err
+	}
+	if result.Net, err = diffLogicalDatabases(left, right); err != nil {
+		return LogResult{}, err
+	}
+	return result, nil
+}
+
+func parseLogRange(spec string) (string, string, error) {
+	from, to, ok := strings.Cut(spec, "..")
+	if !ok || strings.HasPrefix(to, ".") {
+		return "", "", fmt.Errorf("diff: %w, got %q", ErrLogArgs, spec)
+	}
+	if from == "" {
+		from = "HEAD"
+	}
+	if to == "" {
+		to = "HEAD"
+	}
+	return from, to, nil
+}
+
+type logWalker struct {
+	root       string
+	configPath string
+	configRel  string
+
+	// databases caches the logical database at each revision until no
+	// remaining commit in the walk needs it; pending counts those commits.
+	databases map[string]LogicalDatabase
+	pending   map[string]int
+	// unreadable caches the revisions whose config or data does not load.
+	unreadable map[string]error
+}
+
+// addPathspecs adds the config and every type's includes at a revision. A
+// revision without the config adds only the config path.
+func (w *logWalker) addPathspecs(specs *pathspecSet, revision string) error {
+	reader, err := newSnapshotReader(w.root, SnapshotRef{Kind: SnapshotKindRevision, Revision: revision})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if _, exists, err := reader.Read(w.configRel); err != nil {
+		return err
+	} else if !exists {
+		specs.add(w.configRel)
+		return nil
+	}
+	cfg, err := loadSnapshotConfig(reader, w.configPath)
+	if err != nil {
+		return err
+	}
+	specs.addConfig(w.root, w.configRel, cfg, slices.Sorted(maps.Keys(cfg.Types))...)
+	return nil
+}
+
+// commits lists the range oldest first, with full commit messages.
+func (w *logWalker) commits(from, to string, pathspecs []string) ([]historyCommit, error) {
+	args := append([]string{"log", "--reverse", "--topo-order", "-z", "--format=%H%x1f%P%x1f%an%x1f%aI%x1f%B", from + ".." + to, "--"}, pathspecs...)
+	output, err := runGit(w.root, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []historyCommit
+	for _, record := range strings.Split(string(output), "\x00") {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+		parts := strings.SplitN(record, "\x1f", 5)
+		if len(parts) != 5 {
+			return nil, fmt.Errorf("diff: unexpected git log record %q", record)
+		}
+		commits = append(commits, historyCommit{
+			Hash:    parts[0],
+			Parents: strings.Fields(parts[1]),
+			Author:  parts[2],
+			Date:    parts[3],
+			// %B is the full message, not just the subject line.
+			Subject: strings.TrimRight(parts[4], "\n"),
+		})
+	}
+	return commits, nil
+}
+
+// change diffs a commit against its first parent. For merges, entries are
+// kept only when the object also differs from every other parent, so a merge
+// that takes one side's version reports nothing for it.
+func (w *logWalker) change(commit historyCommit) (DiffResult, error) {
+	after, err := w.databaseAt(commit.Hash)
+	if errors.As(err, new(*unreadableRevisionError)) {
+		for _, parent := range commit.Parents {
+			w.pending[parent]--
+			w.release(parent)
+		}
+		return DiffResult{}, nil
+	}
+	if err != nil {
+		return DiffResult{}, err
+	}
+	defer w.release(commit.Hash)
+
+	parents := commit.Parents
+	var result DiffResult
+	if len(parents) == 0 {
+		return diffLogicalDatabases(LogicalDatabase{}, after)
+	}
+	for idx, parent := range parents {
+		before, err := w.readableDatabaseAt(parent)
+		if err != nil {
+			return DiffResult{}, err
+		}
+		w.pending[parent]--
+		w.release(parent)
+
+		changes, err := diffLogicalDatabases(before, after)
+		if err != nil {
+			return DiffResult{}, err
+		}
+		if idx == 0 {
+			result = changes
+			continue
+		}
+		changed := make(map[string]struct{}, len(changes.Entries))
+		for _, entry := range changes.Entries {
+			changed[entry.Type+"\x00"+entry.ObjectID] = struct{}{}
+		}
+		kept := result.Entries[:0]
+		for _, entry := range result.Entries {
+			if _, ok := changed[entry.Type+"\x00"+entry.ObjectID]; ok {
+				kept = append(kept, entry)
+			}
+		}
+		result.Entries = kept
+	}
+	return result, nil
+}
+
+// release drops a cached database once no remaining commit needs it.
+func (w *logWalker) release(revision string) {
+	if w.pending[revision] <= 0 {
+		delete(w.databases, revision)
+		delete(w.pending, revision)
+	}
+}
+
+// readableDatabaseAt returns the logical database at revision or, when
+// revision does not load, at its nearest first-parent ancestor that does.
+// An ancestor reached that way stays cached only while the walk needs it.
+func (w *logWalker) readableDatabaseAt(revision string) (LogicalDatabase, error) {
+	for start := revision; revision != ""; {
+		db, err := w.databaseAt(revision)
+		if !errors.As(err, new(*unreadableRevisionError)) {
+			if err == nil && revision != start {
+				w.release(revision)
+			}
+			return db, err
+		}
+		if revision, err = firstParent(w.root, revision); err != nil {
+			return LogicalDatabase{}, err
+		}
+	}
+	return LogicalDatabase{}, nil
+}
+
+// databaseAt builds the logical database at a revision. A revision without
+// the config has no Mergeway data and yields an empty database; one whose
+// config or data does not load yields an *unreadableRevisionError.
+func (w *logWalker) databaseAt(revision string) (LogicalDatabase, error) {
+	if db, ok := w.databases[revision]; ok {
+		return db, nil
+	}
+	if err, ok := w.unreadable[revision]; ok {
+		return LogicalDatabase{}, err
+	}
+
+	snapshot := SnapshotRef{Kind: SnapshotKindRevision, Revision: revision}
+	reader, err := newSnapshotReader(w.root, snapshot)
+	if err != nil {
+		return LogicalDatabase{}, err
+	}
+	defer reader.Close()
+
+	db := LogicalDatabase{Snapshot: snapshot}
+	if _, exists, err := reader.Read(w.configRel); err != nil {
+		return LogicalDatabase{}, err
+	} else if exists {
+		schema, err := loadSnapshotDiffSchema(reader, w.configPath)
+		if err != nil {
+			w.unreadable[revision] = &unreadableRevisionError{Revision: revision, Err: err}
+			return LogicalDatabase{}, w.unreadable[revision]
+		}
+		side := snapshotSide{reader: reader, schema: schema}
+		paths, err := discoverDiffDataPaths([]snapshotSide{side})
+		if err != nil {
+			return LogicalDatabase{}, err
+		}
+		corpus, err := loadSnapshotDataCorpus(side, paths)
+		if err != nil {
+			return LogicalDatabase{}, err
+		}
+		if db, err = buildLogicalDatabase(corpus); errors.As(err, new(*LogicalDatabaseBuildError)) {
+			w.unreadable[revision] = &unreadableRevisionError{Revision: revision, Err: err}
+			return LogicalDatabase{}, w.unreadable[revision]
+		} else if err != nil {
+			return LogicalDatabase{}, fmt.Errorf("diff: at %s: %w", shortRevision(revision), err)
+		}
+	}
+	w.databases[revision] = db
+	return db, nil
+}
+
+func renderLogResult(result LogResult) string {
+	if len(result.Commits) == 0 {
+		return fmt.Sprintf("No data changes in %s..%s.\n", result.From, result.To)
+	}
+
+	var b strings.Builder
+	for idx, commit := range result.Commits {
+		if idx > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "commit %s\nAuthor: %s\nDate:   %s\n\n", commit.Commit, commit.Author, commit.Date)
+		for _, line := range strings.Split(commit.Message, "\n") {
+			b.WriteString(strings.TrimRight("    "+line, " ") + "\n")
+		}
+		b.WriteByte('\n')
+		b.WriteString(renderDiffResult(commit.Result))
+	}
+	return b.String()
+}
+
+func renderLogSummary(result LogResult) string {
+	var b strings.Builder
+	b.WriteString(logSummaryLine(result, func(s string) string { return s }))
+	b.WriteString("\n\n")
+	b.WriteString(renderDiffResult(result.Net))
+	return b.String()
+}
+
+func renderLogResultMarkdown(result LogResult) string {
+	var b strings.Builder
+	b.WriteString("## Mergeway data changelog\n\n")
+	if len(result.Commits) == 0 {
+		fmt.Fprintf(&b, "No data changes in %s.\n", markdownCode(result.From+".."+result.To))
+		return b.String()
+	}
+
+	for idx, commit := range result.Commits {
+		if idx > 0 {
+			b.WriteByte('\n')
+		}
+		subject, _, _ := strings.Cut(commit.Message, "\n")
+		fmt.Fprintf(&b, "### %s (%s)\n\n", markdownCell(subject), markdownCode(shortRevision(commit.Commit)))
+		fmt.Fprintf(&b, "%s, %s\n\n", commit.Author, commit.Date)
+		for _, entry := range commit.Result.Entries {
+			b.WriteString(logEntryMarkdown(entry) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// logEntryMarkdown renders an entry as a single bullet, listing changed
+// fields inline rather than in a table.
+func logEntryMarkdown(entry DiffEntry) string {
+	line := "- " + markdownKindLabel(entry.Kind) + " " + markdownCode(diffEntryTitle(entry))
+	at, movedFrom := diffEntryLocation(entry)
+	if movedFrom != "" {
+		line += " from " + markdownCode(movedFrom) + " to " + markdownCode(at)
+	}
+	if entry.Kind != DiffEntryKindModified {
+		return line
+	}
+
+	var changes []string
+	for _, row := range diffEntryRows(entry) {
+		changes = append(changes, fmt.Sprintf("%s %s → %s", markdownCode(row.Path), markdownCode(formatReportValue(row.OldValue)), markdownCode(formatReportValue(row.NewValue))))
+	}
+	if len(changes) > 0 {
+		line += ": " + strings.Join(changes, "; ")
+	}
+	return line
+}
+
+func renderLogSummaryMarkdown(result LogResult) string {
+	var b strings.Builder
+	b.WriteString("## Mergeway data changelog\n\n")
+	b.WriteString(logSummaryLine(result, markdownCode))
+	b.WriteString("\n")
+	if len(result.Net.Entries) == 0 {
+		b.WriteString("\nNo changes.\n")
+		return b.String()
+	}
+	b.WriteByte('\n')
+	writeDiffMarkdownBody(&b, result.Net)
+	return b.String()
+}
+
+// logSummaryLine describes the range in one sentence, with code formatting
+// the revisions the way the output format does.
+func logSummaryLine(result LogResult, code func(string) string) string {
+	noun := "commits"
+	if len(result.Commits) == 1 {
+		noun = "commit"
+	}
+	line := fmt.Sprintf("%s: %d %s with data changes", code(result.From+".."+result.To), len(result.Commits), noun)
+	if authors := result.Authors(); len(authors) > 0 {
+		line += " by " + strings.Join(authors, ", ")
+	}
+	return line + "."
+}
+
+type logJSONDocument struct {
+	Version int             `json:"version"`
+	From    string          `json:"from"`
+	To      string          `json:"to"`
+	Commits []logJSONCommit `json:"commits"`
+}
+
+type logJSONCommit struct {
+	Commit  string          `json:"commit"`
+	Author  string          `json:"author"`
+	Date    string          `json:"date"`
+	Message string          `json:"message"`
+	Entries []diffJSONEntry `json:"entries"`
+}
+
+type logJSONSummary struct {
+	Version int               `json:"version"`
+	From    string            `json:"from"`
+	To      string            `json:"to"`
+	Commits int               `json:"commits"`
+	Authors []string          `json:"authors"`
+	Types   []logJSONTypeStat `json:"types"`
+	Entries []diffJSONEntry   `json:"entries"`
+}
+
+type logJSONTypeStat struct {
+	Type      string `json:"type"`
+	Added     int    `json:"added"`
+	Removed   int    `json:"removed"`
+	Modified  int    `json:"modified"`
+	Relocated int    `json:"relocated"`
+}
+
+func marshalLogResultJSON(result LogResult, summary bool) ([]byte, error) {
+	if summary {
+		doc := logJSONSummary{
+			Version: 1,
+			From:    result.From,
+			To:      result.To,
+			Commits: len(result.Commits),
+			Authors: append([]string{}, result.Authors()...),
+			Types:   []logJSONTypeStat{},
+			Entries: jsonEntries(result.Net),
+		}
+		for _, stat := range summarizeDiffByType(result.Net) {
+			doc.Types = append(doc.Types, logJSONTypeStat{
+				Type:      stat.Type,
+				Added:     stat.Added,
+				Removed:   stat.Removed,
+				Modified:  stat.Modified,
+				Relocated: stat.Relocated,
+			})
+		}
+		return json.MarshalIndent(doc, "", "  ")
+	}
+
+	doc := logJSONDocument{
+		Version: 1,
+		From:    result.From,
+		To:      result.To,
+		Commits: make([]logJSONCommit, 0, len(result.Commits)),
+	}
+	for _, commit := range result.Commits {
+		doc.Commits = append(doc.Commits, logJSONCommit{
+			Commit:  commit.Commit,
+			Author:  commit.Author,
+			Date:    commit.Date,
+			Message: commit.Message,
+			Entries: jsonEntries(commit.Result),
+		})
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func jsonEntries(result DiffResult) []diffJSONEntry {
+	entries := make([]diffJSONEntry, 0, len(result.Entries))
+	for _, entry := range result.Entries {
+		entries = append(entries, diffJSONEntryFromDiffEntry(entry))
+	}
+	return entries
+}
@@ -0,0 +1,232 @@
+package diff
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mergewayhq/mergeway-cli/internal/testutil"
+)
+
+type logFixture struct {
+	repo    testutil.GitRepo
+	initial string
+	renamed string
+	tagged  string
+}
+
+// newLogFixture commits a rename, a reformat, a non-data change, and a merge
+// whose branch adds a tag while main edits Bob.
+func newLogFixture(t *testing.T) logFixture {
+	t.Helper()
+	repo := testutil.NewGitRepo(t)
+	f := logFixture{repo: repo, initial: repo.Revision(t, "HEAD")}
+
+	f.renamed = repo.CommitDataChange(t, "data/users/user-alice.yaml", "id: User-Alice\nname: Alice Renamed\nemail: alice@example.com\nrole: admin\n")
+	repo.CommitDataChange(t, "data/users/user-alice.yaml", "role: admin\nemail: alice@example.com\nname: Alice Renamed\nid: User-Alice\n")
+	repo.CommitDataChange(t, "README.md", "notes\n")
+
+	f.tagged = repo.CommitOnBranch(t, "HEAD", "tags", map[string]string{
+		"data/tags/tag-new.yaml": "id: Tag-New\nlabel: New\n",
+	})
+	repo.CommitDataChange(t, "data/users/user-bob.yaml", "id: User-Bob\nname: Bob Changed\nemail: bob@example.com\nrole: editor\n")
+	repo.Git(t, "merge", "--no-ff", "-q", "-m", "merge tags\n\nBrings in Tag-New.", "tags")
+	return f
+}
+
+func (f logFixture) options(spec string) LogOptions {
+	return LogOptions{
+		Root:   f.repo.Root,
+		Config: filepath.Join(f.repo.Root, "mergeway.yaml"),
+		Range:  spec,
+	}
+}
+
+func TestLogListsOnlyCommitsWithSemanticChanges(t *testing.T) {
+	f := newLogFixture(t)
+
+	result, err := Log(f.options(f.initial + ".."))
+	if err != nil {
+		t.Fatalf("Log: %v", err)
+	}
+
+	var got []string
+	for _, commit := range result.Commits {
+		for _, entry := range commit.Result.Entries {
+			got = append(got, commit.Message+":"+string(entry.Kind)+":"+entry.ObjectID)
+		}
+	}
+	expected := []string{
+		"update data/users/user-alice.yaml:modified:User-Alice",
+		"update data/users/user-bob.yaml:modified:User-Bob",
+		"update on tags:added:Tag-New",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected changelog %v, got %v", expected, got)
+	}
+	if result.Commits[0].Commit != f.renamed || result.Commits[2].Commit != f.tagged {
+		t.Fatalf("unexpected commits %+v", result.Commits)
+	}
+	if result.To != "HEAD" || result.From != f.initial {
+		t.Fatalf("unexpected range %s..%s", result.From, result.To)
+	}
+	if len(result.Net.Entries) != 3 {
+		t.Fatalf("expected three net changes, got %+v", result.Net.Entries)
+	}
+	if authors := result.Authors(); !reflect.DeepEqual(authors, []string{"Mergeway Tests"}) {
+		t.Fatalf("unexpected authors %v", authors)
+	}
+}
+
+func TestLogReportsMergesThatChangeBothSides(t *testing.T) {
+	repo := testutil.NewGitRepo(t)
+	start := repo.Revision(t, "HEAD")
+	repo.CommitOnBranch(t, start, "alice", map[string]string{
+		"data/users/user-alice.yaml": "id: User-Alice\nname: Alice Branch\nemail: alice@example.com\nrole: admin\n",
+	})
+	repo.CommitDataChange(t, "data/users/user-alice.yaml", "id: User-Alice\nname: Alice Example\nemail: alice@example.com\nrole: owner\n")
+	repo.Git(t, "merge", "-q", "-m", "merge alice", "alice")
+	merge := repo.Revision(t, "HEAD")
+
+	result, err := Log(LogOptions{
+		Root:   repo.Root,
+		Config: filepath.Join(repo.Root, "mergeway.yaml"),
+		Range:  start + "..HEAD",
+	})
+	if err != nil {
+		t.Fatalf("Log: %v", err)
+	}
+	last := result.Commits[len(result.Commits)-1]
+	if last.Commit != merge || len(last.Result.Entries) != 1 || last.Result.Entries[0].ObjectID != "User-Alice" {
+		t.Fatalf("expected the merge to combine both Alice edits, got %+v", result.Commits)
+	}
+}
+
+func TestRunLogRendersMarkdownAndJSON(t *testing.T) {
+	f := newLogFixture(t)
+	opts := f.options(f.initial + "..HEAD")
+
+	opts.Format = OutputFormatMarkdown
+	markdown, err := RunLog(opts)
+	if err != nil {
+		t.Fatalf("RunLog markdown: %v", err)
+	}
+	for _, want := range []string{
+		"## Mergeway data changelog\n",
+		"### update data/users/user-alice.yaml (`" + shortRevision(f.renamed) + "`)\n",
+		"- Modified `User[User-Alice]`: `name` `\"Alice Example\"` → `\"Alice Renamed\"`\n",
+		"- Added `Tag[Tag-New]`\n",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Fatalf("expected %q in markdown:\n%s", want, markdown)
+		}
+	}
+	if strings.Contains(markdown, "README") || strings.Contains(markdown, "merge tags") {
+		t.Fatalf("expected commits without data changes to be skipped:\n%s", markdown)
+	}
+
+	opts.Format = OutputFormatJSON
+	payload, err := RunLog(opts)
+	if err != nil {
+		t.Fatalf("RunLog json: %v", err)
+	}
+	var doc logJSONDocument
+	if err := json.Unmarshal([]byte(payload), &doc); err != nil {
+		t.Fatalf("decode json: %v\n%s", err, payload)
+	}
+	if len(doc.Commits) != 3 || doc.Commits[2].Commit != f.tagged || doc.Commits[2].Entries[0].ObjectID != "Tag-New" {
+		t.Fatalf("unexpected json changelog %+v", doc.Commits)
+	}
+}
+
+func TestRunLogSummary(t *testing.T) {
+	f := newLogFixture(t)
+	opts := f.options(f.initial + "..HEAD")
+	opts.Summary = true
+
+	opts.Format = OutputFormatMarkdown
+	markdown, err := RunLog(opts)
+	if err != nil {
+		t.Fatalf("RunLog markdown: %v", err)
+	}
+	for _, want := range []string{
+		"`" + f.initial + "..HEAD`: 3 commits with data changes by Mergeway Tests.\n",
+		"| Tag | 1 | 0 | 0 | 0 |\n",
+		"| User | 0 | 0 | 2 | 0 |\n",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Fatalf("expected %q in summary:\n%s", want, markdown)
+		}
+	}
+
+	opts.Format = OutputFormatJSON
+	payload, err := RunLog(opts)
+	if err != nil {
+		t.Fatalf("RunLog json: %v", err)
+	}
+	var doc logJSONSummary
+	if err := json.Unmarshal([]byte(payload), &doc); err != nil {
+		t.Fatalf("decode json: %v\n%s", err, payload)
+	}
+	if doc.Commits != 3 || len(doc.Types) != 2 || len(doc.Entries) != 3 {
+		t.Fatalf("unexpected json summary %+v", doc)
+	}
+}
+
+func TestLogRangeWithoutChanges(t *testing.T) {
+	f := newLogFixture(t)
+
+	output, err := RunLog(f.options("HEAD..HEAD"))
+	if err != nil {
+		t.Fatalf("RunLog: %v", err)
+	}
+	if output != "No data changes in HEAD..HEAD.\n" {
+		t.Fatalf("unexpected output %q", output)
+	}
+}
+
+func TestLogRejectsInvalidRange(t *testing.T) {
+	f := newLogFixture(t)
+
+	for _, spec := range []string{"HEAD", "HEAD...HEAD~1", ""} {
+		if _, err := Log(f.options(spec)); !errors.Is(err, ErrLogArgs) {
+			t.Fatalf("expected ErrLogArgs for %q, got %v", spec, err)
+		}
+	}
+	if _, err := Log(f.options("no-such-rev..HEAD")); err == nil || !strings.Contains(err.Error(), "invalid revision") {
+		t.Fatalf("expected invalid revision, got %v", err)
+	}
+}
+
+func TestLogSkipsRevisionsThatDoNotLoad(t *testing.T) {
+	repo := testutil.NewGitRepo(t)
+	initial := repo.Revision(t, "HEAD")
+	renamed := repo.CommitDataChange(t, "data/users/user-bob.yaml", "id: User-Bob\nname: Bob Renamed\nemail: bob@example.com\nrole: editor\n")
+	repo.CommitDataChange(t, "data/users/user-bob.yaml", "id: User-Bob\nname: [unterminated\n")
+	emailed := repo.CommitDataChange(t, "data/users/user-bob.yaml", "id: User-Bob\nname: Bob Renamed\nemail: bob@example.org\nrole: editor\n")
+
+	result, err := Log(LogOptions{
+		Root:   repo.Root,
+		Config: filepath.Join(repo.Root, "mergeway.yaml"),
+		Range:  initial + "..",
+	})
+	if err != nil {
+		t.Fatalf("Log: %v", err)
+	}
+
+	var got []string
+	for _, commit := range result.Commits {
+		for _, entry := range commit.Result.Entries {
+			for _, field := range entry.FieldChanges {
+				got = append(got, commit.Commit+":"+field.Path)
+			}
+		}
+	}
+	expected := []string{renamed + ":name", emailed + ":email"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected changelog %v, got %v", expected, got)
+	}
+}
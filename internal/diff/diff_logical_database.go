@@ -24,6 +24,9 @@ import (
 type LogicalDatabase struct {
 	Snapshot SnapshotRef
 	Objects  []LogicalObject
+	// Schema is the snapshot's schema, or nil for an export read without
+	// a config.
+	Schema *diffSnapshotSchema
 }
 
 type LogicalObject struct {
@@ -34,10 +37,13 @@ type LogicalObject struct {
 	Sources   []LogicalObjectSource
 }
 
+// LogicalObjectSource locates an object. Inline objects come from an entity's
+// `data:` block; Path is then the config file that declares them.
 type LogicalObjectSource struct {
 	Path     string
 	Selector string
 	ReadOnly bool
+	Inline   bool
 }
 
 type LogicalDatabaseErrorKind string
@@ -129,9 +135,14 @@ func buildLogicalDatabase(corpus SnapshotDataCorpus) (LogicalDatabase, error) {
 		}
 	}
 
+	if err := addInlineLogicalObjects(corpus, objects); err != nil {
+		return LogicalDatabase{}, err
+	}
+
 	result := LogicalDatabase{
 		Snapshot: corpus.Snapshot,
 		Objects:  make([]LogicalObject, 0, len(objects)),
+		Schema:   corpus.Schema,
 	}
 	for _, obj := range objects {
 		result.Objects = append(result.Objects, obj)
@@ -149,6 +160,55 @@ func buildLogicalDatabase(corpus SnapshotDataCorpus) (LogicalDatabase, error) {
 	return result, nil
 }
 
+// addInlineLogicalObjects adds each type's inline records. As in data.Store,
+// a file-backed object with the same identifier takes precedence.
+func addInlineLogicalObjects(corpus SnapshotDataCorpus, objects map[string]LogicalObject) error {
+	for _, typeName := range sortedSchemaTypeNames(corpus.Schema) {
+		typeDef := corpus.Schema.Types[typeName]
+		for idx, item := range typeDef.InlineData {
+			fields := cloneMap(item)
+			id, err := deriveLogicalObjectID(typeDef, fields, "")
+			if err != nil {
+				return &LogicalDatabaseBuildError{
+					Kind:     LogicalDatabaseErrorInvalidObject,
+					Snapshot: corpus.Snapshot,
+					TypeName: typeName,
+					Path:     typeDef.InlineSource,
+					Err:      fmt.Errorf("inline item %d: %w", idx+1, err),
+				}
+			}
+			key := logicalObjectMapKey(typeName, id)
+			if _, exists := objects[key]; exists {
+				continue
+			}
+
+			canonical, err := canonicalizeLogicalFields(fields)
+			if err != nil {
+				return &LogicalDatabaseBuildError{
+					Kind:     LogicalDatabaseErrorInvalidObject,
+					Snapshot: corpus.Snapshot,
+					TypeName: typeName,
+					ObjectID: id,
+					Path:     typeDef.InlineSource,
+					Err:      err,
+				}
+			}
+			objects[key] = LogicalObject{
+				Type:      typeName,
+				ID:        id,
+				Fields:    fields,
+				Canonical: canonical,
+				Sources: []LogicalObjectSource{{
+					Path:     typeDef.InlineSource,
+					ReadOnly: true,
+					Inline:   true,
+				}},
+			}
+		}
+	}
+	return nil
+}
+
 type snapshotTypeIncludeMatch struct {
 	Type    *diffSnapshotType
 	Include diffSnapshotInclude
@@ -221,6 +281,18 @@ func parseLogicalObjectsFromFile(snapshot SnapshotRef, typeDef *diffSnapshotType
 			}
 		}
 
+		if err := addDerivedLogicalFields(typeDef, fields, file.Path); err != nil {
+			return nil, &LogicalDatabaseBuildError{
+				Kind:     LogicalDatabaseErrorInvalidObject,
+				Snapshot: snapshot,
+				TypeName: typeDef.Name,
+				ObjectID: id,
+				Path:     file.Path,
+				Selector: include.Selector,
+				Err:      err,
+			}
+		}
+
 		canonical, err := canonicalizeLogicalFields(fields)
 		if err != nil {
 			return nil, &LogicalDatabaseBuildError{
@@ -333,6 +405,31 @@ func parseIncludedSnapshotObjects(typeDef *diffSnapshotType, include diffSnapsho
 	return items, nil
 }
 
+// addDerivedLogicalFields sets path-derived field values, overriding anything
+// stored in the file, the way data.Store does when it loads an object.
+func addDerivedLogicalFields(typeDef *diffSnapshotType, fields map[string]any, sourcePath string) error {
+	for _, name := range sortedDerivedFieldNames(typeDef) {
+		value, err := internalconfig.DerivePathSourceValue(typeDef.DerivedFields[name], sourcePath)
+		if err != nil {
+			return fmt.Errorf("derive field %q: %w", name, err)
+		}
+		fields[name] = value
+	}
+	return nil
+}
+
+func sortedDerivedFieldNames(typeDef *diffSnapshotType) []string {
+	if typeDef == nil || len(typeDef.DerivedFields) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(typeDef.DerivedFields))
+	for name := range typeDef.DerivedFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func classifyLogicalDatabaseError(err error) LogicalDatabaseErrorKind {
 	if err == nil {
 		return LogicalDatabaseErrorInvalidObject
@@ -0,0 +1,642 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+var ErrMergeArgs = errors.New("merge requires exactly 3 snapshot arguments: <base> <ours> <theirs>")
+
+type MergeOptions struct {
+	Root   string
+	Config string
+	Args   []string
+	JSON   bool
+	DryRun bool
+}
+
+// MergeOutput is the rendered report of a merge run. Conflicted is set when
+// at least one conflict was written to the working tree (or would be, with
+// DryRun).
+type MergeOutput struct {
+	Report     string
+	Conflicted bool
+}
+
+type MergeConflictKind string
+
+const (
+	MergeConflictKindField        MergeConflictKind = "field"
+	MergeConflictKindDeleteModify MergeConflictKind = "delete_modify"
+	MergeConflictKindRelocation   MergeConflictKind = "relocation"
+	MergeConflictKindFile         MergeConflictKind = "file"
+)
+
+// MergeConflict describes one change that could not be resolved
+// automatically. Field is the dotted field path for field conflicts. For
+// delete/modify conflicts Base, Ours, and Theirs hold whole objects, with nil
+// on the side that deleted it; for relocation conflicts they hold source
+// paths. Files lists the data files that carry conflict markers for it.
+type MergeConflict struct {
+	Kind     MergeConflictKind
+	Type     string
+	ObjectID string
+	Field    string
+	Base     any
+	Ours     any
+	Theirs   any
+	Files    []string
+}
+
+// MergeResult is the outcome of a three-way semantic merge.
+//
+// Ours and Theirs are the merged database resolved towards each side: every
+// non-conflicting change from both sides is applied to both, and conflicts keep
+// the respective side's value. They are identical when the merge is clean, and
+// their differences are what conflict markers show.
+type MergeResult struct {
+	Ours      LogicalDatabase
+	Theirs    LogicalDatabase
+	Conflicts []MergeConflict
+}
+
+func Merge(opts MergeOptions) (MergeOutput, error) {
+	snapshots, err := resolveMergeSnapshots(opts.Root, opts.Args)
+	if err != nil {
+		return MergeOutput{}, err
+	}
+
+	corpora, err := loadMergeDataCorpora(opts.Root, opts.Config, snapshots)
+	if err != nil {
+		return MergeOutput{}, err
+	}
+
+	var dbs [3]LogicalDatabase
+	for idx, corpus := range corpora {
+		db, err := buildLogicalDatabase(corpus)
+		if err != nil {
+			return MergeOutput{}, err
+		}
+		dbs[idx] = storedLogicalDatabase(db, corpus.Schema)
+	}
+
+	result, err := mergeLogicalDatabases(dbs[0], dbs[1], dbs[2], corpora[1].Schema)
+	if err != nil {
+		return MergeOutput{}, err
+	}
+
+	plan, err := planMergedFiles(result, corpora, dbs)
+	if err != nil {
+		return MergeOutput{}, err
+	}
+
+	absRoot, err := filepath.Abs(opts.Root)
+	if err != nil {
+		return MergeOutput{}, fmt.Errorf("diff: resolve root: %w", err)
+	}
+	changes, err := applyMergedFiles(absRoot, plan, corpora[1], opts.DryRun)
+	if err != nil {
+		return MergeOutput{}, err
+	}
+
+	output := MergeOutput{Conflicted: len(plan.Conflicts) > 0}
+	if opts.JSON {
+		payload, err := marshalMergeReportJSON(plan.Conflicts, changes)
+		if err != nil {
+			return MergeOutput{}, err
+		}
+		output.Report = string(payload) + "\n"
+		return output, nil
+	}
+	output.Report = renderMergeReport(plan.Conflicts, changes)
+	return output, nil
+}
+
+// storedLogicalDatabase keeps only what data files store: inline records live
+// in the config, which git merges as text, and path-derived values are
+// recomputed on read.
+func storedLogicalDatabase(db LogicalDatabase, schema *diffSnapshotSchema) LogicalDatabase {
+	stored := LogicalDatabase{Snapshot: db.Snapshot, Objects: make([]LogicalObject, 0, len(db.Objects)), Schema: db.Schema}
+	for _, obj := range db.Objects {
+		if len(obj.Sources) > 0 && obj.Sources[0].Inline {
+			continue
+		}
+		if typeDef := schema.Types[obj.Type]; typeDef != nil && len(typeDef.DerivedFields) > 0 {
+			obj.Fields = cloneMap(obj.Fields)
+			for name := range typeDef.DerivedFields {
+				delete(obj.Fields, name)
+			}
+			if canonical, err := canonicalizeLogicalFields(obj.Fields); err == nil {
+				obj.Canonical = canonical
+			}
+		}
+		stored.Objects = append(stored.Objects, obj)
+	}
+	return stored
+}
+
+func resolveMergeSnapshots(root string, args []string) ([3]SnapshotRef, error) {
+	if len(args) != 3 {
+		return [3]SnapshotRef{}, ErrMergeArgs
+	}
+
+	var snapshots [3]SnapshotRef
+	for idx, revision := range args {
+		if err := validateGitRevision(root, revision); err != nil {
+			return [3]SnapshotRef{}, err
+		}
+		snapshots[idx] = SnapshotRef{Kind: SnapshotKindRevision, Revision: revision}
+	}
+	return snapshots, nil
+}
+
+// loadMergeDataCorpora loads base, ours, and theirs over the same set of data
+// paths, so a file that exists in any of them is known in all three.
+func loadMergeDataCorpora(root, configPath string, snapshots [3]SnapshotRef) ([3]SnapshotDataCorpus, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return [3]SnapshotDataCorpus{}, fmt.Errorf("diff: resolve root: %w", err)
+	}
+	absConfig, err := filepath.Abs(configPath)
+	if err != nil {
+		return [3]SnapshotDataCorpus{}, fmt.Errorf("diff: resolve config path: %w", err)
+	}
+
+	readers := newSnapshotReaders(absRoot)
+	defer readers.Close()
+
+	sides, err := readers.openAll(absConfig, snapshots[:]...)
+	if err != nil {
+		return [3]SnapshotDataCorpus{}, err
+	}
+	paths, err := discoverDiffDataPaths(sides)
+	if err != nil {
+		return [3]SnapshotDataCorpus{}, err
+	}
+
+	var corpora [3]SnapshotDataCorpus
+	for idx, side := range sides {
+		corpora[idx], err = loadSnapshotDataCorpus(side, paths)
+		if err != nil {
+			return [3]SnapshotDataCorpus{}, err
+		}
+	}
+	return corpora, nil
+}
+
+// mergeLogicalDatabases merges ours and theirs object by object against base.
+//
+// Invariants:
+//   - objects are matched by logical identity, so moves never look like
+//     delete plus add
+//   - edits to different fields, and a relocation on one side with edits on
+//     the other, merge without conflict
+//   - reference lists merge as sets, matching how diff compares them; other
+//     lists merge by position, and overlapping edits conflict
+//   - results and conflicts are ordered deterministically
+//
+// schema tells reference fields apart; a nil schema merges every list by
+// position.
+func mergeLogicalDatabases(base, ours, theirs LogicalDatabase, schema *diffSnapshotSchema) (MergeResult, error) {
+	baseByKey := logicalObjectsByKey(base)
+	oursByKey := logicalObjectsByKey(ours)
+	theirsByKey := logicalObjectsByKey(theirs)
+
+	keys := make(map[string]struct{}, len(baseByKey)+len(oursByKey)+len(theirsByKey))
+	for _, objects := range []map[string]LogicalObject{baseByKey, oursByKey, theirsByKey} {
+		for key := range objects {
+			keys[key] = struct{}{}
+		}
+	}
+
+	result := MergeResult{
+		Ours:   LogicalDatabase{Snapshot: ours.Snapshot},
+		Theirs: LogicalDatabase{Snapshot: theirs.Snapshot},
+	}
+	for _, key := range sortedKeys(keys) {
+		baseObj, baseOK := baseByKey[key]
+		oursObj, oursOK := oursByKey[key]
+		theirsObj, theirsOK := theirsByKey[key]
+
+		merged, err := mergeLogicalObject(
+			mergeObjectSlot{Object: baseObj, Present: baseOK},
+			mergeObjectSlot{Object: oursObj, Present: oursOK},
+			mergeObjectSlot{Object: theirsObj, Present: theirsOK},
+			schema,
+		)
+		if err != nil {
+			return MergeResult{}, err
+		}
+		if merged.ours.Present {
+			result.Ours.Objects = append(result.Ours.Objects, merged.ours.Object)
+		}
+		if merged.theirs.Present {
+			result.Theirs.Objects = append(result.Theirs.Objects, merged.theirs.Object)
+		}
+		result.Conflicts = append(result.Conflicts, merged.conflicts...)
+	}
+
+	return result, nil
+}
+
+type mergeObjectSlot struct {
+	Object  LogicalObject
+	Present bool
+}
+
+type mergedObject struct {
+	ours      mergeObjectSlot
+	theirs    mergeObjectSlot
+	conflicts []MergeConflict
+}
+
+func mergeLogicalObject(base, ours, theirs mergeObjectSlot, schema *diffSnapshotSchema) (mergedObject, error) {
+	typeName, id := base.Object.Type, base.Object.ID
+	if ours.Present {
+		typeName, id = ours.Object.Type, ours.Object.ID
+	} else if theirs.Present {
+		typeName, id = theirs.Object.Type, theirs.Object.ID
+	}
+
+	if !ours.Present || !theirs.Present {
+		switch {
+		case !ours.Present && !theirs.Present:
+			return mergedObject{}, nil
+		case !base.Present:
+			kept := ours
+			if !ours.Present {
+				kept = theirs
+			}
+			return mergedObject{ours: kept, theirs: kept}, nil
+		}
+
+		kept := ours
+		if !ours.Present {
+			kept = theirs
+		}
+		unchanged, err := semanticValuesEqual(base.Object.Fields, kept.Object.Fields)
+		if err != nil {
+			return mergedObject{}, fmt.Errorf("diff: merge %s %q: %w", typeName, id, err)
+		}
+		if unchanged {
+			return mergedObject{}, nil
+		}
+		return mergedObject{
+			ours:   ours,
+			theirs: theirs,
+			conflicts: []MergeConflict{{
+				Kind:     MergeConflictKindDeleteModify,
+				Type:     typeName,
+				ObjectID: id,
+				Base:     cloneMap(base.Object.Fields),
+				Ours:     mergeSlotFields(ours),
+				Theirs:   mergeSlotFields(theirs),
+			}},
+		}, nil
+	}
+
+	var baseFields mergeValueSlot
+	if base.Present {
+		baseFields = mergeValueSlot{Value: base.Object.Fields, Present: true}
+	}
+	oursValue, theirsValue, fieldConflicts, err := mergeValueSlots(
+		"",
+		baseFields,
+		mergeValueSlot{Value: ours.Object.Fields, Present: true},
+		mergeValueSlot{Value: theirs.Object.Fields, Present: true},
+		schemaTypeReferences(schema, typeName),
+	)
+	if err != nil {
+		return mergedObject{}, fmt.Errorf("diff: merge %s %q: %w", typeName, id, err)
+	}
+
+	merged := mergedObject{}
+	for _, conflict := range fieldConflicts {
+		conflict.Type = typeName
+		conflict.ObjectID = id
+		merged.conflicts = append(merged.conflicts, conflict)
+	}
+
+	oursSources, theirsSources := ours.Object.Sources, theirs.Object.Sources
+	switch {
+	case semanticSourcesEqual(oursSources, theirsSources):
+	case base.Present && semanticSourcesEqual(base.Object.Sources, oursSources):
+		oursSources = theirsSources
+	case base.Present && semanticSourcesEqual(base.Object.Sources, theirsSources):
+		theirsSources = oursSources
+	default:
+		var baseSources any
+		if base.Present {
+			baseSources = sourcePaths(base.Object.Sources)
+		}
+		merged.conflicts = append(merged.conflicts, MergeConflict{
+			Kind:     MergeConflictKindRelocation,
+			Type:     typeName,
+			ObjectID: id,
+			Base:     baseSources,
+			Ours:     sourcePaths(oursSources),
+			Theirs:   sourcePaths(theirsSources),
+		})
+	}
+
+	merged.ours, err = mergedObjectSlot(typeName, id, oursValue, oursSources)
+	if err != nil {
+		return mergedObject{}, err
+	}
+	merged.theirs, err = mergedObjectSlot(typeName, id, theirsValue, theirsSources)
+	if err != nil {
+		return mergedObject{}, err
+	}
+	return merged, nil
+}
+
+func mergedObjectSlot(typeName, id string, value mergeValueSlot, sources []LogicalObjectSource) (mergeObjectSlot, error) {
+	fields, _ := value.Value.(map[string]any)
+	canonical, err := canonicalizeLogicalFields(fields)
+	if err != nil {
+		return mergeObjectSlot{}, fmt.Errorf("diff: merge %s %q: %w", typeName, id, err)
+	}
+	return mergeObjectSlot{
+		Object: LogicalObject{
+			Type:      typeName,
+			ID:        id,
+			Fields:    fields,
+			Canonical: canonical,
+			Sources:   cloneLogicalSources(sources),
+		},
+		Present: true,
+	}, nil
+}
+
+func mergeSlotFields(slot mergeObjectSlot) any {
+	if !slot.Present {
+		return nil
+	}
+	return cloneMap(slot.Object.Fields)
+}
+
+type mergeValueSlot struct {
+	Value   any
+	Present bool
+}
+
+// mergeValueSlots merges one value three ways and returns the result resolved
+// towards ours and towards theirs. Nested objects merge key by key, lists of
+// the reference fields in references merge as sets, and other lists merge by
+// position; any other value changed differently on both sides, and lists
+// edited at overlapping positions, are a conflict at path.
+func mergeValueSlots(path string, base, ours, theirs mergeValueSlot, references map[string][]string) (mergeValueSlot, mergeValueSlot, []MergeConflict, error) {
+	if equal, err := mergeSlotsEqual(ours, theirs); err != nil || equal {
+		return cloneValueSlot(ours), cloneValueSlot(ours), nil, err
+	}
+	if equal, err := mergeSlotsEqual(base, ours); err != nil || equal {
+		return cloneValueSlot(theirs), cloneValueSlot(theirs), nil, err
+	}
+	if equal, err := mergeSlotsEqual(base, theirs); err != nil || equal {
+		return cloneValueSlot(ours), cloneValueSlot(ours), nil, err
+	}
+
+	if ours.Present && theirs.Present {
+		baseMap, baseIsMap := base.Value.(map[string]any)
+		oursMap, oursIsMap := ours.Value.(map[string]any)
+		theirsMap, theirsIsMap := theirs.Value.(map[string]any)
+		if oursIsMap && theirsIsMap && (baseIsMap || !base.Present) {
+			return mergeMapSlots(path, baseMap, oursMap, theirsMap, references)
+		}
+
+		baseList, baseIsList := base.Value.([]any)
+		oursList, oursIsList := ours.Value.([]any)
+		theirsList, theirsIsList := theirs.Value.([]any)
+		if oursIsList && theirsIsList && (baseIsList || !base.Present) {
+			merge := mergeSequenceValues
+			if _, ok := references[path]; ok {
+				merge = mergeListValues
+			}
+			merged, ok, err := merge(baseList, oursList, theirsList)
+			if err != nil {
+				return mergeValueSlot{}, mergeValueSlot{}, nil, err
+			}
+			if ok {
+				slot := mergeValueSlot{Value: merged, Present: true}
+				return slot, cloneValueSlot(slot), nil, nil
+			}
+		}
+	}
+
+	conflict := MergeConflict{
+		Kind:   MergeConflictKindField,
+		Field:  path,
+		Base:   cloneValue(base.Value),
+		Ours:   cloneValue(ours.Value),
+		Theirs: cloneValue(theirs.Value),
+	}
+	return cloneValueSlot(ours), cloneValueSlot(theirs), []MergeConflict{conflict}, nil
+}
+
+func mergeMapSlots(path string, base, ours, theirs map[string]any, references map[string][]string) (mergeValueSlot, mergeValueSlot, []MergeConflict, error) {
+	keySet := make(map[string]struct{}, len(ours)+len(theirs))
+	for _, values := range []map[string]any{base, ours, theirs} {
+		for key := range values {
+			keySet[key] = struct{}{}
+		}
+	}
+
+	oursMerged := make(map[string]any, len(keySet))
+	theirsMerged := make(map[string]any, len(keySet))
+	var conflicts []MergeConflict
+	for _, key := range sortedKeys(keySet) {
+		childPath := key
+		if path != "" {
+			childPath = path + "." + key
+		}
+		oursValue, theirsValue, childConflicts, err := mergeValueSlots(
+			childPath,
+			mapValueSlot(base, key),
+			mapValueSlot(ours, key),
+			mapValueSlot(theirs, key),
+			references,
+		)
+		if err != nil {
+			return mergeValueSlot{}, mergeValueSlot{}, nil, err
+		}
+		if oursValue.Present {
+			oursMerged[key] = oursValue.Value
+		}
+		if theirsValue.Present {
+			theirsMerged[key] = theirsValue.Value
+		}
+		conflicts = append(conflicts, childConflicts...)
+	}
+
+	return mergeValueSlot{Value: oursMerged, Present: true}, mergeValueSlot{Value: theirsMerged, Present: true}, conflicts, nil
+}
+
+// mergeListValues merges reference lists as sets: it keeps ours' order, drops
+// items theirs removed, and appends items theirs added. It never conflicts.
+func mergeListValues(base, ours, theirs []any) ([]any, bool, error) {
+	baseSet, err := canonicalValueSet(base)
+	if err != nil {
+		return nil, false, err
+	}
+	theirsSet, err := canonicalValueSet(theirs)
+	if err != nil {
+		return nil, false, err
+	}
+
+	merged := make([]any, 0, len(ours)+len(theirs))
+	seen := make(map[string]struct{}, len(ours)+len(theirs))
+	for _, item := range ours {
+		canonical, err := semanticCanonicalValue(item)
+		if err != nil {
+			return nil, false, err
+		}
+		_, inBase := baseSet[canonical]
+		_, inTheirs := theirsSet[canonical]
+		if inBase && !inTheirs {
+			continue
+		}
+		seen[canonical] = struct{}{}
+		merged = append(merged, cloneValue(item))
+	}
+	for _, item := range theirs {
+		canonical, err := semanticCanonicalValue(item)
+		if err != nil {
+			return nil, false, err
+		}
+		if _, ok := seen[canonical]; ok {
+			continue
+		}
+		if _, inBase := baseSet[canonical]; inBase {
+			continue
+		}
+		seen[canonical] = struct{}{}
+		merged = append(merged, cloneValue(item))
+	}
+	return merged, true, nil
+}
+
+// mergeSequenceValues merges lists by position. Each side's edit is the span
+// of base between the items it kept at the start and at the end; the edits
+// merge when their spans are disjoint, and the list conflicts when they
+// overlap or both insert at the same position.
+func mergeSequenceValues(base, ours, theirs []any) ([]any, bool, error) {
+	baseKeys, err := canonicalValueList(base)
+	if err != nil {
+		return nil, false, err
+	}
+	oursKeys, err := canonicalValueList(ours)
+	if err != nil {
+		return nil, false, err
+	}
+	theirsKeys, err := canonicalValueList(theirs)
+	if err != nil {
+		return nil, false, err
+	}
+
+	oursEdit := sequenceEditOf(baseKeys, oursKeys)
+	theirsEdit := sequenceEditOf(baseKeys, theirsKeys)
+	first, firstItems, second, secondItems := oursEdit, ours, theirsEdit, theirs
+	if theirsEdit.start < oursEdit.start || (theirsEdit.start == oursEdit.start && theirsEdit.end < oursEdit.end) {
+		first, firstItems, second, secondItems = theirsEdit, theirs, oursEdit, ours
+	}
+	if first.end > second.start || (first.end == second.start && (first.start == first.end || second.start == second.end)) {
+		return nil, false, nil
+	}
+
+	merged := make([]any, 0, len(ours)+len(theirs))
+	for _, segment := range [][]any{
+		base[:first.start],
+		firstItems[first.start : first.start+first.length],
+		base[first.end:second.start],
+		secondItems[second.start : second.start+second.length],
+		base[second.end:],
+	} {
+		for _, item := range segment {
+			merged = append(merged, cloneValue(item))
+		}
+	}
+	return merged, true, nil
+}
+
+// sequenceEdit replaces base[start:end] with length items of the edited list,
+// starting at the same index.
+type sequenceEdit struct {
+	start  int
+	end    int
+	length int
+}
+
+func sequenceEditOf(base, edited []string) sequenceEdit {
+	prefix := 0
+	for prefix < len(base) && prefix < len(edited) && base[prefix] == edited[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(base)-prefix && suffix < len(edited)-prefix && base[len(base)-1-suffix] == edited[len(edited)-1-suffix] {
+		suffix++
+	}
+	return sequenceEdit{start: prefix, end: len(base) - suffix, length: len(edited) - suffix - prefix}
+}
+
+func canonicalValueList(values []any) ([]string, error) {
+	keys := make([]string, 0, len(values))
+	for _, value := range values {
+		canonical, err := semanticCanonicalValue(value)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, canonical)
+	}
+	return keys, nil
+}
+
+// schemaTypeReferences returns the reference fields of typeName, or nil when
+// the schema does not declare the type.
+func schemaTypeReferences(schema *diffSnapshotSchema, typeName string) map[string][]string {
+	if schema == nil || schema.Types[typeName] == nil {
+		return nil
+	}
+	return schema.Types[typeName].References
+}
+
+func canonicalValueSet(values []any) (map[string]struct{}, error) {
+	set := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		canonical, err := semanticCanonicalValue(value)
+		if err != nil {
+			return nil, err
+		}
+		set[canonical] = struct{}{}
+	}
+	return set, nil
+}
+
+func mergeSlotsEqual(left, right mergeValueSlot) (bool, error) {
+	if left.Present != right.Present {
+		return false, nil
+	}
+	if !left.Present {
+		return true, nil
+	}
+	return semanticValuesEqual(left.Value, right.Value)
+}
+
+func mapValueSlot(values map[string]any, key string) mergeValueSlot {
+	value, ok := values[key]
+	return mergeValueSlot{Value: value, Present: ok}
+}
+
+func cloneValueSlot(slot mergeValueSlot) mergeValueSlot {
+	return mergeValueSlot{Value: cloneValue(slot.Value), Present: slot.Present}
+}
+
+func sourcePaths(sources []LogicalObjectSource) []string {
+	paths := make([]string, 0, len(sources))
+	for _, source := range sources {
+		paths = append(paths, source.Path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,181 @@
+package diff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	internalconfig "github.com/mergewayhq/mergeway-cli/internal/config"
+	"github.com/mergewayhq/mergeway-cli/internal/format"
+)
+
+var ErrMergeDriverArgs = errors.New("merge-driver requires 4 arguments: %O %A %B %P")
+
+// MergeDriverOptions describes one invocation of the git merge driver. Base,
+// Ours, and Theirs are the temporary files git passes as %O, %A, and %B; the
+// result is written back to Ours. Path is %P, the file's path in the
+// repository, which decides the entity type through the working-tree config.
+type MergeDriverOptions struct {
+	Root   string
+	Config string
+	Base   string
+	Ours   string
+	Theirs string
+	Path   string
+}
+
+// MergeDriverResult reports the conflicts left in the merged file. Textual is
+// set when the file is not Mergeway data (or does not parse) and the merge fell
+// back to `git merge-file`.
+type MergeDriverResult struct {
+	Conflicts []MergeConflict
+	Textual   bool
+}
+
+// Summary renders the remaining conflicts for git to show; it is empty for a
+// clean merge.
+func (r MergeDriverResult) Summary() string {
+	if len(r.Conflicts) == 0 {
+		return ""
+	}
+	return renderMergeReport(r.Conflicts, nil)
+}
+
+// RunMergeDriver merges one data file for git. Objects are merged by identity
+// exactly as in Merge, so two sides appending different items never conflict;
+// a clean result is formatted like `mergeway-cli fmt`.
+func RunMergeDriver(opts MergeDriverOptions) (MergeDriverResult, error) {
+	absRoot, err := filepath.Abs(opts.Root)
+	if err != nil {
+		return MergeDriverResult{}, fmt.Errorf("diff: resolve root: %w", err)
+	}
+	absConfig, err := filepath.Abs(opts.Config)
+	if err != nil {
+		return MergeDriverResult{}, fmt.Errorf("diff: resolve config path: %w", err)
+	}
+	relPath := filepath.ToSlash(filepath.Clean(opts.Path))
+	if filepath.IsAbs(opts.Path) {
+		if relPath, err = rootRelativePath(absRoot, opts.Path); err != nil {
+			return MergeDriverResult{}, fmt.Errorf("diff: path %s: %w", opts.Path, err)
+		}
+	}
+
+	worktree := SnapshotRef{Kind: SnapshotKindWorkingTree, WorkingTreeView: WorkingTreeViewFull}
+	reader, err := newSnapshotReader(absRoot, worktree)
+	if err != nil {
+		return MergeDriverResult{}, err
+	}
+	defer reader.Close()
+	cfg, err := loadSnapshotConfig(reader, absConfig)
+	if err != nil {
+		return MergeDriverResult{}, err
+	}
+	schema, err := newDiffSnapshotSchema(reader, cfg)
+	if err != nil {
+		return MergeDriverResult{}, err
+	}
+	matches := matchingSnapshotTypeIncludes(schema, relPath)
+	if len(matches) == 0 {
+		return textualMergeDriver(opts)
+	}
+
+	var corpora [3]SnapshotDataCorpus
+	var dbs [3]LogicalDatabase
+	for idx, side := range []struct {
+		label string
+		file  string
+	}{{"base", opts.Base}, {"ours", opts.Ours}, {"theirs", opts.Theirs}} {
+		content, err := os.ReadFile(side.file)
+		if err != nil {
+			return MergeDriverResult{}, fmt.Errorf("diff: read %s version of %s: %w", side.label, relPath, err)
+		}
+		corpora[idx] = SnapshotDataCorpus{
+			Snapshot: SnapshotRef{Kind: SnapshotKindRevision, Revision: side.label},
+			Schema:   schema,
+			Files: []SnapshotDataFile{{
+				Path:    relPath,
+				Exists:  len(bytes.TrimSpace(content)) > 0,
+				Content: content,
+			}},
+		}
+		db, err := buildLogicalDatabase(corpora[idx])
+		if err != nil {
+			var buildErr *LogicalDatabaseBuildError
+			if errors.As(err, &buildErr) && buildErr.Kind == LogicalDatabaseErrorParse {
+				return textualMergeDriver(opts)
+			}
+			return MergeDriverResult{}, err
+		}
+		dbs[idx] = storedLogicalDatabase(db, schema)
+	}
+
+	result, err := mergeLogicalDatabases(dbs[0], dbs[1], dbs[2], schema)
+	if err != nil {
+		return MergeDriverResult{}, err
+	}
+	plan, err := planMergedFiles(result, corpora, dbs)
+	if err != nil {
+		return MergeDriverResult{}, err
+	}
+
+	for _, conflict := range plan.Conflicts {
+		if conflict.Kind == MergeConflictKindFile {
+			return textualMergeDriver(opts)
+		}
+	}
+
+	merged := plan.Files[0]
+	content := merged.Content
+	if !merged.Exists {
+		content = nil
+	} else if !merged.Conflicted {
+		content, err = formatMergedFile(cfg, relPath, matches, content)
+		if err != nil {
+			return MergeDriverResult{}, err
+		}
+	}
+	if err := os.WriteFile(opts.Ours, content, 0o644); err != nil {
+		return MergeDriverResult{}, fmt.Errorf("diff: write merged %s: %w", relPath, err)
+	}
+	return MergeDriverResult{Conflicts: plan.Conflicts}, nil
+}
+
+// formatMergedFile applies the `mergeway-cli fmt` rules, ordering fields by
+// the type's schema when the file holds a single type. Items keep their
+// merged order, so a merge does not re-sort the file.
+func formatMergedFile(cfg *internalconfig.Config, path string, matches []snapshotTypeIncludeMatch, content []byte) ([]byte, error) {
+	var schema *format.Schema
+	if len(matches) == 1 {
+		schema = format.SchemaForType(cfg.Types[matches[0].Type.Name])
+	}
+	formatted, err := format.FormatBytesKeepingOrder(path, content, schema)
+	if err != nil {
+		return nil, fmt.Errorf("diff: format merged %s: %w", path, err)
+	}
+	return formatted, nil
+}
+
+// textualMergeDriver defers to git's line-based merge, which writes the
+// result into the ours file and exits with the number of conflicts (or 128
+// and above on errors).
+func textualMergeDriver(opts MergeDriverOptions) (MergeDriverResult, error) {
+	cmd := exec.Command("git", "merge-file", "-L", "ours", "-L", "base", "-L", "theirs", opts.Ours, opts.Base, opts.Theirs)
+	output, err := cmd.CombinedOutput()
+	result := MergeDriverResult{Textual: true}
+	if err == nil {
+		return result, nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 && exitErr.ExitCode() < 128 {
+		result.Conflicts = append(result.Conflicts, MergeConflict{
+			Kind:  MergeConflictKindFile,
+			Files: []string{filepath.ToSlash(opts.Path)},
+		})
+		return result, nil
+	}
+	return MergeDriverResult{}, fmt.Errorf("diff: git merge-file: %s", bytes.TrimSpace(output))
+}
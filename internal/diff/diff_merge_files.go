@@ -0,0 +1,708 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	mergeMarkerOurs   = "<<<<<<< ours"
+	mergeMarkerSplit  = "======="
+	mergeMarkerTheirs = ">>>>>>> theirs"
+)
+
+type mergedFile struct {
+	Path       string
+	Exists     bool
+	Content    []byte
+	Conflicted bool
+}
+
+type mergePlan struct {
+	Files     []mergedFile
+	Conflicts []MergeConflict
+}
+
+type MergeFileStatus string
+
+const (
+	MergeFileStatusCreated    MergeFileStatus = "created"
+	MergeFileStatusUpdated    MergeFileStatus = "updated"
+	MergeFileStatusRemoved    MergeFileStatus = "removed"
+	MergeFileStatusConflicted MergeFileStatus = "conflicted"
+)
+
+type mergeFileChange struct {
+	Path   string
+	Status MergeFileStatus
+}
+
+// planMergedFiles decides the content of every data file after the merge.
+// A file whose merged objects match one side's file keeps that side's bytes,
+// so formatting and comments survive whenever possible; other files are
+// rewritten from the merged objects, with conflict markers where ours and
+// theirs still disagree.
+func planMergedFiles(result MergeResult, corpora [3]SnapshotDataCorpus, dbs [3]LogicalDatabase) (mergePlan, error) {
+	var sideSignatures [3]map[string]string
+	for idx, db := range dbs {
+		sideSignatures[idx] = objectSignaturesByPath(logicalObjectsByPath(db.Objects))
+	}
+	oursByPath := logicalObjectsByPath(result.Ours.Objects)
+	theirsByPath := logicalObjectsByPath(result.Theirs.Objects)
+
+	plan := mergePlan{Conflicts: append([]MergeConflict(nil), result.Conflicts...)}
+	for idx, file := range corpora[1].Files {
+		path := file.Path
+		sides := [3]SnapshotDataFile{corpora[0].Files[idx], file, corpora[2].Files[idx]}
+
+		oursObjects, theirsObjects := oursByPath[path], theirsByPath[path]
+		signature := logicalObjectsSignature(oursObjects)
+		if signature == logicalObjectsSignature(theirsObjects) {
+			switch signature {
+			case sideSignatures[1][path]:
+				plan.Files = append(plan.Files, mergedFileFromSnapshot(sides[1]))
+				continue
+			case sideSignatures[2][path]:
+				plan.Files = append(plan.Files, mergedFileFromSnapshot(sides[2]))
+				continue
+			case sideSignatures[0][path]:
+				plan.Files = append(plan.Files, mergedFileFromSnapshot(sides[0]))
+				continue
+			}
+		}
+
+		content, conflicted, err := renderMergedFile(path, sides, corpora, oursObjects, theirsObjects)
+		if errors.Is(err, errMergeFileNotRenderable) {
+			plan.Files = append(plan.Files, mergedFile{
+				Path:       path,
+				Exists:     true,
+				Content:    wholeFileConflict(sides[1], sides[2]),
+				Conflicted: true,
+			})
+			plan.Conflicts = append(plan.Conflicts, MergeConflict{
+				Kind:   MergeConflictKindFile,
+				Base:   snapshotFileText(sides[0]),
+				Ours:   snapshotFileText(sides[1]),
+				Theirs: snapshotFileText(sides[2]),
+				Files:  []string{path},
+			})
+			continue
+		}
+		if err != nil {
+			return mergePlan{}, err
+		}
+		plan.Files = append(plan.Files, mergedFile{
+			Path:       path,
+			Exists:     content != nil,
+			Content:    content,
+			Conflicted: conflicted,
+		})
+	}
+
+	for idx := range plan.Conflicts {
+		conflict := &plan.Conflicts[idx]
+		if conflict.Kind == MergeConflictKindFile {
+			continue
+		}
+		conflict.Files = conflictFiles(conflict, result)
+	}
+	sort.SliceStable(plan.Conflicts, func(i, j int) bool {
+		left, right := plan.Conflicts[i], plan.Conflicts[j]
+		if left.Type != right.Type {
+			return left.Type < right.Type
+		}
+		if left.ObjectID != right.ObjectID {
+			return left.ObjectID < right.ObjectID
+		}
+		return strings.Join(left.Files, ",") < strings.Join(right.Files, ",")
+	})
+
+	return plan, nil
+}
+
+// applyMergedFiles writes the planned files to the working tree. It refuses to
+// touch a file whose working-tree content differs from ours, so local edits
+// are never silently lost. New content is first written to temporary files
+// next to the targets and renamed into place only once every write has
+// succeeded, so a failed write leaves the working tree as it was.
+func applyMergedFiles(root string, plan mergePlan, ours SnapshotDataCorpus, dryRun bool) ([]mergeFileChange, error) {
+	oursFiles := make(map[string]SnapshotDataFile, len(ours.Files))
+	for _, file := range ours.Files {
+		oursFiles[file.Path] = file
+	}
+
+	type pendingWrite struct {
+		file   mergedFile
+		change mergeFileChange
+		target string
+		staged string
+	}
+	var pending []pendingWrite
+	for _, file := range plan.Files {
+		current, exists, err := readWorkingTreeFile(root, file.Path)
+		if err != nil {
+			return nil, err
+		}
+		if exists == file.Exists && bytes.Equal(current, file.Content) {
+			continue
+		}
+		if base := oursFiles[file.Path]; exists != base.Exists || !bytes.Equal(current, base.Content) {
+			return nil, fmt.Errorf("merge: local changes to %s would be overwritten; commit or stash them first", file.Path)
+		}
+
+		change := mergeFileChange{Path: file.Path, Status: MergeFileStatusUpdated}
+		switch {
+		case file.Conflicted:
+			change.Status = MergeFileStatusConflicted
+		case !file.Exists:
+			change.Status = MergeFileStatusRemoved
+		case !exists:
+			change.Status = MergeFileStatusCreated
+		}
+		pending = append(pending, pendingWrite{file: file, change: change, target: filepath.Join(root, filepath.FromSlash(file.Path))})
+	}
+
+	changes := make([]mergeFileChange, 0, len(pending))
+	for _, write := range pending {
+		changes = append(changes, write.change)
+	}
+	if dryRun {
+		return changes, nil
+	}
+
+	defer func() {
+		for _, write := range pending {
+			if write.staged != "" {
+				_ = os.Remove(write.staged)
+			}
+		}
+	}()
+	for idx := range pending {
+		write := &pending[idx]
+		if !write.file.Exists {
+			continue
+		}
+		staged, err := stageMergedFile(write.target, write.file.Content)
+		if err != nil {
+			return nil, fmt.Errorf("merge: write %s: %w", write.file.Path, err)
+		}
+		write.staged = staged
+	}
+
+	for idx := range pending {
+		write := &pending[idx]
+		if !write.file.Exists {
+			if err := os.Remove(write.target); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("merge: remove %s: %w", write.file.Path, err)
+			}
+			continue
+		}
+		if err := os.Rename(write.staged, write.target); err != nil {
+			return nil, fmt.Errorf("merge: write %s: %w", write.file.Path, err)
+		}
+		write.staged = ""
+	}
+	return changes, nil
+}
+
+// stageMergedFile writes content to a temporary file in target's directory,
+// with target's mode when it exists, and returns the temporary file's path.
+func stageMergedFile(target string, content []byte) (string, error) {
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(dir, ".mergeway-merge-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+
+	mode := fs.FileMode(0o644)
+	if info, err := os.Stat(target); err == nil {
+		mode = info.Mode()
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+func mergedFileFromSnapshot(file SnapshotDataFile) mergedFile {
+	return mergedFile{Path: file.Path, Exists: file.Exists, Content: file.Content}
+}
+
+func logicalObjectsByPath(objects []LogicalObject) map[string][]LogicalObject {
+	byPath := make(map[string][]LogicalObject)
+	for _, obj := range objects {
+		for _, source := range obj.Sources {
+			byPath[source.Path] = append(byPath[source.Path], obj)
+		}
+	}
+	return byPath
+}
+
+func objectSignaturesByPath(byPath map[string][]LogicalObject) map[string]string {
+	signatures := make(map[string]string, len(byPath))
+	for path, objects := range byPath {
+		signatures[path] = logicalObjectsSignature(objects)
+	}
+	return signatures
+}
+
+// logicalObjectsSignature identifies the logical content of one file:
+// which objects it holds and their normalized values.
+func logicalObjectsSignature(objects []LogicalObject) string {
+	parts := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		canonical, err := semanticCanonicalValue(obj.Fields)
+		if err != nil {
+			canonical = obj.Canonical
+		}
+		parts = append(parts, logicalObjectMapKey(obj.Type, obj.ID)+"\x00"+canonical)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "\n")
+}
+
+func conflictFiles(conflict *MergeConflict, result MergeResult) []string {
+	paths := make(map[string]struct{})
+	for _, db := range []LogicalDatabase{result.Ours, result.Theirs} {
+		for _, obj := range db.Objects {
+			if obj.Type != conflict.Type || obj.ID != conflict.ObjectID {
+				continue
+			}
+			for _, source := range obj.Sources {
+				paths[source.Path] = struct{}{}
+			}
+		}
+	}
+	return sortedKeys(paths)
+}
+
+var errMergeFileNotRenderable = errors.New("merged file cannot be rewritten")
+
+type mergeFileLayout struct {
+	json    bool
+	items   bool
+	typed   bool
+	typeDef *diffSnapshotType
+}
+
+// renderMergedFile rewrites a data file from the merged objects. It returns
+// nil content when the file ends up without objects, and
+// errMergeFileNotRenderable for files that cannot be regenerated faithfully:
+// selector includes, files shared by several types, or unknown formats.
+func renderMergedFile(path string, sides [3]SnapshotDataFile, corpora [3]SnapshotDataCorpus, ours, theirs []LogicalObject) ([]byte, bool, error) {
+	if len(ours) == 0 && len(theirs) == 0 {
+		return nil, false, nil
+	}
+
+	layout, err := mergedFileLayout(path, sides, corpora, ours, theirs)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(ours) > 1 || len(theirs) > 1 {
+		layout.items = true
+	}
+
+	oursByKey := make(map[string]LogicalObject, len(ours))
+	theirsByKey := make(map[string]LogicalObject, len(theirs))
+	keySet := make(map[string]struct{}, len(ours)+len(theirs))
+	for _, obj := range ours {
+		key := logicalObjectMapKey(obj.Type, obj.ID)
+		oursByKey[key] = obj
+		keySet[key] = struct{}{}
+	}
+	for _, obj := range theirs {
+		key := logicalObjectMapKey(obj.Type, obj.ID)
+		theirsByKey[key] = obj
+		keySet[key] = struct{}{}
+	}
+	keys := mergedObjectOrder(fileObjectKeys(corpora[1], sides[1]), fileObjectKeys(corpora[2], sides[2]), keySet)
+
+	var lines []string
+	conflicted := false
+	if layout.items {
+		lines = append(lines, mergeFileHeader(layout)...)
+	}
+	for idx, key := range keys {
+		oursObj, oursOK := oursByKey[key]
+		theirsObj, theirsOK := theirsByKey[key]
+		last := idx == len(keys)-1
+
+		var objectLines []string
+		switch {
+		case oursOK && theirsOK:
+			objectLines = layout.objectLines(oursObj.Fields, theirsObj.Fields, last)
+		case oursOK:
+			objectLines = conflictBlock(layout.objectLines(oursObj.Fields, oursObj.Fields, last), nil)
+		default:
+			objectLines = conflictBlock(nil, layout.objectLines(theirsObj.Fields, theirsObj.Fields, last))
+		}
+		for _, line := range objectLines {
+			if line == mergeMarkerOurs {
+				conflicted = true
+			}
+		}
+		if layout.items && !layout.json {
+			objectLines = prefixYAMLSequenceItem(objectLines)
+		}
+		lines = append(lines, objectLines...)
+	}
+	if layout.items && layout.json {
+		lines = append(lines, "  ]", "}")
+	}
+
+	return []byte(strings.Join(lines, "\n") + "\n"), conflicted, nil
+}
+
+// mergedObjectOrder orders the merged objects of a file as ours lists them,
+// so a merge does not reorder a file. An object only theirs lists goes after
+// the object preceding it in theirs, past any objects only ours added there.
+// Objects neither side's file lists, such as ones moved in from another
+// file, follow in identifier order.
+func mergedObjectOrder(oursKeys, theirsKeys []string, keySet map[string]struct{}) []string {
+	inTheirs := make(map[string]bool, len(theirsKeys))
+	for _, key := range theirsKeys {
+		inTheirs[key] = true
+	}
+
+	order := make([]string, 0, len(keySet))
+	placed := make(map[string]bool, len(keySet))
+	for _, key := range oursKeys {
+		if _, ok := keySet[key]; ok && !placed[key] {
+			order = append(order, key)
+			placed[key] = true
+		}
+	}
+
+	anchor := ""
+	for _, key := range theirsKeys {
+		if _, ok := keySet[key]; !ok || placed[key] {
+			if placed[key] {
+				anchor = key
+			}
+			continue
+		}
+		pos := 0
+		if anchor != "" {
+			pos = slices.Index(order, anchor) + 1
+		}
+		for pos < len(order) && !inTheirs[order[pos]] {
+			pos++
+		}
+		order = slices.Insert(order, pos, key)
+		placed[key] = true
+		anchor = key
+	}
+
+	var rest []string
+	for key := range keySet {
+		if !placed[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	return append(order, rest...)
+}
+
+// fileObjectKeys lists the objects of a data file in the order the file
+// holds them, or nil when the file cannot be read as data.
+func fileObjectKeys(corpus SnapshotDataCorpus, file SnapshotDataFile) []string {
+	if !file.Exists || corpus.Schema == nil {
+		return nil
+	}
+	var keys []string
+	for _, match := range matchingSnapshotTypeIncludes(corpus.Schema, file.Path) {
+		parsed, err := parseLogicalObjectsFromFile(corpus.Snapshot, match.Type, match.Include, file)
+		if err != nil {
+			return nil
+		}
+		for _, obj := range parsed {
+			keys = append(keys, logicalObjectMapKey(obj.Type, obj.ID))
+		}
+	}
+	return keys
+}
+
+func mergedFileLayout(path string, sides [3]SnapshotDataFile, corpora [3]SnapshotDataCorpus, ours, theirs []LogicalObject) (mergeFileLayout, error) {
+	layout := mergeFileLayout{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		layout.json = true
+	case ".yaml", ".yml":
+	default:
+		return mergeFileLayout{}, errMergeFileNotRenderable
+	}
+
+	typeName := ""
+	for _, obj := range append(append([]LogicalObject(nil), ours...), theirs...) {
+		if typeName != "" && obj.Type != typeName {
+			return mergeFileLayout{}, errMergeFileNotRenderable
+		}
+		typeName = obj.Type
+		for _, source := range obj.Sources {
+			if source.ReadOnly || source.Selector != "" {
+				return mergeFileLayout{}, errMergeFileNotRenderable
+			}
+		}
+	}
+	for _, corpus := range []SnapshotDataCorpus{corpora[1], corpora[2], corpora[0]} {
+		if corpus.Schema != nil && corpus.Schema.Types[typeName] != nil {
+			layout.typeDef = corpus.Schema.Types[typeName]
+			break
+		}
+	}
+	if layout.typeDef == nil {
+		return mergeFileLayout{}, errMergeFileNotRenderable
+	}
+
+	for _, side := range []SnapshotDataFile{sides[1], sides[2], sides[0]} {
+		if !side.Exists {
+			continue
+		}
+		var doc map[string]any
+		if err := yaml.Unmarshal(side.Content, &doc); err != nil {
+			continue
+		}
+		_, layout.items = doc["items"]
+		_, layout.typed = doc["type"]
+		break
+	}
+	return layout, nil
+}
+
+func mergeFileHeader(layout mergeFileLayout) []string {
+	if layout.json {
+		lines := []string{"{"}
+		if layout.typed {
+			lines = append(lines, fmt.Sprintf("  \"type\": %s,", mustJSONString(layout.typeDef.Name)))
+		}
+		return append(lines, "  \"items\": [")
+	}
+
+	var lines []string
+	if layout.typed {
+		lines = append(lines, "type: "+layout.typeDef.Name)
+	}
+	return append(lines, "items:")
+}
+
+// objectLines renders one object, marking the fields where ours and theirs
+// differ. last reports whether the object closes a JSON items array.
+func (l mergeFileLayout) objectLines(ours, theirs map[string]any, last bool) []string {
+	indent := 0
+	switch {
+	case l.items && l.json:
+		indent = 6
+	case l.items:
+		indent = 4
+	case l.json:
+		indent = 2
+	}
+
+	keys := l.fieldOrder(ours, theirs)
+	oursLast, theirsLast := lastPresentKey(keys, ours), lastPresentKey(keys, theirs)
+
+	var lines, oursBlock, theirsBlock []string
+	flush := func() {
+		if len(oursBlock) > 0 || len(theirsBlock) > 0 {
+			lines = append(lines, conflictBlock(oursBlock, theirsBlock)...)
+		}
+		oursBlock, theirsBlock = nil, nil
+	}
+	for _, key := range keys {
+		oursValue, oursOK := ours[key]
+		theirsValue, theirsOK := theirs[key]
+		var oursLines, theirsLines []string
+		if oursOK {
+			oursLines = l.fieldLines(key, oursValue, indent, key != oursLast)
+		}
+		if theirsOK {
+			theirsLines = l.fieldLines(key, theirsValue, indent, key != theirsLast)
+		}
+		if strings.Join(oursLines, "\n") == strings.Join(theirsLines, "\n") {
+			flush()
+			lines = append(lines, oursLines...)
+			continue
+		}
+		oursBlock = append(oursBlock, oursLines...)
+		theirsBlock = append(theirsBlock, theirsLines...)
+	}
+	flush()
+
+	switch {
+	case l.items && l.json:
+		closing := "    }"
+		if !last {
+			closing += ","
+		}
+		return append(append([]string{"    {"}, lines...), closing)
+	case l.json:
+		return append(append([]string{"{"}, lines...), "}")
+	case len(lines) == 0 && l.items:
+		return []string{"    {}"}
+	case len(lines) == 0:
+		return []string{"{}"}
+	default:
+		return lines
+	}
+}
+
+// fieldOrder lists field names in schema order, identifier first, followed by
+// any remaining names alphabetically.
+func (l mergeFileLayout) fieldOrder(ours, theirs map[string]any) []string {
+	present := make(map[string]struct{}, len(ours)+len(theirs))
+	for _, values := range []map[string]any{ours, theirs} {
+		for key := range values {
+			present[key] = struct{}{}
+		}
+	}
+
+	keys := make([]string, 0, len(present))
+	take := func(key string) {
+		if _, ok := present[key]; ok {
+			keys = append(keys, key)
+			delete(present, key)
+		}
+	}
+	take(l.typeDef.IdentifierField)
+	for _, key := range l.typeDef.FieldOrder {
+		take(key)
+	}
+	return append(keys, sortedKeys(present)...)
+}
+
+func (l mergeFileLayout) fieldLines(key string, value any, indent int, comma bool) []string {
+	pad := strings.Repeat(" ", indent)
+	if l.json {
+		encoded, err := json.MarshalIndent(value, pad, "  ")
+		if err != nil {
+			encoded = []byte("null")
+		}
+		line := pad + mustJSONString(key) + ": " + string(encoded)
+		if comma {
+			line += ","
+		}
+		return strings.Split(line, "\n")
+	}
+
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(map[string]any{key: value}); err != nil {
+		return []string{pad + key + ": null"}
+	}
+	_ = enc.Close()
+
+	lines := strings.Split(strings.TrimRight(b.String(), "\n"), "\n")
+	for idx := range lines {
+		lines[idx] = pad + lines[idx]
+	}
+	return lines
+}
+
+func lastPresentKey(keys []string, values map[string]any) string {
+	for idx := len(keys) - 1; idx >= 0; idx-- {
+		if _, ok := values[keys[idx]]; ok {
+			return keys[idx]
+		}
+	}
+	return ""
+}
+
+func conflictBlock(ours, theirs []string) []string {
+	lines := make([]string, 0, len(ours)+len(theirs)+3)
+	lines = append(lines, mergeMarkerOurs)
+	lines = append(lines, ours...)
+	lines = append(lines, mergeMarkerSplit)
+	lines = append(lines, theirs...)
+	return append(lines, mergeMarkerTheirs)
+}
+
+// prefixYAMLSequenceItem turns the first field line of an object, on each side
+// of any conflict block it starts in, into a `- ` sequence entry.
+func prefixYAMLSequenceItem(lines []string) []string {
+	out := append([]string(nil), lines...)
+	section := ""
+	oursDone, theirsDone := false, false
+	for idx, line := range out {
+		switch line {
+		case mergeMarkerOurs:
+			section = "ours"
+			continue
+		case mergeMarkerSplit:
+			section = "theirs"
+			continue
+		case mergeMarkerTheirs:
+			section = ""
+			continue
+		}
+
+		prefix := false
+		switch section {
+		case "ours":
+			prefix, oursDone = !oursDone, true
+		case "theirs":
+			prefix, theirsDone = !theirsDone, true
+		default:
+			prefix = !oursDone && !theirsDone
+			oursDone, theirsDone = true, true
+		}
+		if prefix && strings.HasPrefix(line, "    ") {
+			out[idx] = "  - " + line[4:]
+		}
+	}
+	return out
+}
+
+func wholeFileConflict(ours, theirs SnapshotDataFile) []byte {
+	var b bytes.Buffer
+	for _, part := range []struct {
+		marker string
+		file   SnapshotDataFile
+	}{{mergeMarkerOurs, ours}, {mergeMarkerSplit, theirs}} {
+		b.WriteString(part.marker + "\n")
+		b.Write(part.file.Content)
+		if len(part.file.Content) > 0 && !bytes.HasSuffix(part.file.Content, []byte("\n")) {
+			b.WriteByte('\n')
+		}
+	}
+	b.WriteString(mergeMarkerTheirs + "\n")
+	return b.Bytes()
+}
+
+func snapshotFileText(file SnapshotDataFile) any {
+	if !file.Exists {
+		return nil
+	}
+	return string(file.Content)
+}
+
+func mustJSONString(value string) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return `""`
+	}
+	return string(encoded)
+}
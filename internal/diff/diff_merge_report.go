@@ -0,0 +1,105 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type mergeJSONDocument struct {
+	Version   int                 `json:"version"`
+	Clean     bool                `json:"clean"`
+	Files     []mergeJSONFile     `json:"files"`
+	Conflicts []mergeJSONConflict `json:"conflicts"`
+}
+
+type mergeJSONFile struct {
+	Path   string          `json:"path"`
+	Status MergeFileStatus `json:"status"`
+}
+
+type mergeJSONConflict struct {
+	Kind     MergeConflictKind `json:"kind"`
+	Type     string            `json:"type,omitempty"`
+	ObjectID string            `json:"object_id,omitempty"`
+	Field    string            `json:"field,omitempty"`
+	Base     any               `json:"base"`
+	Ours     any               `json:"ours"`
+	Theirs   any               `json:"theirs"`
+	Files    []string          `json:"files"`
+}
+
+func marshalMergeReportJSON(conflicts []MergeConflict, changes []mergeFileChange) ([]byte, error) {
+	doc := mergeJSONDocument{
+		Version:   1,
+		Clean:     len(conflicts) == 0,
+		Files:     make([]mergeJSONFile, 0, len(changes)),
+		Conflicts: make([]mergeJSONConflict, 0, len(conflicts)),
+	}
+	for _, change := range changes {
+		doc.Files = append(doc.Files, mergeJSONFile(change))
+	}
+	for _, conflict := range conflicts {
+		doc.Conflicts = append(doc.Conflicts, mergeJSONConflict{
+			Kind:     conflict.Kind,
+			Type:     conflict.Type,
+			ObjectID: conflict.ObjectID,
+			Field:    conflict.Field,
+			Base:     cloneValue(conflict.Base),
+			Ours:     cloneValue(conflict.Ours),
+			Theirs:   cloneValue(conflict.Theirs),
+			Files:    append([]string{}, conflict.Files...),
+		})
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func renderMergeReport(conflicts []MergeConflict, changes []mergeFileChange) string {
+	if len(conflicts) == 0 && len(changes) == 0 {
+		return "No changes.\n"
+	}
+
+	var b strings.Builder
+	for _, change := range changes {
+		fmt.Fprintf(&b, "%s %s\n", strings.ToUpper(string(change.Status)), change.Path)
+	}
+
+	for _, conflict := range conflicts {
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		switch conflict.Kind {
+		case MergeConflictKindFile:
+			fmt.Fprintf(&b, "CONFLICT (%s) %s\n", conflict.Kind, strings.Join(conflict.Files, ", "))
+			b.WriteString("  the file cannot be rewritten from merged objects; both versions were kept\n")
+			continue
+		case MergeConflictKindField:
+			fmt.Fprintf(&b, "CONFLICT (%s) %s[%s] %s\n", conflict.Kind, conflict.Type, conflict.ObjectID, conflict.Field)
+		default:
+			fmt.Fprintf(&b, "CONFLICT (%s) %s[%s]\n", conflict.Kind, conflict.Type, conflict.ObjectID)
+		}
+		fmt.Fprintf(&b, "  base: %s\n", formatMergeConflictValue(conflict.Kind, conflict.Base))
+		fmt.Fprintf(&b, "  ours: %s\n", formatMergeConflictValue(conflict.Kind, conflict.Ours))
+		fmt.Fprintf(&b, "  theirs: %s\n", formatMergeConflictValue(conflict.Kind, conflict.Theirs))
+		if len(conflict.Files) > 0 {
+			fmt.Fprintf(&b, "  in: %s\n", strings.Join(conflict.Files, ", "))
+		}
+	}
+
+	if len(conflicts) > 0 {
+		fmt.Fprintf(&b, "\nAutomatic merge failed with %d conflict(s); resolve the conflict markers and commit the result.\n", len(conflicts))
+	}
+	return b.String()
+}
+
+func formatMergeConflictValue(kind MergeConflictKind, value any) string {
+	if value == nil {
+		switch kind {
+		case MergeConflictKindDeleteModify:
+			return "(deleted)"
+		case MergeConflictKindRelocation:
+			return "(absent)"
+		}
+	}
+	return formatDiffValue(value)
+}
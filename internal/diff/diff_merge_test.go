@@ -0,0 +1,388 @@
+package diff
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mergewayhq/mergeway-cli/internal/testutil"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMergeLogicalDatabasesCombinesNonOverlappingFieldEdits(t *testing.T) {
+	base := logicalDatabaseFixture(
+		logicalObjectFixture("User", "User-1", map[string]any{"id": "User-1", "name": "Ada", "email": "ada@example.com"}, "data/users/user-1.yaml"),
+	)
+	ours := logicalDatabaseFixture(
+		logicalObjectFixture("User", "User-1", map[string]any{"id": "User-1", "name": "Ada Lovelace", "email": "ada@example.com"}, "data/users/user-1.yaml"),
+	)
+	theirs := logicalDatabaseFixture(
+		logicalObjectFixture("User", "User-1", map[string]any{"id": "User-1", "name": "Ada", "email": "ada@lovelace.dev"}, "data/users/user-1.yaml"),
+	)
+
+	result := mustMergeLogicalDatabases(t, base, ours, theirs)
+
+	if len(result.Conflicts) != 0 {
+		t.Fatalf("expected a clean merge, got %+v", result.Conflicts)
+	}
+	want := map[string]any{"id": "User-1", "name": "Ada Lovelace", "email": "ada@lovelace.dev"}
+	for _, db := range []LogicalDatabase{result.Ours, result.Theirs} {
+		if len(db.Objects) != 1 || !reflect.DeepEqual(db.Objects[0].Fields, want) {
+			t.Fatalf("expected both edits in the merged object, got %+v", db.Objects)
+		}
+	}
+}
+
+func TestMergeLogicalDatabasesReportsFieldConflicts(t *testing.T) {
+	base := logicalDatabaseFixture(
+		logicalObjectFixture("User", "User-1", map[string]any{"id": "User-1", "profile": map[string]any{"name": "Ada", "team": "core"}}, "data/users/user-1.yaml"),
+	)
+	ours := logicalDatabaseFixture(
+		logicalObjectFixture("User", "User-1", map[string]any{"id": "User-1", "profile": map[string]any{"name": "Ada L", "team": "core"}}, "data/users/user-1.yaml"),
+	)
+	theirs := logicalDatabaseFixture(
+		logicalObjectFixture("User", "User-1", map[string]any{"id": "User-1", "profile": map[string]any{"name": "A. Lovelace", "team": "docs"}}, "data/users/user-1.yaml"),
+	)
+
+	result := mustMergeLogicalDatabases(t, base, ours, theirs)
+
+	want := []MergeConflict{{
+		Kind:     MergeConflictKindField,
+		Type:     "User",
+		ObjectID: "User-1",
+		Field:    "profile.name",
+		Base:     "Ada",
+		Ours:     "Ada L",
+		Theirs:   "A. Lovelace",
+	}}
+	if !reflect.DeepEqual(result.Conflicts, want) {
+		t.Fatalf("unexpected conflicts\nwant: %#v\ngot:  %#v", want, result.Conflicts)
+	}
+	oursProfile := result.Ours.Objects[0].Fields["profile"].(map[string]any)
+	theirsProfile := result.Theirs.Objects[0].Fields["profile"].(map[string]any)
+	if oursProfile["name"] != "Ada L" || theirsProfile["name"] != "A. Lovelace" {
+		t.Fatalf("expected each view to keep its side of the conflict, got %v and %v", oursProfile, theirsProfile)
+	}
+	if oursProfile["team"] != "docs" || theirsProfile["team"] != "docs" {
+		t.Fatalf("expected the non-conflicting sibling change in both views, got %v and %v", oursProfile, theirsProfile)
+	}
+}
+
+func TestMergeLogicalDatabasesHandlesDeletions(t *testing.T) {
+	base := logicalDatabaseFixture(
+		logicalObjectFixture("Tag", "Tag-1", map[string]any{"id": "Tag-1", "label": "One"}, "data/tags/tag-1.yaml"),
+		logicalObjectFixture("Tag", "Tag-2", map[string]any{"id": "Tag-2", "label": "Two"}, "data/tags/tag-2.yaml"),
+	)
+	ours := logicalDatabaseFixture()
+	theirs := logicalDatabaseFixture(
+		logicalObjectFixture("Tag", "Tag-1", map[string]any{"id": "Tag-1", "label": "One"}, "data/tags/tag-1.yaml"),
+		logicalObjectFixture("Tag", "Tag-2", map[string]any{"id": "Tag-2", "label": "Two!"}, "data/tags/tag-2.yaml"),
+	)
+
+	result := mustMergeLogicalDatabases(t, base, ours, theirs)
+
+	if len(result.Conflicts) != 1 {
+		t.Fatalf("expected one delete/modify conflict, got %+v", result.Conflicts)
+	}
+	conflict := result.Conflicts[0]
+	if conflict.Kind != MergeConflictKindDeleteModify || conflict.ObjectID != "Tag-2" || conflict.Ours != nil {
+		t.Fatalf("expected Tag-2 deleted on ours and modified on theirs, got %+v", conflict)
+	}
+	if got := logicalObjectKeys(result.Ours); len(got) != 0 {
+		t.Fatalf("expected the ours view to keep the deletion, got %v", got)
+	}
+	if got := logicalObjectKeys(result.Theirs); !reflect.DeepEqual(got, []string{"Tag:Tag-2"}) {
+		t.Fatalf("expected the unmodified Tag-1 deletion to merge cleanly, got %v", got)
+	}
+}
+
+func TestMergeLogicalDatabasesCombinesRelocationWithEdit(t *testing.T) {
+	base := logicalDatabaseFixture(
+		logicalObjectFixture("User", "User-1", map[string]any{"id": "User-1", "name": "Ada"}, "data/users/user-1.yaml"),
+	)
+	ours := logicalDatabaseFixture(
+		logicalObjectFixture("User", "User-1", map[string]any{"id": "User-1", "name": "Ada"}, "data/users/ada.yaml"),
+	)
+	theirs := logicalDatabaseFixture(
+		logicalObjectFixture("User", "User-1", map[string]any{"id": "User-1", "name": "Ada Lovelace"}, "data/users/user-1.yaml"),
+	)
+
+	result := mustMergeLogicalDatabases(t, base, ours, theirs)
+
+	if len(result.Conflicts) != 0 {
+		t.Fatalf("expected relocation and edit to merge cleanly, got %+v", result.Conflicts)
+	}
+	merged := result.Theirs.Objects[0]
+	if merged.Fields["name"] != "Ada Lovelace" || merged.Sources[0].Path != "data/users/ada.yaml" {
+		t.Fatalf("expected the edited object at the new location, got %+v", merged)
+	}
+
+	theirs = logicalDatabaseFixture(
+		logicalObjectFixture("User", "User-1", map[string]any{"id": "User-1", "name": "Ada"}, "data/users/lovelace.yaml"),
+	)
+	result = mustMergeLogicalDatabases(t, base, ours, theirs)
+	if len(result.Conflicts) != 1 || result.Conflicts[0].Kind != MergeConflictKindRelocation {
+		t.Fatalf("expected a relocation conflict when both sides move the object, got %+v", result.Conflicts)
+	}
+}
+
+func TestMergeLogicalDatabasesMergesReferenceListsAsSets(t *testing.T) {
+	base := logicalDatabaseFixture(
+		logicalObjectFixture("Post", "Post-1", map[string]any{"id": "Post-1", "tags": []any{"a", "b"}}, "data/posts/posts.yaml"),
+	)
+	ours := logicalDatabaseFixture(
+		logicalObjectFixture("Post", "Post-1", map[string]any{"id": "Post-1", "tags": []any{"a", "b", "c"}}, "data/posts/posts.yaml"),
+	)
+	theirs := logicalDatabaseFixture(
+		logicalObjectFixture("Post", "Post-1", map[string]any{"id": "Post-1", "tags": []any{"b", "d"}}, "data/posts/posts.yaml"),
+	)
+
+	result := mustMergeLogicalDatabases(t, base, ours, theirs)
+
+	if len(result.Conflicts) != 0 {
+		t.Fatalf("expected list edits to merge, got %+v", result.Conflicts)
+	}
+	if got := result.Ours.Objects[0].Fields["tags"]; !reflect.DeepEqual(got, []any{"b", "c", "d"}) {
+		t.Fatalf("expected removal of a and additions of c and d, got %v", got)
+	}
+}
+
+func TestMergeLogicalDatabasesMergesValueListsByPosition(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     []any
+		ours     []any
+		theirs   []any
+		want     []any
+		conflict bool
+	}{
+		{name: "edits at both ends", base: []any{"a", "b", "c"}, ours: []any{"z", "b", "c"}, theirs: []any{"a", "b", "c", "d"}, want: []any{"z", "b", "c", "d"}},
+		{name: "reorder on one side", base: []any{"a", "b", "c"}, ours: []any{"c", "b", "a"}, theirs: []any{"a", "b", "c"}, want: []any{"c", "b", "a"}},
+		{name: "adjacent replacements", base: []any{"a", "b"}, ours: []any{"x", "b"}, theirs: []any{"a", "y"}, want: []any{"x", "y"}},
+		{name: "duplicates kept", base: []any{"a"}, ours: []any{"a", "a"}, theirs: []any{"b", "a"}, want: []any{"b", "a", "a"}},
+		{name: "same item changed differently", base: []any{"a", "b", "c"}, ours: []any{"a", "x", "c"}, theirs: []any{"a", "y", "c"}, conflict: true},
+		{name: "inserts at the same position", base: []any{"a", "b"}, ours: []any{"a", "x", "b"}, theirs: []any{"a", "y", "b"}, conflict: true},
+		{name: "removal overlapping an edit", base: []any{"a", "b", "c"}, ours: []any{"a"}, theirs: []any{"a", "b", "z"}, conflict: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db := func(aliases []any) LogicalDatabase {
+				return logicalDatabaseFixture(
+					logicalObjectFixture("User", "User-1", map[string]any{"id": "User-1", "aliases": aliases}, "data/users/user-1.yaml"),
+				)
+			}
+
+			result := mustMergeLogicalDatabases(t, db(tc.base), db(tc.ours), db(tc.theirs))
+
+			if tc.conflict {
+				if len(result.Conflicts) != 1 || result.Conflicts[0].Field != "aliases" {
+					t.Fatalf("expected a conflict on aliases, got %+v", result.Conflicts)
+				}
+				return
+			}
+			if len(result.Conflicts) != 0 {
+				t.Fatalf("expected a clean merge, got %+v", result.Conflicts)
+			}
+			if got := result.Ours.Objects[0].Fields["aliases"]; !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMergeWritesMergedFilesToWorkingTree(t *testing.T) {
+	repo := testutil.NewGitRepo(t)
+	base := repo.Revision(t, "HEAD")
+	ours := repo.CommitDataChange(t, "data/posts/posts.yaml", "items:\n  - id: Post-001\n    title: First Post (ours)\n    author: User-Alice\n    tags:\n      - Tag-Writing\n      - Tag-Product\n    body: Hello world\n  - id: Post-002\n    title: Second Post\n    author: User-Alice\n    tags:\n      - Tag-Writing\n    body: Another post\n")
+	theirs := repo.CommitOnBranch(t, base, "theirs", map[string]string{
+		"data/posts/posts.yaml":     "items:\n  - id: Post-001\n    title: First Post\n    author: User-Alice\n    tags:\n      - Tag-Writing\n      - Tag-Product\n    body: Hello world\n  - id: Post-002\n    title: Second Post\n    author: User-Alice\n    tags:\n      - Tag-Writing\n    body: Another post (theirs)\n",
+		"data/tags/tag-design.yaml": "id: Tag-Design\nlabel: Design\n",
+	})
+
+	output, err := Merge(MergeOptions{
+		Root:   repo.Root,
+		Config: filepath.Join(repo.Root, "mergeway.yaml"),
+		Args:   []string{base, ours, theirs},
+	})
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if output.Conflicted {
+		t.Fatalf("expected a clean merge, got %s", output.Report)
+	}
+	if output.Report != "UPDATED data/posts/posts.yaml\nCREATED data/tags/tag-design.yaml\n" {
+		t.Fatalf("unexpected report %q", output.Report)
+	}
+
+	posts := testutil.ReadFile(t, repo.Root, "data/posts/posts.yaml")
+	want := "items:\n  - id: Post-001\n    title: First Post (ours)\n    author: User-Alice\n    tags:\n      - Tag-Writing\n      - Tag-Product\n    body: Hello world\n  - id: Post-002\n    title: Second Post\n    author: User-Alice\n    tags:\n      - Tag-Writing\n    body: Another post (theirs)\n"
+	if posts != want {
+		t.Fatalf("expected both post edits in schema field order\nwant:\n%s\ngot:\n%s", want, posts)
+	}
+	if tag := testutil.ReadFile(t, repo.Root, "data/tags/tag-design.yaml"); tag != "id: Tag-Design\nlabel: Design\n" {
+		t.Fatalf("expected theirs' new file verbatim, got %q", tag)
+	}
+}
+
+func TestMergeKeepsItemOrderWhenBothSidesAppend(t *testing.T) {
+	repo := testutil.NewGitRepo(t)
+	base := repo.Revision(t, "HEAD")
+	posts := testutil.ReadFile(t, repo.Root, "data/posts/posts.yaml")
+	ours := repo.CommitDataChange(t, "data/posts/posts.yaml", posts+"  - id: Draft-Ours\n    title: Ours\n    author: User-Bob\n")
+	theirs := repo.CommitOnBranch(t, base, "theirs", map[string]string{
+		"data/posts/posts.yaml": posts + "  - id: Draft-Theirs\n    title: Theirs\n    author: User-Bob\n",
+	})
+
+	output, err := Merge(MergeOptions{
+		Root:   repo.Root,
+		Config: filepath.Join(repo.Root, "mergeway.yaml"),
+		Args:   []string{base, ours, theirs},
+	})
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if output.Conflicted {
+		t.Fatalf("expected a clean merge, got %s", output.Report)
+	}
+
+	var doc struct {
+		Items []struct {
+			ID string `yaml:"id"`
+		} `yaml:"items"`
+	}
+	if err := yaml.Unmarshal([]byte(testutil.ReadFile(t, repo.Root, "data/posts/posts.yaml")), &doc); err != nil {
+		t.Fatalf("parse merged posts: %v", err)
+	}
+	var order []string
+	for _, item := range doc.Items {
+		order = append(order, item.ID)
+	}
+	if want := []string{"Post-001", "Post-002", "Draft-Ours", "Draft-Theirs"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("expected items in file order %v, got %v", want, order)
+	}
+}
+
+func TestApplyMergedFilesWritesNothingWhenAWriteFails(t *testing.T) {
+	root := t.TempDir()
+	testutil.WriteFile(t, root, "data/a.yaml", "id: A\n")
+	// data/broken is a dangling directory link: reads see no file, but the
+	// directory for data/broken/b.yaml cannot be created.
+	if err := os.Symlink(filepath.Join(root, "missing"), filepath.Join(root, "data", "broken")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	plan := mergePlan{Files: []mergedFile{
+		{Path: "data/a.yaml", Exists: true, Content: []byte("id: A\nname: merged\n")},
+		{Path: "data/broken/b.yaml", Exists: true, Content: []byte("id: B\n")},
+	}}
+	ours := SnapshotDataCorpus{Files: []SnapshotDataFile{
+		{Path: "data/a.yaml", Exists: true, Content: []byte("id: A\n")},
+	}}
+
+	if _, err := applyMergedFiles(root, plan, ours, false); err == nil || !strings.Contains(err.Error(), "merge: write data/broken/b.yaml") {
+		t.Fatalf("expected the second write to fail, got %v", err)
+	}
+	if got := testutil.ReadFile(t, root, "data/a.yaml"); got != "id: A\n" {
+		t.Fatalf("expected data/a.yaml to be left alone, got %q", got)
+	}
+	entries, err := os.ReadDir(filepath.Join(root, "data"))
+	if err != nil {
+		t.Fatalf("read data dir: %v", err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".mergeway-merge-") {
+			t.Fatalf("expected staged files to be cleaned up, found %s", entry.Name())
+		}
+	}
+}
+
+func TestMergeWritesConflictMarkersAndJSONReport(t *testing.T) {
+	repo := testutil.NewGitRepo(t)
+	base := repo.Revision(t, "HEAD")
+	ours := repo.CommitDataChange(t, "data/users/user-bob.yaml", "id: User-Bob\nname: Bob Ours\nemail: bob@example.com\nrole: editor\n")
+	theirs := repo.CommitOnBranch(t, base, "theirs", map[string]string{
+		"data/users/user-bob.yaml": "id: User-Bob\nname: Bob Theirs\nemail: bob@theirs.example.com\nrole: editor\n",
+	})
+
+	output, err := Merge(MergeOptions{
+		Root:   repo.Root,
+		Config: filepath.Join(repo.Root, "mergeway.yaml"),
+		Args:   []string{base, ours, theirs},
+		JSON:   true,
+	})
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if !output.Conflicted {
+		t.Fatalf("expected conflicts, got %s", output.Report)
+	}
+
+	var doc struct {
+		Clean     bool `json:"clean"`
+		Conflicts []struct {
+			Kind   string   `json:"kind"`
+			Field  string   `json:"field"`
+			Ours   any      `json:"ours"`
+			Theirs any      `json:"theirs"`
+			Files  []string `json:"files"`
+		} `json:"conflicts"`
+	}
+	if err := json.Unmarshal([]byte(output.Report), &doc); err != nil {
+		t.Fatalf("parse report: %v\n%s", err, output.Report)
+	}
+	if doc.Clean || len(doc.Conflicts) != 1 || doc.Conflicts[0].Field != "name" || doc.Conflicts[0].Theirs != "Bob Theirs" {
+		t.Fatalf("expected one name conflict, got %s", output.Report)
+	}
+	if !reflect.DeepEqual(doc.Conflicts[0].Files, []string{"data/users/user-bob.yaml"}) {
+		t.Fatalf("expected the conflict file to be listed, got %v", doc.Conflicts[0].Files)
+	}
+
+	want := "id: User-Bob\n<<<<<<< ours\nname: Bob Ours\n=======\nname: Bob Theirs\n>>>>>>> theirs\nemail: bob@theirs.example.com\nrole: editor\n"
+	if got := testutil.ReadFile(t, repo.Root, "data/users/user-bob.yaml"); got != want {
+		t.Fatalf("expected field-level conflict markers\nwant:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestMergeRefusesToOverwriteLocalChanges(t *testing.T) {
+	repo := testutil.NewGitRepo(t)
+	base := repo.Revision(t, "HEAD")
+	ours := repo.Revision(t, "HEAD")
+	theirs := repo.CommitOnBranch(t, base, "theirs", map[string]string{
+		"data/tags/tag-product.yaml": "id: Tag-Product\nlabel: Product (theirs)\n",
+	})
+	repo.WriteDataChange(t, "data/tags/tag-product.yaml", "id: Tag-Product\nlabel: Product (local)\n")
+
+	_, err := Merge(MergeOptions{
+		Root:   repo.Root,
+		Config: filepath.Join(repo.Root, "mergeway.yaml"),
+		Args:   []string{base, ours, theirs},
+	})
+	if err == nil || !strings.Contains(err.Error(), "local changes to data/tags/tag-product.yaml would be overwritten") {
+		t.Fatalf("expected local changes to block the merge, got %v", err)
+	}
+	if got := FormatMergeCommandError(err); !strings.HasPrefix(got, "merge: repository state error: ") {
+		t.Fatalf("expected a repository state error, got %q", got)
+	}
+	if got := testutil.ReadFile(t, repo.Root, "data/tags/tag-product.yaml"); !strings.Contains(got, "(local)") {
+		t.Fatalf("expected the local edit to survive, got %q", got)
+	}
+}
+
+// mergeTestSchema declares Post.tags as a reference list; every other list in
+// the merge tests is a plain value list.
+var mergeTestSchema = &diffSnapshotSchema{Types: map[string]*diffSnapshotType{
+	"Post": {Name: "Post", IdentifierField: "id", References: map[string][]string{"tags": {"Tag"}}},
+}}
+
+func mustMergeLogicalDatabases(t *testing.T, base, ours, theirs LogicalDatabase) MergeResult {
+	t.Helper()
+	result, err := mergeLogicalDatabases(base, ours, theirs, mergeTestSchema)
+	if err != nil {
+		t.Fatalf("merge logical databases: %v", err)
+	}
+	return result
+}
@@ -0,0 +1,447 @@
+package diff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	internalconfig "github.com/mergewayhq/mergeway-cli/internal/config"
+	"github.com/mergewayhq/mergeway-cli/internal/data"
+)
+
+var ErrInvalidPatch = errors.New("invalid patch")
+
+// PatchOptions applies Patch, a diff written by `mergeway-diff --format json`,
+// to the repository at Root.
+type PatchOptions struct {
+	Root     string
+	Config   string
+	Patch    []byte
+	ThreeWay bool
+}
+
+type PatchStatus string
+
+const (
+	// PatchStatusApplied entries were written as recorded in the patch.
+	PatchStatusApplied PatchStatus = "applied"
+	// PatchStatusMerged entries had drifted and were written after a clean
+	// three-way merge.
+	PatchStatusMerged PatchStatus = "merged"
+	// PatchStatusUnchanged entries already hold the patch's result.
+	PatchStatusUnchanged PatchStatus = "unchanged"
+	// PatchStatusSkipped entries are relocations, which depend on the file
+	// layout the patch was taken from.
+	PatchStatusSkipped PatchStatus = "skipped"
+	// PatchStatusDrifted entries no longer match the patch's old value.
+	PatchStatusDrifted PatchStatus = "drifted"
+	// PatchStatusConflict entries drifted and the three-way merge conflicted.
+	PatchStatusConflict PatchStatus = "conflict"
+	// PatchStatusReadOnly entries target inline or selector-sourced objects,
+	// which the store cannot write.
+	PatchStatusReadOnly PatchStatus = "read_only"
+	// PatchStatusNotApplied entries would have applied, but the patch was
+	// aborted because another entry drifted.
+	PatchStatusNotApplied PatchStatus = "not_applied"
+)
+
+// PatchEntryResult is the outcome of one patch entry. OldObjectID is set for
+// renames and Conflicts for PatchStatusConflict.
+type PatchEntryResult struct {
+	Kind        DiffEntryKind
+	Type        string
+	ObjectID    string
+	OldObjectID string
+	Status      PatchStatus
+	Conflicts   []MergeConflict
+}
+
+// PatchResult lists the outcome of every patch entry, in patch order.
+type PatchResult struct {
+	Entries []PatchEntryResult
+}
+
+// Failed reports whether any entry was left unapplied because it drifted,
+// conflicted, or targets a read-only object.
+func (r PatchResult) Failed() bool {
+	for _, entry := range r.Entries {
+		switch entry.Status {
+		case PatchStatusDrifted, PatchStatusConflict, PatchStatusReadOnly:
+			return true
+		}
+	}
+	return false
+}
+
+// Written reports whether any entry was written to the repository.
+func (r PatchResult) Written() bool {
+	for _, entry := range r.Entries {
+		switch entry.Status {
+		case PatchStatusApplied, PatchStatusMerged:
+			return true
+		}
+	}
+	return false
+}
+
+// patchStep is the planned write for one entry: the fields to store, or a
+// deletion when fields is nil. A rename then deletes renameFrom.
+type patchStep struct {
+	result     PatchEntryResult
+	create     bool
+	write      bool
+	renameFrom string
+	fields     map[string]any
+}
+
+// ApplyPatch replays the added, removed, modified, and renamed entries of a
+// semantic diff against the working tree through data.Store, so objects are matched
+// by identity and written wherever the current config places them.
+//
+// An entry applies when the object still holds the patch's old value. For
+// modified and renamed entries only the paths listed in the entry's changes
+// are compared and written, so a diff narrowed by --field or --ignore-field
+// replays just what it shows.
+// Without ThreeWay, any drifted entry aborts the whole patch before anything
+// is written. With ThreeWay, drifted entries are merged field by field, with
+// the patch's old value as base, the current value as ours, and the patch's
+// new value as theirs; clean entries are written and conflicting ones are
+// left untouched. Path-derived fields are ignored throughout, since they
+// follow the file layout rather than the data.
+//
+// When a write fails, the error is returned together with the result: entries
+// written before it keep their status and the remaining ones are reported as
+// not applied.
+func ApplyPatch(opts PatchOptions) (PatchResult, error) {
+	var doc diffJSONDocument
+	if err := json.Unmarshal(opts.Patch, &doc); err != nil {
+		return PatchResult{}, fmt.Errorf("diff: %w: %v", ErrInvalidPatch, err)
+	}
+	if doc.Version != 1 {
+		return PatchResult{}, fmt.Errorf("diff: %w: unsupported version %d", ErrInvalidPatch, doc.Version)
+	}
+
+	absRoot, err := filepath.Abs(opts.Root)
+	if err != nil {
+		return PatchResult{}, fmt.Errorf("diff: resolve root: %w", err)
+	}
+	absConfig, err := filepath.Abs(opts.Config)
+	if err != nil {
+		return PatchResult{}, fmt.Errorf("diff: resolve config path: %w", err)
+	}
+
+	current, schema, err := loadPatchTarget(absRoot, absConfig)
+	if err != nil {
+		return PatchResult{}, err
+	}
+	objects := logicalObjectsByKey(current)
+
+	steps := make([]patchStep, 0, len(doc.Entries))
+	for idx, entry := range doc.Entries {
+		typeDef := schema.Types[entry.Type]
+		if typeDef == nil {
+			return PatchResult{}, fmt.Errorf("diff: %w: entry %d: unknown type %q", ErrInvalidPatch, idx+1, entry.Type)
+		}
+		var step patchStep
+		if entry.Kind == DiffEntryKindRenamed {
+			step, err = planRenamedPatchEntry(entry, objects, typeDef, opts.ThreeWay)
+		} else {
+			var obj *LogicalObject
+			if found, ok := objects[logicalObjectMapKey(entry.Type, entry.ObjectID)]; ok {
+				obj = &found
+			}
+			step, err = planPatchEntry(entry, obj, typeDef, opts.ThreeWay)
+		}
+		if err != nil {
+			return PatchResult{}, err
+		}
+		steps = append(steps, step)
+	}
+
+	result := PatchResult{Entries: make([]PatchEntryResult, 0, len(steps))}
+	for _, step := range steps {
+		result.Entries = append(result.Entries, step.result)
+	}
+	if result.Failed() && !opts.ThreeWay {
+		markPatchNotApplied(&result, steps, 0)
+		return result, nil
+	}
+	if !result.Written() {
+		return result, nil
+	}
+
+	cfg, err := internalconfig.Load(absConfig)
+	if err != nil {
+		markPatchNotApplied(&result, steps, 0)
+		return result, err
+	}
+	store, err := data.NewStore(absRoot, cfg)
+	if err != nil {
+		markPatchNotApplied(&result, steps, 0)
+		return result, err
+	}
+	for idx, step := range steps {
+		if !step.write {
+			continue
+		}
+		if err := writePatchStep(store, step); err != nil {
+			markPatchNotApplied(&result, steps, idx)
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// writePatchStep writes one planned entry. A rename stores the object under
+// its new identifier before deleting the old one, so a failed write never
+// loses it.
+func writePatchStep(store *data.Store, step patchStep) error {
+	var err error
+	switch {
+	case step.create:
+		_, err = store.Create(step.result.Type, step.fields)
+	case step.fields == nil:
+		err = store.Delete(step.result.Type, step.result.ObjectID)
+	default:
+		_, err = store.Update(step.result.Type, step.result.ObjectID, step.fields, false)
+	}
+	if err != nil {
+		return err
+	}
+	if step.renameFrom != "" {
+		if err := store.Delete(step.result.Type, step.renameFrom); err != nil {
+			return fmt.Errorf("diff: %s[%s] was created but %s[%s] could not be removed: %w", step.result.Type, step.result.ObjectID, step.result.Type, step.renameFrom, err)
+		}
+	}
+	return nil
+}
+
+// markPatchNotApplied reports the entries from index from on that were
+// planned to be written as not applied.
+func markPatchNotApplied(result *PatchResult, steps []patchStep, from int) {
+	for idx := from; idx < len(steps); idx++ {
+		if steps[idx].write {
+			result.Entries[idx].Status = PatchStatusNotApplied
+		}
+	}
+}
+
+// loadPatchTarget reads the logical database of the repository the patch is
+// applied to. It is read as a plain directory, so git is not required.
+func loadPatchTarget(root, configPath string) (LogicalDatabase, *diffSnapshotSchema, error) {
+	readers := newSnapshotReaders(root)
+	defer readers.Close()
+
+	sides, err := readers.openAll(configPath, SnapshotRef{Kind: SnapshotKindDirectory, Path: root})
+	if err != nil {
+		return LogicalDatabase{}, nil, err
+	}
+	paths, err := discoverDiffDataPaths(sides)
+	if err != nil {
+		return LogicalDatabase{}, nil, err
+	}
+	corpus, err := loadSnapshotDataCorpus(sides[0], paths)
+	if err != nil {
+		return LogicalDatabase{}, nil, err
+	}
+	db, err := buildLogicalDatabase(corpus)
+	if err != nil {
+		return LogicalDatabase{}, nil, err
+	}
+	return db, sides[0].schema, nil
+}
+
+// planPatchEntry decides what one entry does to obj, the object's current
+// state or nil when it does not exist.
+func planPatchEntry(entry diffJSONEntry, obj *LogicalObject, typeDef *diffSnapshotType, threeWay bool) (patchStep, error) {
+	step := patchStep{result: PatchEntryResult{Kind: entry.Kind, Type: entry.Type, ObjectID: entry.ObjectID, OldObjectID: entry.OldID}}
+
+	var base, target mergeValueSlot
+	switch entry.Kind {
+	case DiffEntryKindAdded:
+		target = mergeValueSlot{Value: storedPatchFields(typeDef, entry.Value), Present: true}
+	case DiffEntryKindRemoved:
+		base = mergeValueSlot{Value: storedPatchFields(typeDef, entry.Value), Present: true}
+	case DiffEntryKindModified, DiffEntryKindRenamed:
+		if entry.Kind == DiffEntryKindModified && len(entry.Changes) == 0 {
+			return patchStep{}, fmt.Errorf("diff: %w: modified entry %s[%s] lists no changes", ErrInvalidPatch, entry.Type, entry.ObjectID)
+		}
+		base = mergeValueSlot{Value: storedPatchFields(typeDef, entry.OldValue), Present: true}
+		target = mergeValueSlot{Value: storedPatchFields(typeDef, entry.NewValue), Present: true}
+	case DiffEntryKindRelocated:
+		step.result.Status = PatchStatusSkipped
+		return step, nil
+	default:
+		return patchStep{}, fmt.Errorf("diff: %w: unknown entry kind %q for %s[%s]", ErrInvalidPatch, entry.Kind, entry.Type, entry.ObjectID)
+	}
+
+	var current mergeValueSlot
+	if obj != nil {
+		current = mergeValueSlot{Value: storedPatchFields(typeDef, obj.Fields), Present: true}
+		if entry.Kind == DiffEntryKindModified || entry.Kind == DiffEntryKindRenamed {
+			base, target = patchChangeSlots(entry, obj.Fields, typeDef)
+		}
+	}
+
+	if done, err := mergeSlotsEqual(current, target); err != nil {
+		return patchStep{}, err
+	} else if done {
+		step.result.Status = PatchStatusUnchanged
+		return step, nil
+	}
+
+	resolved := target
+	step.result.Status = PatchStatusApplied
+	if clean, err := mergeSlotsEqual(current, base); err != nil {
+		return patchStep{}, err
+	} else if !clean {
+		if !threeWay {
+			step.result.Status = PatchStatusDrifted
+			return step, nil
+		}
+		merged, conflicts, err := mergePatchEntry(entry, typeDef, base, current, target)
+		if err != nil {
+			return patchStep{}, err
+		}
+		if len(conflicts) > 0 {
+			step.result.Status = PatchStatusConflict
+			step.result.Conflicts = conflicts
+			return step, nil
+		}
+		if unchanged, err := mergeSlotsEqual(current, merged); err != nil {
+			return patchStep{}, err
+		} else if unchanged {
+			step.result.Status = PatchStatusUnchanged
+			return step, nil
+		}
+		resolved = merged
+		step.result.Status = PatchStatusMerged
+	}
+
+	if obj != nil && len(obj.Sources) > 0 && (obj.Sources[0].Inline || obj.Sources[0].ReadOnly) {
+		step.result.Status = PatchStatusReadOnly
+		return step, nil
+	}
+
+	step.write = true
+	step.create = !current.Present
+	if resolved.Present {
+		step.fields, _ = resolved.Value.(map[string]any)
+	}
+	return step, nil
+}
+
+// planRenamedPatchEntry plans a rename as a change to the object under its
+// old identifier that is then stored under the new one. When only the new
+// identifier exists, the rename was already made and the entry is compared
+// against that object instead. When both exist, the new identifier belongs to
+// another object and the entry has drifted.
+func planRenamedPatchEntry(entry diffJSONEntry, objects map[string]LogicalObject, typeDef *diffSnapshotType, threeWay bool) (patchStep, error) {
+	oldObj, oldOK := objects[logicalObjectMapKey(entry.Type, entry.OldID)]
+	newObj, newOK := objects[logicalObjectMapKey(entry.Type, entry.ObjectID)]
+	switch {
+	case oldOK && newOK:
+		result := PatchEntryResult{Kind: entry.Kind, Type: entry.Type, ObjectID: entry.ObjectID, OldObjectID: entry.OldID, Status: PatchStatusDrifted}
+		return patchStep{result: result}, nil
+	case newOK:
+		return planPatchEntry(entry, &newObj, typeDef, threeWay)
+	case oldOK:
+		step, err := planPatchEntry(entry, &oldObj, typeDef, threeWay)
+		if err != nil || !step.write {
+			return step, err
+		}
+		step.renameFrom = entry.OldID
+		step.create = true
+		return step, nil
+	default:
+		return planPatchEntry(entry, nil, typeDef, threeWay)
+	}
+}
+
+// mergePatchEntry merges the patch's change into the current object. An
+// object deleted on one side and changed on the other is a delete/modify
+// conflict.
+func mergePatchEntry(entry diffJSONEntry, typeDef *diffSnapshotType, base, current, target mergeValueSlot) (mergeValueSlot, []MergeConflict, error) {
+	if base.Present && (!current.Present || !target.Present) {
+		conflict := MergeConflict{
+			Kind:     MergeConflictKindDeleteModify,
+			Type:     entry.Type,
+			ObjectID: entry.ObjectID,
+			Base:     cloneValue(base.Value),
+			Ours:     cloneValue(current.Value),
+			Theirs:   cloneValue(target.Value),
+		}
+		return mergeValueSlot{}, []MergeConflict{conflict}, nil
+	}
+
+	merged, _, conflicts, err := mergeValueSlots("", base, current, target, typeDef.References)
+	if err != nil {
+		return mergeValueSlot{}, nil, err
+	}
+	for idx := range conflicts {
+		conflicts[idx].Type = entry.Type
+		conflicts[idx].ObjectID = entry.ObjectID
+	}
+	return merged, conflicts, nil
+}
+
+// patchChangeSlots limits a modified or renamed entry to the paths listed in
+// its changes: base and target are the object's current fields with just
+// those paths, and the identifier of a rename, set to their old and new
+// values. A diff narrowed by --field or --ignore-field lists only the changes
+// it kept, so the fields it left out are neither checked for drift nor
+// written.
+func patchChangeSlots(entry diffJSONEntry, current map[string]any, typeDef *diffSnapshotType) (mergeValueSlot, mergeValueSlot) {
+	base := cloneMap(current)
+	target := cloneMap(current)
+	for _, change := range entry.Changes {
+		copyFieldPath(base, entry.OldValue, change.Path)
+		copyFieldPath(target, entry.NewValue, change.Path)
+	}
+	if entry.Kind == DiffEntryKindRenamed && typeDef.IdentifierField != "" {
+		copyFieldPath(base, entry.OldValue, typeDef.IdentifierField)
+		copyFieldPath(target, entry.NewValue, typeDef.IdentifierField)
+	}
+	return mergeValueSlot{Value: storedPatchFields(typeDef, base), Present: true},
+		mergeValueSlot{Value: storedPatchFields(typeDef, target), Present: true}
+}
+
+// copyFieldPath sets the dotted fieldPath in dst to its value in src, or
+// removes it from dst when src does not hold it.
+func copyFieldPath(dst, src map[string]any, fieldPath string) {
+	keys := strings.Split(fieldPath, ".")
+	for _, key := range keys[:len(keys)-1] {
+		src, _ = src[key].(map[string]any)
+		child, ok := dst[key].(map[string]any)
+		if !ok {
+			if src == nil {
+				return
+			}
+			child = make(map[string]any)
+			dst[key] = child
+		}
+		dst = child
+	}
+
+	last := keys[len(keys)-1]
+	if value, ok := src[last]; ok {
+		dst[last] = cloneValue(value)
+	} else {
+		delete(dst, last)
+	}
+}
+
+// storedPatchFields drops path-derived fields, which the store recomputes
+// from wherever the object ends up.
+func storedPatchFields(typeDef *diffSnapshotType, fields map[string]any) map[string]any {
+	stored := cloneMap(fields)
+	if stored == nil {
+		stored = make(map[string]any)
+	}
+	for name := range typeDef.DerivedFields {
+		delete(stored, name)
+	}
+	return stored
+}
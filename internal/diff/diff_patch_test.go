@@ -0,0 +1,203 @@
+package diff
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mergewayhq/mergeway-cli/internal/testutil"
+)
+
+// newPatchFixture returns a diff that renames Bob, adds Tag-New, and removes
+// Tag-Product, and a copy of the fixture with every user moved into one file.
+func newPatchFixture(t *testing.T) ([]byte, string) {
+	t.Helper()
+	left := testutil.CopyFixture(t)
+	right := testutil.CopyFixture(t)
+	writeFixtureFiles(t, right, map[string]string{
+		"data/users/user-bob.yaml": "id: User-Bob\nname: Robert Example\nemail: bob@example.com\nrole: editor\n",
+		"data/tags/tag-new.yaml":   "id: Tag-New\nlabel: New\n",
+	})
+	if err := os.Remove(filepath.Join(right, "data", "tags", "tag-product.yaml")); err != nil {
+		t.Fatalf("remove tag: %v", err)
+	}
+
+	patch, err := Run(Options{
+		Root:   left,
+		Config: filepath.Join(left, "mergeway.yaml"),
+		Args:   []string{"dir:" + left, "dir:" + right},
+		Format: OutputFormatJSON,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	target := testutil.CopyFixture(t)
+	for _, name := range []string{"user-alice.yaml", "user-bob.yaml"} {
+		if err := os.Remove(filepath.Join(target, "data", "users", name)); err != nil {
+			t.Fatalf("remove user: %v", err)
+		}
+	}
+	writeFixtureFiles(t, target, map[string]string{
+		"data/users/all.yaml": "items:\n" +
+			"  - id: User-Alice\n    name: Alice Example\n    email: alice@example.com\n    role: admin\n" +
+			"  - id: User-Bob\n    name: Bob Example\n    email: bob@example.com\n    role: editor\n",
+	})
+	return []byte(patch), target
+}
+
+func writeFixtureFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(path)), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+}
+
+func applyPatch(t *testing.T, target string, patch []byte, threeWay bool) PatchResult {
+	t.Helper()
+	result, err := ApplyPatch(PatchOptions{
+		Root:     target,
+		Config:   filepath.Join(target, "mergeway.yaml"),
+		Patch:    patch,
+		ThreeWay: threeWay,
+	})
+	if err != nil {
+		t.Fatalf("ApplyPatch: %v", err)
+	}
+	return result
+}
+
+func patchStatuses(result PatchResult) []string {
+	statuses := make([]string, 0, len(result.Entries))
+	for _, entry := range result.Entries {
+		statuses = append(statuses, entry.ObjectID+":"+string(entry.Status))
+	}
+	return statuses
+}
+
+func TestApplyPatchAcrossLayouts(t *testing.T) {
+	patch, target := newPatchFixture(t)
+
+	result := applyPatch(t, target, patch, false)
+	expected := []string{"Tag-New:applied", "Tag-Product:applied", "User-Bob:applied"}
+	if got := patchStatuses(result); !reflect.DeepEqual(got, expected) || result.Failed() {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+
+	users := testutil.ReadFile(t, target, "data/users/all.yaml")
+	if !strings.Contains(users, "name: Robert Example") || !strings.Contains(users, "name: Alice Example") {
+		t.Fatalf("expected Bob renamed in place:\n%s", users)
+	}
+	if _, err := os.Stat(filepath.Join(target, "data", "users", "user-bob.yaml")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected no per-user file to be created, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "data", "tags", "tag-product.yaml")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected Tag-Product to be removed, got %v", err)
+	}
+
+	again := applyPatch(t, target, patch, false)
+	expected = []string{"Tag-New:unchanged", "Tag-Product:unchanged", "User-Bob:unchanged"}
+	if got := patchStatuses(again); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected a second apply to change nothing, got %v", got)
+	}
+}
+
+func TestApplyPatchRefusesDriftWithoutThreeWay(t *testing.T) {
+	patch, target := newPatchFixture(t)
+	users := strings.Replace(testutil.ReadFile(t, target, "data/users/all.yaml"), "Bob Example", "Bobby Example", 1)
+	writeFixtureFiles(t, target, map[string]string{"data/users/all.yaml": users})
+
+	result := applyPatch(t, target, patch, false)
+	expected := []string{"Tag-New:not_applied", "Tag-Product:not_applied", "User-Bob:drifted"}
+	if got := patchStatuses(result); !reflect.DeepEqual(got, expected) || !result.Failed() || result.Written() {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	if _, err := os.Stat(filepath.Join(target, "data", "tags", "tag-product.yaml")); err != nil {
+		t.Fatalf("expected nothing to be applied, got %v", err)
+	}
+}
+
+func TestApplyPatchKeepsFieldsOutsideTheChanges(t *testing.T) {
+	patch, target := newPatchFixture(t)
+	users := strings.Replace(testutil.ReadFile(t, target, "data/users/all.yaml"), "bob@example.com", "bob@example.org", 1)
+	writeFixtureFiles(t, target, map[string]string{"data/users/all.yaml": users})
+
+	result := applyPatch(t, target, patch, false)
+	expected := []string{"Tag-New:applied", "Tag-Product:applied", "User-Bob:applied"}
+	if got := patchStatuses(result); !reflect.DeepEqual(got, expected) || result.Failed() {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	merged := testutil.ReadFile(t, target, "data/users/all.yaml")
+	if !strings.Contains(merged, "name: Robert Example") || !strings.Contains(merged, "email: bob@example.org") {
+		t.Fatalf("expected both Bob edits to be kept:\n%s", merged)
+	}
+}
+
+func TestApplyPatchThreeWayMergesDriftedLists(t *testing.T) {
+	left, right := changedFixture(t, nil, map[string]string{
+		"data/posts/posts.yaml": strings.Replace(testutil.ReadFile(t, testutil.FixtureRepo(), "data/posts/posts.yaml"),
+			"      - Tag-Product\n", "      - Tag-Product\n      - Tag-New\n", 1),
+	})
+	patch := runDirDiff(t, left, right, Options{Format: OutputFormatJSON})
+
+	target := copyFixture(t)
+	writeFixtureFiles(t, target, map[string]string{
+		"data/posts/posts.yaml": strings.Replace(testutil.ReadFile(t, target, "data/posts/posts.yaml"), "      - Tag-Product\n", "", 1),
+	})
+	if result := applyPatch(t, target, []byte(patch), false); !reflect.DeepEqual(patchStatuses(result), []string{"Post-001:drifted"}) {
+		t.Fatalf("expected the tags to have drifted, got %v", patchStatuses(result))
+	}
+
+	result := applyPatch(t, target, []byte(patch), true)
+	if got := patchStatuses(result); !reflect.DeepEqual(got, []string{"Post-001:merged"}) {
+		t.Fatalf("expected a merged entry, got %v", got)
+	}
+	posts := testutil.ReadFile(t, target, "data/posts/posts.yaml")
+	if !strings.Contains(posts, "- Tag-New") || strings.Contains(posts, "- Tag-Product") {
+		t.Fatalf("expected Tag-New added and Tag-Product kept removed:\n%s", posts)
+	}
+}
+
+func TestApplyPatchThreeWayReportsConflicts(t *testing.T) {
+	patch, target := newPatchFixture(t)
+	users := strings.Replace(testutil.ReadFile(t, target, "data/users/all.yaml"), "Bob Example", "Bobby Example", 1)
+	writeFixtureFiles(t, target, map[string]string{"data/users/all.yaml": users})
+	if err := os.Remove(filepath.Join(target, "data", "tags", "tag-product.yaml")); err != nil {
+		t.Fatalf("remove tag: %v", err)
+	}
+
+	result := applyPatch(t, target, patch, true)
+	expected := []string{"Tag-New:applied", "Tag-Product:unchanged", "User-Bob:conflict"}
+	if got := patchStatuses(result); !reflect.DeepEqual(got, expected) || !result.Failed() {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	conflict := result.Entries[2].Conflicts[0]
+	if conflict.Kind != MergeConflictKindField || conflict.Field != "name" || conflict.Ours != "Bobby Example" || conflict.Theirs != "Robert Example" {
+		t.Fatalf("unexpected conflict %+v", conflict)
+	}
+	if !strings.Contains(testutil.ReadFile(t, target, "data/users/all.yaml"), "Bobby Example") {
+		t.Fatalf("expected the conflicting object to be left unchanged")
+	}
+	if _, err := os.Stat(filepath.Join(target, "data", "tags", "Tag-New.yaml")); err != nil {
+		t.Fatalf("expected Tag-New to be created, got %v", err)
+	}
+}
+
+func TestApplyPatchRejectsInvalidPatches(t *testing.T) {
+	target := testutil.CopyFixture(t)
+	for _, patch := range []string{
+		"not json",
+		`{"version": 2, "entries": []}`,
+		`{"version": 1, "entries": [{"kind": "added", "type": "Widget", "object_id": "w1", "value": {"id": "w1"}}]}`,
+	} {
+		_, err := ApplyPatch(PatchOptions{Root: target, Config: filepath.Join(target, "mergeway.yaml"), Patch: []byte(patch)})
+		if !errors.Is(err, ErrInvalidPatch) {
+			t.Fatalf("expected ErrInvalidPatch for %s, got %v", patch, err)
+		}
+	}
+}
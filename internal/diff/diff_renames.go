@@ -0,0 +1,187 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var ErrInvalidRenameThreshold = errors.New("invalid rename threshold")
+
+// DefaultRenameThreshold is the similarity, in percent, used when rename
+// detection is requested without a threshold.
+const DefaultRenameThreshold = 50
+
+func validateRenameThreshold(threshold int) error {
+	if threshold < 0 || threshold > 100 {
+		return fmt.Errorf("%w: %d (expected a percentage from 1 to 100, or 0 to disable)", ErrInvalidRenameThreshold, threshold)
+	}
+	return nil
+}
+
+type renameCandidate struct {
+	removed int
+	added   int
+	matches int
+	total   int
+}
+
+// better orders candidates by similarity, then by entry position so pairing
+// is deterministic.
+func (c renameCandidate) better(other renameCandidate) bool {
+	if left, right := c.matches*other.total, other.matches*c.total; left != right {
+		return left > right
+	}
+	if c.removed != other.removed {
+		return c.removed < other.removed
+	}
+	return c.added < other.added
+}
+
+// detectRenames pairs removed and added objects of the same type whose
+// fields, other than the identifier and path-derived fields, match by at
+// least threshold percent, and replaces each pair with a renamed entry.
+// Pairs are chosen greedily from the most similar down, so every object is
+// part of at most one rename. right supplies the schema and the references
+// that still hold the old identifier.
+//
+// A renamed entry takes the position of its added entry, which keeps the
+// result ordered by type and current identifier.
+func detectRenames(result DiffResult, right LogicalDatabase, threshold int) (DiffResult, error) {
+	removedByType := make(map[string][]int)
+	addedByType := make(map[string][]int)
+	for idx, entry := range result.Entries {
+		switch entry.Kind {
+		case DiffEntryKindRemoved:
+			removedByType[entry.Type] = append(removedByType[entry.Type], idx)
+		case DiffEntryKindAdded:
+			addedByType[entry.Type] = append(addedByType[entry.Type], idx)
+		}
+	}
+
+	var candidates []renameCandidate
+	for typeName, removed := range removedByType {
+		ignored := renameIgnoredFields(right.Schema, typeName)
+		for _, removedIdx := range removed {
+			for _, addedIdx := range addedByType[typeName] {
+				matches, total, err := renameSimilarity(result.Entries[removedIdx].OldValue, result.Entries[addedIdx].NewValue, ignored)
+				if err != nil {
+					return DiffResult{}, fmt.Errorf("diff: compare %s %q with %q: %w", typeName, result.Entries[removedIdx].ObjectID, result.Entries[addedIdx].ObjectID, err)
+				}
+				if total == 0 || matches*100 < threshold*total {
+					continue
+				}
+				candidates = append(candidates, renameCandidate{removed: removedIdx, added: addedIdx, matches: matches, total: total})
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return result, nil
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].better(candidates[j])
+	})
+
+	index := newReferenceIndex(right)
+	paired := make(map[int]bool)
+	renamed := make(map[int]DiffEntry)
+	for _, candidate := range candidates {
+		if paired[candidate.removed] || paired[candidate.added] {
+			continue
+		}
+		paired[candidate.removed] = true
+		paired[candidate.added] = true
+
+		entry, err := renamedDiffEntry(result.Entries[candidate.removed], result.Entries[candidate.added], right.Schema, index)
+		if err != nil {
+			return DiffResult{}, err
+		}
+		renamed[candidate.added] = entry
+	}
+
+	out := DiffResult{Entries: make([]DiffEntry, 0, len(result.Entries)-len(renamed))}
+	for idx, entry := range result.Entries {
+		if entry, ok := renamed[idx]; ok {
+			out.Entries = append(out.Entries, entry)
+			continue
+		}
+		if paired[idx] {
+			continue
+		}
+		out.Entries = append(out.Entries, entry)
+	}
+	return out, nil
+}
+
+func renamedDiffEntry(removed, added DiffEntry, schema *diffSnapshotSchema, index referenceIndex) (DiffEntry, error) {
+	changes, err := diffObjectFields("", removed.OldValue, added.NewValue)
+	if err != nil {
+		return DiffEntry{}, fmt.Errorf("diff: compare %s %q with %q: %w", added.Type, removed.ObjectID, added.ObjectID, err)
+	}
+	identifier := renameIdentifierField(schema, added.Type)
+	changes = filterFieldChanges(changes, func(change DiffFieldChange) bool {
+		return change.Path != identifier
+	})
+
+	return DiffEntry{
+		Kind:         DiffEntryKindRenamed,
+		Type:         added.Type,
+		ObjectID:     added.ObjectID,
+		OldObjectID:  removed.ObjectID,
+		OldValue:     removed.OldValue,
+		NewValue:     added.NewValue,
+		FieldChanges: changes,
+		OldSources:   removed.OldSources,
+		NewSources:   added.NewSources,
+		References:   index.to(added.Type, removed.ObjectID),
+	}, nil
+}
+
+// renameSimilarity counts the fields, outside ignored, that hold the same
+// value on both sides, out of the fields present on either side.
+func renameSimilarity(oldValue, newValue map[string]any, ignored map[string]struct{}) (int, int, error) {
+	keys := make(map[string]struct{}, len(oldValue)+len(newValue))
+	for _, values := range []map[string]any{oldValue, newValue} {
+		for key := range values {
+			if _, skip := ignored[key]; !skip {
+				keys[key] = struct{}{}
+			}
+		}
+	}
+
+	matches := 0
+	for key := range keys {
+		oldField, oldOK := oldValue[key]
+		newField, newOK := newValue[key]
+		if !oldOK || !newOK {
+			continue
+		}
+		equal, err := semanticValuesEqual(oldField, newField)
+		if err != nil {
+			return 0, 0, err
+		}
+		if equal {
+			matches++
+		}
+	}
+	return matches, len(keys), nil
+}
+
+// renameIgnoredFields are the fields a rename is expected to change: the
+// identifier and any field derived from the file path.
+func renameIgnoredFields(schema *diffSnapshotSchema, typeName string) map[string]struct{} {
+	ignored := map[string]struct{}{renameIdentifierField(schema, typeName): {}}
+	if schema != nil && schema.Types[typeName] != nil {
+		for name := range schema.Types[typeName].DerivedFields {
+			ignored[name] = struct{}{}
+		}
+	}
+	return ignored
+}
+
+func renameIdentifierField(schema *diffSnapshotSchema, typeName string) string {
+	if schema != nil && schema.Types[typeName] != nil {
+		return schema.Types[typeName].IdentifierField
+	}
+	return "id"
+}
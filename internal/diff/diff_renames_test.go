@@ -0,0 +1,187 @@
+package diff
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mergewayhq/mergeway-cli/internal/testutil"
+)
+
+// changedFixture returns two copies of the fixture: the left one untouched and
+// the right one with remove deleted and write written.
+func changedFixture(t *testing.T, remove []string, write map[string]string) (string, string) {
+	t.Helper()
+	left := copyFixture(t)
+	right := copyFixture(t)
+	for _, name := range remove {
+		if err := os.Remove(filepath.Join(right, filepath.FromSlash(name))); err != nil {
+			t.Fatalf("remove %s: %v", name, err)
+		}
+	}
+	writeFixtureFiles(t, right, write)
+	return left, right
+}
+
+// dirDiffOptions completes opts to compare the left and right directories,
+// loading the schema from left.
+func dirDiffOptions(left, right string, opts Options) Options {
+	opts.Root = left
+	opts.Config = filepath.Join(left, "mergeway.yaml")
+	opts.Args = []string{"dir:" + left, "dir:" + right}
+	return opts
+}
+
+func runDirDiff(t *testing.T, left, right string, opts Options) string {
+	t.Helper()
+	output, err := Run(dirDiffOptions(left, right, opts))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return output
+}
+
+// newRenameFixture returns two copies of the fixture where User-Alice became
+// User-Alicia with a new email, and Tag-Product was replaced by an unrelated
+// Tag-Design.
+func newRenameFixture(t *testing.T) (string, string) {
+	t.Helper()
+	return changedFixture(t, []string{"data/users/user-alice.yaml", "data/tags/tag-product.yaml"}, map[string]string{
+		"data/users/user-alicia.yaml": "id: User-Alicia\nname: Alice Example\nemail: alicia@example.com\nrole: admin\n",
+		"data/tags/tag-design.yaml":   "id: Tag-Design\nlabel: Design\n",
+	})
+}
+
+func TestDetectRenames(t *testing.T) {
+	left, right := newRenameFixture(t)
+	renamed := strings.Join([]string{
+		"ADDED Tag[Tag-Design]",
+		"  at: data/tags/tag-design.yaml",
+		"",
+		"REMOVED Tag[Tag-Product]",
+		"  from: data/tags/tag-product.yaml",
+		"",
+		"RENAMED User[User-Alice -> User-Alicia]",
+		"  from: data/users/user-alice.yaml",
+		"  to: data/users/user-alicia.yaml",
+		`  email: "alice@example.com" -> "alicia@example.com"`,
+		"  still referenced by: Post[Post-001].author (dangling)",
+		"  still referenced by: Post[Post-002].author (dangling)",
+		"",
+	}, "\n")
+	unpaired := strings.Join([]string{
+		"ADDED Tag[Tag-Design]",
+		"  at: data/tags/tag-design.yaml",
+		"",
+		"REMOVED Tag[Tag-Product]",
+		"  from: data/tags/tag-product.yaml",
+		"",
+		"REMOVED User[User-Alice]",
+		"  from: data/users/user-alice.yaml",
+		"",
+		"ADDED User[User-Alicia]",
+		"  at: data/users/user-alicia.yaml",
+		"",
+	}, "\n")
+
+	tests := []struct {
+		name      string
+		threshold int
+		expected  string
+	}{
+		{name: "off by default", threshold: 0, expected: unpaired},
+		{name: "default threshold", threshold: DefaultRenameThreshold, expected: renamed},
+		// Two of Alice's three other fields are unchanged.
+		{name: "at the similarity", threshold: 66, expected: renamed},
+		{name: "above the similarity", threshold: 67, expected: unpaired},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if output := runDirDiff(t, left, right, Options{RenameThreshold: tc.threshold}); output != tc.expected {
+				t.Fatalf("unexpected output:\n%s", output)
+			}
+		})
+	}
+}
+
+func TestDetectRenamesRejectsInvalidThreshold(t *testing.T) {
+	left, right := newRenameFixture(t)
+
+	_, err := Run(dirDiffOptions(left, right, Options{RenameThreshold: 101}))
+	if !errors.Is(err, ErrInvalidRenameThreshold) {
+		t.Fatalf("expected ErrInvalidRenameThreshold, got %v", err)
+	}
+}
+
+func TestDetectRenamesJSON(t *testing.T) {
+	left, right := newRenameFixture(t)
+
+	var doc diffJSONDocument
+	if err := json.Unmarshal([]byte(runDirDiff(t, left, right, Options{Format: OutputFormatJSON, RenameThreshold: DefaultRenameThreshold})), &doc); err != nil {
+		t.Fatalf("decode json: %v", err)
+	}
+	entry := doc.Entries[2]
+	if entry.Kind != DiffEntryKindRenamed || entry.ObjectID != "User-Alicia" || entry.OldID != "User-Alice" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+	expected := []diffJSONReference{
+		{Type: "Post", ObjectID: "Post-001", Field: "author", Dangling: true},
+		{Type: "Post", ObjectID: "Post-002", Field: "author", Dangling: true},
+	}
+	if !reflect.DeepEqual(entry.Refs, expected) {
+		t.Fatalf("expected references %v, got %v", expected, entry.Refs)
+	}
+	if len(entry.Changes) != 1 || entry.Changes[0].Path != "email" {
+		t.Fatalf("expected only the email change, got %+v", entry.Changes)
+	}
+}
+
+func TestApplyPatchReplaysRenames(t *testing.T) {
+	left, right := newRenameFixture(t)
+	patch := runDirDiff(t, left, right, Options{Format: OutputFormatJSON, RenameThreshold: DefaultRenameThreshold})
+
+	target := testutil.CopyFixture(t)
+	result := applyPatch(t, target, []byte(patch), false)
+	expected := []string{"Tag-Design:applied", "Tag-Product:applied", "User-Alicia:applied"}
+	if got := patchStatuses(result); !reflect.DeepEqual(got, expected) || result.Failed() {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	if _, err := os.Stat(filepath.Join(target, "data", "users", "user-alice.yaml")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected User-Alice to be removed, got %v", err)
+	}
+	if !strings.Contains(testutil.ReadFile(t, target, "data/users/User-Alicia.yaml"), "email: alicia@example.com") {
+		t.Fatalf("expected User-Alicia to be created")
+	}
+
+	again := applyPatch(t, target, []byte(patch), false)
+	expected = []string{"Tag-Design:unchanged", "Tag-Product:unchanged", "User-Alicia:unchanged"}
+	if got := patchStatuses(again); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected a second apply to change nothing, got %v", got)
+	}
+}
+
+func TestApplyPatchKeepsRenamedObjectWhenWriteFails(t *testing.T) {
+	left, right := newRenameFixture(t)
+	patch := runDirDiff(t, left, right, Options{Format: OutputFormatJSON, RenameThreshold: DefaultRenameThreshold})
+
+	target := testutil.CopyFixture(t)
+	// A directory where the store writes User-Alicia makes the create fail.
+	if err := os.MkdirAll(filepath.Join(target, "data", "users", "User-Alicia.yaml"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	result, err := ApplyPatch(PatchOptions{Root: target, Config: filepath.Join(target, "mergeway.yaml"), Patch: []byte(patch)})
+	if err == nil {
+		t.Fatalf("expected the create to fail")
+	}
+	expected := []string{"Tag-Design:applied", "Tag-Product:applied", "User-Alicia:not_applied"}
+	if got := patchStatuses(result); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected the partial result %v, got %v", expected, got)
+	}
+	if _, err := os.Stat(filepath.Join(target, "data", "users", "user-alice.yaml")); err != nil {
+		t.Fatalf("expected User-Alice to survive the failed rename, got %v", err)
+	}
+}
@@ -25,6 +25,7 @@ func renderDiffResult(result DiffResult) string {
 		case DiffEntryKindRemoved:
 			fmt.Fprintf(&b, "REMOVED %s[%s]\n", entry.Type, entry.ObjectID)
 			fmt.Fprintf(&b, "  from: %s\n", summarizeSources(entry.OldSources))
+			writeReferenceLines(&b, entry)
 		case DiffEntryKindModified:
 			fmt.Fprintf(&b, "MODIFIED %s[%s]\n", entry.Type, entry.ObjectID)
 			if !semanticSourcesEqual(entry.OldSources, entry.NewSources) {
@@ -40,10 +41,27 @@ func renderDiffResult(result DiffResult) string {
 					formatDiffValue(change.NewValue),
 				)
 			}
+			writeReferenceLines(&b, entry)
 		case DiffEntryKindRelocated:
 			fmt.Fprintf(&b, "RELOCATED %s[%s]\n", entry.Type, entry.ObjectID)
 			fmt.Fprintf(&b, "  from: %s\n", summarizeSources(entry.OldSources))
 			fmt.Fprintf(&b, "  to: %s\n", summarizeSources(entry.NewSources))
+		case DiffEntryKindRenamed:
+			fmt.Fprintf(&b, "RENAMED %s\n", diffEntryTitle(entry))
+			if !semanticSourcesEqual(entry.OldSources, entry.NewSources) {
+				fmt.Fprintf(&b, "  from: %s\n", summarizeSources(entry.OldSources))
+				fmt.Fprintf(&b, "  to: %s\n", summarizeSources(entry.NewSources))
+			}
+			for _, change := range sortedFieldChanges(entry.FieldChanges) {
+				fmt.Fprintf(
+					&b,
+					"  %s: %s -> %s\n",
+					change.Path,
+					formatDiffValue(change.OldValue),
+					formatDiffValue(change.NewValue),
+				)
+			}
+			writeReferenceLines(&b, entry)
 		default:
 			fmt.Fprintf(&b, "UNKNOWN %s[%s]\n", entry.Type, entry.ObjectID)
 		}
@@ -52,6 +70,34 @@ func renderDiffResult(result DiffResult) string {
 	return b.String()
 }
 
+func writeReferenceLines(b *strings.Builder, entry DiffEntry) {
+	for _, reference := range entry.References {
+		fmt.Fprintf(b, "  %s: %s", referencedByLabel(entry.Kind), reference)
+		if reference.Dangling {
+			b.WriteString(" (dangling)")
+		}
+		b.WriteByte('\n')
+	}
+}
+
+// referencedByLabel introduces an entry's references. A removed or renamed
+// object is gone from the identifier its references hold.
+func referencedByLabel(kind DiffEntryKind) string {
+	if kind == DiffEntryKindModified {
+		return "referenced by"
+	}
+	return "still referenced by"
+}
+
+// diffEntryTitle names the entry's object as Type[ID], or Type[Old -> New]
+// for a rename.
+func diffEntryTitle(entry DiffEntry) string {
+	if entry.Kind == DiffEntryKindRenamed {
+		return entry.Type + "[" + entry.OldObjectID + " -> " + entry.ObjectID + "]"
+	}
+	return entry.Type + "[" + entry.ObjectID + "]"
+}
+
 func sortedFieldChanges(changes []DiffFieldChange) []DiffFieldChange {
 	if len(changes) == 0 {
 		return nil
@@ -83,11 +129,14 @@ func summarizeSources(sources []LogicalObjectSource) string {
 
 	values := make([]string, 0, len(sources))
 	for _, source := range sources {
-		if source.Selector != "" {
+		switch {
+		case source.Inline:
+			values = append(values, source.Path+" (inline data)")
+		case source.Selector != "":
 			values = append(values, source.Path+" @ "+source.Selector)
-			continue
+		default:
+			values = append(values, source.Path)
 		}
-		values = append(values, source.Path)
 	}
 	sort.Strings(values)
 	return strings.Join(values, ", ")
@@ -0,0 +1,160 @@
+package diff
+
+import (
+	"html/template"
+	"strings"
+)
+
+type htmlReport struct {
+	Summaries []htmlTypeSection
+	Renamed   bool
+}
+
+type htmlTypeSection struct {
+	diffTypeSummary
+	Label   string
+	Objects []htmlObject
+}
+
+type htmlObject struct {
+	Kind         DiffEntryKind
+	Title        string
+	At           string
+	MovedFrom    string
+	Modified     bool
+	ReferencedBy string
+	References   []htmlReference
+	Rows         []htmlRow
+}
+
+type htmlReference struct {
+	Name     string
+	Dangling bool
+}
+
+type htmlRow struct {
+	Path   string
+	Before string
+	After  string
+}
+
+var diffHTMLTemplate = template.Must(template.New("diff").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Mergeway data diff</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2rem; color: #1f2328; }
+table { border-collapse: collapse; margin: 0.5rem 0 1rem; }
+th, td { border: 1px solid #d0d7de; padding: 0.3rem 0.6rem; text-align: left; vertical-align: top; }
+td.count { text-align: right; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; font-size: 0.9em; white-space: pre-wrap; }
+summary { cursor: pointer; margin: 0.5rem 0; }
+h3 { font-size: 1rem; margin: 1rem 0 0.25rem; }
+.added { color: #1a7f37; }
+.removed { color: #cf222e; }
+.modified { color: #9a6700; }
+.relocated { color: #0969da; }
+.renamed { color: #8250df; }
+</style>
+</head>
+<body>
+<h1>Mergeway data diff</h1>
+{{- if not .Summaries}}
+<p>No changes.</p>
+{{- else}}
+<table>
+<thead><tr><th>Type</th><th>Added</th><th>Removed</th><th>Modified</th><th>Relocated</th>{{if .Renamed}}<th>Renamed</th>{{end}}</tr></thead>
+<tbody>
+{{- range .Summaries}}
+<tr><td>{{.Type}}</td><td class="count">{{.Added}}</td><td class="count">{{.Removed}}</td><td class="count">{{.Modified}}</td><td class="count">{{.Relocated}}</td>{{if $.Renamed}}<td class="count">{{.Renamed}}</td>{{end}}</tr>
+{{- end}}
+</tbody>
+</table>
+{{- range .Summaries}}
+<details>
+<summary><strong>{{.Type}}</strong>: {{.Label}}</summary>
+{{- range .Objects}}
+<h3><span class="{{.Kind}}">{{.Kind}}</span> <code>{{.Title}}</code></h3>
+{{- if .MovedFrom}}
+<p>Moved from <code>{{.MovedFrom}}</code> to <code>{{.At}}</code>.</p>
+{{- else}}
+<p>In <code>{{.At}}</code>.</p>
+{{- end}}
+{{- if .References}}
+<p>{{.ReferencedBy}} {{range $i, $ref := .References}}{{if $i}}, {{end}}<code>{{$ref.Name}}</code>{{if $ref.Dangling}} <span class="removed">(dangling)</span>{{end}}{{end}}.</p>
+{{- end}}
+{{- if .Rows}}
+{{- if .Modified}}
+<table>
+<thead><tr><th>Field</th><th>Before</th><th>After</th></tr></thead>
+<tbody>
+{{- range .Rows}}
+<tr><td><code>{{.Path}}</code></td><td><code>{{.Before}}</code></td><td><code>{{.After}}</code></td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- else}}
+<table>
+<thead><tr><th>Field</th><th>Value</th></tr></thead>
+<tbody>
+{{- range .Rows}}
+<tr><td><code>{{.Path}}</code></td><td><code>{{.After}}</code></td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+{{- end}}
+{{- end}}
+</details>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// renderDiffResultHTML renders a self-contained HTML page: styles are inline
+// and nothing is loaded from the network.
+func renderDiffResultHTML(result DiffResult) (string, error) {
+	summaries := summarizeDiffByType(result)
+	report := htmlReport{Renamed: hasRenames(summaries)}
+	for _, summary := range summaries {
+		section := htmlTypeSection{diffTypeSummary: summary, Label: summary.label()}
+		for _, entry := range summary.Entries {
+			object := htmlObject{
+				Kind:     entry.Kind,
+				Title:    diffEntryTitle(entry),
+				Modified: entry.Kind == DiffEntryKindModified || entry.Kind == DiffEntryKindRenamed,
+			}
+			if len(entry.References) > 0 {
+				label := referencedByLabel(entry.Kind)
+				object.ReferencedBy = strings.ToUpper(label[:1]) + label[1:]
+			}
+			for _, reference := range entry.References {
+				object.References = append(object.References, htmlReference{Name: reference.String(), Dangling: reference.Dangling})
+			}
+			object.At, object.MovedFrom = diffEntryLocation(entry)
+			for _, row := range diffEntryRows(entry) {
+				value := htmlRow{Path: row.Path}
+				switch entry.Kind {
+				case DiffEntryKindModified, DiffEntryKindRenamed:
+					value.Before = formatReportValue(row.OldValue)
+					value.After = formatReportValue(row.NewValue)
+				case DiffEntryKindRemoved:
+					value.After = formatReportValue(row.OldValue)
+				default:
+					value.After = formatReportValue(row.NewValue)
+				}
+				object.Rows = append(object.Rows, value)
+			}
+			section.Objects = append(section.Objects, object)
+		}
+		report.Summaries = append(report.Summaries, section)
+	}
+
+	var b strings.Builder
+	if err := diffHTMLTemplate.Execute(&b, report); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
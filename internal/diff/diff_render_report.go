@@ -0,0 +1,240 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// diffTypeSummary counts the entries of one type for the summary table at the
+// top of the markdown and HTML reports.
+type diffTypeSummary struct {
+	Type      string
+	Added     int
+	Removed   int
+	Modified  int
+	Relocated int
+	Renamed   int
+	Entries   []DiffEntry
+}
+
+func (s diffTypeSummary) label() string {
+	var parts []string
+	for _, count := range []struct {
+		n    int
+		kind DiffEntryKind
+	}{
+		{s.Added, DiffEntryKindAdded},
+		{s.Removed, DiffEntryKindRemoved},
+		{s.Modified, DiffEntryKindModified},
+		{s.Relocated, DiffEntryKindRelocated},
+		{s.Renamed, DiffEntryKindRenamed},
+	} {
+		if count.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", count.n, count.kind))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// summarizeDiffByType groups entries by type in order of first appearance,
+// keeping the result's order within each type.
+func summarizeDiffByType(result DiffResult) []diffTypeSummary {
+	var summaries []diffTypeSummary
+	index := make(map[string]int)
+	for _, entry := range result.Entries {
+		idx, ok := index[entry.Type]
+		if !ok {
+			idx = len(summaries)
+			index[entry.Type] = idx
+			summaries = append(summaries, diffTypeSummary{Type: entry.Type})
+		}
+		summary := &summaries[idx]
+		summary.Entries = append(summary.Entries, entry)
+		switch entry.Kind {
+		case DiffEntryKindAdded:
+			summary.Added++
+		case DiffEntryKindRemoved:
+			summary.Removed++
+		case DiffEntryKindModified:
+			summary.Modified++
+		case DiffEntryKindRelocated:
+			summary.Relocated++
+		case DiffEntryKindRenamed:
+			summary.Renamed++
+		}
+	}
+	return summaries
+}
+
+// diffEntryRows returns the field rows shown for an entry: before/after pairs
+// for modifications and renames, and the full object for additions and removals.
+func diffEntryRows(entry DiffEntry) []DiffFieldChange {
+	switch entry.Kind {
+	case DiffEntryKindModified, DiffEntryKindRenamed:
+		return sortedFieldChanges(entry.FieldChanges)
+	case DiffEntryKindAdded:
+		rows := make([]DiffFieldChange, 0, len(entry.NewValue))
+		for _, key := range slices.Sorted(maps.Keys(entry.NewValue)) {
+			rows = append(rows, DiffFieldChange{Path: key, NewValue: entry.NewValue[key]})
+		}
+		return rows
+	case DiffEntryKindRemoved:
+		rows := make([]DiffFieldChange, 0, len(entry.OldValue))
+		for _, key := range slices.Sorted(maps.Keys(entry.OldValue)) {
+			rows = append(rows, DiffFieldChange{Path: key, OldValue: entry.OldValue[key]})
+		}
+		return rows
+	default:
+		return nil
+	}
+}
+
+// diffEntryLocation returns the files an entry lives in and, when it moved
+// between files, the files it moved from.
+func diffEntryLocation(entry DiffEntry) (at string, movedFrom string) {
+	switch entry.Kind {
+	case DiffEntryKindAdded:
+		return summarizeSources(entry.NewSources), ""
+	case DiffEntryKindRemoved:
+		return summarizeSources(entry.OldSources), ""
+	default:
+		if semanticSourcesEqual(entry.OldSources, entry.NewSources) {
+			return summarizeSources(entry.NewSources), ""
+		}
+		return summarizeSources(entry.NewSources), summarizeSources(entry.OldSources)
+	}
+}
+
+func renderDiffResultMarkdown(result DiffResult) string {
+	var b strings.Builder
+	b.WriteString("## Mergeway data diff\n\n")
+	if len(result.Entries) == 0 {
+		b.WriteString("No changes.\n")
+		return b.String()
+	}
+	writeDiffMarkdownBody(&b, result)
+	return b.String()
+}
+
+// writeDiffMarkdownBody writes the per-type summary table and the collapsible
+// per-object details for a non-empty result.
+func writeDiffMarkdownBody(b *strings.Builder, result DiffResult) {
+	summaries := summarizeDiffByType(result)
+	renamed := hasRenames(summaries)
+	if renamed {
+		b.WriteString("| Type | Added | Removed | Modified | Relocated | Renamed |\n")
+		b.WriteString("| ---- | ----: | ------: | -------: | --------: | ------: |\n")
+	} else {
+		b.WriteString("| Type | Added | Removed | Modified | Relocated |\n")
+		b.WriteString("| ---- | ----: | ------: | -------: | --------: |\n")
+	}
+	for _, summary := range summaries {
+		fmt.Fprintf(b, "| %s | %d | %d | %d | %d |", markdownCell(summary.Type), summary.Added, summary.Removed, summary.Modified, summary.Relocated)
+		if renamed {
+			fmt.Fprintf(b, " %d |", summary.Renamed)
+		}
+		b.WriteString("\n")
+	}
+
+	for _, summary := range summaries {
+		fmt.Fprintf(b, "\n<details>\n<summary><strong>%s</strong>: %s</summary>\n", html.EscapeString(summary.Type), summary.label())
+		for _, entry := range summary.Entries {
+			fmt.Fprintf(b, "\n#### %s %s\n\n", markdownKindLabel(entry.Kind), markdownCode(diffEntryTitle(entry)))
+			if at, movedFrom := diffEntryLocation(entry); movedFrom != "" {
+				fmt.Fprintf(b, "Moved from %s to %s.\n", markdownCode(movedFrom), markdownCode(at))
+			} else {
+				fmt.Fprintf(b, "In %s.\n", markdownCode(at))
+			}
+			if len(entry.References) > 0 {
+				references := make([]string, 0, len(entry.References))
+				for _, reference := range entry.References {
+					text := markdownCode(reference.String())
+					if reference.Dangling {
+						text += " (dangling)"
+					}
+					references = append(references, text)
+				}
+				label := referencedByLabel(entry.Kind)
+				fmt.Fprintf(b, "\n%s %s.\n", strings.ToUpper(label[:1])+label[1:], strings.Join(references, ", "))
+			}
+
+			rows := diffEntryRows(entry)
+			if len(rows) == 0 {
+				continue
+			}
+			switch entry.Kind {
+			case DiffEntryKindModified, DiffEntryKindRenamed:
+				b.WriteString("\n| Field | Before | After |\n| ----- | ------ | ----- |\n")
+				for _, row := range rows {
+					fmt.Fprintf(b, "| %s | %s | %s |\n", markdownCode(row.Path), markdownCode(formatReportValue(row.OldValue)), markdownCode(formatReportValue(row.NewValue)))
+				}
+			default:
+				b.WriteString("\n| Field | Value |\n| ----- | ----- |\n")
+				for _, row := range rows {
+					value := row.NewValue
+					if entry.Kind == DiffEntryKindRemoved {
+						value = row.OldValue
+					}
+					fmt.Fprintf(b, "| %s | %s |\n", markdownCode(row.Path), markdownCode(formatReportValue(value)))
+				}
+			}
+		}
+		b.WriteString("\n</details>\n")
+	}
+}
+
+// hasRenames reports whether any type has renamed entries. The Renamed
+// column is only shown when rename detection found something.
+func hasRenames(summaries []diffTypeSummary) bool {
+	for _, summary := range summaries {
+		if summary.Renamed > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// formatReportValue is formatDiffValue without JSON's HTML escaping; the
+// markdown and HTML renderers escape for their own context.
+func formatReportValue(value any) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return formatDiffValue(value)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func markdownKindLabel(kind DiffEntryKind) string {
+	label := string(kind)
+	if label == "" {
+		return "Changed"
+	}
+	return strings.ToUpper(label[:1]) + label[1:]
+}
+
+// markdownCode wraps text in a code span that is safe inside a table cell.
+// The fence grows past any backtick run in the text, and pipes are escaped so
+// they do not split the cell.
+func markdownCode(text string) string {
+	fence := "`"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	text = strings.ReplaceAll(text, "|", `\|`)
+	text = strings.ReplaceAll(text, "\n", " ")
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return fence + text + fence
+}
+
+func markdownCell(text string) string {
+	return strings.ReplaceAll(text, "|", `\|`)
+}
@@ -1,6 +1,9 @@
 package diff
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestRenderDiffResultSingleModifiedObjectIsDeterministic(t *testing.T) {
 	result := DiffResult{
@@ -148,3 +151,120 @@ func TestRenderDiffResultRelocationRenderingIsStable(t *testing.T) {
 		t.Fatalf("unexpected rendered diff\nwant:\n%s\ngot:\n%s", want, got)
 	}
 }
+
+func TestRenderDiffResultMarkdownGroupsEntriesByType(t *testing.T) {
+	result := DiffResult{
+		Entries: []DiffEntry{
+			{
+				Kind:       DiffEntryKindAdded,
+				Type:       "Tag",
+				ObjectID:   "Tag-New",
+				NewValue:   map[string]any{"id": "Tag-New", "label": "a|b"},
+				NewSources: []LogicalObjectSource{{Path: "data/tags/tag-new.yaml"}},
+			},
+			{
+				Kind:       DiffEntryKindModified,
+				Type:       "User",
+				ObjectID:   "User-Alice",
+				OldSources: []LogicalObjectSource{{Path: "data/users/user-alice.yaml"}},
+				NewSources: []LogicalObjectSource{{Path: "data/users/user-alice.yaml"}},
+				FieldChanges: []DiffFieldChange{
+					{Path: "name", OldValue: "Alice", NewValue: "Alice Updated"},
+				},
+			},
+			{
+				Kind:       DiffEntryKindRelocated,
+				Type:       "Tag",
+				ObjectID:   "Tag-Old",
+				OldSources: []LogicalObjectSource{{Path: "data/tags/tag-old.yaml"}},
+				NewSources: []LogicalObjectSource{{Path: "data/tags/all.yaml", Selector: "$.items[*]"}},
+			},
+		},
+	}
+
+	got := renderDiffResultMarkdown(result)
+	want := "" +
+		"## Mergeway data diff\n" +
+		"\n" +
+		"| Type | Added | Removed | Modified | Relocated |\n" +
+		"| ---- | ----: | ------: | -------: | --------: |\n" +
+		"| Tag | 1 | 0 | 0 | 1 |\n" +
+		"| User | 0 | 0 | 1 | 0 |\n" +
+		"\n" +
+		"<details>\n" +
+		"<summary><strong>Tag</strong>: 1 added, 1 relocated</summary>\n" +
+		"\n" +
+		"#### Added `Tag[Tag-New]`\n" +
+		"\n" +
+		"In `data/tags/tag-new.yaml`.\n" +
+		"\n" +
+		"| Field | Value |\n" +
+		"| ----- | ----- |\n" +
+		"| `id` | `\"Tag-New\"` |\n" +
+		"| `label` | `\"a\\|b\"` |\n" +
+		"\n" +
+		"#### Relocated `Tag[Tag-Old]`\n" +
+		"\n" +
+		"Moved from `data/tags/tag-old.yaml` to `data/tags/all.yaml @ $.items[*]`.\n" +
+		"\n" +
+		"</details>\n" +
+		"\n" +
+		"<details>\n" +
+		"<summary><strong>User</strong>: 1 modified</summary>\n" +
+		"\n" +
+		"#### Modified `User[User-Alice]`\n" +
+		"\n" +
+		"In `data/users/user-alice.yaml`.\n" +
+		"\n" +
+		"| Field | Before | After |\n" +
+		"| ----- | ------ | ----- |\n" +
+		"| `name` | `\"Alice\"` | `\"Alice Updated\"` |\n" +
+		"\n" +
+		"</details>\n"
+	if got != want {
+		t.Fatalf("unexpected markdown\nwant:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestRenderDiffResultMarkdownEmptyDiffIsStable(t *testing.T) {
+	if got := renderDiffResultMarkdown(DiffResult{}); got != "## Mergeway data diff\n\nNo changes.\n" {
+		t.Fatalf("unexpected empty markdown %q", got)
+	}
+}
+
+func TestRenderDiffResultHTMLIsSelfContainedAndEscaped(t *testing.T) {
+	result := DiffResult{
+		Entries: []DiffEntry{{
+			Kind:       DiffEntryKindModified,
+			Type:       "User",
+			ObjectID:   "User-Alice",
+			OldSources: []LogicalObjectSource{{Path: "data/users/user-alice.yaml"}},
+			NewSources: []LogicalObjectSource{{Path: "data/users/user-alice.yaml"}},
+			FieldChanges: []DiffFieldChange{
+				{Path: "name", OldValue: "Alice", NewValue: "<script>alert(1)</script>"},
+			},
+		}},
+	}
+
+	got, err := renderDiffResultHTML(result)
+	if err != nil {
+		t.Fatalf("renderDiffResultHTML: %v", err)
+	}
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"<style>",
+		`<tr><td>User</td><td class="count">0</td><td class="count">0</td><td class="count">1</td><td class="count">0</td></tr>`,
+		"<summary><strong>User</strong>: 1 modified</summary>",
+		"<code>User[User-Alice]</code>",
+		"&lt;script&gt;",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected HTML to contain %q, got:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"<script>", "<link", "src="} {
+		if strings.Contains(got, unwanted) {
+			t.Fatalf("expected HTML without %q, got:\n%s", unwanted, got)
+		}
+	}
+}
@@ -0,0 +1,461 @@
+package diff
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	internalconfig "github.com/mergewayhq/mergeway-cli/internal/config"
+)
+
+// SchemaChangeSeverity tells whether existing data stays valid after a change.
+type SchemaChangeSeverity string
+
+const (
+	SchemaChangeSafe     SchemaChangeSeverity = "safe"
+	SchemaChangeBreaking SchemaChangeSeverity = "breaking"
+)
+
+type SchemaChangeKind string
+
+const (
+	SchemaChangeTypeAdded              SchemaChangeKind = "type_added"
+	SchemaChangeTypeRemoved            SchemaChangeKind = "type_removed"
+	SchemaChangeIdentifierChanged      SchemaChangeKind = "identifier_changed"
+	SchemaChangeExtendsChanged         SchemaChangeKind = "extends_changed"
+	SchemaChangeIncludeAdded           SchemaChangeKind = "include_added"
+	SchemaChangeIncludeRemoved         SchemaChangeKind = "include_removed"
+	SchemaChangeFieldAdded             SchemaChangeKind = "field_added"
+	SchemaChangeRequiredFieldAdded     SchemaChangeKind = "required_field_added"
+	SchemaChangeFieldRemoved           SchemaChangeKind = "field_removed"
+	SchemaChangeFieldTypeChanged       SchemaChangeKind = "field_type_changed"
+	SchemaChangeFieldRequired          SchemaChangeKind = "field_required"
+	SchemaChangeFieldOptional          SchemaChangeKind = "field_optional"
+	SchemaChangeEnumNarrowed           SchemaChangeKind = "enum_narrowed"
+	SchemaChangeEnumWidened            SchemaChangeKind = "enum_widened"
+	SchemaChangeReferenceNarrowed      SchemaChangeKind = "reference_narrowed"
+	SchemaChangeReferenceWidened       SchemaChangeKind = "reference_widened"
+	SchemaChangeConstraintTightened    SchemaChangeKind = "constraint_tightened"
+	SchemaChangeConstraintRelaxed      SchemaChangeKind = "constraint_relaxed"
+	SchemaChangeFieldAnnotationChanged SchemaChangeKind = "annotation_changed"
+)
+
+// SchemaChange is one classified difference between two normalized configs.
+// Field is a dotted path for nested object properties; Attribute names the
+// constraint or annotation for constraint and annotation changes.
+type SchemaChange struct {
+	Kind      SchemaChangeKind
+	Severity  SchemaChangeSeverity
+	Type      string
+	Field     string
+	Attribute string
+	Before    any
+	After     any
+}
+
+type SchemaDiffResult struct {
+	Left    SnapshotRef
+	Right   SnapshotRef
+	Changes []SchemaChange
+}
+
+// Breaking reports whether any change invalidates data that was valid before.
+func (r SchemaDiffResult) Breaking() bool {
+	for _, change := range r.Changes {
+		if change.Severity == SchemaChangeBreaking {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaOutput is the rendered schema diff plus whether it found breaking
+// changes, which the command turns into a non-zero exit.
+type SchemaOutput struct {
+	Report   string
+	Breaking bool
+}
+
+// RunSchema compares the normalized configuration between the snapshots
+// selected by opts.Args, using the same snapshot rules as Run.
+func RunSchema(opts Options) (SchemaOutput, error) {
+	if err := validateOutputFormat(opts.Format, OutputFormatText, OutputFormatJSON); err != nil {
+		return SchemaOutput{}, err
+	}
+
+	snapshots, err := resolveDiffSnapshots(opts.Root, opts.Args, opts.Mode)
+	if err != nil {
+		return SchemaOutput{}, err
+	}
+
+	result, err := diffSnapshotConfigs(opts.Root, opts.Config, snapshots.Left, snapshots.Right)
+	if err != nil {
+		return SchemaOutput{}, err
+	}
+
+	output := SchemaOutput{Breaking: result.Breaking()}
+	if opts.Format == OutputFormatJSON {
+		payload, err := marshalSchemaDiffJSON(result)
+		if err != nil {
+			return SchemaOutput{}, err
+		}
+		output.Report = string(payload) + "\n"
+		return output, nil
+	}
+
+	output.Report = renderSchemaDiffResult(result)
+	return output, nil
+}
+
+func diffSnapshotConfigs(root, configPath string, left, right SnapshotRef) (SchemaDiffResult, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return SchemaDiffResult{}, fmt.Errorf("diff: resolve root: %w", err)
+	}
+	absConfig, err := filepath.Abs(configPath)
+	if err != nil {
+		return SchemaDiffResult{}, fmt.Errorf("diff: resolve config path: %w", err)
+	}
+
+	readers := newSnapshotReaders(absRoot)
+	defer readers.Close()
+
+	var cfgs [2]*internalconfig.Config
+	for idx, snapshot := range []SnapshotRef{left, right} {
+		reader, err := readers.Get(snapshot)
+		if err != nil {
+			return SchemaDiffResult{}, err
+		}
+		readerConfig, err := reader.configPath(absRoot, absConfig)
+		if err != nil {
+			return SchemaDiffResult{}, err
+		}
+		if cfgs[idx], err = loadSnapshotConfig(reader, readerConfig); err != nil {
+			return SchemaDiffResult{}, err
+		}
+	}
+	leftCfg, rightCfg := cfgs[0], cfgs[1]
+
+	result := diffConfigs(leftCfg, rightCfg)
+	result.Left = left
+	result.Right = right
+	return result, nil
+}
+
+func diffConfigs(left, right *internalconfig.Config) SchemaDiffResult {
+	var result SchemaDiffResult
+	add := func(change SchemaChange) {
+		result.Changes = append(result.Changes, change)
+	}
+
+	names := make(map[string]struct{})
+	for name := range left.Types {
+		names[name] = struct{}{}
+	}
+	for name := range right.Types {
+		names[name] = struct{}{}
+	}
+
+	for _, name := range sortedKeys(names) {
+		oldType, newType := left.Types[name], right.Types[name]
+		switch {
+		case oldType == nil:
+			add(SchemaChange{Kind: SchemaChangeTypeAdded, Severity: SchemaChangeSafe, Type: name})
+			continue
+		case newType == nil:
+			add(SchemaChange{Kind: SchemaChangeTypeRemoved, Severity: SchemaChangeBreaking, Type: name})
+			continue
+		}
+
+		if oldType.Identifier.Field != newType.Identifier.Field || oldType.Identifier.Pattern != newType.Identifier.Pattern {
+			add(SchemaChange{
+				Kind:     SchemaChangeIdentifierChanged,
+				Severity: SchemaChangeBreaking,
+				Type:     name,
+				Before:   identifierLabel(oldType.Identifier),
+				After:    identifierLabel(newType.Identifier),
+			})
+		}
+		if oldType.Extends != newType.Extends {
+			add(SchemaChange{
+				Kind:     SchemaChangeExtendsChanged,
+				Severity: SchemaChangeBreaking,
+				Type:     name,
+				Before:   optionalString(oldType.Extends),
+				After:    optionalString(newType.Extends),
+			})
+		}
+
+		oldIncludes, newIncludes := includeLabels(oldType.Include), includeLabels(newType.Include)
+		for _, include := range sortedKeys(oldIncludes) {
+			if _, ok := newIncludes[include]; !ok {
+				add(SchemaChange{Kind: SchemaChangeIncludeRemoved, Severity: SchemaChangeBreaking, Type: name, Before: include})
+			}
+		}
+		for _, include := range sortedKeys(newIncludes) {
+			if _, ok := oldIncludes[include]; !ok {
+				add(SchemaChange{Kind: SchemaChangeIncludeAdded, Severity: SchemaChangeSafe, Type: name, After: include})
+			}
+		}
+
+		// Inherited fields are reported once, on the type that declares them.
+		oldFields := ownFields(left, oldType)
+		newFields := ownFields(right, newType)
+		for _, change := range diffFieldDefinitions(name, "", oldFields, newFields) {
+			add(change)
+		}
+	}
+
+	sortSchemaChanges(result.Changes)
+	return result
+}
+
+func diffFieldDefinitions(typeName, prefix string, left, right map[string]*internalconfig.FieldDefinition) []SchemaChange {
+	names := make(map[string]struct{})
+	for name := range left {
+		names[name] = struct{}{}
+	}
+	for name := range right {
+		names[name] = struct{}{}
+	}
+
+	var changes []SchemaChange
+	for _, name := range sortedKeys(names) {
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		oldField, newField := left[name], right[name]
+
+		switch {
+		case oldField == nil:
+			change := SchemaChange{Kind: SchemaChangeFieldAdded, Severity: SchemaChangeSafe, Type: typeName, Field: path, After: fieldTypeLabel(newField)}
+			if newField.Required {
+				change.Kind = SchemaChangeRequiredFieldAdded
+				change.Severity = SchemaChangeBreaking
+			}
+			changes = append(changes, change)
+			continue
+		case newField == nil:
+			changes = append(changes, SchemaChange{Kind: SchemaChangeFieldRemoved, Severity: SchemaChangeBreaking, Type: typeName, Field: path, Before: fieldTypeLabel(oldField)})
+			continue
+		}
+
+		changes = append(changes, diffFieldDefinition(typeName, path, oldField, newField)...)
+	}
+	return changes
+}
+
+func diffFieldDefinition(typeName, path string, oldField, newField *internalconfig.FieldDefinition) []SchemaChange {
+	var changes []SchemaChange
+	change := func(kind SchemaChangeKind, severity SchemaChangeSeverity, attribute string, before, after any) {
+		changes = append(changes, SchemaChange{
+			Kind:      kind,
+			Severity:  severity,
+			Type:      typeName,
+			Field:     path,
+			Attribute: attribute,
+			Before:    before,
+			After:     after,
+		})
+	}
+
+	if oldField.IsReference() && newField.IsReference() && oldField.Repeated == newField.Repeated {
+		// Reference targets are a set: more targets accept every old value,
+		// fewer or different targets may reject some.
+		if !stringSetsEqual(oldField.ReferenceTypes, newField.ReferenceTypes) {
+			kind, severity := SchemaChangeFieldTypeChanged, SchemaChangeBreaking
+			switch {
+			case stringSetContains(newField.ReferenceTypes, oldField.ReferenceTypes):
+				kind, severity = SchemaChangeReferenceWidened, SchemaChangeSafe
+			case stringSetContains(oldField.ReferenceTypes, newField.ReferenceTypes):
+				kind = SchemaChangeReferenceNarrowed
+			}
+			change(kind, severity, "", fieldTypeLabel(oldField), fieldTypeLabel(newField))
+		}
+	} else if fieldTypeLabel(oldField) != fieldTypeLabel(newField) {
+		change(SchemaChangeFieldTypeChanged, SchemaChangeBreaking, "", fieldTypeLabel(oldField), fieldTypeLabel(newField))
+		// Nested properties and constraints of a different type are not comparable.
+		return changes
+	}
+
+	switch {
+	case !oldField.Required && newField.Required:
+		change(SchemaChangeFieldRequired, SchemaChangeBreaking, "", false, true)
+	case oldField.Required && !newField.Required:
+		change(SchemaChangeFieldOptional, SchemaChangeSafe, "", true, false)
+	}
+
+	if !stringSetsEqual(oldField.Enum, newField.Enum) {
+		// An empty enum accepts any value, so adding one narrows and dropping one widens.
+		severity, kind := SchemaChangeSafe, SchemaChangeEnumWidened
+		if len(newField.Enum) > 0 && (len(oldField.Enum) == 0 || !stringSetContains(newField.Enum, oldField.Enum)) {
+			severity, kind = SchemaChangeBreaking, SchemaChangeEnumNarrowed
+		}
+		change(kind, severity, "enum", enumValue(oldField.Enum), enumValue(newField.Enum))
+	}
+
+	for _, constraint := range []struct {
+		name   string
+		before string
+		after  string
+	}{
+		{"format", oldField.Format, newField.Format},
+		{"pattern", oldField.Pattern, newField.Pattern},
+	} {
+		if constraint.before == constraint.after {
+			continue
+		}
+		if constraint.after == "" {
+			change(SchemaChangeConstraintRelaxed, SchemaChangeSafe, constraint.name, constraint.before, nil)
+			continue
+		}
+		change(SchemaChangeConstraintTightened, SchemaChangeBreaking, constraint.name, optionalString(constraint.before), constraint.after)
+	}
+
+	switch {
+	case !oldField.Unique && newField.Unique:
+		change(SchemaChangeConstraintTightened, SchemaChangeBreaking, "unique", false, true)
+	case oldField.Unique && !newField.Unique:
+		change(SchemaChangeConstraintRelaxed, SchemaChangeSafe, "unique", true, false)
+	}
+
+	if equal, err := semanticValuesEqual(oldField.Default, newField.Default); err != nil || !equal {
+		change(SchemaChangeFieldAnnotationChanged, SchemaChangeSafe, "default", cloneValue(oldField.Default), cloneValue(newField.Default))
+	}
+	if oldField.Description != newField.Description {
+		change(SchemaChangeFieldAnnotationChanged, SchemaChangeSafe, "description", optionalString(oldField.Description), optionalString(newField.Description))
+	}
+
+	if len(oldField.Properties) > 0 || len(newField.Properties) > 0 {
+		changes = append(changes, diffFieldDefinitions(typeName, path, oldField.Properties, newField.Properties)...)
+	}
+	return changes
+}
+
+// ownFields drops fields the type inherits unchanged from its parent.
+func ownFields(cfg *internalconfig.Config, typeDef *internalconfig.TypeDefinition) map[string]*internalconfig.FieldDefinition {
+	parent := cfg.Types[typeDef.Extends]
+	if typeDef.Extends == "" || parent == nil {
+		return typeDef.Fields
+	}
+
+	fields := make(map[string]*internalconfig.FieldDefinition, len(typeDef.Fields))
+	for name, field := range typeDef.Fields {
+		if _, inherited := parent.Fields[name]; inherited {
+			continue
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+func fieldTypeLabel(field *internalconfig.FieldDefinition) string {
+	label := field.ReferenceLabel()
+	if field.Repeated {
+		return label + "[]"
+	}
+	return label
+}
+
+func identifierLabel(identifier internalconfig.IdentifierDefinition) string {
+	if identifier.Pattern == "" {
+		return identifier.Field
+	}
+	return identifier.Field + " (pattern " + identifier.Pattern + ")"
+}
+
+func includeLabels(includes []internalconfig.IncludeDefinition) map[string]struct{} {
+	labels := make(map[string]struct{}, len(includes))
+	for _, include := range includes {
+		label := include.Path
+		if include.Selector != "" {
+			label += " (selector " + include.Selector + ")"
+		}
+		labels[label] = struct{}{}
+	}
+	return labels
+}
+
+func optionalString(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func enumValue(values []string) any {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func stringSetsEqual(left, right []string) bool {
+	return stringSetContains(left, right) && stringSetContains(right, left)
+}
+
+// stringSetContains reports whether every value of subset appears in set.
+func stringSetContains(set, subset []string) bool {
+	members := make(map[string]struct{}, len(set))
+	for _, value := range set {
+		members[value] = struct{}{}
+	}
+	for _, value := range subset {
+		if _, ok := members[value]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func sortSchemaChanges(changes []SchemaChange) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Field != b.Field {
+			return a.Field < b.Field
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Attribute < b.Attribute
+	})
+}
+
+func schemaChangeTarget(change SchemaChange) string {
+	target := change.Type
+	if change.Field != "" {
+		target += "." + change.Field
+	}
+	if change.Attribute != "" {
+		target += " (" + change.Attribute + ")"
+	}
+	return target
+}
+
+func renderSchemaDiffResult(result SchemaDiffResult) string {
+	if len(result.Changes) == 0 {
+		return "No schema changes.\n"
+	}
+
+	var b strings.Builder
+	breaking := 0
+	for _, change := range result.Changes {
+		if change.Severity == SchemaChangeBreaking {
+			breaking++
+		}
+		fmt.Fprintf(&b, "%s %s %s\n", strings.ToUpper(string(change.Severity)), change.Kind, schemaChangeTarget(change))
+		if change.Before != nil || change.After != nil {
+			fmt.Fprintf(&b, "  %s -> %s\n", formatDiffValue(change.Before), formatDiffValue(change.After))
+		}
+	}
+
+	fmt.Fprintf(&b, "\n%d breaking change(s), %d safe change(s).\n", breaking, len(result.Changes)-breaking)
+	return b.String()
+}
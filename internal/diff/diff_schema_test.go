@@ -0,0 +1,235 @@
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	internalconfig "github.com/mergewayhq/mergeway-cli/internal/config"
+	"github.com/mergewayhq/mergeway-cli/internal/testutil"
+)
+
+const schemaTestUserType = `mergeway:
+  version: 1
+
+entities:
+  User:
+    identifier:
+      field: id
+    include:
+      - data/users/*.yaml
+    fields:
+      id:
+        type: string
+        required: true
+      name:
+        type: string
+        required: true
+      email:
+        type: string
+        required: true
+      role:
+        type: string
+`
+
+func TestDiffConfigsClassifiesFieldChanges(t *testing.T) {
+	left := schemaTestConfig(map[string]*internalconfig.FieldDefinition{
+		"id":     {Name: "id", Type: "string", Required: true},
+		"status": {Name: "status", Type: "string", Enum: []string{"draft", "published", "archived"}},
+		"rank":   {Name: "rank", Type: "integer"},
+		"legacy": {Name: "legacy", Type: "string"},
+		"note":   {Name: "note", Type: "string", Required: true},
+	})
+	right := schemaTestConfig(map[string]*internalconfig.FieldDefinition{
+		"id":       {Name: "id", Type: "string", Required: true},
+		"status":   {Name: "status", Type: "string", Enum: []string{"draft", "published"}},
+		"rank":     {Name: "rank", Type: "string"},
+		"note":     {Name: "note", Type: "string"},
+		"nickname": {Name: "nickname", Type: "string"},
+		"summary":  {Name: "summary", Type: "string", Required: true},
+	})
+
+	result := diffConfigs(left, right)
+
+	got := make(map[string]SchemaChangeSeverity)
+	for _, change := range result.Changes {
+		got[string(change.Kind)+" "+change.Field] = change.Severity
+	}
+	want := map[string]SchemaChangeSeverity{
+		"field_removed legacy":         SchemaChangeBreaking,
+		"field_added nickname":         SchemaChangeSafe,
+		"field_optional note":          SchemaChangeSafe,
+		"field_type_changed rank":      SchemaChangeBreaking,
+		"enum_narrowed status":         SchemaChangeBreaking,
+		"required_field_added summary": SchemaChangeBreaking,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected changes:\n got %#v\nwant %#v", got, want)
+	}
+	if !result.Breaking() {
+		t.Fatalf("expected breaking result")
+	}
+}
+
+func TestDiffConfigsClassifiesTypeAndIdentifierChanges(t *testing.T) {
+	left := schemaTestConfig(map[string]*internalconfig.FieldDefinition{
+		"id": {Name: "id", Type: "string", Required: true},
+	})
+	left.Types["Tag"] = &internalconfig.TypeDefinition{Name: "Tag", Identifier: internalconfig.IdentifierDefinition{Field: "id"}}
+	right := schemaTestConfig(map[string]*internalconfig.FieldDefinition{
+		"id":   {Name: "id", Type: "string", Required: true},
+		"slug": {Name: "slug", Type: "string", Required: true},
+	})
+	right.Types["Post"].Identifier.Field = "slug"
+	right.Types["Note"] = &internalconfig.TypeDefinition{Name: "Note", Identifier: internalconfig.IdentifierDefinition{Field: "id"}}
+
+	result := diffConfigs(left, right)
+
+	var kinds []string
+	for _, change := range result.Changes {
+		kinds = append(kinds, change.Type+" "+string(change.Kind)+" "+string(change.Severity))
+	}
+	want := []string{
+		"Note type_added safe",
+		"Post identifier_changed breaking",
+		"Post required_field_added breaking",
+		"Tag type_removed breaking",
+	}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("unexpected changes:\n got %v\nwant %v", kinds, want)
+	}
+}
+
+func TestDiffConfigsTreatsWidenedEnumAsSafe(t *testing.T) {
+	left := schemaTestConfig(map[string]*internalconfig.FieldDefinition{
+		"status": {Name: "status", Type: "string", Enum: []string{"draft"}},
+	})
+	right := schemaTestConfig(map[string]*internalconfig.FieldDefinition{
+		"status": {Name: "status", Type: "string", Enum: []string{"draft", "published"}},
+	})
+
+	result := diffConfigs(left, right)
+	if len(result.Changes) != 1 || result.Changes[0].Kind != SchemaChangeEnumWidened || result.Breaking() {
+		t.Fatalf("expected a single safe enum widening, got %#v", result.Changes)
+	}
+}
+
+func TestDiffConfigsComparesReferenceTargetsAsSets(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   []string
+		after    []string
+		kind     SchemaChangeKind
+		breaking bool
+	}{
+		{name: "reordered", before: []string{"User", "Team"}, after: []string{"Team", "User"}},
+		{name: "widened", before: []string{"User"}, after: []string{"User", "Team"}, kind: SchemaChangeReferenceWidened},
+		{name: "narrowed", before: []string{"User", "Team"}, after: []string{"User"}, kind: SchemaChangeReferenceNarrowed, breaking: true},
+		{name: "unrelated", before: []string{"User", "Team"}, after: []string{"User", "Group"}, kind: SchemaChangeFieldTypeChanged, breaking: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			left := schemaTestConfig(map[string]*internalconfig.FieldDefinition{
+				"owner": {Name: "owner", Type: strings.Join(tc.before, " | "), ReferenceTypes: tc.before},
+			})
+			right := schemaTestConfig(map[string]*internalconfig.FieldDefinition{
+				"owner": {Name: "owner", Type: strings.Join(tc.after, " | "), ReferenceTypes: tc.after},
+			})
+
+			result := diffConfigs(left, right)
+			if tc.kind == "" {
+				if len(result.Changes) != 0 {
+					t.Fatalf("expected no changes, got %#v", result.Changes)
+				}
+				return
+			}
+			if len(result.Changes) != 1 || result.Changes[0].Kind != tc.kind || result.Breaking() != tc.breaking {
+				t.Fatalf("expected a single %s change (breaking %v), got %#v", tc.kind, tc.breaking, result.Changes)
+			}
+		})
+	}
+}
+
+func TestRunSchemaComparesConfigBetweenRevisions(t *testing.T) {
+	repo := testutil.NewGitRepo(t)
+	left := repo.Revision(t, "HEAD")
+	right := repo.CommitDataChange(t, "types/User.yaml", schemaTestUserType+"      nickname:\n        type: string\n")
+
+	output, err := RunSchema(Options{
+		Root:   repo.Root,
+		Config: repo.Root + "/mergeway.yaml",
+		Args:   []string{left, right},
+	})
+	if err != nil {
+		t.Fatalf("RunSchema: %v", err)
+	}
+	if output.Breaking {
+		t.Fatalf("expected only safe changes, got:\n%s", output.Report)
+	}
+	want := "SAFE field_added User.nickname\n  null -> \"string\"\n\n0 breaking change(s), 1 safe change(s).\n"
+	if output.Report != want {
+		t.Fatalf("unexpected report:\n%s", output.Report)
+	}
+}
+
+func TestRunSchemaReportsBreakingChangesAsJSON(t *testing.T) {
+	repo := testutil.NewGitRepo(t)
+	left := repo.Revision(t, "HEAD")
+	updated := strings.Replace(schemaTestUserType, "      role:\n        type: string\n", "      role:\n        type: string\n        enum: [admin, editor]\n", 1)
+	right := repo.CommitDataChange(t, "types/User.yaml", updated)
+
+	output, err := RunSchema(Options{
+		Root:   repo.Root,
+		Config: repo.Root + "/mergeway.yaml",
+		Args:   []string{left, right},
+		Format: OutputFormatJSON,
+	})
+	if err != nil {
+		t.Fatalf("RunSchema: %v", err)
+	}
+	if !output.Breaking {
+		t.Fatalf("expected breaking change")
+	}
+
+	var doc struct {
+		Version  int  `json:"version"`
+		Breaking bool `json:"breaking"`
+		Changes  []struct {
+			Kind      string `json:"kind"`
+			Severity  string `json:"severity"`
+			Type      string `json:"type"`
+			Field     string `json:"field"`
+			Attribute string `json:"attribute"`
+			After     []any  `json:"after"`
+		} `json:"changes"`
+	}
+	if err := json.Unmarshal([]byte(output.Report), &doc); err != nil {
+		t.Fatalf("decode json: %v\n%s", err, output.Report)
+	}
+	if doc.Version != 1 || !doc.Breaking || len(doc.Changes) != 1 {
+		t.Fatalf("unexpected document: %+v", doc)
+	}
+	change := doc.Changes[0]
+	if change.Kind != "enum_narrowed" || change.Severity != "breaking" || change.Type != "User" || change.Field != "role" || change.Attribute != "enum" {
+		t.Fatalf("unexpected change: %+v", change)
+	}
+	if !reflect.DeepEqual(change.After, []any{"admin", "editor"}) {
+		t.Fatalf("unexpected enum values: %#v", change.After)
+	}
+}
+
+func schemaTestConfig(fields map[string]*internalconfig.FieldDefinition) *internalconfig.Config {
+	return &internalconfig.Config{
+		Version: 1,
+		Types: map[string]*internalconfig.TypeDefinition{
+			"Post": {
+				Name:       "Post",
+				Identifier: internalconfig.IdentifierDefinition{Field: "id"},
+				Include:    []internalconfig.IncludeDefinition{{Path: "data/posts/*.yaml"}},
+				Fields:     fields,
+			},
+		},
+	}
+}
@@ -18,17 +18,39 @@ const (
 	DiffEntryKindRemoved   DiffEntryKind = "removed"
 	DiffEntryKindModified  DiffEntryKind = "modified"
 	DiffEntryKindRelocated DiffEntryKind = "relocated"
+	DiffEntryKindRenamed   DiffEntryKind = "renamed"
 )
 
+// DiffEntry is one object's change. For renamed entries ObjectID is the new
+// identifier and OldObjectID the old one. References lists the references
+// that point at the object, or at the old identifier of a rename; it is
+// always set for renames and, with impact analysis, for removals and
+// modifications.
 type DiffEntry struct {
 	Kind         DiffEntryKind
 	Type         string
 	ObjectID     string
+	OldObjectID  string
 	OldValue     map[string]any
 	NewValue     map[string]any
 	FieldChanges []DiffFieldChange
 	OldSources   []LogicalObjectSource
 	NewSources   []LogicalObjectSource
+	References   []DiffReference
+}
+
+// DiffReference is a reference field of one object. Dangling is set when no
+// object the field may refer to has the referenced identifier.
+type DiffReference struct {
+	Type     string
+	ObjectID string
+	Field    string
+	Dangling bool
+}
+
+// String formats the reference as Type[ID].field.
+func (r DiffReference) String() string {
+	return r.Type + "[" + r.ObjectID + "]." + r.Field
 }
 
 type DiffFieldChange struct {
@@ -90,7 +112,9 @@ func diffLogicalDatabases(left, right LogicalDatabase) (DiffResult, error) {
 				return DiffResult{}, fmt.Errorf("diff: compare %s %q: %w", leftObj.Type, leftObj.ID, err)
 			}
 
-			relocated := !semanticSourcesEqual(leftObj.Sources, rightObj.Sources)
+			// An export records no file layout, so nothing moves across one.
+			relocated := left.Snapshot.Kind != SnapshotKindExport && right.Snapshot.Kind != SnapshotKindExport &&
+				!semanticSourcesEqual(leftObj.Sources, rightObj.Sources)
 			switch {
 			case len(fieldChanges) > 0:
 				result.Entries = append(result.Entries, DiffEntry{
@@ -267,7 +291,7 @@ func semanticSourcesEqual(left, right []LogicalObjectSource) bool {
 func logicalSourceKeys(sources []LogicalObjectSource) []string {
 	values := make([]string, 0, len(sources))
 	for _, source := range sources {
-		values = append(values, source.Path+"\x00"+source.Selector+"\x00"+fmt.Sprintf("%t", source.ReadOnly))
+		values = append(values, source.Path+"\x00"+source.Selector+"\x00"+fmt.Sprintf("%t\x00%t", source.ReadOnly, source.Inline))
 	}
 	sort.Strings(values)
 	return values
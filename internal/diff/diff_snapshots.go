@@ -3,11 +3,18 @@ package diff
 import (
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+
+	"github.com/mergewayhq/mergeway-cli/internal/fileutil"
 )
 
-var ErrTooManyArgs = errors.New("diff accepts at most 2 snapshot arguments")
+var (
+	ErrTooManyArgs     = errors.New("diff accepts at most 2 snapshot arguments")
+	ErrInvalidSnapshot = errors.New("invalid snapshot")
+)
 
 type SnapshotKind string
 
@@ -15,6 +22,29 @@ const (
 	SnapshotKindHead        SnapshotKind = "head"
 	SnapshotKindRevision    SnapshotKind = "revision"
 	SnapshotKindWorkingTree SnapshotKind = "working_tree"
+	SnapshotKindDirectory   SnapshotKind = "directory"
+	SnapshotKindExport      SnapshotKind = "export"
+	SnapshotKindIndex       SnapshotKind = "index"
+)
+
+// SnapshotMode selects what the snapshot arguments are compared with.
+type SnapshotMode string
+
+const (
+	SnapshotModeDefault SnapshotMode = ""
+	// SnapshotModeStaged compares HEAD, or the one snapshot argument, with
+	// the index: what the next commit would change.
+	SnapshotModeStaged SnapshotMode = "staged"
+	// SnapshotModeIndex compares the index with the full working tree: what
+	// is not staged yet.
+	SnapshotModeIndex SnapshotMode = "index"
+)
+
+// Snapshot specifier prefixes for data outside git: a plain directory laid
+// out like a repository, and a file written by `mergeway-cli export`.
+const (
+	snapshotPrefixDirectory = "dir:"
+	snapshotPrefixExport    = "export:"
 )
 
 type WorkingTreeView string
@@ -25,10 +55,13 @@ const (
 	WorkingTreeViewUnspecified WorkingTreeView = ""
 )
 
+// SnapshotRef identifies one side of a diff. Path is the directory or export
+// file for the directory and export kinds.
 type SnapshotRef struct {
 	Kind            SnapshotKind
 	Revision        string
 	WorkingTreeView WorkingTreeView
+	Path            string
 }
 
 type DiffSnapshots struct {
@@ -49,6 +82,12 @@ func (s SnapshotRef) String() string {
 		default:
 			return "WORKTREE"
 		}
+	case SnapshotKindDirectory:
+		return snapshotPrefixDirectory + s.Path
+	case SnapshotKindExport:
+		return snapshotPrefixExport + s.Path
+	case SnapshotKindIndex:
+		return "INDEX"
 	default:
 		return "UNKNOWN"
 	}
@@ -56,7 +95,23 @@ func (s SnapshotRef) String() string {
 
 // resolveDiffSnapshots keeps the working-tree modes explicit so staged vs
 // unstaged semantics remain visible in code and protected by tests.
-func resolveDiffSnapshots(root string, args []string) (DiffSnapshots, error) {
+func resolveDiffSnapshots(root string, args []string, mode SnapshotMode) (DiffSnapshots, error) {
+	switch mode {
+	case SnapshotModeStaged:
+		return resolveStagedSnapshots(root, args)
+	case SnapshotModeIndex:
+		if len(args) > 0 {
+			return DiffSnapshots{}, fmt.Errorf("%w: --index compares the index with the working tree and takes no snapshot arguments", ErrInvalidSnapshot)
+		}
+		return DiffSnapshots{
+			Left: SnapshotRef{Kind: SnapshotKindIndex},
+			Right: SnapshotRef{
+				Kind:            SnapshotKindWorkingTree,
+				WorkingTreeView: WorkingTreeViewFull,
+			},
+		}, nil
+	}
+
 	switch len(args) {
 	case 0:
 		if err := validateGitRevision(root, "HEAD"); err != nil {
@@ -72,41 +127,97 @@ func resolveDiffSnapshots(root string, args []string) (DiffSnapshots, error) {
 			},
 		}, nil
 	case 1:
-		if err := validateGitRevision(root, args[0]); err != nil {
+		left, err := parseSnapshotArg(root, args[0])
+		if err != nil {
 			return DiffSnapshots{}, err
 		}
 		return DiffSnapshots{
-			Left: SnapshotRef{
-				Kind:     SnapshotKindRevision,
-				Revision: args[0],
-			},
+			Left: left,
 			Right: SnapshotRef{
 				Kind:            SnapshotKindWorkingTree,
 				WorkingTreeView: WorkingTreeViewFull,
 			},
 		}, nil
 	case 2:
-		if err := validateGitRevision(root, args[0]); err != nil {
+		left, err := parseSnapshotArg(root, args[0])
+		if err != nil {
 			return DiffSnapshots{}, err
 		}
-		if err := validateGitRevision(root, args[1]); err != nil {
+		right, err := parseSnapshotArg(root, args[1])
+		if err != nil {
 			return DiffSnapshots{}, err
 		}
-		return DiffSnapshots{
-			Left: SnapshotRef{
-				Kind:     SnapshotKindRevision,
-				Revision: args[0],
-			},
-			Right: SnapshotRef{
-				Kind:     SnapshotKindRevision,
-				Revision: args[1],
-			},
-		}, nil
+		return DiffSnapshots{Left: left, Right: right}, nil
 	default:
 		return DiffSnapshots{}, ErrTooManyArgs
 	}
 }
 
+// resolveStagedSnapshots compares HEAD, or the one snapshot argument, with
+// the index, like `git diff --cached`.
+func resolveStagedSnapshots(root string, args []string) (DiffSnapshots, error) {
+	index := SnapshotRef{Kind: SnapshotKindIndex}
+	switch len(args) {
+	case 0:
+		if err := validateGitRevision(root, "HEAD"); err != nil {
+			return DiffSnapshots{}, err
+		}
+		return DiffSnapshots{Left: SnapshotRef{Kind: SnapshotKindHead}, Right: index}, nil
+	case 1:
+		left, err := parseSnapshotArg(root, args[0])
+		if err != nil {
+			return DiffSnapshots{}, err
+		}
+		return DiffSnapshots{Left: left, Right: index}, nil
+	default:
+		return DiffSnapshots{}, fmt.Errorf("%w: --staged compares at most 1 snapshot argument with the index", ErrInvalidSnapshot)
+	}
+}
+
+// parseSnapshotArg reads a snapshot argument: `dir:<path>` for a directory,
+// `export:<file>` for an export file, and a git revision otherwise. Relative
+// paths are resolved against the current directory, not root.
+func parseSnapshotArg(root, arg string) (SnapshotRef, error) {
+	switch {
+	case strings.HasPrefix(arg, snapshotPrefixDirectory):
+		path, err := filepath.Abs(strings.TrimPrefix(arg, snapshotPrefixDirectory))
+		if err != nil {
+			return SnapshotRef{}, fmt.Errorf("%w %q: %v", ErrInvalidSnapshot, arg, err)
+		}
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			return SnapshotRef{}, fmt.Errorf("%w %q: not a directory", ErrInvalidSnapshot, arg)
+		}
+		return SnapshotRef{Kind: SnapshotKindDirectory, Path: path}, nil
+	case strings.HasPrefix(arg, snapshotPrefixExport):
+		path := strings.TrimPrefix(arg, snapshotPrefixExport)
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			return SnapshotRef{}, fmt.Errorf("%w %q: not a file", ErrInvalidSnapshot, arg)
+		}
+		return SnapshotRef{Kind: SnapshotKindExport, Path: path}, nil
+	default:
+		if err := validateGitRevision(root, arg); err != nil {
+			return SnapshotRef{}, err
+		}
+		return SnapshotRef{Kind: SnapshotKindRevision, Revision: arg}, nil
+	}
+}
+
+// OpenRevision opens a git revision read-only, so config, data, and
+// validation loaders can run against it through the tree's Ops without a
+// checkout. Close the tree when done.
+func OpenRevision(root, revision string) (*fileutil.GitTree, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("resolve root: %w", err)
+	}
+	if err := validateGitRevision(absRoot, revision); err != nil {
+		return nil, err
+	}
+	return fileutil.OpenGitTree(absRoot, revision)
+}
+
 func validateGitRevision(root, revision string) error {
 	cmd := exec.Command("git", "-C", root, "rev-parse", "--verify", "--quiet", revision+"^{commit}")
 	output, err := cmd.CombinedOutput()
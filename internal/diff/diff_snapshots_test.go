@@ -1,6 +1,7 @@
 package diff
 
 import (
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -11,7 +12,7 @@ import (
 func TestResolveDiffSnapshotsZeroArgs(t *testing.T) {
 	repo := newGitRepoFixture(t)
 
-	got, err := resolveDiffSnapshots(repo.Root, nil)
+	got, err := resolveDiffSnapshots(repo.Root, nil, SnapshotModeDefault)
 	if err != nil {
 		t.Fatalf("resolve diff snapshots: %v", err)
 	}
@@ -28,7 +29,7 @@ func TestResolveDiffSnapshotsOneArg(t *testing.T) {
 	repo := newGitRepoFixture(t)
 	left := repo.Revision(t, "HEAD")
 
-	got, err := resolveDiffSnapshots(repo.Root, []string{left})
+	got, err := resolveDiffSnapshots(repo.Root, []string{left}, SnapshotModeDefault)
 	if err != nil {
 		t.Fatalf("resolve diff snapshots: %v", err)
 	}
@@ -46,7 +47,7 @@ func TestResolveDiffSnapshotsTwoArgs(t *testing.T) {
 	left := repo.Revision(t, "HEAD")
 	right := repo.CommitDataChange(t, "data/users/user-bob.yaml", "id: User-Bob\nname: Bob Changed\nemail: bob@example.com\n")
 
-	got, err := resolveDiffSnapshots(repo.Root, []string{left, right})
+	got, err := resolveDiffSnapshots(repo.Root, []string{left, right}, SnapshotModeDefault)
 	if err != nil {
 		t.Fatalf("resolve diff snapshots: %v", err)
 	}
@@ -59,10 +60,71 @@ func TestResolveDiffSnapshotsTwoArgs(t *testing.T) {
 	}
 }
 
+func TestResolveDiffSnapshotsStagedZeroArgs(t *testing.T) {
+	repo := newGitRepoFixture(t)
+
+	got, err := resolveDiffSnapshots(repo.Root, nil, SnapshotModeStaged)
+	if err != nil {
+		t.Fatalf("resolve diff snapshots: %v", err)
+	}
+
+	if got.Left.Kind != SnapshotKindHead {
+		t.Fatalf("expected left snapshot kind %q, got %q", SnapshotKindHead, got.Left.Kind)
+	}
+	if got.Right.Kind != SnapshotKindIndex {
+		t.Fatalf("expected index right snapshot, got %+v", got.Right)
+	}
+}
+
+func TestResolveDiffSnapshotsStagedOneArg(t *testing.T) {
+	repo := newGitRepoFixture(t)
+	left := repo.Revision(t, "HEAD")
+
+	got, err := resolveDiffSnapshots(repo.Root, []string{left}, SnapshotModeStaged)
+	if err != nil {
+		t.Fatalf("resolve diff snapshots: %v", err)
+	}
+
+	if got.Left.Kind != SnapshotKindRevision || got.Left.Revision != left {
+		t.Fatalf("expected left revision %q, got %+v", left, got.Left)
+	}
+	if got.Right.Kind != SnapshotKindIndex {
+		t.Fatalf("expected index right snapshot, got %+v", got.Right)
+	}
+}
+
+func TestResolveDiffSnapshotsIndexZeroArgs(t *testing.T) {
+	repo := newGitRepoFixture(t)
+
+	got, err := resolveDiffSnapshots(repo.Root, nil, SnapshotModeIndex)
+	if err != nil {
+		t.Fatalf("resolve diff snapshots: %v", err)
+	}
+
+	if got.Left.Kind != SnapshotKindIndex {
+		t.Fatalf("expected index left snapshot, got %+v", got.Left)
+	}
+	if got.Right.Kind != SnapshotKindWorkingTree || got.Right.WorkingTreeView != WorkingTreeViewFull {
+		t.Fatalf("expected full working tree right snapshot, got %+v", got.Right)
+	}
+}
+
+func TestResolveDiffSnapshotsIndexModesRejectExtraArgs(t *testing.T) {
+	repo := newGitRepoFixture(t)
+	head := repo.Revision(t, "HEAD")
+
+	if _, err := resolveDiffSnapshots(repo.Root, []string{head}, SnapshotModeIndex); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Fatalf("expected --index with an argument to fail, got %v", err)
+	}
+	if _, err := resolveDiffSnapshots(repo.Root, []string{head, head}, SnapshotModeStaged); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Fatalf("expected --staged with two arguments to fail, got %v", err)
+	}
+}
+
 func TestResolveDiffSnapshotsInvalidRevision(t *testing.T) {
 	repo := newGitRepoFixture(t)
 
-	_, err := resolveDiffSnapshots(repo.Root, []string{"does-not-exist"})
+	_, err := resolveDiffSnapshots(repo.Root, []string{"does-not-exist"}, SnapshotModeDefault)
 	if err == nil {
 		t.Fatalf("expected invalid revision to fail")
 	}
@@ -0,0 +1,141 @@
+package diff
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mergewayhq/mergeway-cli/internal/data"
+	"github.com/mergewayhq/mergeway-cli/internal/testutil"
+)
+
+const parityTagType = `mergeway:
+  version: 1
+
+entities:
+  Tag:
+    include:
+      - data/tags/*.yaml
+    fields:
+      id:
+        type: string
+        required: true
+      label:
+        type: string
+        required: true
+    identifier: id
+    data:
+      - id: Tag-Inline
+        label: Declared inline
+      - id: Tag-Product
+        label: Shadowed by the file
+`
+
+const parityPostFolderField = `      folder:
+        type: string
+        source:
+          path_segment_rev: 1
+`
+
+func newParityRepoFixture(t *testing.T) testutil.GitRepo {
+	t.Helper()
+	return addParityChanges(t, testutil.NewGitRepo(t))
+}
+
+// addParityChanges commits inline Tag records (one shadowed by a file) and a
+// path-derived Post field.
+func addParityChanges(t *testing.T, repo testutil.GitRepo) testutil.GitRepo {
+	t.Helper()
+	postType := testutil.ReadFile(t, repo.Root, "types/Post.yaml")
+	postType = strings.Replace(postType, "    identifier: id\n", parityPostFolderField+"    identifier: id\n", 1)
+	repo.StageDataChange(t, "types/Post.yaml", postType)
+	repo.CommitDataChange(t, "types/Tag.yaml", parityTagType)
+	return repo
+}
+
+func TestLogicalDatabaseMatchesStoreAtRevision(t *testing.T) {
+	repo := newParityRepoFixture(t)
+	revision := SnapshotRef{Kind: SnapshotKindRevision, Revision: repo.Revision(t, "HEAD")}
+	configPath := filepath.Join(repo.Root, "mergeway.yaml")
+
+	corpora, err := loadDiffDataCorpora(repo.Root, configPath, revision, revision)
+	if err != nil {
+		t.Fatalf("loadDiffDataCorpora: %v", err)
+	}
+	db, err := buildLogicalDatabase(corpora.Left)
+	if err != nil {
+		t.Fatalf("buildLogicalDatabase: %v", err)
+	}
+	got := make(map[string]string, len(db.Objects))
+	for _, obj := range db.Objects {
+		got[logicalObjectMapKey(obj.Type, obj.ID)] = mustCanonicalValue(t, obj.Fields)
+	}
+
+	reader, err := newSnapshotReader(repo.Root, revision)
+	if err != nil {
+		t.Fatalf("newSnapshotReader: %v", err)
+	}
+	defer reader.Close()
+	cfg, err := loadSnapshotConfig(reader, configPath)
+	if err != nil {
+		t.Fatalf("loadSnapshotConfig: %v", err)
+	}
+	store, err := data.NewStoreWithOps(repo.Root, cfg, reader.FileOps())
+	if err != nil {
+		t.Fatalf("NewStoreWithOps: %v", err)
+	}
+	want := make(map[string]string)
+	for typeName := range cfg.Types {
+		objects, err := store.LoadExactAll(typeName)
+		if err != nil {
+			t.Fatalf("LoadExactAll(%s): %v", typeName, err)
+		}
+		for _, obj := range objects {
+			want[logicalObjectMapKey(obj.Type, obj.ID)] = mustCanonicalValue(t, obj.Fields)
+		}
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("diff view differs from data.Store\n got %v\nwant %v", got, want)
+	}
+	if got[logicalObjectMapKey("Tag", "Tag-Product")] != mustCanonicalValue(t, map[string]any{"id": "Tag-Product", "label": "Product"}) {
+		t.Fatalf("expected the file-backed Tag-Product to win over inline data, got %s", got[logicalObjectMapKey("Tag", "Tag-Product")])
+	}
+}
+
+func TestRunReportsInlineRecordsAndDerivedFields(t *testing.T) {
+	repo := testutil.NewGitRepo(t)
+	left := repo.Revision(t, "HEAD")
+	addParityChanges(t, repo)
+	right := repo.Revision(t, "HEAD")
+
+	output, err := Run(Options{
+		Root:   repo.Root,
+		Config: filepath.Join(repo.Root, "mergeway.yaml"),
+		Args:   []string{left, right},
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for _, want := range []string{
+		"ADDED Tag[Tag-Inline]\n  at: types/Tag.yaml (inline data)\n",
+		"MODIFIED Post[Post-001]\n  folder: null -> \"posts\"\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected %q in diff output:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "Tag[Tag-Product]") {
+		t.Fatalf("expected shadowed inline record to be hidden:\n%s", output)
+	}
+}
+
+func mustCanonicalValue(t *testing.T, value map[string]any) string {
+	t.Helper()
+	canonical, err := semanticCanonicalValue(value)
+	if err != nil {
+		t.Fatalf("canonicalize %v: %v", value, err)
+	}
+	return canonical
+}
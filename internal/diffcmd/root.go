@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	diffpkg "github.com/mergewayhq/mergeway-cli/internal/diff"
@@ -15,6 +16,8 @@ type context struct {
 	Root   string
 	Config string
 	Format string
+	Schema bool
+	Filter diffpkg.DiffFilter
 	Stdout io.Writer
 	Stderr io.Writer
 }
@@ -60,6 +63,7 @@ func newRootCommand(stdout, stderr io.Writer) *cobra.Command {
 		Short:         "Compare Mergeway-managed data between snapshots",
 		SilenceUsage:  true,
 		SilenceErrors: true,
+		Args:          cobra.ArbitraryArgs,
 		Long: `Compare Mergeway-managed data between logical repository snapshots.
 
 This command is a data-only diff. It compares logical Mergeway records and excludes configuration files entirely.
@@ -68,20 +72,56 @@ Snapshot modes:
   mergeway-diff                compare HEAD data vs working tree data with unstaged changes only
   mergeway-diff <left>         compare <left> vs current working tree data including unstaged changes
   mergeway-diff <left> <right> compare <left> vs <right>
+  mergeway-diff --staged [<left>]
+                               compare HEAD, or <left>, vs the index (what the next commit changes)
+  mergeway-diff --index        compare the index vs current working tree data (what is not staged yet)
 
-Use --format json to emit machine-readable semantic diff output.`,
+A snapshot is a git revision, dir:<path> for a directory laid out like the repository, or export:<file> for the output of "mergeway-cli export". Directory and export snapshots do not need git.
+
+Use --format json to emit machine-readable semantic diff output, or --format markdown or --format html for a report to post in pull requests.
+
+Use --type, --id, --field, and --kind to scope the diff, and --ignore-field to drop noisy fields such as updated_at. --id, --field, and --ignore-field take globs; a field glob also matches nested paths.
+
+Use --find-renames to report an object whose identifier changed as one renamed entry instead of a removal and an addition. Objects of the same type are paired when at least 50% of their other fields match, or the percentage given as --find-renames=<percent>. Renamed entries list the references that still point at the old identifier.
+
+Use --impact to list, for each removed or renamed object, the references in <right> that still hold its identifier, flagging those that would dangle, and for each modified object the objects that reference it.
+
+Use --schema to compare the normalized configuration instead of data. Each change is classified as safe or breaking, and the command exits 1 when any change is breaking.
+
+Use "mergeway-diff log <from>..<to>" for a per-commit changelog of data changes in a revision range.
+
+Use "mergeway-diff merge-driver" as a git merge driver for Mergeway data files.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx, err := contextFromCommand(cmd)
 			if err != nil {
 				return err
 			}
 
-			output, err := diffpkg.Run(diffpkg.Options{
+			mode, err := snapshotModeFromFlags(cmd)
+			if err != nil {
+				_, _ = fmt.Fprintln(ctx.Stderr, diffpkg.FormatCommandError(err))
+				return newExitError(1)
+			}
+
+			opts := diffpkg.Options{
 				Root:   ctx.Root,
 				Config: ctx.Config,
 				Args:   args,
-				JSON:   ctx.Format == "json",
-			})
+				Mode:   mode,
+				Format: diffpkg.OutputFormat(ctx.Format),
+				Filter: ctx.Filter,
+			}
+			if opts.RenameThreshold, err = cmd.Flags().GetInt("find-renames"); err != nil {
+				return err
+			}
+			if opts.Impact, err = cmd.Flags().GetBool("impact"); err != nil {
+				return err
+			}
+			if ctx.Schema {
+				return runSchemaDiff(cmd, ctx, opts)
+			}
+
+			output, err := diffpkg.Run(opts)
 			if err != nil {
 				if errors.Is(err, diffpkg.ErrTooManyArgs) {
 					_ = cmd.Help()
@@ -96,15 +136,139 @@ Use --format json to emit machine-readable semantic diff output.`,
 	}
 	cmd.SetOut(stdout)
 	cmd.SetErr(stderr)
+	cmd.AddCommand(newLogCommand())
+	cmd.AddCommand(newMergeDriverCommand())
 
-	flags := cmd.Flags()
+	flags := cmd.PersistentFlags()
 	flags.String("root", ".", "Repository root containing config and data directories")
 	flags.String("config", "", "Path to configuration entry file")
-	flags.String("format", "yaml", "Output format (yaml|json)")
+	flags.String("format", "yaml", "Output format (yaml|json|markdown|html)")
+	cmd.Flags().Bool("schema", false, "Compare configuration schemas and classify breaking changes")
+	cmd.Flags().Bool("staged", false, "Compare HEAD, or the one given snapshot, with the index")
+	cmd.Flags().Bool("index", false, "Compare the index with the working tree")
+	cmd.Flags().StringSlice("type", nil, "Only show changes to these entity types")
+	cmd.Flags().StringSlice("id", nil, "Only show objects whose identifier matches these globs")
+	cmd.Flags().StringSlice("field", nil, "Only show field changes whose path matches these globs")
+	cmd.Flags().StringSlice("kind", nil, "Only show these change kinds (added|removed|modified|relocated|renamed)")
+	cmd.Flags().StringSlice("ignore-field", nil, "Ignore changes to fields whose path matches these globs")
+	cmd.Flags().Int("find-renames", 0, "Pair removed and added objects whose other fields match by at least this percentage as renames")
+	cmd.Flags().Lookup("find-renames").NoOptDefVal = strconv.Itoa(diffpkg.DefaultRenameThreshold)
+	cmd.Flags().Bool("impact", false, "List the references to removed, renamed, and modified objects")
 
 	return cmd
 }
 
+func runSchemaDiff(cmd *cobra.Command, ctx *context, opts diffpkg.Options) error {
+	output, err := diffpkg.RunSchema(opts)
+	if err != nil {
+		if errors.Is(err, diffpkg.ErrTooManyArgs) {
+			_ = cmd.Help()
+		}
+		_, _ = fmt.Fprintln(ctx.Stderr, diffpkg.FormatCommandError(err))
+		return newExitError(1)
+	}
+
+	_, _ = fmt.Fprint(ctx.Stdout, output.Report)
+	if output.Breaking {
+		return newExitError(1)
+	}
+	return nil
+}
+
+func newLogCommand() *cobra.Command {
+	var summary bool
+
+	cmd := &cobra.Command{
+		Use:   "log <from>..<to>",
+		Short: "List the semantic data changes made by each commit in a range",
+		Long: `List the commits in <from>..<to> that changed Mergeway data, oldest first, with each commit's author, date, message, and semantic changes.
+
+Only commits that touch the config or data files are inspected, and commits whose changes are formatting-only are skipped. A merge lists only the objects that differ from every parent. Either side of the range may be omitted to mean HEAD.
+
+Use --summary for the net changes across the range with per-type counts, the number of commits, and their authors. Supports --format yaml (text), json, and markdown.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := contextFromCommand(cmd)
+			if err != nil {
+				return err
+			}
+			if len(args) != 1 {
+				_, _ = fmt.Fprintln(ctx.Stderr, diffpkg.FormatCommandError(diffpkg.ErrLogArgs))
+				return newExitError(1)
+			}
+
+			output, err := diffpkg.RunLog(diffpkg.LogOptions{
+				Root:    ctx.Root,
+				Config:  ctx.Config,
+				Range:   args[0],
+				Format:  diffpkg.OutputFormat(ctx.Format),
+				Summary: summary,
+			})
+			if err != nil {
+				_, _ = fmt.Fprintln(ctx.Stderr, diffpkg.FormatCommandError(err))
+				return newExitError(1)
+			}
+
+			_, _ = fmt.Fprint(ctx.Stdout, output)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&summary, "summary", false, "Show the range's net changes and authors instead of each commit")
+
+	return cmd
+}
+
+func newMergeDriverCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "merge-driver %O %A %B %P",
+		Short: "Merge one data file for git (merge=mergeway)",
+		Long: `Merge one Mergeway data file as a git merge driver.
+
+Git passes the common ancestor (%O), the current version (%A), the other branch's version (%B), and the file's path in the repository (%P). Objects are merged by identity and field, so edits that only collide line by line, such as two appended items, merge cleanly. The formatted result is written to %A. Conflict markers are left only for semantic conflicts, and the command exits non-zero when any remain.
+
+Files that are not Mergeway data, or that do not parse, fall back to git merge-file.
+
+Register the driver once per repository:
+
+  git config merge.mergeway.name "Mergeway semantic merge"
+  git config merge.mergeway.driver "mergeway-diff merge-driver %O %A %B %P"
+
+and route data files to it in .gitattributes:
+
+  data/**/*.yaml merge=mergeway`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := contextFromCommand(cmd)
+			if err != nil {
+				return err
+			}
+			if len(args) != 4 {
+				_, _ = fmt.Fprintln(ctx.Stderr, diffpkg.FormatMergeCommandError(diffpkg.ErrMergeDriverArgs))
+				return newExitError(2)
+			}
+
+			result, err := diffpkg.RunMergeDriver(diffpkg.MergeDriverOptions{
+				Root:   ctx.Root,
+				Config: ctx.Config,
+				Base:   args[0],
+				Ours:   args[1],
+				Theirs: args[2],
+				Path:   args[3],
+			})
+			if err != nil {
+				_, _ = fmt.Fprintln(ctx.Stderr, diffpkg.FormatMergeCommandError(err))
+				return newExitError(2)
+			}
+			if len(result.Conflicts) > 0 {
+				if !result.Textual {
+					_, _ = fmt.Fprint(ctx.Stderr, result.Summary())
+				}
+				return newExitError(1)
+			}
+			return nil
+		},
+	}
+}
+
 func contextFromCommand(cmd *cobra.Command) (*context, error) {
 	root, err := cmd.Flags().GetString("root")
 	if err != nil {
@@ -119,10 +283,20 @@ func contextFromCommand(cmd *cobra.Command) (*context, error) {
 		return nil, err
 	}
 
+	// --schema is defined on the root command only; subcommands read false.
+	schema, _ := cmd.Flags().GetBool("schema")
+
+	filter, err := diffFilterFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx := &context{
 		Root:   root,
 		Config: configPath,
 		Format: strings.ToLower(format),
+		Schema: schema,
+		Filter: filter,
 		Stdout: cmd.OutOrStdout(),
 		Stderr: cmd.ErrOrStderr(),
 	}
@@ -133,3 +307,55 @@ func contextFromCommand(cmd *cobra.Command) (*context, error) {
 
 	return ctx, nil
 }
+
+func snapshotModeFromFlags(cmd *cobra.Command) (diffpkg.SnapshotMode, error) {
+	staged, err := cmd.Flags().GetBool("staged")
+	if err != nil {
+		return "", err
+	}
+	index, err := cmd.Flags().GetBool("index")
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case staged && index:
+		return "", fmt.Errorf("%w: --staged and --index cannot be combined", diffpkg.ErrInvalidSnapshot)
+	case staged:
+		return diffpkg.SnapshotModeStaged, nil
+	case index:
+		return diffpkg.SnapshotModeIndex, nil
+	default:
+		return diffpkg.SnapshotModeDefault, nil
+	}
+}
+
+// diffFilterFromFlags reads the filter flags; subcommands without them get an
+// empty filter.
+func diffFilterFromFlags(cmd *cobra.Command) (diffpkg.DiffFilter, error) {
+	var filter diffpkg.DiffFilter
+	if cmd.Flags().Lookup("type") == nil {
+		return filter, nil
+	}
+
+	var err error
+	if filter.Types, err = cmd.Flags().GetStringSlice("type"); err != nil {
+		return filter, err
+	}
+	if filter.IDs, err = cmd.Flags().GetStringSlice("id"); err != nil {
+		return filter, err
+	}
+	if filter.Fields, err = cmd.Flags().GetStringSlice("field"); err != nil {
+		return filter, err
+	}
+	if filter.IgnoreFields, err = cmd.Flags().GetStringSlice("ignore-field"); err != nil {
+		return filter, err
+	}
+	kinds, err := cmd.Flags().GetStringSlice("kind")
+	if err != nil {
+		return filter, err
+	}
+	for _, kind := range kinds {
+		filter.Kinds = append(filter.Kinds, diffpkg.DiffEntryKind(strings.ToLower(kind)))
+	}
+	return filter, nil
+}
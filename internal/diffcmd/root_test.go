@@ -98,6 +98,52 @@ func TestDiffRejectsInvalidRevision(t *testing.T) {
 	}
 }
 
+func TestDiffStagedComparesHeadWithIndex(t *testing.T) {
+	repo := newGitRepoFixture(t)
+	bob := filepath.Join(repo.Root, "data", "users", "user-bob.yaml")
+	if err := os.WriteFile(bob, []byte("id: User-Bob\nname: Bob Staged\nemail: bob@example.com\nrole: editor\n"), 0o644); err != nil {
+		t.Fatalf("write bob: %v", err)
+	}
+	runGitCommand(t, repo.Root, "add", "data/users/user-bob.yaml")
+	if err := os.WriteFile(bob, []byte("id: User-Bob\nname: Bob Unstaged\nemail: bob@example.com\nrole: editor\n"), 0o644); err != nil {
+		t.Fatalf("write bob: %v", err)
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	code := Run([]string{"--root", repo.Root, "--staged"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("expected --staged to succeed, exit %d stderr %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "  name: \"Bob Example\" -> \"Bob Staged\"") {
+		t.Fatalf("expected staged change in diff output, got %s", stdout.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	code = Run([]string{"--root", repo.Root, "--index"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("expected --index to succeed, exit %d stderr %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "  name: \"Bob Staged\" -> \"Bob Unstaged\"") {
+		t.Fatalf("expected unstaged change in diff output, got %s", stdout.String())
+	}
+}
+
+func TestDiffRejectsStagedWithIndex(t *testing.T) {
+	repo := newGitRepoFixture(t)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	code := Run([]string{"--root", repo.Root, "--staged", "--index"}, stdout, stderr)
+	if code == 0 {
+		t.Fatalf("expected --staged with --index to fail")
+	}
+	if !strings.Contains(stderr.String(), "input error") || !strings.Contains(stderr.String(), "cannot be combined") {
+		t.Fatalf("expected input error, got %s", stderr.String())
+	}
+}
+
 func TestDiffHelpMentionsDataOnlyDiffing(t *testing.T) {
 	stdout := &bytes.Buffer{}
 	stderr := &bytes.Buffer{}
@@ -144,6 +190,124 @@ func TestDiffUsesFormatFlagForJSONOutput(t *testing.T) {
 	}
 }
 
+func TestDiffUsesFormatFlagForMarkdownOutput(t *testing.T) {
+	repo := newGitRepoFixture(t)
+	repo.CommitDataChange(t, "data/users/user-bob.yaml", "id: User-Bob\nname: Bob Changed\nemail: bob@example.com\nrole: editor\n")
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	code := Run([]string{"--root", repo.Root, "--format", "markdown", "HEAD~1", "HEAD"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("expected markdown diff to succeed, exit %d stderr %s", code, stderr.String())
+	}
+	for _, want := range []string{
+		"| User | 0 | 0 | 1 | 0 |",
+		"#### Modified `User[User-Bob]`",
+		"| `name` | `\"Bob Example\"` | `\"Bob Changed\"` |",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Fatalf("expected markdown to contain %q, got:\n%s", want, stdout.String())
+		}
+	}
+}
+
+func TestDiffFiltersByTypeAndIgnoredField(t *testing.T) {
+	repo := newGitRepoFixture(t)
+	repo.CommitDataChange(t, "data/users/user-bob.yaml", "id: User-Bob\nname: Bob Example\nemail: bob@example.org\nrole: editor\n")
+	repo.CommitDataChange(t, "data/tags/tag-product.yaml", "id: Tag-Product\nlabel: Products\n")
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	code := Run([]string{"--root", repo.Root, "--type", "User", "HEAD~2", "HEAD"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("expected filtered diff to succeed, exit %d stderr %s", code, stderr.String())
+	}
+	if stdout.String() != "MODIFIED User[User-Bob]\n  email: \"bob@example.com\" -> \"bob@example.org\"\n" {
+		t.Fatalf("unexpected filtered output %q", stdout.String())
+	}
+
+	stdout.Reset()
+	code = Run([]string{"--root", repo.Root, "--type", "User", "--ignore-field", "email", "HEAD~2", "HEAD"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("expected filtered diff to succeed, exit %d stderr %s", code, stderr.String())
+	}
+	if stdout.String() != "No changes.\n" {
+		t.Fatalf("expected ignored field to hide the change, got %q", stdout.String())
+	}
+}
+
+func TestDiffFindRenamesReportsRenamedObjects(t *testing.T) {
+	repo := newGitRepoFixture(t)
+	runGitCommand(t, repo.Root, "rm", "-q", "data/users/user-bob.yaml")
+	repo.CommitDataChange(t, "data/users/user-robert.yaml", "id: User-Robert\nname: Bob Example\nemail: bob@example.com\nrole: editor\n")
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	code := Run([]string{"--root", repo.Root, "--format", "markdown", "--find-renames", "HEAD~1", "HEAD"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("expected rename diff to succeed, exit %d stderr %s", code, stderr.String())
+	}
+	for _, want := range []string{
+		"| User | 0 | 0 | 0 | 0 | 1 |",
+		"#### Renamed `User[User-Bob -> User-Robert]`",
+		"Moved from `data/users/user-bob.yaml` to `data/users/user-robert.yaml`.",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Fatalf("expected markdown to contain %q, got:\n%s", want, stdout.String())
+		}
+	}
+
+	stdout.Reset()
+	code = Run([]string{"--root", repo.Root, "--find-renames=101", "HEAD~1", "HEAD"}, stdout, stderr)
+	if code != 1 || !strings.Contains(stderr.String(), "diff: input error: invalid rename threshold: 101") {
+		t.Fatalf("expected an invalid threshold to be rejected, exit %d stderr %q", code, stderr.String())
+	}
+}
+
+func TestDiffImpactFlagsDanglingReferences(t *testing.T) {
+	repo := newGitRepoFixture(t)
+	runGitCommand(t, repo.Root, "rm", "-q", "data/users/user-alice.yaml")
+	runGitCommand(t, repo.Root, "commit", "-q", "-m", "remove alice")
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	code := Run([]string{"--root", repo.Root, "--impact", "HEAD~1", "HEAD"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("expected impact diff to succeed, exit %d stderr %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "  still referenced by: Post[Post-001].author (dangling)\n") {
+		t.Fatalf("expected a dangling reference, got:\n%s", stdout.String())
+	}
+}
+
+func TestDiffRejectsUnknownKindFilter(t *testing.T) {
+	repo := newGitRepoFixture(t)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	code := Run([]string{"--root", repo.Root, "--kind", "moved"}, stdout, stderr)
+	if code != 1 {
+		t.Fatalf("expected unknown kind to exit 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), `diff: input error: invalid filter: unknown kind "moved"`) {
+		t.Fatalf("unexpected stderr %q", stderr.String())
+	}
+}
+
+func TestDiffRejectsUnknownFormat(t *testing.T) {
+	repo := newGitRepoFixture(t)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	code := Run([]string{"--root", repo.Root, "--format", "xml"}, stdout, stderr)
+	if code != 1 {
+		t.Fatalf("expected unknown format to exit 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), `diff: input error: unsupported output format "xml"`) {
+		t.Fatalf("unexpected stderr %q", stderr.String())
+	}
+}
+
 func TestDiffRejectsLegacyJSONFlag(t *testing.T) {
 	repo := newGitRepoFixture(t)
 	stdout := &bytes.Buffer{}
@@ -233,3 +397,196 @@ func runGitCommand(t *testing.T, root string, args ...string) string {
 	}
 	return string(output)
 }
+
+func TestMergeDriverMergesAppendedItemsCleanly(t *testing.T) {
+	root := copyDiffFixture(t)
+	base := readFixtureFile(t, root, "data/posts/posts.yaml")
+	ours := base + "  - id: Post-003\n    title: Third Post\n    author: User-Bob\n    body: From ours\n"
+	theirs := base + "  - id: Post-004\n    title: Fourth Post\n    author: User-Alice\n    body: From theirs\n"
+	files := writeMergeDriverInputs(t, base, ours, theirs)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	code := Run([]string{"merge-driver", "--root", root, files[0], files[1], files[2], "data/posts/posts.yaml"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("expected clean merge, exit %d stderr %s", code, stderr.String())
+	}
+
+	merged := readFixtureFile(t, filepath.Dir(files[1]), filepath.Base(files[1]))
+	for _, want := range []string{"Post-001", "Post-003", "Post-004", "From ours", "From theirs"} {
+		if !strings.Contains(merged, want) {
+			t.Fatalf("expected merged file to contain %q, got:\n%s", want, merged)
+		}
+	}
+	if strings.Contains(merged, "<<<<<<<") {
+		t.Fatalf("expected no conflict markers, got:\n%s", merged)
+	}
+}
+
+func TestMergeDriverMatchesGitForNonOverlappingAppends(t *testing.T) {
+	root := copyDiffFixture(t)
+	base := readFixtureFile(t, root, "data/posts/posts.yaml")
+	first, rest, _ := strings.Cut(base, "  - id: Post-002\n")
+	ours := first + "  - id: Draft-Ours\n    title: Ours\n    author: User-Bob\n    body: From ours\n  - id: Post-002\n" + rest
+	theirs := base + "  - id: Draft-Theirs\n    title: Theirs\n    author: User-Alice\n    body: From theirs\n"
+	files := writeMergeDriverInputs(t, base, ours, theirs)
+
+	textual, err := exec.Command("git", "merge-file", "-p", files[1], files[0], files[2]).Output()
+	if err != nil {
+		t.Fatalf("git merge-file: %v", err)
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	code := Run([]string{"merge-driver", "--root", root, files[0], files[1], files[2], "data/posts/posts.yaml"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("expected clean merge, exit %d stderr %s", code, stderr.String())
+	}
+	if merged := readFixtureFile(t, filepath.Dir(files[1]), filepath.Base(files[1])); merged != string(textual) {
+		t.Fatalf("expected the driver to match git merge-file\nwant:\n%s\ngot:\n%s", textual, merged)
+	}
+}
+
+func TestMergeDriverLeavesMarkersForFieldConflicts(t *testing.T) {
+	root := copyDiffFixture(t)
+	base := readFixtureFile(t, root, "data/posts/posts.yaml")
+	ours := strings.Replace(base, "title: First Post", "title: Ours Title", 1)
+	theirs := strings.Replace(base, "title: First Post", "title: Theirs Title", 1)
+	files := writeMergeDriverInputs(t, base, ours, theirs)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	code := Run([]string{"merge-driver", "--root", root, files[0], files[1], files[2], "data/posts/posts.yaml"}, stdout, stderr)
+	if code != 1 {
+		t.Fatalf("expected conflict exit 1, got %d stderr %s", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "CONFLICT (field) Post[Post-001] title") {
+		t.Fatalf("expected field conflict summary, got %q", stderr.String())
+	}
+
+	merged := readFixtureFile(t, filepath.Dir(files[1]), filepath.Base(files[1]))
+	for _, want := range []string{"<<<<<<< ours", "Ours Title", "Theirs Title", ">>>>>>> theirs"} {
+		if !strings.Contains(merged, want) {
+			t.Fatalf("expected merged file to contain %q, got:\n%s", want, merged)
+		}
+	}
+	if strings.Count(merged, "<<<<<<<") != 1 {
+		t.Fatalf("expected a single conflict block, got:\n%s", merged)
+	}
+}
+
+func TestMergeDriverFallsBackToTextualMergeForUnmanagedFiles(t *testing.T) {
+	root := copyDiffFixture(t)
+	files := writeMergeDriverInputs(t, "one\ntwo\nthree\n", "uno\ntwo\nthree\n", "one\ntwo\ntres\n")
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	code := Run([]string{"merge-driver", "--root", root, files[0], files[1], files[2], "notes.txt"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("expected clean textual merge, exit %d stderr %s", code, stderr.String())
+	}
+	if merged := readFixtureFile(t, filepath.Dir(files[1]), filepath.Base(files[1])); merged != "uno\ntwo\ntres\n" {
+		t.Fatalf("unexpected textual merge result %q", merged)
+	}
+}
+
+func TestMergeDriverRejectsWrongArgumentCount(t *testing.T) {
+	root := copyDiffFixture(t)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	code := Run([]string{"merge-driver", "--root", root, "a", "b"}, stdout, stderr)
+	if code != 2 {
+		t.Fatalf("expected exit 2, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "merge: input error: merge-driver requires 4 arguments") {
+		t.Fatalf("unexpected stderr %q", stderr.String())
+	}
+}
+
+func writeMergeDriverInputs(t *testing.T, base, ours, theirs string) [3]string {
+	t.Helper()
+	dir := t.TempDir()
+	var files [3]string
+	for idx, content := range []string{base, ours, theirs} {
+		files[idx] = filepath.Join(dir, []string{"base", "ours", "theirs"}[idx])
+		if err := os.WriteFile(files[idx], []byte(content), 0o644); err != nil {
+			t.Fatalf("write merge driver input: %v", err)
+		}
+	}
+	return files
+}
+
+func readFixtureFile(t *testing.T, root, relativePath string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(relativePath)))
+	if err != nil {
+		t.Fatalf("read %s: %v", relativePath, err)
+	}
+	return string(data)
+}
+
+func TestDiffSchemaExitsNonZeroOnBreakingChanges(t *testing.T) {
+	repo := newGitRepoFixture(t)
+	postType := readFixtureFile(t, repo.Root, "types/Post.yaml")
+	repo.CommitDataChange(t, "types/Post.yaml", strings.Replace(postType, "      body:\n        type: string\n", "", 1))
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	code := Run([]string{"--root", repo.Root, "--schema", "HEAD~1", "HEAD"}, stdout, stderr)
+	if code != 1 {
+		t.Fatalf("expected breaking schema diff to exit 1, got %d stderr %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "BREAKING field_removed Post.body") {
+		t.Fatalf("expected removed field in output, got %q", stdout.String())
+	}
+}
+
+func TestDiffSchemaSucceedsWithoutConfigChanges(t *testing.T) {
+	repo := newGitRepoFixture(t)
+	repo.CommitDataChange(t, "data/users/user-bob.yaml", "id: User-Bob\nname: Robert Example\nemail: bob@example.com\n")
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	code := Run([]string{"--root", repo.Root, "--schema", "HEAD~1", "HEAD"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("expected schema diff to succeed, exit %d stderr %s", code, stderr.String())
+	}
+	if stdout.String() != "No schema changes.\n" {
+		t.Fatalf("unexpected output %q", stdout.String())
+	}
+}
+
+func TestLogPrintsCommitsWithDataChanges(t *testing.T) {
+	repo := newGitRepoFixture(t)
+	commit := repo.CommitDataChange(t, "data/users/user-bob.yaml", "id: User-Bob\nname: Robert Example\nemail: bob@example.com\nrole: editor\n")
+	repo.CommitDataChange(t, "notes.txt", "unrelated\n")
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	code := Run([]string{"--root", repo.Root, "log", "HEAD~2..HEAD"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("expected log to succeed, exit %d stderr %s", code, stderr.String())
+	}
+	want := "commit " + commit + "\n"
+	if !strings.HasPrefix(stdout.String(), want) || strings.Count(stdout.String(), "commit ") != 1 {
+		t.Fatalf("expected only %s in log, got %q", commit, stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "MODIFIED User[User-Bob]\n  name: \"Bob Example\" -> \"Robert Example\"\n") {
+		t.Fatalf("expected Bob's rename in log, got %q", stdout.String())
+	}
+}
+
+func TestLogRejectsMissingRange(t *testing.T) {
+	repo := newGitRepoFixture(t)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	code := Run([]string{"--root", repo.Root, "log"}, stdout, stderr)
+	if code != 1 {
+		t.Fatalf("expected exit 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "diff: input error: log requires one revision range") {
+		t.Fatalf("unexpected stderr %q", stderr.String())
+	}
+}
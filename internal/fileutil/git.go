@@ -0,0 +1,326 @@
+package fileutil
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitTree serves the files of one git revision. The tree is listed once when
+// it is opened; blobs are read on demand through a single long-lived
+// `git cat-file --batch` process, so call Close when done.
+type GitTree struct {
+	root     string
+	revision string
+
+	// blobs maps slash-separated, root-relative paths to blob object IDs.
+	blobs map[string]string
+	paths []string
+	dirs  map[string]struct{}
+
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	closed bool
+}
+
+// OpenGitTree lists revision as seen from root. Paths are relative to root,
+// which may be a subdirectory of the repository.
+func OpenGitTree(root, revision string) (*GitTree, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("resolve root: %w", err)
+	}
+
+	cmd := exec.Command("git", "-C", absRoot, "ls-tree", "-r", "-z", revision)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return nil, fmt.Errorf("git ls-tree %s: %s", revision, message)
+	}
+
+	tree := &GitTree{
+		root:     absRoot,
+		revision: revision,
+		blobs:    make(map[string]string),
+		dirs:     map[string]struct{}{".": {}},
+	}
+	for _, entry := range bytes.Split(output, []byte{0}) {
+		if len(entry) == 0 {
+			continue
+		}
+		meta, name, ok := strings.Cut(string(entry), "\t")
+		if !ok {
+			return nil, fmt.Errorf("git ls-tree %s: unexpected entry %q", revision, entry)
+		}
+		fields := strings.Fields(meta)
+		if len(fields) != 3 || fields[1] != "blob" {
+			continue
+		}
+		tree.add(name, fields[2])
+	}
+	sort.Strings(tree.paths)
+	return tree, nil
+}
+
+// OpenGitIndex lists the files staged in the index as seen from root: what
+// the next commit would record. Read errors name files as :<path>, git's
+// syntax for the index. It fails while the index has unmerged paths.
+func OpenGitIndex(root string) (*GitTree, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("resolve root: %w", err)
+	}
+
+	cmd := exec.Command("git", "-C", absRoot, "ls-files", "--stage", "-z")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return nil, fmt.Errorf("git ls-files --stage: %s", message)
+	}
+
+	tree := &GitTree{
+		root:  absRoot,
+		blobs: make(map[string]string),
+		dirs:  map[string]struct{}{".": {}},
+	}
+	for _, entry := range bytes.Split(output, []byte{0}) {
+		if len(entry) == 0 {
+			continue
+		}
+		meta, name, ok := strings.Cut(string(entry), "\t")
+		if !ok {
+			return nil, fmt.Errorf("git ls-files --stage: unexpected entry %q", entry)
+		}
+		fields := strings.Fields(meta)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("git ls-files --stage: unexpected entry %q", entry)
+		}
+		if fields[2] != "0" {
+			return nil, fmt.Errorf("git index has unmerged path %s", name)
+		}
+		if fields[0] == "160000" {
+			continue
+		}
+		tree.add(name, fields[1])
+	}
+	sort.Strings(tree.paths)
+	return tree, nil
+}
+
+func (t *GitTree) add(name, oid string) {
+	t.blobs[name] = oid
+	t.paths = append(t.paths, name)
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		t.dirs[dir] = struct{}{}
+	}
+}
+
+// Files returns the sorted, slash-separated, root-relative paths of every
+// file in the tree.
+func (t *GitTree) Files() []string {
+	return append([]string(nil), t.paths...)
+}
+
+// Read returns the content of a root-relative file and whether it exists.
+func (t *GitTree) Read(rel string) ([]byte, bool, error) {
+	oid, ok := t.blobs[filepath.ToSlash(filepath.Clean(rel))]
+	if !ok {
+		return nil, false, nil
+	}
+	content, err := t.readBlob(oid)
+	if err != nil {
+		return nil, false, fmt.Errorf("git cat-file %s:%s: %w", t.revision, filepath.ToSlash(rel), err)
+	}
+	return content, true, nil
+}
+
+// Ops exposes the tree as file operations over absolute paths under root,
+// for loaders such as config.LoadWithOps and data.NewStoreWithOps.
+func (t *GitTree) Ops() Ops {
+	return Ops{
+		ReadFile: func(name string) ([]byte, error) {
+			rel, ok := t.relative(name)
+			if !ok {
+				return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+			}
+			content, exists, err := t.Read(rel)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+			}
+			return content, nil
+		},
+		Stat: func(name string) (os.FileInfo, error) {
+			rel, ok := t.relative(name)
+			if !ok {
+				return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+			}
+			if _, ok := t.dirs[rel]; ok {
+				return gitFileInfo{name: path.Base(rel), dir: true}, nil
+			}
+			content, exists, err := t.Read(rel)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+			}
+			return gitFileInfo{name: path.Base(rel), size: int64(len(content))}, nil
+		},
+		Glob: func(pattern string) ([]string, error) {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return nil, err
+			}
+			rel, ok := t.relative(pattern)
+			if !ok {
+				return nil, nil
+			}
+			var matches []string
+			for _, candidates := range [][]string{t.paths, t.dirList()} {
+				for _, candidate := range candidates {
+					if matched, _ := path.Match(rel, candidate); matched {
+						matches = append(matches, filepath.Join(t.root, filepath.FromSlash(candidate)))
+					}
+				}
+			}
+			sort.Strings(matches)
+			return matches, nil
+		},
+	}
+}
+
+// Close stops the cat-file process, if one was started.
+func (t *GitTree) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	if t.cmd == nil {
+		return nil
+	}
+	_ = t.stdin.Close()
+	return t.cmd.Wait()
+}
+
+// relative maps a path, resolved like the os package would, to the tree's
+// slash-separated form, reporting false for paths outside root.
+func (t *GitTree) relative(name string) (string, bool) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(t.root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+func (t *GitTree) dirList() []string {
+	dirs := make([]string, 0, len(t.dirs))
+	for dir := range t.dirs {
+		if dir != "." {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+func (t *GitTree) readBlob(oid string) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, errors.New("tree is closed")
+	}
+	if t.cmd == nil {
+		if err := t.startCatFile(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := io.WriteString(t.stdin, oid+"\n"); err != nil {
+		return nil, err
+	}
+	header, err := t.stdout.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("unexpected cat-file response %q", strings.TrimSpace(header))
+	}
+	size, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("unexpected cat-file response %q", strings.TrimSpace(header))
+	}
+	content := make([]byte, size+1)
+	if _, err := io.ReadFull(t.stdout, content); err != nil {
+		return nil, err
+	}
+	return content[:size], nil
+}
+
+func (t *GitTree) startCatFile() error {
+	cmd := exec.Command("git", "-C", t.root, "cat-file", "--batch")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start git cat-file: %w", err)
+	}
+	t.cmd = cmd
+	t.stdin = stdin
+	t.stdout = bufio.NewReader(stdout)
+	return nil
+}
+
+type gitFileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i gitFileInfo) Name() string { return i.name }
+func (i gitFileInfo) Size() int64  { return i.size }
+func (i gitFileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0o755
+	}
+	return 0o644
+}
+func (i gitFileInfo) ModTime() time.Time { return time.Time{} }
+func (i gitFileInfo) IsDir() bool        { return i.dir }
+func (i gitFileInfo) Sys() any           { return nil }
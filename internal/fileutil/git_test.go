@@ -0,0 +1,138 @@
+package fileutil_test
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mergewayhq/mergeway-cli/internal/config"
+	"github.com/mergewayhq/mergeway-cli/internal/fileutil"
+	"github.com/mergewayhq/mergeway-cli/internal/testutil"
+	"github.com/mergewayhq/mergeway-cli/internal/validation"
+)
+
+const gitTreeConfig = `mergeway:
+  version: 1
+
+entities:
+  User:
+    include:
+      - data/users/*.yaml
+    identifier: id
+    fields:
+      id:
+        type: string
+        required: true
+      name:
+        type: string
+        required: true
+`
+
+func TestGitTreeServesCommittedFiles(t *testing.T) {
+	root := testutil.NewGitRepoWithFiles(t, map[string]string{
+		"mergeway.yaml":              gitTreeConfig,
+		"data/users/user-alice.yaml": "id: User-Alice\nname: Alice\n",
+	}).Root
+	testutil.WriteFile(t, root, "data/users/user-alice.yaml", "id: User-Alice\nname: Changed\n")
+	testutil.WriteFile(t, root, "data/users/user-new.yaml", "id: User-New\nname: New\n")
+
+	tree, err := fileutil.OpenGitTree(root, "HEAD")
+	if err != nil {
+		t.Fatalf("OpenGitTree: %v", err)
+	}
+	defer tree.Close()
+	ops := tree.Ops()
+
+	content, err := ops.ReadFile(filepath.Join(root, "data", "users", "user-alice.yaml"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(content) != "id: User-Alice\nname: Alice\n" {
+		t.Fatalf("expected committed content, got %q", content)
+	}
+	if _, err := ops.ReadFile(filepath.Join(root, "data", "users", "user-new.yaml")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected untracked file to be missing, got %v", err)
+	}
+
+	info, err := ops.Stat(filepath.Join(root, "data", "users"))
+	if err != nil || !info.IsDir() {
+		t.Fatalf("expected data/users to stat as a directory, got %v, %v", info, err)
+	}
+
+	matches, err := ops.Glob(filepath.Join(root, "data", "users", "*.yaml"))
+	if err != nil {
+		t.Fatalf("Glob: %v", err)
+	}
+	expected := []string{filepath.Join(root, "data", "users", "user-alice.yaml")}
+	if !reflect.DeepEqual(matches, expected) {
+		t.Fatalf("expected matches %v, got %v", expected, matches)
+	}
+}
+
+func TestGitTreeLoadsConfigAndValidatesRevision(t *testing.T) {
+	root := testutil.NewGitRepoWithFiles(t, map[string]string{
+		"mergeway.yaml":              gitTreeConfig,
+		"data/users/user-alice.yaml": "id: User-Alice\nname: Alice\n",
+	}).Root
+	revision := strings.TrimSpace(testutil.RunGit(t, root, "rev-parse", "HEAD"))
+	testutil.WriteFile(t, root, "data/users/user-alice.yaml", "id: User-Alice\n")
+	testutil.RunGit(t, root, "commit", "-am", "break alice")
+
+	tree, err := fileutil.OpenGitTree(root, revision)
+	if err != nil {
+		t.Fatalf("OpenGitTree: %v", err)
+	}
+	defer tree.Close()
+
+	cfg, err := config.LoadWithOps(filepath.Join(root, "mergeway.yaml"), tree.Ops())
+	if err != nil {
+		t.Fatalf("LoadWithOps: %v", err)
+	}
+	result, err := validation.ValidateWithOps(root, cfg, validation.Options{}, tree.Ops())
+	if err != nil {
+		t.Fatalf("ValidateWithOps: %v", err)
+	}
+	if len(result.Errors) != 0 {
+		t.Fatalf("expected the earlier revision to validate, got %v", result.Errors)
+	}
+}
+
+func TestOpenGitTreeRejectsUnknownRevision(t *testing.T) {
+	root := testutil.NewGitRepoWithFiles(t, map[string]string{"mergeway.yaml": gitTreeConfig}).Root
+
+	if _, err := fileutil.OpenGitTree(root, "does-not-exist"); err == nil || !strings.Contains(err.Error(), "git ls-tree does-not-exist") {
+		t.Fatalf("expected ls-tree error, got %v", err)
+	}
+}
+
+func TestGitIndexServesStagedFiles(t *testing.T) {
+	root := testutil.NewGitRepoWithFiles(t, map[string]string{
+		"mergeway.yaml":            gitTreeConfig,
+		"data/users/user-bob.yaml": "id: User-Bob\nname: Bob\n",
+	}).Root
+	testutil.WriteFile(t, root, "data/users/user-bob.yaml", "id: User-Bob\nname: Bob Staged\n")
+	testutil.WriteFile(t, root, "data/users/user-eve.yaml", "id: User-Eve\nname: Eve\n")
+	testutil.RunGit(t, root, "add", ".")
+	testutil.WriteFile(t, root, "data/users/user-bob.yaml", "id: User-Bob\nname: Bob Unstaged\n")
+	testutil.WriteFile(t, root, "data/users/user-zed.yaml", "id: User-Zed\nname: Zed\n")
+
+	index, err := fileutil.OpenGitIndex(root)
+	if err != nil {
+		t.Fatalf("OpenGitIndex: %v", err)
+	}
+	defer func() {
+		_ = index.Close()
+	}()
+
+	expected := []string{"data/users/user-bob.yaml", "data/users/user-eve.yaml", "mergeway.yaml"}
+	if got := index.Files(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected staged files %v, got %v", expected, got)
+	}
+	content, ok, err := index.Read("data/users/user-bob.yaml")
+	if err != nil || !ok || string(content) != "id: User-Bob\nname: Bob Staged\n" {
+		t.Fatalf("expected the staged Bob, got %q, %v, %v", content, ok, err)
+	}
+}
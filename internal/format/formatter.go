@@ -43,6 +43,17 @@ func FormatFile(path string, schema *Schema) (*Result, error) {
 
 // FormatBytes normalizes raw file contents according to the configured rules.
 func FormatBytes(path string, data []byte, schema *Schema) ([]byte, error) {
+	return formatBytes(path, data, schema, true)
+}
+
+// FormatBytesKeepingOrder applies the same rules as FormatBytes but leaves
+// items in the order the file lists them, for callers such as the merge
+// driver that must not reorder a file.
+func FormatBytesKeepingOrder(path string, data []byte, schema *Schema) ([]byte, error) {
+	return formatBytes(path, data, schema, false)
+}
+
+func formatBytes(path string, data []byte, schema *Schema, sortItems bool) ([]byte, error) {
 	trimmed := bytes.TrimSpace(data)
 	if len(trimmed) == 0 {
 		return data, nil
@@ -61,7 +72,7 @@ func FormatBytes(path string, data []byte, schema *Schema) ([]byte, error) {
 		return data, nil
 	}
 
-	applyOnDocument(&root)
+	applyOnDocument(&root, sortItems)
 	applySchemaOrdering(&root, schema)
 
 	ext := strings.ToLower(filepath.Ext(path))
@@ -86,27 +97,27 @@ func FormatBytes(path string, data []byte, schema *Schema) ([]byte, error) {
 	}
 }
 
-func applyOnDocument(node *yaml.Node) {
+func applyOnDocument(node *yaml.Node, sortItems bool) {
 	if node == nil {
 		return
 	}
 	switch node.Kind {
 	case yaml.DocumentNode:
 		for _, child := range node.Content {
-			applyOnDocument(child)
+			applyOnDocument(child, sortItems)
 		}
 	case yaml.MappingNode:
-		rewriteMapping(node)
+		rewriteMapping(node, sortItems)
 	case yaml.SequenceNode:
 		for _, child := range node.Content {
-			applyOnDocument(child)
+			applyOnDocument(child, sortItems)
 		}
 	default:
 		// No-op on scalars or aliases.
 	}
 }
 
-func rewriteMapping(node *yaml.Node) {
+func rewriteMapping(node *yaml.Node, sortItems bool) {
 	if node == nil {
 		return
 	}
@@ -118,12 +129,12 @@ func rewriteMapping(node *yaml.Node) {
 		key := node.Content[idx]
 		val := node.Content[idx+1]
 
-		if strings.EqualFold(key.Value, "items") && val != nil && val.Kind == yaml.SequenceNode {
+		if sortItems && strings.EqualFold(key.Value, "items") && val != nil && val.Kind == yaml.SequenceNode {
 			reorderItems(val)
 			continue
 		}
 
-		applyOnDocument(val)
+		applyOnDocument(val, sortItems)
 	}
 }
 
@@ -138,7 +149,7 @@ type entityNode struct {
 func reorderItems(seq *yaml.Node) {
 	if seq == nil || len(seq.Content) < 2 {
 		for _, child := range seq.Content {
-			applyOnDocument(child)
+			applyOnDocument(child, true)
 		}
 		return
 	}
@@ -147,7 +158,7 @@ func reorderItems(seq *yaml.Node) {
 	keyed := 0
 
 	for i, child := range seq.Content {
-		applyOnDocument(child)
+		applyOnDocument(child, true)
 		key, hasKey := entitySortKey(child)
 		if hasKey {
 			keyed++
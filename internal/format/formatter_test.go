@@ -33,6 +33,34 @@ items:
 	}
 }
 
+func TestFormatBytesKeepingOrderLeavesItemsInPlace(t *testing.T) {
+	input := `
+type: Post
+items:
+  - title: Beta
+    id: post-b
+  - id: post-a
+    title: Alpha
+`
+
+	out, err := FormatBytesKeepingOrder("data.yaml", []byte(input), NewSchema([]*SchemaField{{Name: "id"}, {Name: "title"}}))
+	if err != nil {
+		t.Fatalf("format bytes: %v", err)
+	}
+
+	expected := `type: Post
+items:
+  - id: post-b
+    title: Beta
+  - id: post-a
+    title: Alpha
+`
+
+	if got := string(out); strings.TrimSpace(got) != strings.TrimSpace(expected) {
+		t.Fatalf("unexpected output:\n%s", got)
+	}
+}
+
 func TestFormatBytesPreservesComments(t *testing.T) {
 	input := `
 items:
@@ -1,5 +1,7 @@
 package format
 
+import "github.com/mergewayhq/mergeway-cli/internal/config"
+
 // Schema describes the canonical field ordering for an entity.
 type Schema struct {
 	fields []*SchemaField
@@ -43,3 +45,47 @@ func (s *Schema) orderedFields() []*SchemaField {
 	}
 	return s.fields
 }
+
+// SchemaForType derives the canonical field ordering from an entity definition.
+func SchemaForType(typeDef *config.TypeDefinition) *Schema {
+	if typeDef == nil || len(typeDef.FieldOrder) == 0 {
+		return nil
+	}
+
+	fields := make([]*SchemaField, 0, len(typeDef.FieldOrder))
+	for _, name := range typeDef.FieldOrder {
+		field := typeDef.Fields[name]
+		if field == nil {
+			continue
+		}
+		if schemaField := schemaFieldFor(field); schemaField != nil {
+			fields = append(fields, schemaField)
+		}
+	}
+
+	return NewSchema(fields)
+}
+
+func schemaFieldFor(field *config.FieldDefinition) *SchemaField {
+	if field == nil || field.Name == "" {
+		return nil
+	}
+	schemaField := &SchemaField{
+		Name:     field.Name,
+		Repeated: field.Repeated,
+	}
+	if field.Type == "object" && len(field.PropertyOrder) > 0 {
+		children := make([]*SchemaField, 0, len(field.PropertyOrder))
+		for _, propName := range field.PropertyOrder {
+			child := field.Properties[propName]
+			if child == nil {
+				continue
+			}
+			if nested := schemaFieldFor(child); nested != nil {
+				children = append(children, nested)
+			}
+		}
+		schemaField.Nested = NewSchema(children)
+	}
+	return schemaField
+}
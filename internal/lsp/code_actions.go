@@ -3,8 +3,8 @@ package lsp
 import (
 	"context"
 	"fmt"
-	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/mergewayhq/mergeway-cli/internal/config"
@@ -13,13 +13,35 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-func (s *Server) codeActions(ctx context.Context, params *protocol.CodeActionParams) ([]protocol.CodeAction, error) {
+// codeAction mirrors protocol.CodeAction with an edit that may carry resource
+// operations, which the protocol package's WorkspaceEdit cannot express.
+type codeAction struct {
+	Title       string                  `json:"title"`
+	Kind        protocol.CodeActionKind `json:"kind,omitempty"`
+	Diagnostics []protocol.Diagnostic   `json:"diagnostics,omitempty"`
+	IsPreferred bool                    `json:"isPreferred,omitempty"`
+	Edit        *workspaceEdit          `json:"edit,omitempty"`
+}
+
+// workspaceEdit mirrors protocol.WorkspaceEdit, allowing documentChanges to mix
+// text document edits with file creations.
+type workspaceEdit struct {
+	Changes         map[protocol.DocumentURI][]protocol.TextEdit `json:"changes,omitempty"`
+	DocumentChanges []interface{}                                `json:"documentChanges,omitempty"`
+}
+
+type createFileOperation struct {
+	Kind string               `json:"kind"`
+	URI  protocol.DocumentURI `json:"uri"`
+}
+
+func (s *Server) codeActions(ctx context.Context, params *protocol.CodeActionParams) ([]codeAction, error) {
 	if !requestsQuickFixes(params.Context.Only) {
 		return nil, nil
 	}
 
 	path := params.TextDocument.URI.Filename()
-	var actions []protocol.CodeAction
+	var actions []codeAction
 	for _, diagnostic := range params.Context.Diagnostics {
 		actions = append(actions, s.quickFixesForDiagnostic(path, diagnostic)...)
 	}
@@ -38,7 +60,7 @@ func requestsQuickFixes(only []protocol.CodeActionKind) bool {
 	return false
 }
 
-func (s *Server) quickFixesForDiagnostic(path string, diagnostic protocol.Diagnostic) []protocol.CodeAction {
+func (s *Server) quickFixesForDiagnostic(path string, diagnostic protocol.Diagnostic) []codeAction {
 	analysis, err := s.analyzePosition(path, diagnostic.Range.Start)
 	if err != nil || analysis == nil || analysis.data == nil || analysis.data.typeDef == nil || analysis.data.objectNode == nil {
 		return nil
@@ -56,7 +78,7 @@ func (s *Server) quickFixesForDiagnostic(path string, diagnostic protocol.Diagno
 	}
 }
 
-func (s *Server) quickFixesForMissingRequiredField(path string, analysis *documentAnalysis, diagnostic protocol.Diagnostic) []protocol.CodeAction {
+func (s *Server) quickFixesForMissingRequiredField(path string, analysis *documentAnalysis, diagnostic protocol.Diagnostic) []codeAction {
 	fieldName := quotedFieldName(diagnostic.Message)
 	if fieldName == "" {
 		return nil
@@ -67,7 +89,7 @@ func (s *Server) quickFixesForMissingRequiredField(path string, analysis *docume
 		return nil
 	}
 
-	var actions []protocol.CodeAction
+	var actions []codeAction
 	if renameEdit, oldField, ok := renameFieldEdit(analysis.data.objectNode, analysis.data.typeDef, fieldName); ok {
 		actions = append(actions, quickFixAction(
 			fmt.Sprintf(`Rename field "%s" to "%s"`, oldField, fieldName),
@@ -78,7 +100,7 @@ func (s *Server) quickFixesForMissingRequiredField(path string, analysis *docume
 		))
 	}
 
-	if insertEdit, ok := missingFieldInsertEdit(path, analysis, fieldName, fieldDef); ok {
+	if insertEdit, ok := missingFieldInsertEdit(analysis, fieldName, fieldDef); ok {
 		actions = append(actions, quickFixAction(
 			fmt.Sprintf(`Insert missing field "%s"`, fieldName),
 			path,
@@ -91,7 +113,7 @@ func (s *Server) quickFixesForMissingRequiredField(path string, analysis *docume
 	return actions
 }
 
-func (s *Server) quickFixesForEnum(path string, analysis *documentAnalysis, diagnostic protocol.Diagnostic) []protocol.CodeAction {
+func (s *Server) quickFixesForEnum(path string, analysis *documentAnalysis, diagnostic protocol.Diagnostic) []codeAction {
 	fieldName := quotedFieldName(diagnostic.Message)
 	if fieldName == "" {
 		return nil
@@ -107,7 +129,7 @@ func (s *Server) quickFixesForEnum(path string, analysis *documentAnalysis, diag
 		return nil
 	}
 
-	return []protocol.CodeAction{quickFixAction(
+	return []codeAction{quickFixAction(
 		fmt.Sprintf(`Replace with "%s"`, replacement),
 		path,
 		edit,
@@ -116,7 +138,7 @@ func (s *Server) quickFixesForEnum(path string, analysis *documentAnalysis, diag
 	)}
 }
 
-func (s *Server) quickFixesForReference(path string, analysis *documentAnalysis, diagnostic protocol.Diagnostic) []protocol.CodeAction {
+func (s *Server) quickFixesForReference(path string, analysis *documentAnalysis, diagnostic protocol.Diagnostic) []codeAction {
 	fieldName := quotedFieldName(diagnostic.Message)
 	if fieldName == "" {
 		return nil
@@ -127,32 +149,34 @@ func (s *Server) quickFixesForReference(path string, analysis *documentAnalysis,
 		return nil
 	}
 
+	actions := s.createReferencedObjectActions(analysis, fieldDef, diagnostic)
+
 	candidates := referenceIDs(analysis.root, fieldDef)
 	if len(candidates) == 0 {
-		return nil
+		return actions
 	}
 
 	edit, replacement, ok := referenceReplacementEdit(analysis.data.objectNode, fieldName, candidates)
 	if !ok {
-		return nil
+		return actions
 	}
 
-	return []protocol.CodeAction{quickFixAction(
+	return append(actions, quickFixAction(
 		fmt.Sprintf(`Replace with "%s"`, replacement),
 		path,
 		edit,
 		diagnostic,
 		true,
-	)}
+	))
 }
 
-func quickFixAction(title, path string, edit protocol.TextEdit, diagnostic protocol.Diagnostic, preferred bool) protocol.CodeAction {
-	return protocol.CodeAction{
+func quickFixAction(title, path string, edit protocol.TextEdit, diagnostic protocol.Diagnostic, preferred bool) codeAction {
+	return codeAction{
 		Title:       title,
 		Kind:        protocol.QuickFix,
 		Diagnostics: []protocol.Diagnostic{diagnostic},
 		IsPreferred: preferred,
-		Edit: &protocol.WorkspaceEdit{
+		Edit: &workspaceEdit{
 			Changes: map[protocol.DocumentURI][]protocol.TextEdit{
 				protocol.DocumentURI(uri.File(path)): {edit},
 			},
@@ -184,9 +208,9 @@ func renameFieldEdit(objectNode *yaml.Node, typeDef *config.TypeDefinition, miss
 	}, bestName, true
 }
 
-func missingFieldInsertEdit(path string, analysis *documentAnalysis, fieldName string, fieldDef *config.FieldDefinition) (protocol.TextEdit, bool) {
-	if !isYAMLPath(path) {
-		return protocol.TextEdit{}, false
+func missingFieldInsertEdit(analysis *documentAnalysis, fieldName string, fieldDef *config.FieldDefinition) (protocol.TextEdit, bool) {
+	if analysis.data.objectNode.Style&yaml.FlowStyle != 0 {
+		return flowMissingFieldInsertEdit(analysis, fieldName, fieldDef)
 	}
 
 	insertRange, prefix, ok := insertionRangeForField(analysis.content, analysis.data.objectNode, analysis.data.typeDef, fieldName)
@@ -202,6 +226,117 @@ func missingFieldInsertEdit(path string, analysis *documentAnalysis, fieldName s
 	}, true
 }
 
+// flowMissingFieldInsertEdit inserts a JSON-style entry into a flow mapping,
+// which covers JSON data files: before the next field in schema order when one
+// is present, and otherwise after the last entry.
+func flowMissingFieldInsertEdit(analysis *documentAnalysis, fieldName string, fieldDef *config.FieldDefinition) (protocol.TextEdit, bool) {
+	objectNode := analysis.data.objectNode
+	if len(objectNode.Content) < 2 {
+		return protocol.TextEdit{}, false
+	}
+
+	lines := strings.Split(string(analysis.content), "\n")
+	entry := strconv.Quote(fieldName) + ": " + flowFieldPlaceholder(fieldDef)
+
+	present := make(map[string]*yaml.Node)
+	for idx := 0; idx+1 < len(objectNode.Content); idx += 2 {
+		present[objectNode.Content[idx].Value] = objectNode.Content[idx]
+	}
+	if index := fieldOrderIndex(analysis.data.typeDef, fieldName); index >= 0 {
+		for _, nextField := range analysis.data.typeDef.FieldOrder[index+1:] {
+			keyNode := present[nextField]
+			if keyNode == nil {
+				continue
+			}
+			start := scalarTokenRange(keyNode).Start
+			separator := ", "
+			if leading, ok := leadingWhitespace(lines, start); ok {
+				separator = ",\n" + leading
+			}
+			return protocol.TextEdit{
+				Range:   protocol.Range{Start: start, End: start},
+				NewText: entry + separator,
+			}, true
+		}
+	}
+
+	lastKey := objectNode.Content[len(objectNode.Content)-2]
+	end, ok := flowValueEnd(lines, objectNode.Content[len(objectNode.Content)-1])
+	if !ok {
+		return protocol.TextEdit{}, false
+	}
+	separator := ", "
+	if leading, ok := leadingWhitespace(lines, scalarTokenRange(lastKey).Start); ok {
+		separator = ",\n" + leading
+	}
+	return protocol.TextEdit{
+		Range:   protocol.Range{Start: end, End: end},
+		NewText: separator + entry,
+	}, true
+}
+
+func flowFieldPlaceholder(fieldDef *config.FieldDefinition) string {
+	if fieldDef != nil && !fieldDef.Repeated && !fieldDef.IsReference() && len(fieldDef.Enum) > 0 {
+		return strconv.Quote(fieldDef.Enum[0])
+	}
+	return fieldPlaceholder(fieldDef)
+}
+
+// leadingWhitespace returns the indentation before pos when only whitespace
+// precedes it on its line.
+func leadingWhitespace(lines []string, pos protocol.Position) (string, bool) {
+	line := lineAt(lines, int(pos.Line))
+	if int(pos.Character) > len(line) {
+		return "", false
+	}
+	leading := line[:pos.Character]
+	if strings.TrimSpace(leading) != "" {
+		return "", false
+	}
+	return leading, true
+}
+
+// flowValueEnd returns the position just past a flow value, scanning to the
+// matching bracket for flow collections since nodes only record their start.
+func flowValueEnd(lines []string, node *yaml.Node) (protocol.Position, bool) {
+	if node == nil {
+		return protocol.Position{}, false
+	}
+	if node.Kind == yaml.ScalarNode {
+		return scalarTokenRange(node).End, true
+	}
+	if node.Style&yaml.FlowStyle == 0 {
+		return protocol.Position{}, false
+	}
+
+	depth := 0
+	inString := false
+	for lineNo := node.Line - 1; lineNo < len(lines); lineNo++ {
+		line := lines[lineNo]
+		start := 0
+		if lineNo == node.Line-1 {
+			start = node.Column - 1
+		}
+		for idx := start; idx < len(line); idx++ {
+			switch c := line[idx]; {
+			case inString && c == '\\':
+				idx++
+			case c == '"':
+				inString = !inString
+			case inString:
+			case c == '[' || c == '{':
+				depth++
+			case c == ']' || c == '}':
+				depth--
+				if depth == 0 {
+					return protocol.Position{Line: uint32(lineNo), Character: uint32(idx + 1)}, true
+				}
+			}
+		}
+	}
+	return protocol.Position{}, false
+}
+
 func enumReplacementEdit(objectNode *yaml.Node, fieldName string, candidates []string) (protocol.TextEdit, string, bool) {
 	rng, current, ok := replacementTarget(objectNode, fieldName, func(value string) bool {
 		return !containsString(candidates, value)
@@ -295,12 +430,20 @@ func insertionRangeForField(content []byte, objectNode *yaml.Node, typeDef *conf
 		}
 	}
 
-	endLine := int(structuralNodeRange(objectNode).End.Line) + 1
+	insertRange, prefix := insertionRangeAfterNode(lines, objectNode)
+	return insertRange, prefix, true
+}
+
+// insertionRangeAfterNode returns an empty range at the start of the line
+// following node, or at the end of the document with a newline prefix when
+// node ends on the last line.
+func insertionRangeAfterNode(lines []string, node *yaml.Node) (protocol.Range, string) {
+	endLine := int(structuralNodeRange(node).End.Line) + 1
 	if endLine < len(lines) {
 		return protocol.Range{
 			Start: protocol.Position{Line: uint32(endLine), Character: 0},
 			End:   protocol.Position{Line: uint32(endLine), Character: 0},
-		}, "", true
+		}, ""
 	}
 
 	lastLine := maxInt(0, len(lines)-1)
@@ -312,7 +455,7 @@ func insertionRangeForField(content []byte, objectNode *yaml.Node, typeDef *conf
 	return protocol.Range{
 		Start: protocol.Position{Line: uint32(lastLine), Character: uint32(len(lastText))},
 		End:   protocol.Position{Line: uint32(lastLine), Character: uint32(len(lastText))},
-	}, "\n", true
+	}, "\n"
 }
 
 func fieldOrderIndex(typeDef *config.TypeDefinition, fieldName string) int {
@@ -441,7 +584,7 @@ func damerauLevenshtein(left, right string) int {
 	return matrix[len(a)][len(b)]
 }
 
-func uniqueCodeActions(actions []protocol.CodeAction) []protocol.CodeAction {
+func uniqueCodeActions(actions []codeAction) []codeAction {
 	if len(actions) == 0 {
 		return nil
 	}
@@ -450,7 +593,7 @@ func uniqueCodeActions(actions []protocol.CodeAction) []protocol.CodeAction {
 		return actions[i].Title < actions[j].Title
 	})
 
-	var result []protocol.CodeAction
+	var result []codeAction
 	seen := make(map[string]struct{}, len(actions))
 	for _, action := range actions {
 		key := action.Title
@@ -497,8 +640,3 @@ func maxInt(left, right int) int {
 	}
 	return right
 }
-
-func isYAMLPath(path string) bool {
-	ext := strings.ToLower(filepath.Ext(path))
-	return ext == ".yaml" || ext == ".yml"
-}
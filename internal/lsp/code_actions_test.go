@@ -96,7 +96,7 @@ func initializeCodeActionServer(t *testing.T, root string) (*Server, *diagnostic
 	return server, capture, absRoot
 }
 
-func openDocumentAndCodeActions(t *testing.T, server *Server, capture *diagnosticCapture, path, text string) []protocol.CodeAction {
+func openDocumentAndCodeActions(t *testing.T, server *Server, capture *diagnosticCapture, path, text string) []codeAction {
 	t.Helper()
 
 	openDocument(t, server, path, languageForPath(path), 1, text)
@@ -109,7 +109,7 @@ func openDocumentAndCodeActions(t *testing.T, server *Server, capture *diagnosti
 		t.Fatalf("expected diagnostics for %s, got %#v", path, params)
 	}
 
-	var actions []protocol.CodeAction
+	var actions []codeAction
 	callServer(t, server, protocol.MethodTextDocumentCodeAction, 2, &protocol.CodeActionParams{
 		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(path))},
 		Range:        params.Diagnostics[0].Range,
@@ -121,7 +121,7 @@ func openDocumentAndCodeActions(t *testing.T, server *Server, capture *diagnosti
 	return actions
 }
 
-func requireCodeAction(t *testing.T, actions []protocol.CodeAction, title string) protocol.CodeAction {
+func requireCodeAction(t *testing.T, actions []codeAction, title string) codeAction {
 	t.Helper()
 
 	for _, action := range actions {
@@ -130,10 +130,10 @@ func requireCodeAction(t *testing.T, actions []protocol.CodeAction, title string
 		}
 	}
 	t.Fatalf("expected code action %q in %#v", title, actions)
-	return protocol.CodeAction{}
+	return codeAction{}
 }
 
-func requireSingleEdit(t *testing.T, action protocol.CodeAction, path string) protocol.TextEdit {
+func requireSingleEdit(t *testing.T, action codeAction, path string) protocol.TextEdit {
 	t.Helper()
 
 	if action.Edit == nil {
@@ -0,0 +1,152 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mergewayhq/mergeway-cli/internal/workspace"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+	"gopkg.in/yaml.v3"
+)
+
+// showReferencesCommand is the client-side command that code lenses invoke to
+// display inbound references. Its arguments mirror VS Code's
+// editor.action.showReferences: document URI, position, and locations. Only
+// clients that set the showReferencesCommand initialization option get it;
+// other clients see the lens title without a command to run.
+const showReferencesCommand = "mergeway.showReferences"
+
+type inboundUsage struct {
+	location       protocol.Location
+	referenceTypes []string
+}
+
+func (s *Server) codeLenses(ctx context.Context, params *protocol.CodeLensParams) ([]protocol.CodeLens, error) {
+	path := params.TextDocument.URI.Filename()
+	analysis, typeDef, err := s.dataDocument(path)
+	if err != nil || typeDef == nil {
+		return nil, err
+	}
+
+	inbound := s.inboundReferenceIndex(analysis.root)
+	var lenses []protocol.CodeLens
+	for index, objectNode := range documentObjectNodes(analysis.doc) {
+		objectID, selectionRange := objectIdentifierSelection(objectNode, typeDef)
+		if objectID == "" {
+			continue
+		}
+
+		obj := workspaceObjectForNode(analysis.root, typeDef.Name, path, objectID, index)
+		objectType := typeDef.Name
+		if obj != nil {
+			objectType = obj.Type
+		}
+		accepted := make(map[string]struct{})
+		for _, typeName := range referenceableTypeNames(analysis.cfg, objectType) {
+			accepted[typeName] = struct{}{}
+		}
+
+		var locations []protocol.Location
+		for _, usage := range inbound[objectID] {
+			for _, typeName := range usage.referenceTypes {
+				if _, ok := accepted[typeName]; ok {
+					locations = append(locations, usage.location)
+					break
+				}
+			}
+		}
+		locations = sortUniqueLocations(locations)
+		if locations == nil {
+			locations = []protocol.Location{}
+		}
+
+		command := &protocol.Command{Title: inboundReferencesTitle(len(locations))}
+		if s.showReferences {
+			command.Command = showReferencesCommand
+			command.Arguments = []interface{}{params.TextDocument.URI, selectionRange.Start, locations}
+		}
+		lenses = append(lenses, protocol.CodeLens{Range: selectionRange, Command: command})
+	}
+	return lenses, nil
+}
+
+// supportsShowReferencesCommand reports whether the client's initialization
+// options declare that it handles showReferencesCommand.
+func supportsShowReferencesCommand(raw json.RawMessage) bool {
+	var options struct {
+		ShowReferencesCommand bool `json:"showReferencesCommand"`
+	}
+	if len(raw) == 0 || json.Unmarshal(raw, &options) != nil {
+		return false
+	}
+	return options.ShowReferencesCommand
+}
+
+// inboundReferenceIndex scans every reference field in the root once and
+// groups the referencing locations by referenced identifier. Each file is
+// parsed once however many objects it holds.
+func (s *Server) inboundReferenceIndex(root *workspace.RootRuntime) map[string][]inboundUsage {
+	cfg := validationConfig(root)
+	if root == nil || root.Workspace == nil || cfg == nil {
+		return nil
+	}
+
+	index := make(map[string][]inboundUsage)
+	docs := make(map[string]*yaml.Node)
+	for _, sourceType := range sortedTypeNames(cfg.Types) {
+		typeDef := cfg.Types[sourceType]
+		for _, obj := range root.Workspace.Objects(sourceType) {
+			if obj.Type != sourceType || obj.Inline {
+				continue
+			}
+			doc, parsed := docs[obj.File]
+			if !parsed {
+				if content, err := s.documentContent(obj.File); err == nil {
+					doc, _ = parseDocumentNode(content)
+				}
+				docs[obj.File] = doc
+			}
+			if doc == nil {
+				continue
+			}
+			objectNode := objectNodeForObject(doc, typeDef, obj)
+			if objectNode == nil || objectNode.Kind != yaml.MappingNode {
+				continue
+			}
+
+			for idx := 0; idx+1 < len(objectNode.Content); idx += 2 {
+				fieldDef := typeDef.Fields[objectNode.Content[idx].Value]
+				if !fieldDef.IsReference() {
+					continue
+				}
+				valueNode := objectNode.Content[idx+1]
+				valueNodes := []*yaml.Node{valueNode}
+				if valueNode.Kind == yaml.SequenceNode {
+					valueNodes = valueNode.Content
+				}
+				for _, node := range valueNodes {
+					if node == nil || node.Kind != yaml.ScalarNode || node.Value == "" {
+						continue
+					}
+					index[node.Value] = append(index[node.Value], inboundUsage{
+						location: protocol.Location{
+							URI:   protocol.DocumentURI(uri.File(obj.File)),
+							Range: nodeRange(node),
+						},
+						referenceTypes: fieldDef.ReferenceTypes,
+					})
+				}
+			}
+		}
+	}
+	return index
+}
+
+func inboundReferencesTitle(count int) string {
+	if count == 1 {
+		return "1 inbound reference"
+	}
+	return fmt.Sprintf("%d inbound references", count)
+}
@@ -0,0 +1,98 @@
+package lsp
+
+import (
+	"path/filepath"
+	"testing"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func TestHandleCodeLensCountsInboundReferences(t *testing.T) {
+	t.Run("single object document", func(t *testing.T) {
+		server, root := initializeCodeLensServer(t, true)
+		userPath := filepath.Join(root, "data", "users", "alice.yaml")
+		userContent := readFile(t, userPath)
+
+		var result []protocol.CodeLens
+		callServer(t, server, protocol.MethodTextDocumentCodeLens, 2, &protocol.CodeLensParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(userPath))},
+		}, &result)
+
+		if len(result) != 1 {
+			t.Fatalf("expected one lens, got %#v", result)
+		}
+		if result[0].Range != scalarFieldValueRange(t, userContent, "id") {
+			t.Fatalf("expected lens on identifier, got %+v", result[0].Range)
+		}
+		if result[0].Command == nil || result[0].Command.Title != "1 inbound reference" {
+			t.Fatalf("expected one inbound reference, got %#v", result[0].Command)
+		}
+		if result[0].Command.Command != showReferencesCommand || len(result[0].Command.Arguments) != 3 {
+			t.Fatalf("expected show references command with uri, position, locations, got %#v", result[0].Command)
+		}
+	})
+
+	t.Run("title only without client command support", func(t *testing.T) {
+		server, root := initializeExampleFullServer(t)
+		userPath := filepath.Join(root, "data", "users", "alice.yaml")
+
+		var result []protocol.CodeLens
+		callServer(t, server, protocol.MethodTextDocumentCodeLens, 2, &protocol.CodeLensParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(userPath))},
+		}, &result)
+
+		if len(result) != 1 || result[0].Command == nil || result[0].Command.Title != "1 inbound reference" {
+			t.Fatalf("expected one inbound reference, got %#v", result)
+		}
+		if result[0].Command.Command != "" || len(result[0].Command.Arguments) != 0 {
+			t.Fatalf("expected no client command, got %#v", result[0].Command)
+		}
+	})
+
+	t.Run("multi object document", func(t *testing.T) {
+		server, root := initializeExampleFullServer(t)
+		tagPath := filepath.Join(root, "data", "tags", "product.yaml")
+
+		var result []protocol.CodeLens
+		callServer(t, server, protocol.MethodTextDocumentCodeLens, 2, &protocol.CodeLensParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(tagPath))},
+		}, &result)
+
+		if len(result) != 2 {
+			t.Fatalf("expected one lens per tag, got %#v", result)
+		}
+		if result[0].Command.Title != "1 inbound reference" || result[1].Command.Title != "0 inbound references" {
+			t.Fatalf("unexpected lens titles %q and %q", result[0].Command.Title, result[1].Command.Title)
+		}
+	})
+
+	t.Run("parent-typed references count for descendants", func(t *testing.T) {
+		server, root := initializeInheritanceServer(t)
+		dogPath := filepath.Join(root, "data", "dogs", "dog.yaml")
+
+		var result []protocol.CodeLens
+		callServer(t, server, protocol.MethodTextDocumentCodeLens, 2, &protocol.CodeLensParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(dogPath))},
+		}, &result)
+
+		if len(result) != 1 || result[0].Command.Title != "1 inbound reference" {
+			t.Fatalf("expected kennel reference to count for dog-1, got %#v", result)
+		}
+	})
+}
+
+func initializeCodeLensServer(t *testing.T, showReferences bool) (*Server, string) {
+	t.Helper()
+
+	server := NewServer(Options{Logger: testLogger()})
+	root, err := filepath.Abs(filepath.Join("..", "..", "examples", "full"))
+	if err != nil {
+		t.Fatalf("filepath.Abs(root): %v", err)
+	}
+	callServer(t, server, protocol.MethodInitialize, 1, map[string]any{
+		"rootUri":               string(uri.File(root)),
+		"initializationOptions": map[string]any{"showReferencesCommand": showReferences},
+	}, (*protocol.InitializeResult)(nil))
+	return server, root
+}
@@ -0,0 +1,378 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mergewayhq/mergeway-cli/internal/config"
+	"github.com/mergewayhq/mergeway-cli/internal/data"
+	diffpkg "github.com/mergewayhq/mergeway-cli/internal/diff"
+	"github.com/mergewayhq/mergeway-cli/internal/format"
+	"github.com/mergewayhq/mergeway-cli/internal/workspace"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+// Commands executed by the server through workspace/executeCommand.
+const (
+	validateWorkspaceCommand = "mergeway.validateWorkspace"
+	createObjectCommand      = "mergeway.createObject"
+	showDiffCommand          = "mergeway.showDiff"
+	formatAllCommand         = "mergeway.formatAll"
+)
+
+// window/showDocument (LSP 3.16) has params but no method constant in the
+// protocol package.
+const methodWindowShowDocument = "window/showDocument"
+
+var workspaceCommands = []string{
+	validateWorkspaceCommand,
+	createObjectCommand,
+	showDiffCommand,
+	formatAllCommand,
+}
+
+type validateWorkspaceResult struct {
+	Errors   int `json:"errors"`
+	Warnings int `json:"warnings"`
+	Files    int `json:"files"`
+}
+
+type createObjectResult struct {
+	URI protocol.DocumentURI `json:"uri"`
+}
+
+type showDiffResult struct {
+	URI    protocol.DocumentURI `json:"uri,omitempty"`
+	Output string               `json:"output"`
+}
+
+type formatAllResult struct {
+	Files []protocol.DocumentURI `json:"files"`
+}
+
+// applyWorkspaceEditParams mirrors protocol.ApplyWorkspaceEditParams with the
+// local workspaceEdit, which can carry file creation operations.
+type applyWorkspaceEditParams struct {
+	Label string         `json:"label,omitempty"`
+	Edit  *workspaceEdit `json:"edit"`
+}
+
+func (s *Server) executeCommand(ctx context.Context, params *protocol.ExecuteCommandParams) (interface{}, error) {
+	if s.runtime == nil {
+		return nil, nil
+	}
+
+	switch params.Command {
+	case validateWorkspaceCommand:
+		return s.validateWorkspace(ctx)
+	case createObjectCommand:
+		return s.createObject(ctx, params.Arguments)
+	case showDiffCommand:
+		return s.showDiff(ctx, params.Arguments)
+	case formatAllCommand:
+		return s.formatAll(ctx)
+	default:
+		return nil, jsonrpc2.NewError(jsonrpc2.InvalidParams, fmt.Sprintf("unknown command %q", params.Command))
+	}
+}
+
+// validateWorkspace re-reads every root from disk and reloads it right away,
+// which publishes (or refreshes) diagnostics before the summary is returned.
+func (s *Server) validateWorkspace(ctx context.Context) (*validateWorkspaceResult, error) {
+	if err := s.runtime.Rescan(); err != nil {
+		return nil, err
+	}
+	if err := s.runtime.FlushReload(); err != nil {
+		return nil, err
+	}
+
+	result := &validateWorkspaceResult{}
+	for _, params := range s.snapshotDiagnostics(s.runtime.Snapshot()) {
+		if len(params.Diagnostics) == 0 {
+			continue
+		}
+		result.Files++
+		for _, diagnostic := range params.Diagnostics {
+			switch diagnostic.Severity {
+			case protocol.DiagnosticSeverityError:
+				result.Errors++
+			case protocol.DiagnosticSeverityWarning:
+				result.Warnings++
+			}
+		}
+	}
+	return result, nil
+}
+
+// createObject adds an object of the given type, filled from the same
+// template as the create-object quick fix, and opens the file it lands in.
+// Arguments are the type name and the new object's identifier.
+func (s *Server) createObject(ctx context.Context, args []interface{}) (*createObjectResult, error) {
+	typeName, id := commandStringArg(args, 0), commandStringArg(args, 1)
+	if typeName == "" || id == "" {
+		return nil, jsonrpc2.NewError(jsonrpc2.InvalidParams, createObjectCommand+" expects a type name and an object identifier")
+	}
+
+	root, cfg := s.rootForType(typeName)
+	if cfg == nil {
+		return nil, jsonrpc2.NewError(jsonrpc2.InvalidParams, fmt.Sprintf("unknown type %q", typeName))
+	}
+	if root.Workspace != nil && len(root.Workspace.Find(typeName, id)) > 0 {
+		return nil, fmt.Errorf("%s %q already exists", typeName, id)
+	}
+
+	store, err := data.NewStore(root.Index.Root, cfg)
+	if err != nil {
+		return nil, err
+	}
+	path, multi, err := store.CreateTarget(typeName, id)
+	if err != nil {
+		return nil, err
+	}
+	edit, ok := s.createObjectEdit(cfg.Types[typeName], id, path, multi)
+	if !ok {
+		return nil, fmt.Errorf("cannot create %s %q in %s", typeName, id, displayPath(root.Index.Root, path))
+	}
+
+	if err := s.applyEdit(ctx, fmt.Sprintf(`Create %s "%s"`, typeName, id), edit); err != nil {
+		return nil, err
+	}
+	documentURI := protocol.DocumentURI(uri.File(path))
+	if err := s.showDocument(ctx, documentURI); err != nil {
+		return nil, err
+	}
+	return &createObjectResult{URI: documentURI}, nil
+}
+
+// showDiff runs the semantic diff of each root's saved files against a
+// revision, HEAD unless one is passed, and opens the report when the client
+// can show documents. Reports are kept in the server's report directory until
+// shutdown.
+func (s *Server) showDiff(ctx context.Context, args []interface{}) (*showDiffResult, error) {
+	revision := commandStringArg(args, 0)
+	if revision == "" {
+		revision = "HEAD"
+	}
+
+	snapshot := s.runtime.Snapshot()
+	rootPaths := sortedRootPaths(snapshot.Roots)
+	var b strings.Builder
+	for _, rootPath := range rootPaths {
+		index := snapshot.Roots[rootPath].Index
+		if index == nil {
+			continue
+		}
+		output, err := diffpkg.Run(diffpkg.Options{
+			Root:   index.Root,
+			Config: index.ConfigPath,
+			Args:   []string{revision},
+		})
+		if err != nil {
+			return nil, errors.New(diffpkg.FormatCommandError(err))
+		}
+		if len(rootPaths) > 1 {
+			fmt.Fprintf(&b, "# %s\n\n", index.Root)
+		}
+		b.WriteString(output)
+	}
+
+	result := &showDiffResult{Output: b.String()}
+	if !s.canShowDocuments() {
+		return result, nil
+	}
+
+	dir, err := s.reportDirectory()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.CreateTemp(dir, "diff-*.txt")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.WriteString(result.Output); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+	result.URI = protocol.DocumentURI(uri.File(file.Name()))
+	if err := s.showDocument(ctx, result.URI); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// formatAll applies the `mergeway-cli fmt` rules to every data file, using
+// open buffers where they exist, and sends the changes as one workspace edit.
+// Files that do not parse are left alone; they already carry diagnostics.
+func (s *Server) formatAll(ctx context.Context) (*formatAllResult, error) {
+	snapshot := s.runtime.Snapshot()
+	edit := &workspaceEdit{Changes: make(map[protocol.DocumentURI][]protocol.TextEdit)}
+	result := &formatAllResult{Files: []protocol.DocumentURI{}}
+
+	for _, rootPath := range sortedRootPaths(snapshot.Roots) {
+		root := snapshot.Roots[rootPath]
+		cfg := validationConfig(root)
+		if root.Index == nil || cfg == nil {
+			continue
+		}
+
+		paths := make([]string, 0, len(root.Index.DataFiles))
+		for path := range root.Index.DataFiles {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			content, err := s.documentContent(path)
+			if err != nil {
+				continue
+			}
+			var schema *format.Schema
+			if typeNames := root.Index.DataFiles[path]; len(typeNames) == 1 {
+				schema = format.SchemaForType(cfg.Types[typeNames[0]])
+			}
+			formatted, err := format.FormatBytes(path, content, schema)
+			if err != nil || bytes.Equal(content, formatted) {
+				continue
+			}
+
+			documentURI := protocol.DocumentURI(uri.File(path))
+			edit.Changes[documentURI] = []protocol.TextEdit{{
+				Range:   fullDocumentRange(content),
+				NewText: string(formatted),
+			}}
+			result.Files = append(result.Files, documentURI)
+		}
+	}
+
+	if len(result.Files) == 0 {
+		return result, nil
+	}
+	if err := s.applyEdit(ctx, "Format Mergeway data files", edit); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// rootForType returns the first root, in path order, whose config defines
+// typeName.
+func (s *Server) rootForType(typeName string) (*workspace.RootRuntime, *config.Config) {
+	snapshot := s.runtime.Snapshot()
+	for _, rootPath := range sortedRootPaths(snapshot.Roots) {
+		root := snapshot.Roots[rootPath]
+		if cfg := validationConfig(root); cfg != nil && cfg.Types[typeName] != nil && root.Index != nil {
+			return root, cfg
+		}
+	}
+	return nil, nil
+}
+
+// applyEdit sends edit to the client and waits until the client reports
+// whether it was applied. A rejected edit is returned as an error, so callers
+// only go on once the files hold the edit.
+func (s *Server) applyEdit(ctx context.Context, label string, edit *workspaceEdit) error {
+	s.mu.Lock()
+	supported := s.applyEdits && s.callClient != nil
+	call := s.callClient
+	s.mu.Unlock()
+	if !supported {
+		return errors.New("client does not support workspace/applyEdit")
+	}
+
+	var result protocol.ApplyWorkspaceEditResponse
+	if err := call(ctx, protocol.MethodWorkspaceApplyEdit, &applyWorkspaceEditParams{Label: label, Edit: edit}, &result); err != nil {
+		return err
+	}
+	if !result.Applied {
+		if result.FailureReason != "" {
+			return fmt.Errorf("client did not apply the edit: %s", result.FailureReason)
+		}
+		return errors.New("client did not apply the edit")
+	}
+	return nil
+}
+
+// showDocument asks the client to open documentURI. Clients without
+// window/showDocument support are left to open the returned URI themselves.
+func (s *Server) showDocument(ctx context.Context, documentURI protocol.DocumentURI) error {
+	if !s.canShowDocuments() {
+		return nil
+	}
+	s.mu.Lock()
+	call := s.callClient
+	s.mu.Unlock()
+	return call(ctx, methodWindowShowDocument, &protocol.ShowDocumentParams{
+		URI:       protocol.URI(documentURI),
+		TakeFocus: true,
+	}, nil)
+}
+
+// reportDirectory returns the temporary directory that holds the reports this
+// server opens in the client, creating it on first use.
+func (s *Server) reportDirectory() (string, error) {
+	s.reportMu.Lock()
+	defer s.reportMu.Unlock()
+	if s.reportDir == "" {
+		dir, err := os.MkdirTemp("", "mergeway-lsp-*")
+		if err != nil {
+			return "", err
+		}
+		s.reportDir = dir
+	}
+	return s.reportDir, nil
+}
+
+// removeReports deletes the report directory and everything in it.
+func (s *Server) removeReports() {
+	s.reportMu.Lock()
+	defer s.reportMu.Unlock()
+	if s.reportDir == "" {
+		return
+	}
+	if err := os.RemoveAll(s.reportDir); err != nil {
+		s.logger.Error("remove_reports", slog.Any("error", err))
+	}
+	s.reportDir = ""
+}
+
+func (s *Server) canShowDocuments() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.showDocuments && s.callClient != nil
+}
+
+func commandStringArg(args []interface{}, index int) string {
+	if index >= len(args) {
+		return ""
+	}
+	value, _ := args[index].(string)
+	return strings.TrimSpace(value)
+}
+
+func fullDocumentRange(content []byte) protocol.Range {
+	lines := strings.Split(string(content), "\n")
+	last := len(lines) - 1
+	return protocol.Range{
+		End: protocol.Position{Line: uint32(last), Character: uint32(len(lines[last]))},
+	}
+}
+
+func supportsApplyEdit(capabilities protocol.ClientCapabilities) bool {
+	return capabilities.Workspace != nil && capabilities.Workspace.ApplyEdit
+}
+
+func supportsShowDocument(capabilities protocol.ClientCapabilities) bool {
+	return capabilities.Window != nil &&
+		capabilities.Window.ShowDocument != nil &&
+		capabilities.Window.ShowDocument.Support
+}
@@ -0,0 +1,382 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mergewayhq/mergeway-cli/internal/testutil"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func TestHandleInitializeAdvertisesWorkspaceCommands(t *testing.T) {
+	root := absTestPath(t, t.TempDir())
+	writeTestFiles(t, root, commandTestFiles())
+
+	server := NewServer(Options{Logger: testLogger()})
+	var result initializeResult
+	callServer(t, server, protocol.MethodInitialize, 1, map[string]any{
+		"rootUri":      string(uri.File(root)),
+		"capabilities": map[string]any{},
+	}, &result)
+
+	provider := result.Capabilities.ExecuteCommandProvider
+	if provider == nil {
+		t.Fatal("expected executeCommandProvider")
+	}
+	for _, command := range []string{validateWorkspaceCommand, createObjectCommand, showDiffCommand, formatAllCommand} {
+		if !contains(provider.Commands, command) {
+			t.Fatalf("expected %q in %v", command, provider.Commands)
+		}
+	}
+}
+
+func TestExecuteValidateWorkspacePublishesDiagnostics(t *testing.T) {
+	server, _, root := initializeCommandServer(t)
+	capture := &diagnosticCapture{}
+	server.publishDiagnostics = capture.PublishDiagnostics
+	postPath := filepath.Join(root, "data", "posts", "post-1.yaml")
+
+	writeTestFiles(t, root, map[string]string{
+		"data/posts/post-1.yaml": "id: post-1\nauthor: ghost\ntitle: Hello\n",
+	})
+
+	var result validateWorkspaceResult
+	callServer(t, server, protocol.MethodWorkspaceExecuteCommand, 2, &protocol.ExecuteCommandParams{
+		Command: validateWorkspaceCommand,
+	}, &result)
+
+	if result.Errors != 1 || result.Files != 1 {
+		t.Fatalf("expected one error in one file, got %+v", result)
+	}
+	params := capture.latestByPath()[postPath]
+	if params == nil || len(params.Diagnostics) != 1 || !strings.Contains(params.Diagnostics[0].Message, "ghost") {
+		t.Fatalf("expected the dangling reference to be published for %s, got %#v", postPath, params)
+	}
+}
+
+func TestExecuteCreateObjectAppliesTemplateAndShowsDocument(t *testing.T) {
+	server, calls, root := initializeCommandServer(t)
+	userPath := filepath.Join(root, "data", "users", "user-2.yaml")
+
+	var result createObjectResult
+	callServer(t, server, protocol.MethodWorkspaceExecuteCommand, 2, &protocol.ExecuteCommandParams{
+		Command:   createObjectCommand,
+		Arguments: []interface{}{"User", "user-2"},
+	}, &result)
+
+	if result.URI.Filename() != userPath {
+		t.Fatalf("expected %s, got %s", userPath, result.URI.Filename())
+	}
+	edit := calls.appliedEdit(t)
+	if len(edit.DocumentChanges) != 2 {
+		t.Fatalf("expected create and insert operations, got %#v", edit.DocumentChanges)
+	}
+	body, _ := json.Marshal(edit.DocumentChanges[1])
+	if !strings.Contains(string(body), `id: user-2\nname: \"\"\n`) {
+		t.Fatalf("expected the object template, got %s", body)
+	}
+	if shown := calls.shownDocument(t); shown.URI.Filename() != userPath || !shown.TakeFocus {
+		t.Fatalf("expected %s to be shown with focus, got %#v", userPath, shown)
+	}
+}
+
+func TestRunCreateObjectAwaitsAppliedEdit(t *testing.T) {
+	root := absTestPath(t, t.TempDir())
+	writeTestFiles(t, root, commandTestFiles())
+
+	clientConn, serverConn := net.Pipe()
+	mustCleanupClose(t, clientConn)
+
+	done := make(chan serveResult, 1)
+	go func() {
+		code, err := Run(context.Background(), serverConn, Options{Logger: testLogger()})
+		done <- serveResult{code: code, err: err}
+	}()
+
+	var mu sync.Mutex
+	var methods []string
+	client := jsonrpc2.NewConn(jsonrpc2.NewStream(clientConn))
+	client.Go(context.Background(), func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		switch req.Method() {
+		case protocol.MethodWorkspaceApplyEdit, methodWindowShowDocument:
+			mu.Lock()
+			methods = append(methods, req.Method())
+			mu.Unlock()
+		}
+		if req.Method() == protocol.MethodWorkspaceApplyEdit {
+			return reply(ctx, &protocol.ApplyWorkspaceEditResponse{Applied: true}, nil)
+		}
+		return reply(ctx, nil, nil)
+	})
+	mustCleanupClose(t, client)
+
+	_, err := client.Call(context.Background(), protocol.MethodInitialize, map[string]any{
+		"rootUri": string(uri.File(root)),
+		"capabilities": map[string]any{
+			"workspace": map[string]any{
+				"applyEdit":     true,
+				"workspaceEdit": map[string]any{"documentChanges": true, "resourceOperations": []string{"create"}},
+			},
+			"window": map[string]any{"showDocument": map[string]any{"support": true}},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("initialize: %v", err)
+	}
+	if err := client.Notify(context.Background(), protocol.MethodInitialized, &protocol.InitializedParams{}); err != nil {
+		t.Fatalf("initialized: %v", err)
+	}
+
+	var result createObjectResult
+	_, err = client.Call(context.Background(), protocol.MethodWorkspaceExecuteCommand, &protocol.ExecuteCommandParams{
+		Command:   createObjectCommand,
+		Arguments: []interface{}{"User", "user-2"},
+	}, &result)
+	if err != nil {
+		t.Fatalf("execute %s: %v", createObjectCommand, err)
+	}
+	if want := filepath.Join(root, "data", "users", "user-2.yaml"); result.URI.Filename() != want {
+		t.Fatalf("expected %s, got %s", want, result.URI.Filename())
+	}
+	mu.Lock()
+	got := append([]string(nil), methods...)
+	mu.Unlock()
+	if want := []string{protocol.MethodWorkspaceApplyEdit, methodWindowShowDocument}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	if _, err := client.Call(context.Background(), protocol.MethodShutdown, nil, nil); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if err := client.Notify(context.Background(), protocol.MethodExit, nil); err != nil {
+		t.Fatalf("exit notify: %v", err)
+	}
+	if res := waitServeResult(t, done); res.err != nil || res.code != 0 {
+		t.Fatalf("expected clean exit, got %d, %v", res.code, res.err)
+	}
+}
+
+func TestExecuteCreateObjectFailsWhenClientRejectsEdit(t *testing.T) {
+	server, calls, _ := initializeCommandServer(t)
+	calls.rejectEdits = true
+
+	err := executeCommandError(t, server, createObjectCommand, "User", "user-2")
+	if err == nil || !strings.Contains(err.Error(), "client did not apply the edit: file is read-only") {
+		t.Fatalf("expected rejected edit error, got %v", err)
+	}
+	if calls.called(methodWindowShowDocument) {
+		t.Fatal("expected no document to be shown for a rejected edit")
+	}
+}
+
+func TestExecuteCreateObjectRejectsExistingObject(t *testing.T) {
+	server, _, _ := initializeCommandServer(t)
+
+	err := executeCommandError(t, server, createObjectCommand, "User", "user-1")
+	if err == nil || !strings.Contains(err.Error(), `User "user-1" already exists`) {
+		t.Fatalf("expected already-exists error, got %v", err)
+	}
+}
+
+func TestExecuteFormatAllRewritesUnformattedFiles(t *testing.T) {
+	server, calls, root := initializeCommandServer(t)
+	postPath := filepath.Join(root, "data", "posts", "post-1.yaml")
+
+	var result formatAllResult
+	callServer(t, server, protocol.MethodWorkspaceExecuteCommand, 2, &protocol.ExecuteCommandParams{
+		Command: formatAllCommand,
+	}, &result)
+
+	if len(result.Files) != 1 || result.Files[0].Filename() != postPath {
+		t.Fatalf("expected only %s to change, got %v", postPath, result.Files)
+	}
+	edits := calls.appliedEdit(t).Changes[protocol.DocumentURI(uri.File(postPath))]
+	if len(edits) != 1 || edits[0].NewText != "id: post-1\nauthor: user-1\ntitle: Hello\n" {
+		t.Fatalf("expected fields in schema order, got %#v", edits)
+	}
+	if edits[0].Range.End != (protocol.Position{Line: 3, Character: 0}) {
+		t.Fatalf("expected the edit to replace the whole file, got %+v", edits[0].Range)
+	}
+}
+
+func TestExecuteShowDiffReportsChangesAgainstHead(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	t.Setenv("TMPDIR", t.TempDir())
+
+	server, calls, root := initializeCommandServer(t)
+	testutil.InitGitRepo(t, root)
+	writeTestFiles(t, root, map[string]string{
+		"data/users/user-1.yaml": "id: user-1\nname: Renamed\n",
+	})
+
+	var result showDiffResult
+	callServer(t, server, protocol.MethodWorkspaceExecuteCommand, 2, &protocol.ExecuteCommandParams{
+		Command: showDiffCommand,
+	}, &result)
+
+	if !strings.Contains(result.Output, "MODIFIED User[user-1]") || !strings.Contains(result.Output, `name: "Ada" -> "Renamed"`) {
+		t.Fatalf("expected the modified user in the diff, got %q", result.Output)
+	}
+	shown := calls.shownDocument(t)
+	if shown.URI != protocol.URI(result.URI) {
+		t.Fatalf("expected the report %s to be shown, got %s", result.URI, shown.URI)
+	}
+	report, err := os.ReadFile(result.URI.Filename())
+	if err != nil || string(report) != result.Output {
+		t.Fatalf("expected the report file to hold the diff output, got %q (%v)", report, err)
+	}
+
+	var again showDiffResult
+	callServer(t, server, protocol.MethodWorkspaceExecuteCommand, 3, &protocol.ExecuteCommandParams{
+		Command: showDiffCommand,
+	}, &again)
+	dir := filepath.Dir(result.URI.Filename())
+	if again.URI == result.URI || filepath.Dir(again.URI.Filename()) != dir {
+		t.Fatalf("expected a second report next to the first, got %s and %s", result.URI, again.URI)
+	}
+
+	shutdown, err := jsonrpc2.DecodeMessage([]byte(`{"jsonrpc":"2.0","id":4,"method":"shutdown"}`))
+	if err != nil {
+		t.Fatalf("decode shutdown: %v", err)
+	}
+	if err := server.Handle(context.Background(), captureReply(t, (*struct{})(nil)), shutdown.(jsonrpc2.Request)); err != nil {
+		t.Fatalf("Handle(shutdown): %v", err)
+	}
+	if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected shutdown to remove the reports, got %v", err)
+	}
+}
+
+func TestExecuteUnknownCommandFails(t *testing.T) {
+	server, _, _ := initializeCommandServer(t)
+
+	err := executeCommandError(t, server, "mergeway.unknown")
+	if err == nil || !strings.Contains(err.Error(), `unknown command "mergeway.unknown"`) {
+		t.Fatalf("expected unknown command error, got %v", err)
+	}
+}
+
+func initializeCommandServer(t *testing.T) (*Server, *clientCallCapture, string) {
+	t.Helper()
+
+	root := absTestPath(t, t.TempDir())
+	writeTestFiles(t, root, commandTestFiles())
+
+	calls := &clientCallCapture{}
+	server := NewServer(Options{
+		Logger:             testLogger(),
+		PublishDiagnostics: (&diagnosticCapture{}).PublishDiagnostics,
+		CallClient:         calls.CallClient,
+	})
+	callServer(t, server, protocol.MethodInitialize, 1, map[string]any{
+		"rootUri": string(uri.File(root)),
+		"capabilities": map[string]any{
+			"workspace": map[string]any{
+				"applyEdit":     true,
+				"workspaceEdit": map[string]any{"documentChanges": true, "resourceOperations": []string{"create"}},
+			},
+			"window": map[string]any{"showDocument": map[string]any{"support": true}},
+		},
+	}, (*protocol.InitializeResult)(nil))
+	callServer(t, server, protocol.MethodInitialized, 2, &protocol.InitializedParams{}, (*struct{})(nil))
+	return server, calls, root
+}
+
+func commandTestFiles() map[string]string {
+	return map[string]string{
+		"mergeway.yaml": `mergeway:
+  version: 1
+
+entities:
+  User:
+    identifier: id
+    include:
+      - data/users/*.yaml
+    fields:
+      id: string
+      name:
+        type: string
+        required: true
+  Post:
+    identifier: id
+    include:
+      - data/posts/*.yaml
+    fields:
+      id: string
+      author:
+        type: User
+        required: true
+      title: string
+`,
+		"data/users/user-1.yaml": "id: user-1\nname: Ada\n",
+		"data/posts/post-1.yaml": "title: Hello\nid: post-1\nauthor: user-1\n",
+	}
+}
+
+func executeCommandError(t *testing.T, server *Server, command string, args ...interface{}) error {
+	t.Helper()
+
+	req, err := jsonrpc2.NewCall(jsonrpc2.NewNumberID(9), protocol.MethodWorkspaceExecuteCommand, &protocol.ExecuteCommandParams{
+		Command:   command,
+		Arguments: args,
+	})
+	if err != nil {
+		t.Fatalf("NewCall: %v", err)
+	}
+	return server.Handle(context.Background(), captureReply(t, (*struct{})(nil)), req)
+}
+
+func (c *clientCallCapture) appliedEdit(t *testing.T) workspaceEdit {
+	t.Helper()
+
+	var params struct {
+		Edit workspaceEdit `json:"edit"`
+	}
+	c.lastCall(t, protocol.MethodWorkspaceApplyEdit, &params)
+	return params.Edit
+}
+
+func (c *clientCallCapture) shownDocument(t *testing.T) protocol.ShowDocumentParams {
+	t.Helper()
+
+	var params protocol.ShowDocumentParams
+	c.lastCall(t, methodWindowShowDocument, &params)
+	return params
+}
+
+func (c *clientCallCapture) lastCall(t *testing.T, method string, target any) {
+	t.Helper()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for idx := len(c.calls) - 1; idx >= 0; idx-- {
+		if c.calls[idx].method != method {
+			continue
+		}
+		if err := json.Unmarshal(c.calls[idx].params, target); err != nil {
+			t.Fatalf("unmarshal %s: %v", method, err)
+		}
+		return
+	}
+	t.Fatalf("expected a %s call, got %v", method, c.methodsLocked())
+}
+
+func (c *clientCallCapture) methodsLocked() []string {
+	methods := make([]string, 0, len(c.calls))
+	for _, call := range c.calls {
+		methods = append(methods, call.method)
+	}
+	return methods
+}
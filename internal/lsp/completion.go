@@ -0,0 +1,434 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mergewayhq/mergeway-cli/internal/config"
+	"github.com/mergewayhq/mergeway-cli/internal/data"
+	"github.com/mergewayhq/mergeway-cli/internal/workspace"
+	"go.lsp.dev/protocol"
+	"gopkg.in/yaml.v3"
+)
+
+// completionSentinel replaces the token under the cursor so that documents
+// which are incomplete there, such as JSON with an unterminated string, still
+// parse and the value being completed can be located structurally.
+const completionSentinel = "mergeway__completion__"
+
+// completionSite is the data value or new list item at the cursor, resolved
+// through nested objects and repeated fields.
+type completionSite struct {
+	fieldDef  *config.FieldDefinition
+	listNode  *yaml.Node
+	skeleton  *objectSkeleton
+	prefix    string
+	editRange protocol.Range
+	leading   string
+	trailing  string
+	quoted    bool
+	json      bool
+	indent    string
+}
+
+// objectSkeleton describes the object a new list item should hold: either an
+// entity object in an `items` list or an element of a repeated object field.
+type objectSkeleton struct {
+	label      string
+	order      []string
+	fields     map[string]*config.FieldDefinition
+	identifier string
+}
+
+type completionStep struct {
+	parent *yaml.Node
+	key    string
+	index  int
+}
+
+// completionSiteForAnalysis locates the value under the cursor in a data
+// file. It returns nil when the cursor is not on a value it understands, which
+// leaves the line-based completion in charge.
+func completionSiteForAnalysis(analysis *documentAnalysis) *completionSite {
+	if analysis == nil || analysis.kind != analysisKindData || analysis.cfg == nil {
+		return nil
+	}
+
+	lineNo := int(analysis.position.Line)
+	if lineNo >= len(analysis.lines) {
+		return nil
+	}
+	line := analysis.lines[lineNo]
+	cursor := minInt(int(analysis.position.Character), len(line))
+	start, end := cursor, cursor
+	for start > 0 && isCompletionTokenByte(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isCompletionTokenByte(line[end]) {
+		end++
+	}
+
+	site := &completionSite{
+		prefix: line[start:cursor],
+		editRange: protocol.Range{
+			Start: protocol.Position{Line: uint32(lineNo), Character: uint32(start)},
+			End:   protocol.Position{Line: uint32(lineNo), Character: uint32(end)},
+		},
+		json:   looksLikeJSON(analysis.content),
+		indent: line[:indentation(line)],
+	}
+
+	replacement := completionSentinel
+	switch {
+	case start > 0 && (line[start-1] == '"' || line[start-1] == '\''):
+		site.quoted = true
+		if end >= len(line) || line[end] != line[start-1] {
+			replacement += string(line[start-1])
+		}
+	case site.json:
+		replacement = `"` + completionSentinel + `"`
+		site.leading, site.trailing = `"`, `"`
+	case start > 0 && line[start-1] == ':':
+		replacement = " " + completionSentinel
+		site.leading = " "
+	}
+
+	lines := append([]string(nil), analysis.lines...)
+	lines[lineNo] = line[:start] + replacement + line[end:]
+	repaired := strings.Join(lines, "\n")
+	if site.json {
+		repaired += jsonClosers(repaired)
+	}
+	doc, ok := parseDocumentNode([]byte(repaired))
+	if !ok {
+		return nil
+	}
+
+	path, isKey, found := sentinelPath(documentRoot(doc), nil)
+	if !found || isKey {
+		return nil
+	}
+	typeDef := resolveDataTypeDefinition(&documentAnalysis{path: analysis.path, root: analysis.root, cfg: analysis.cfg, doc: doc})
+	if typeDef == nil {
+		return nil
+	}
+	if !site.resolve(typeDef, documentRoot(doc), path) {
+		return nil
+	}
+	return site
+}
+
+// resolve walks the path from the document root to the sentinel through the
+// entity's fields and object properties.
+func (c *completionSite) resolve(typeDef *config.TypeDefinition, docRoot *yaml.Node, path []completionStep) bool {
+	steps := path
+	if len(steps) > 0 && steps[0].parent == docRoot && steps[0].key == "items" {
+		if len(steps) < 2 || steps[1].parent.Kind != yaml.SequenceNode {
+			return false
+		}
+		if len(steps) == 2 {
+			identifier := typeDef.Identifier.Field
+			if typeDef.Identifier.IsPath() {
+				identifier = ""
+			}
+			c.skeleton = &objectSkeleton{label: "New " + typeDef.Name, order: typeDef.FieldOrder, fields: typeDef.Fields, identifier: identifier}
+			return true
+		}
+		steps = steps[2:]
+	}
+
+	fields := typeDef.Fields
+	for idx, step := range steps {
+		if step.parent.Kind == yaml.MappingNode {
+			c.fieldDef = fields[step.key]
+			c.listNode = nil
+			if c.fieldDef == nil {
+				return false
+			}
+			fields = c.fieldDef.Properties
+			continue
+		}
+
+		if c.fieldDef == nil || !c.fieldDef.Repeated {
+			return false
+		}
+		c.listNode = step.parent
+		if idx == len(steps)-1 && c.fieldDef.Type == "object" {
+			c.skeleton = &objectSkeleton{label: fmt.Sprintf("New %s item", c.fieldDef.Name), order: c.fieldDef.PropertyOrder, fields: c.fieldDef.Properties}
+		}
+	}
+	return c.fieldDef != nil
+}
+
+func (c *completionSite) completionItems(root *workspace.RootRuntime, snippets bool) []protocol.CompletionItem {
+	if c.skeleton != nil {
+		if !snippets || c.quoted {
+			return []protocol.CompletionItem{}
+		}
+		return []protocol.CompletionItem{c.skeletonCompletionItem()}
+	}
+
+	var items []protocol.CompletionItem
+	switch {
+	case len(c.fieldDef.Enum) > 0:
+		items = enumCompletionItems(c.fieldDef, c.prefix)
+	case c.fieldDef.IsReference():
+		items = referenceCompletionItems(root, c.fieldDef, c.prefix, c.listValues())
+	}
+	for idx := range items {
+		items[idx].TextEdit = &protocol.TextEdit{Range: c.editRange, NewText: c.leading + items[idx].Label + c.trailing}
+	}
+	if items == nil {
+		items = []protocol.CompletionItem{}
+	}
+	return items
+}
+
+// listValues returns the values already present in the enclosing list so a
+// repeated reference does not offer the same identifier twice.
+func (c *completionSite) listValues() map[string]struct{} {
+	if c.listNode == nil {
+		return nil
+	}
+	values := make(map[string]struct{}, len(c.listNode.Content))
+	for _, item := range c.listNode.Content {
+		if item.Kind == yaml.ScalarNode && item.Value != completionSentinel {
+			values[item.Value] = struct{}{}
+		}
+	}
+	return values
+}
+
+// skeletonCompletionItem inserts the identifier plus every required field of
+// the new object as snippet placeholders, indented to line up with the list
+// item being completed.
+func (c *completionSite) skeletonCompletionItem() protocol.CompletionItem {
+	var names []string
+	for _, name := range c.skeleton.order {
+		fieldDef := c.skeleton.fields[name]
+		if fieldDef == nil || fieldDef.Source != nil {
+			continue
+		}
+		if name == c.skeleton.identifier || fieldDef.Required {
+			names = append(names, name)
+		}
+	}
+
+	var text string
+	if c.json {
+		lines := make([]string, 0, len(names))
+		for idx, name := range names {
+			lines = append(lines, fmt.Sprintf("%s  %s: %s", c.indent, strconv.Quote(name), snippetFieldValue(c.skeleton.fields[name], idx+1, true)))
+		}
+		text = "{\n" + strings.Join(lines, ",\n") + "\n" + c.indent + "}"
+	} else {
+		column := strings.Repeat(" ", int(c.editRange.Start.Character))
+		lines := make([]string, 0, len(names))
+		for idx, name := range names {
+			line := fmt.Sprintf("%s: %s", name, snippetFieldValue(c.skeleton.fields[name], idx+1, false))
+			if idx > 0 {
+				line = column + line
+			}
+			lines = append(lines, line)
+		}
+		text = strings.Join(lines, "\n")
+	}
+
+	return protocol.CompletionItem{
+		Label:            c.skeleton.label,
+		Kind:             protocol.CompletionItemKindSnippet,
+		Detail:           strings.Join(names, ", "),
+		FilterText:       c.prefix,
+		InsertTextFormat: protocol.InsertTextFormatSnippet,
+		InsertTextMode:   protocol.InsertTextModeAsIs,
+		TextEdit:         &protocol.TextEdit{Range: c.editRange, NewText: text},
+	}
+}
+
+// snippetFieldValue renders a placeholder for one skeleton field: choices for
+// enums and booleans, the configured default when there is one, and an empty
+// list for repeated fields.
+func snippetFieldValue(fieldDef *config.FieldDefinition, tabstop int, isJSON bool) string {
+	quoted := func(value string) string {
+		if isJSON {
+			return `"` + value + `"`
+		}
+		return value
+	}
+
+	switch {
+	case fieldDef.Repeated:
+		return fmt.Sprintf("[${%d}]", tabstop)
+	case len(fieldDef.Enum) > 0:
+		return quoted(fmt.Sprintf("${%d|%s|}", tabstop, strings.Join(escapeSnippetChoices(fieldDef.Enum), ",")))
+	case fieldDef.Default != nil:
+		return fmt.Sprintf("${%d:%s}", tabstop, escapeSnippetText(snippetDefault(fieldDef.Default, isJSON)))
+	}
+
+	switch fieldDef.Type {
+	case "boolean":
+		return fmt.Sprintf("${%d|false,true|}", tabstop)
+	case "integer", "number":
+		return fmt.Sprintf("${%d:0}", tabstop)
+	case "object":
+		return fmt.Sprintf("{${%d}}", tabstop)
+	default:
+		return quoted(fmt.Sprintf("${%d}", tabstop))
+	}
+}
+
+func snippetDefault(value any, isJSON bool) string {
+	if !isJSON {
+		if text, ok := value.(string); ok {
+			return text
+		}
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+func escapeSnippetText(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `$`, `\$`, `}`, `\}`).Replace(value)
+}
+
+func escapeSnippetChoices(values []string) []string {
+	replacer := strings.NewReplacer(`\`, `\\`, `$`, `\$`, `|`, `\|`, `,`, `\,`)
+	escaped := make([]string, 0, len(values))
+	for _, value := range values {
+		escaped = append(escaped, replacer.Replace(value))
+	}
+	return escaped
+}
+
+// referenceCompletionItems offers the objects a reference field may point at,
+// grouped by target type in declaration order. Within a type, identifiers
+// starting with prefix rank ahead of identifiers or primary text that merely
+// contain it; values listed in exclude are skipped.
+func referenceCompletionItems(root *workspace.RootRuntime, fieldDef *config.FieldDefinition, prefix string, exclude map[string]struct{}) []protocol.CompletionItem {
+	if root == nil || root.Workspace == nil || fieldDef == nil {
+		return nil
+	}
+
+	query := strings.ToLower(prefix)
+	var items []protocol.CompletionItem
+	for typeIndex, refType := range fieldDef.ReferenceTypes {
+		objects := append([]*data.Object(nil), root.Workspace.Objects(refType)...)
+		sort.Slice(objects, func(i, j int) bool { return objects[i].ID < objects[j].ID })
+
+		for _, obj := range objects {
+			if _, ok := exclude[obj.ID]; ok {
+				continue
+			}
+			rank, ok := referenceMatchRank(obj, query)
+			if !ok {
+				continue
+			}
+			detail := refType
+			if primary := objectPrimaryText(obj); primary != "" && primary != obj.ID {
+				detail = fmt.Sprintf("%s · %s", refType, primary)
+			}
+			items = append(items, protocol.CompletionItem{
+				Label:         obj.ID,
+				Kind:          protocol.CompletionItemKindReference,
+				Detail:        detail,
+				Documentation: objectSummaryMarkdown(obj, root),
+				SortText:      fmt.Sprintf("%03d-%d-%s", typeIndex, rank, obj.ID),
+			})
+		}
+	}
+	return items
+}
+
+func referenceMatchRank(obj *data.Object, query string) (int, bool) {
+	id := strings.ToLower(obj.ID)
+	switch {
+	case strings.HasPrefix(id, query):
+		return 0, true
+	case strings.Contains(id, query):
+		return 1, true
+	case strings.Contains(strings.ToLower(objectPrimaryText(obj)), query):
+		return 2, true
+	default:
+		return 0, false
+	}
+}
+
+// sentinelPath returns the mapping keys and sequence indexes leading from node
+// to the sentinel scalar, and whether the sentinel sits in key position.
+func sentinelPath(node *yaml.Node, path []completionStep) ([]completionStep, bool, bool) {
+	if node == nil {
+		return nil, false, false
+	}
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return path, false, node.Value == completionSentinel
+	case yaml.MappingNode:
+		for idx := 0; idx+1 < len(node.Content); idx += 2 {
+			keyNode := node.Content[idx]
+			if keyNode.Value == completionSentinel {
+				return path, true, true
+			}
+			step := completionStep{parent: node, key: keyNode.Value}
+			if result, isKey, ok := sentinelPath(node.Content[idx+1], append(path, step)); ok {
+				return result, isKey, true
+			}
+		}
+	case yaml.SequenceNode:
+		for idx, item := range node.Content {
+			step := completionStep{parent: node, index: idx}
+			if result, isKey, ok := sentinelPath(item, append(path, step)); ok {
+				return result, isKey, true
+			}
+		}
+	}
+	return nil, false, false
+}
+
+// jsonClosers returns the brackets needed to close every object and array
+// left open in content, so a document truncated after the cursor parses.
+func jsonClosers(content string) string {
+	var stack []byte
+	inString, escaped := false, false
+	for idx := 0; idx < len(content); idx++ {
+		c := content[idx]
+		switch {
+		case inString && escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{':
+			stack = append(stack, '}')
+		case c == '[':
+			stack = append(stack, ']')
+		case (c == '}' || c == ']') && len(stack) > 0:
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	closers := make([]byte, 0, len(stack))
+	for idx := len(stack) - 1; idx >= 0; idx-- {
+		closers = append(closers, stack[idx])
+	}
+	return string(closers)
+}
+
+func isCompletionTokenByte(c byte) bool {
+	return !strings.ContainsRune(" \t\"'[]{},:#", rune(c))
+}
+
+func supportsCompletionSnippets(capabilities protocol.ClientCapabilities) bool {
+	return capabilities.TextDocument != nil &&
+		capabilities.TextDocument.Completion != nil &&
+		capabilities.TextDocument.Completion.CompletionItem != nil &&
+		capabilities.TextDocument.Completion.CompletionItem.SnippetSupport
+}
@@ -0,0 +1,254 @@
+package lsp
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func TestHandleCompletionOffersReferencesInRepeatedLists(t *testing.T) {
+	server, root := initializeCompletionServer(t, true)
+	path := filepath.Join(root, "data", "posts", "post-1.yaml")
+	text, pos := cursorContent("id: post-1\ntitle: Hello\nreviewers:\n  - user-ada\n  - |\n")
+	openDocument(t, server, path, "yaml", 1, text)
+
+	items := requestCompletion(t, server, path, pos)
+	labels := completionLabels(items)
+	if len(labels) != 2 || labels[0] != "user-bob" || labels[1] != "user-cy" {
+		t.Fatalf("expected remaining users only, got %v", labels)
+	}
+	if items[0].Detail != "User · Bob Builder" {
+		t.Fatalf("expected primary text in detail, got %q", items[0].Detail)
+	}
+}
+
+func TestHandleCompletionRanksPrefixMatchesFirst(t *testing.T) {
+	server, root := initializeCompletionServer(t, true)
+	path := filepath.Join(root, "data", "posts", "post-1.yaml")
+	text, pos := cursorContent("id: post-1\ntitle: Hello\nreviewers: [b|]\n")
+	openDocument(t, server, path, "yaml", 1, text)
+
+	items := requestCompletion(t, server, path, pos)
+	sorted := sortedCompletionLabels(items)
+	if len(sorted) != 2 || sorted[0] != "user-bob" || sorted[1] != "user-ada" {
+		t.Fatalf("expected id match before primary-text match (Ada Byron), got %v", sorted)
+	}
+	if edit := items[0].TextEdit; edit == nil || edit.NewText != items[0].Label || edit.Range.Start.Character != 12 || edit.Range.End.Character != 13 {
+		t.Fatalf("expected the edit to replace the typed prefix, got %#v", edit)
+	}
+}
+
+func TestHandleCompletionOffersReferencesInNestedProperties(t *testing.T) {
+	server, root := initializeCompletionServer(t, true)
+	path := filepath.Join(root, "data", "posts", "post-1.yaml")
+	text, pos := cursorContent("id: post-1\ntitle: Hello\nreview:\n  approver: user-c|\n")
+	openDocument(t, server, path, "yaml", 1, text)
+
+	labels := completionLabels(requestCompletion(t, server, path, pos))
+	if len(labels) != 1 || labels[0] != "user-cy" {
+		t.Fatalf("expected nested property reference completion, got %v", labels)
+	}
+}
+
+func TestHandleCompletionGroupsReferenceUnionsByType(t *testing.T) {
+	server, root := initializeCompletionServer(t, true)
+	path := filepath.Join(root, "data", "posts", "post-1.yaml")
+	text, pos := cursorContent("id: post-1\ntitle: Hello\nowner: |\n")
+	openDocument(t, server, path, "yaml", 1, text)
+
+	items := requestCompletion(t, server, path, pos)
+	sorted := sortedCompletionLabels(items)
+	want := []string{"team-core", "team-docs", "user-ada", "user-bob", "user-cy"}
+	if strings.Join(sorted, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected Team objects grouped before User objects, got %v", sorted)
+	}
+	for _, item := range items {
+		if strings.HasPrefix(item.Label, "team-") && !strings.HasPrefix(item.Detail, "Team") {
+			t.Fatalf("expected the target type in detail, got %q for %s", item.Detail, item.Label)
+		}
+	}
+}
+
+func TestHandleCompletionInsideJSONFiles(t *testing.T) {
+	server, root := initializeCompletionServer(t, true)
+	path := filepath.Join(root, "data", "posts", "post-2.json")
+
+	t.Run("unterminated string", func(t *testing.T) {
+		text, pos := cursorContent("{\n  \"id\": \"post-2\",\n  \"title\": \"Hi\",\n  \"owner\": \"user-a|\n")
+		openDocument(t, server, path, "json", 1, text)
+
+		items := requestCompletion(t, server, path, pos)
+		if labels := completionLabels(items); len(labels) != 1 || labels[0] != "user-ada" {
+			t.Fatalf("expected user-ada, got %v", labels)
+		}
+		if items[0].TextEdit.NewText != "user-ada" {
+			t.Fatalf("expected the quoted value to be replaced without quotes, got %q", items[0].TextEdit.NewText)
+		}
+	})
+
+	t.Run("bare value in a list", func(t *testing.T) {
+		text, pos := cursorContent("{\n  \"id\": \"post-2\",\n  \"title\": \"Hi\",\n  \"reviewers\": [\"user-ada\", |]\n}\n")
+		openDocument(t, server, path, "json", 2, text)
+
+		items := requestCompletion(t, server, path, pos)
+		if labels := completionLabels(items); len(labels) != 2 || contains(labels, "user-ada") {
+			t.Fatalf("expected the other users, got %v", labels)
+		}
+		if items[0].TextEdit.NewText != `"user-bob"` {
+			t.Fatalf("expected a quoted insertion, got %q", items[0].TextEdit.NewText)
+		}
+	})
+}
+
+func TestHandleCompletionInsertsObjectSkeletons(t *testing.T) {
+	server, root := initializeCompletionServer(t, true)
+
+	t.Run("yaml items", func(t *testing.T) {
+		path := filepath.Join(root, "data", "teams", "teams.yaml")
+		text, pos := cursorContent("type: Team\nitems:\n  - id: team-core\n    name: Core\n  - |\n")
+		openDocument(t, server, path, "yaml", 1, text)
+
+		items := requestCompletion(t, server, path, pos)
+		if len(items) != 1 || items[0].Label != "New Team" || items[0].InsertTextFormat != protocol.InsertTextFormatSnippet {
+			t.Fatalf("expected a Team snippet, got %#v", items)
+		}
+		want := "id: ${1}\n    name: ${2}\n    tier: ${3|gold,silver|}"
+		if items[0].TextEdit.NewText != want {
+			t.Fatalf("expected skeleton %q, got %q", want, items[0].TextEdit.NewText)
+		}
+	})
+
+	t.Run("repeated object field", func(t *testing.T) {
+		path := filepath.Join(root, "data", "posts", "post-1.yaml")
+		text, pos := cursorContent("id: post-1\ntitle: Hello\nlinks:\n  - |\n")
+		openDocument(t, server, path, "yaml", 2, text)
+
+		items := requestCompletion(t, server, path, pos)
+		if len(items) != 1 || items[0].TextEdit.NewText != "url: ${1}" {
+			t.Fatalf("expected a links item skeleton with its required property, got %#v", items)
+		}
+	})
+
+	t.Run("json items", func(t *testing.T) {
+		path := filepath.Join(root, "data", "teams", "more.json")
+		text, pos := cursorContent("{\n  \"type\": \"Team\",\n  \"items\": [\n    |\n  ]\n}\n")
+		openDocument(t, server, path, "json", 1, text)
+
+		items := requestCompletion(t, server, path, pos)
+		want := "{\n      \"id\": \"${1}\",\n      \"name\": \"${2}\",\n      \"tier\": \"${3|gold,silver|}\"\n    }"
+		if len(items) != 1 || items[0].TextEdit.NewText != want {
+			t.Fatalf("expected JSON skeleton %q, got %#v", want, items)
+		}
+	})
+}
+
+func TestHandleCompletionOmitsSnippetsWithoutClientSupport(t *testing.T) {
+	server, root := initializeCompletionServer(t, false)
+	path := filepath.Join(root, "data", "teams", "teams.yaml")
+	text, pos := cursorContent("type: Team\nitems:\n  - id: team-core\n    name: Core\n  - |\n")
+	openDocument(t, server, path, "yaml", 1, text)
+
+	if items := requestCompletion(t, server, path, pos); len(items) != 0 {
+		t.Fatalf("expected no snippets, got %#v", items)
+	}
+}
+
+func initializeCompletionServer(t *testing.T, snippets bool) (*Server, string) {
+	t.Helper()
+
+	root := absTestPath(t, t.TempDir())
+	writeTestFiles(t, root, map[string]string{
+		"mergeway.yaml": `mergeway:
+  version: 1
+
+entities:
+  User:
+    identifier: id
+    include:
+      - data/users/*.yaml
+    fields:
+      id: string
+      name: string
+  Team:
+    identifier: id
+    include:
+      - data/teams/*.yaml
+      - data/teams/*.json
+    fields:
+      id: string
+      name:
+        type: string
+        required: true
+      tier:
+        type: enum
+        enum: [gold, silver]
+        required: true
+      notes: string
+  Post:
+    identifier: id
+    include:
+      - data/posts/*.yaml
+      - data/posts/*.json
+    fields:
+      id: string
+      title: string
+      owner: Team | User
+      reviewers:
+        type: User
+        repeated: true
+      review:
+        type: object
+        properties:
+          approver: User
+      links:
+        type: object
+        repeated: true
+        properties:
+          url:
+            type: string
+            required: true
+          label: string
+`,
+		"data/users/ada.yaml":    "id: user-ada\nname: Ada Byron\n",
+		"data/users/bob.yaml":    "id: user-bob\nname: Bob Builder\n",
+		"data/users/cy.yaml":     "id: user-cy\nname: Cy Young\n",
+		"data/teams/teams.yaml":  "type: Team\nitems:\n  - id: team-core\n    name: Core\n    tier: gold\n  - id: team-docs\n    name: Docs\n    tier: silver\n",
+		"data/posts/post-1.yaml": "id: post-1\ntitle: Hello\n",
+		"data/posts/post-2.json": "{\"id\": \"post-2\", \"title\": \"Hi\"}\n",
+		"data/teams/more.json":   "{\"type\": \"Team\", \"items\": []}\n",
+	})
+
+	server := NewServer(Options{Logger: testLogger(), PublishDiagnostics: (&diagnosticCapture{}).PublishDiagnostics})
+	callServer(t, server, protocol.MethodInitialize, 1, map[string]any{
+		"rootUri": string(uri.File(root)),
+		"capabilities": map[string]any{
+			"textDocument": map[string]any{
+				"completion": map[string]any{"completionItem": map[string]any{"snippetSupport": snippets}},
+			},
+		},
+	}, (*protocol.InitializeResult)(nil))
+	return server, root
+}
+
+func requestCompletion(t *testing.T, server *Server, path string, pos protocol.Position) []protocol.CompletionItem {
+	t.Helper()
+
+	var result protocol.CompletionList
+	callServer(t, server, protocol.MethodTextDocumentCompletion, 2, &protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(path))},
+			Position:     pos,
+		},
+	}, &result)
+	return result.Items
+}
+
+func sortedCompletionLabels(items []protocol.CompletionItem) []string {
+	sorted := append([]protocol.CompletionItem(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].SortText < sorted[j].SortText })
+	return completionLabels(sorted)
+}
@@ -0,0 +1,318 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mergewayhq/mergeway-cli/internal/workspace"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+	"gopkg.in/yaml.v3"
+)
+
+// hoverFileListLimit caps how many matched files an include hover lists.
+const hoverFileListLimit = 20
+
+type configPathKind string
+
+const (
+	configPathConfigInclude configPathKind = "config include"
+	configPathDataInclude   configPathKind = "include"
+	configPathSchema        configPathKind = "json_schema"
+)
+
+// configPathEntry is one path-valued string in a config file: a top-level
+// config include, an entity data include, or an entity json_schema.
+type configPathEntry struct {
+	kind     configPathKind
+	typeName string
+	node     *yaml.Node
+	pattern  string
+}
+
+func (s *Server) documentLinks(ctx context.Context, params *protocol.DocumentLinkParams) ([]protocol.DocumentLink, error) {
+	path := params.TextDocument.URI.Filename()
+	entries, root, err := s.configPathEntries(path)
+	if err != nil || root == nil {
+		return nil, err
+	}
+
+	documents := s.runtime.Snapshot().Documents
+	links := make([]protocol.DocumentLink, 0, len(entries))
+	for _, entry := range entries {
+		matches := matchConfigPath(entry.pattern, documents)
+		switch len(matches) {
+		case 0:
+			continue
+		case 1:
+			links = append(links, protocol.DocumentLink{
+				Range:   nodeRange(entry.node),
+				Target:  protocol.DocumentURI(uri.File(matches[0])),
+				Tooltip: displayPath(root.Index.Root, matches[0]),
+			})
+		default:
+			dir := globBaseDir(entry.pattern)
+			links = append(links, protocol.DocumentLink{
+				Range:   nodeRange(entry.node),
+				Target:  protocol.DocumentURI(uri.File(dir)),
+				Tooltip: fmt.Sprintf("%d matching files in %s", len(matches), displayPath(root.Index.Root, dir)),
+			})
+		}
+	}
+	return links, nil
+}
+
+// configPathHover describes the include or json_schema value under the cursor,
+// listing the files a glob expands to.
+func (s *Server) configPathHover(analysis *documentAnalysis) (string, *protocol.Range) {
+	if analysis == nil || analysis.kind != analysisKindConfig || analysis.doc == nil || analysis.root == nil || analysis.root.Index == nil {
+		return "", nil
+	}
+
+	for _, entry := range collectConfigPathEntries(analysis.doc, analysis.path, analysis.root.Index.Root) {
+		if !positionWithinNode(analysis.position, entry.node) {
+			continue
+		}
+		matches := matchConfigPath(entry.pattern, s.runtime.Snapshot().Documents)
+		rng := nodeRange(entry.node)
+		return configPathHoverContent(analysis.root, entry, matches), &rng
+	}
+	return "", nil
+}
+
+func (s *Server) configPathEntries(path string) ([]configPathEntry, *workspace.RootRuntime, error) {
+	if s.runtime == nil {
+		return nil, nil, nil
+	}
+	root := s.runtime.RootByPath(path)
+	if root == nil || root.Index == nil {
+		return nil, nil, nil
+	}
+	if _, ok := root.Index.ConfigFiles[path]; !ok {
+		return nil, nil, nil
+	}
+
+	content, err := s.documentContent(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc, ok := parseDocumentNode(content)
+	if !ok {
+		return nil, root, nil
+	}
+	return collectConfigPathEntries(doc, path, root.Index.Root), root, nil
+}
+
+// collectConfigPathEntries resolves config includes and json_schema paths
+// against the config file's directory and data includes against the root,
+// matching how the config and data loaders resolve them.
+func collectConfigPathEntries(doc *yaml.Node, configPath, rootPath string) []configPathEntry {
+	docRoot := documentRoot(doc)
+	configDir := filepath.Dir(configPath)
+
+	var entries []configPathEntry
+	if _, includeNode := mappingEntry(docRoot, "include"); includeNode != nil && includeNode.Kind == yaml.SequenceNode {
+		for _, item := range includeNode.Content {
+			if item.Kind == yaml.ScalarNode && strings.TrimSpace(item.Value) != "" {
+				entries = append(entries, configPathEntry{
+					kind:    configPathConfigInclude,
+					node:    item,
+					pattern: resolveConfigPath(configDir, item.Value),
+				})
+			}
+		}
+	}
+
+	_, entitiesNode := mappingEntry(docRoot, "entities")
+	if entitiesNode == nil || entitiesNode.Kind != yaml.MappingNode {
+		return entries
+	}
+	for idx := 0; idx+1 < len(entitiesNode.Content); idx += 2 {
+		typeName := entitiesNode.Content[idx].Value
+		entityNode := entitiesNode.Content[idx+1]
+
+		if _, includeNode := mappingEntry(entityNode, "include"); includeNode != nil && includeNode.Kind == yaml.SequenceNode {
+			for _, item := range includeNode.Content {
+				pathNode := item
+				if item.Kind == yaml.MappingNode {
+					_, pathNode = mappingEntry(item, "path")
+				}
+				if pathNode == nil || pathNode.Kind != yaml.ScalarNode || strings.TrimSpace(pathNode.Value) == "" {
+					continue
+				}
+				entries = append(entries, configPathEntry{
+					kind:     configPathDataInclude,
+					typeName: typeName,
+					node:     pathNode,
+					pattern:  resolveConfigPath(rootPath, pathNode.Value),
+				})
+			}
+		}
+
+		if _, schemaNode := mappingEntry(entityNode, "json_schema"); schemaNode != nil && schemaNode.Kind == yaml.ScalarNode && strings.TrimSpace(schemaNode.Value) != "" {
+			entries = append(entries, configPathEntry{
+				kind:     configPathSchema,
+				typeName: typeName,
+				node:     schemaNode,
+				pattern:  resolveConfigPath(configDir, schemaNode.Value),
+			})
+		}
+	}
+	return entries
+}
+
+func configPathHoverContent(root *workspace.RootRuntime, entry configPathEntry, matches []string) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "**%s** `%s`\n\n", entry.kind, entry.node.Value)
+
+	if entry.kind == configPathSchema {
+		if len(matches) == 0 {
+			builder.WriteString("File not found.")
+			return builder.String()
+		}
+		fmt.Fprintf(&builder, "Schema for `%s`: %s", entry.typeName, fileLinkMarkdown(root.Index.Root, matches[0]))
+		return builder.String()
+	}
+
+	objectCounts := make(map[string]int, len(matches))
+	total := 0
+	if entry.kind == configPathDataInclude {
+		matched := make(map[string]struct{}, len(matches))
+		for _, match := range matches {
+			matched[match] = struct{}{}
+		}
+		for _, obj := range root.Workspace.Objects(entry.typeName) {
+			if _, ok := matched[obj.File]; ok {
+				objectCounts[obj.File]++
+				total++
+			}
+		}
+	}
+
+	switch {
+	case len(matches) == 0:
+		builder.WriteString("Matches no files.")
+		return builder.String()
+	case entry.kind == configPathDataInclude:
+		fmt.Fprintf(&builder, "Matches %s with %s.\n", pluralize(len(matches), "file"), pluralize(total, "`"+entry.typeName+"` object"))
+	default:
+		fmt.Fprintf(&builder, "Matches %s.\n", pluralize(len(matches), "config file"))
+	}
+
+	builder.WriteString("\n")
+	for idx, match := range matches {
+		if idx == hoverFileListLimit {
+			fmt.Fprintf(&builder, "- …and %d more\n", len(matches)-hoverFileListLimit)
+			break
+		}
+		builder.WriteString("- " + fileLinkMarkdown(root.Index.Root, match))
+		if entry.kind == configPathDataInclude {
+			fmt.Fprintf(&builder, " (%s)", pluralize(objectCounts[match], "object"))
+		}
+		builder.WriteString("\n")
+	}
+	return strings.TrimSuffix(builder.String(), "\n")
+}
+
+// collectIncludeDiagnostics warns about entity include globs that match no
+// files. Unmatched config includes already fail config loading, and a config
+// that does not load reports that error instead.
+func (c *diagnosticCollector) collectIncludeDiagnostics(grouped map[string][]protocol.Diagnostic, root *workspace.RootRuntime) {
+	if validationConfig(root) == nil {
+		return
+	}
+
+	configFiles := make([]string, 0, len(root.Index.ConfigFiles))
+	for path := range root.Index.ConfigFiles {
+		configFiles = append(configFiles, path)
+	}
+	sort.Strings(configFiles)
+
+	for _, path := range configFiles {
+		content, ok := c.readContent(path)
+		if !ok {
+			continue
+		}
+		doc, ok := parseDocumentNode(content)
+		if !ok {
+			continue
+		}
+		for _, entry := range collectConfigPathEntries(doc, path, root.Index.Root) {
+			if entry.kind != configPathDataInclude || len(matchConfigPath(entry.pattern, c.documents)) > 0 {
+				continue
+			}
+			grouped[path] = append(grouped[path], protocol.Diagnostic{
+				Range:    nodeRange(entry.node),
+				Severity: protocol.DiagnosticSeverityWarning,
+				Code:     "include",
+				Source:   diagnosticSource,
+				Message:  fmt.Sprintf("include pattern %q for type %q matched no files", entry.node.Value, entry.typeName),
+			})
+		}
+	}
+}
+
+// matchConfigPath expands pattern against the disk and any open, unsaved
+// documents, returning regular files only.
+func matchConfigPath(pattern string, documents map[string]*workspace.OpenDocument) []string {
+	seen := make(map[string]struct{})
+	if matches, err := filepath.Glob(pattern); err == nil {
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && !info.IsDir() {
+				seen[filepath.Clean(match)] = struct{}{}
+			}
+		}
+	}
+	for path := range documents {
+		if ok, err := filepath.Match(pattern, path); err == nil && ok {
+			seen[path] = struct{}{}
+		}
+	}
+
+	result := make([]string, 0, len(seen))
+	for path := range seen {
+		result = append(result, path)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func resolveConfigPath(baseDir, value string) string {
+	value = strings.TrimSpace(value)
+	if filepath.IsAbs(value) {
+		return filepath.Clean(value)
+	}
+	return filepath.Join(baseDir, filepath.Clean(value))
+}
+
+// globBaseDir returns the longest leading directory of pattern that contains
+// no glob metacharacters.
+func globBaseDir(pattern string) string {
+	dir := filepath.Dir(pattern)
+	for strings.ContainsAny(dir, "*?[") {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+func displayPath(root, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(path)
+}
+
+func fileLinkMarkdown(root, path string) string {
+	return fmt.Sprintf("[%s](%s)", displayPath(root, path), uri.File(path))
+}
+
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
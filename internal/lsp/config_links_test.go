@@ -0,0 +1,141 @@
+package lsp
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func TestHandleDocumentLinkResolvesIncludesAndSchemas(t *testing.T) {
+	t.Run("config include glob", func(t *testing.T) {
+		server, root := initializeExampleFullServer(t)
+		configPath := filepath.Join(root, "mergeway.yaml")
+
+		links := requestDocumentLinks(t, server, configPath)
+		if len(links) != 1 {
+			t.Fatalf("expected one link, got %#v", links)
+		}
+		if got := links[0].Target.Filename(); got != filepath.Join(root, "entities") {
+			t.Fatalf("expected glob link to the entities directory, got %s", got)
+		}
+		if links[0].Tooltip != "4 matching files in entities" {
+			t.Fatalf("unexpected tooltip %q", links[0].Tooltip)
+		}
+		start := positionInContent(t, readFile(t, configPath), "entities/*.yaml")
+		end := protocol.Position{Line: start.Line, Character: start.Character + uint32(len("entities/*.yaml"))}
+		if links[0].Range != (protocol.Range{Start: start, End: end}) {
+			t.Fatalf("expected link on the include value, got %+v", links[0].Range)
+		}
+	})
+
+	t.Run("single-match data include", func(t *testing.T) {
+		server, root := initializeExampleFullServer(t)
+
+		links := requestDocumentLinks(t, server, filepath.Join(root, "entities", "Post.yaml"))
+		if len(links) != 1 {
+			t.Fatalf("expected one link, got %#v", links)
+		}
+		want := filepath.Join(root, "data", "posts", "launch.yaml")
+		if got := links[0].Target.Filename(); got != want {
+			t.Fatalf("expected link to %s, got %s", want, got)
+		}
+	})
+
+	t.Run("json schema", func(t *testing.T) {
+		server, _, root := initializeCodeActionServer(t, filepath.Join("..", "..", "examples", "json-schema"))
+
+		links := requestDocumentLinks(t, server, filepath.Join(root, "mergeway.yaml"))
+		targets := make([]string, 0, len(links))
+		for _, link := range links {
+			targets = append(targets, link.Target.Filename())
+		}
+		if !contains(targets, filepath.Join(root, "schemas", "customer.json")) {
+			t.Fatalf("expected link to the schema file, got %v", targets)
+		}
+	})
+}
+
+func TestHandleHoverDescribesIncludeMatches(t *testing.T) {
+	server, root := initializeExampleFullServer(t)
+	configPath := filepath.Join(root, "entities", "Post.yaml")
+	content := readFile(t, configPath)
+	pos := positionInContent(t, content, "data/posts/*.yaml")
+
+	var hover protocol.Hover
+	callServer(t, server, protocol.MethodTextDocumentHover, 2, &protocol.HoverParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(configPath))},
+			Position:     pos,
+		},
+	}, &hover)
+
+	if !strings.Contains(hover.Contents.Value, "Matches 1 file with 1 `Post` object.") {
+		t.Fatalf("expected match summary, got %q", hover.Contents.Value)
+	}
+	if !strings.Contains(hover.Contents.Value, "[data/posts/launch.yaml](") {
+		t.Fatalf("expected matched file listing, got %q", hover.Contents.Value)
+	}
+	if hover.Range == nil || hover.Range.Start != pos {
+		t.Fatalf("expected hover range on the include value, got %+v", hover.Range)
+	}
+}
+
+func TestIncludeDiagnosticsWarnAboutUnmatchedGlobs(t *testing.T) {
+	root := absTestPath(t, t.TempDir())
+	writeTestFiles(t, root, map[string]string{
+		"mergeway.yaml": `mergeway:
+  version: 1
+
+entities:
+  User:
+    identifier: id
+    include:
+      - data/users/*.yaml
+      - path: data/archive/*.json
+    fields:
+      id: string
+`,
+		"data/users/alice.yaml": "id: alice\n",
+	})
+
+	capture := &diagnosticCapture{}
+	server := NewServer(Options{
+		Logger:             testLogger(),
+		PublishDiagnostics: capture.PublishDiagnostics,
+	})
+	initializeServerForDiagnostics(t, server, root)
+
+	configPath := filepath.Join(root, "mergeway.yaml")
+	published := capture.latestByPath()[configPath]
+	if published == nil || len(published.Diagnostics) != 1 {
+		t.Fatalf("expected one include warning, got %#v", published)
+	}
+	diag := published.Diagnostics[0]
+	if diag.Severity != protocol.DiagnosticSeverityWarning || !strings.Contains(diag.Message, `"data/archive/*.json"`) {
+		t.Fatalf("unexpected diagnostic %#v", diag)
+	}
+	if want := positionInContent(t, readFile(t, configPath), "data/archive"); diag.Range.Start != want {
+		t.Fatalf("expected warning on the unmatched path at %+v, got %+v", want, diag.Range)
+	}
+
+	openDocument(t, server, filepath.Join(root, "data", "archive", "old.json"), "json", 1, "{\"id\": \"old\"}\n")
+	if err := server.runtime.FlushReload(); err != nil {
+		t.Fatalf("FlushReload: %v", err)
+	}
+	if cleared := capture.latestByPath()[configPath]; cleared == nil || len(cleared.Diagnostics) != 0 {
+		t.Fatalf("expected an open unsaved match to clear the warning, got %#v", cleared)
+	}
+}
+
+func requestDocumentLinks(t *testing.T, server *Server, path string) []protocol.DocumentLink {
+	t.Helper()
+
+	var links []protocol.DocumentLink
+	callServer(t, server, protocol.MethodTextDocumentDocumentLink, 2, &protocol.DocumentLinkParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(path))},
+	}, &links)
+	return links
+}
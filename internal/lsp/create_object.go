@@ -0,0 +1,276 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mergewayhq/mergeway-cli/internal/config"
+	"github.com/mergewayhq/mergeway-cli/internal/data"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+	"gopkg.in/yaml.v3"
+)
+
+type objectField struct {
+	name  string
+	value any
+}
+
+// createReferencedObjectActions offers to create the object a dangling
+// reference points at, once per referenceable type, in the file the data store
+// would write it to.
+func (s *Server) createReferencedObjectActions(analysis *documentAnalysis, fieldDef *config.FieldDefinition, diagnostic protocol.Diagnostic) []codeAction {
+	refID := quotedReferenceID(diagnostic.Message)
+	if refID == "" || analysis.cfg == nil || analysis.root == nil || analysis.root.Index == nil {
+		return nil
+	}
+
+	store, err := data.NewStore(analysis.root.Index.Root, analysis.cfg)
+	if err != nil {
+		return nil
+	}
+
+	var actions []codeAction
+	for _, typeName := range fieldDef.ReferenceTypes {
+		typeDef := analysis.cfg.Types[typeName]
+		if typeDef == nil {
+			continue
+		}
+		path, multi, err := store.CreateTarget(typeName, refID)
+		if err != nil {
+			continue
+		}
+		edit, ok := s.createObjectEdit(typeDef, refID, path, multi)
+		if !ok {
+			continue
+		}
+		actions = append(actions, codeAction{
+			Title:       fmt.Sprintf(`Create %s "%s"`, typeName, refID),
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diagnostic},
+			Edit:        edit,
+		})
+	}
+	return actions
+}
+
+// createObjectEdit creates path when it does not exist yet, which requires
+// client support for file creation, and otherwise appends the new object to an
+// existing YAML multi-object file.
+func (s *Server) createObjectEdit(typeDef *config.TypeDefinition, id, path string, multi bool) (*workspaceEdit, bool) {
+	fields := newObjectFields(typeDef, id)
+	documentURI := protocol.DocumentURI(uri.File(path))
+	isJSON := strings.EqualFold(filepath.Ext(path), ".json")
+
+	content, err := s.documentContent(path)
+	if err != nil {
+		if !s.canCreateFiles() {
+			return nil, false
+		}
+		text, err := newObjectFileText(typeDef.Name, fields, multi, isJSON)
+		if err != nil {
+			return nil, false
+		}
+		return &workspaceEdit{
+			DocumentChanges: []interface{}{
+				createFileOperation{Kind: "create", URI: documentURI},
+				protocol.TextDocumentEdit{
+					TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{
+						TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: documentURI},
+					},
+					Edits: []protocol.TextEdit{{NewText: text}},
+				},
+			},
+		}, true
+	}
+
+	if !multi || isJSON {
+		return nil, false
+	}
+	edit, ok := appendMultiObjectEdit(content, fields)
+	if !ok {
+		return nil, false
+	}
+	return &workspaceEdit{
+		Changes: map[protocol.DocumentURI][]protocol.TextEdit{documentURI: {edit}},
+	}, true
+}
+
+// newObjectFields lists the identifier plus every required or defaulted field
+// in schema order, skipping fields derived from the file path.
+func newObjectFields(typeDef *config.TypeDefinition, id string) []objectField {
+	var fields []objectField
+	for _, name := range typeDef.FieldOrder {
+		fieldDef := typeDef.Fields[name]
+		if fieldDef == nil || fieldDef.Source != nil {
+			continue
+		}
+		switch {
+		case name == typeDef.Identifier.Field && !typeDef.Identifier.IsPath():
+			fields = append(fields, objectField{name: name, value: identifierFieldValue(fieldDef, id)})
+		case fieldDef.Required || fieldDef.Default != nil:
+			fields = append(fields, objectField{name: name, value: defaultFieldValue(fieldDef)})
+		}
+	}
+	return fields
+}
+
+func identifierFieldValue(fieldDef *config.FieldDefinition, id string) any {
+	if fieldDef.Type == "integer" {
+		if value, err := strconv.ParseInt(id, 10, 64); err == nil {
+			return value
+		}
+	}
+	return id
+}
+
+func defaultFieldValue(fieldDef *config.FieldDefinition) any {
+	switch {
+	case fieldDef.Default != nil:
+		return fieldDef.Default
+	case fieldDef.Repeated:
+		return []any{}
+	case fieldDef.IsReference():
+		return ""
+	case len(fieldDef.Enum) > 0:
+		return fieldDef.Enum[0]
+	}
+
+	switch fieldDef.Type {
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return false
+	case "object":
+		return map[string]any{}
+	default:
+		return ""
+	}
+}
+
+func newObjectFileText(typeName string, fields []objectField, multi, isJSON bool) (string, error) {
+	if isJSON {
+		object, err := jsonObjectText(fields, "")
+		if err != nil {
+			return "", err
+		}
+		if !multi {
+			return object + "\n", nil
+		}
+		item, err := jsonObjectText(fields, "    ")
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("{\n  \"type\": %s,\n  \"items\": [\n    %s\n  ]\n}\n", strconv.Quote(typeName), item), nil
+	}
+
+	object, err := yamlObjectText(fields)
+	if err != nil {
+		return "", err
+	}
+	if !multi {
+		return object, nil
+	}
+	return "type: " + typeName + "\nitems:\n" + yamlSequenceItem(object, 2), nil
+}
+
+func appendMultiObjectEdit(content []byte, fields []objectField) (protocol.TextEdit, bool) {
+	doc, ok := parseDocumentNode(content)
+	if !ok {
+		return protocol.TextEdit{}, false
+	}
+	_, itemsNode := mappingEntry(documentRoot(doc), "items")
+	if itemsNode == nil || itemsNode.Kind != yaml.SequenceNode || itemsNode.Style&yaml.FlowStyle != 0 || len(itemsNode.Content) == 0 {
+		return protocol.TextEdit{}, false
+	}
+
+	object, err := yamlObjectText(fields)
+	if err != nil {
+		return protocol.TextEdit{}, false
+	}
+
+	dashIndent := maxInt(0, itemsNode.Content[0].Column-3)
+	insertRange, prefix := insertionRangeAfterNode(strings.Split(string(content), "\n"), itemsNode)
+	return protocol.TextEdit{
+		Range:   insertRange,
+		NewText: prefix + yamlSequenceItem(object, dashIndent),
+	}, true
+}
+
+func yamlObjectText(fields []objectField) (string, error) {
+	mapping := &yaml.Node{Kind: yaml.MappingNode}
+	for _, field := range fields {
+		var value yaml.Node
+		if err := value.Encode(field.value); err != nil {
+			return "", err
+		}
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: field.name}, &value)
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(mapping); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// yamlSequenceItem indents a rendered mapping as a block sequence item whose
+// dash sits at the given column.
+func yamlSequenceItem(object string, dashIndent int) string {
+	var builder strings.Builder
+	for index, line := range strings.Split(strings.TrimSuffix(object, "\n"), "\n") {
+		if index == 0 {
+			builder.WriteString(strings.Repeat(" ", dashIndent) + "- " + line + "\n")
+			continue
+		}
+		builder.WriteString(strings.Repeat(" ", dashIndent+2) + line + "\n")
+	}
+	return builder.String()
+}
+
+func jsonObjectText(fields []objectField, indent string) (string, error) {
+	if len(fields) == 0 {
+		return "{}", nil
+	}
+
+	lines := make([]string, 0, len(fields))
+	for _, field := range fields {
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, fmt.Sprintf("%s  %s: %s", indent, strconv.Quote(field.name), value))
+	}
+	return "{\n" + strings.Join(lines, ",\n") + "\n" + indent + "}", nil
+}
+
+// quotedReferenceID extracts the trailing quoted identifier from a validation
+// message such as `field "author" references missing User "user-1"`.
+func quotedReferenceID(message string) string {
+	start := strings.LastIndex(message, ` "`)
+	if start < 0 {
+		return ""
+	}
+	value, err := strconv.Unquote(message[start+1:])
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+func supportsCreateFileEdits(capabilities protocol.ClientCapabilities) bool {
+	if capabilities.Workspace == nil || capabilities.Workspace.WorkspaceEdit == nil {
+		return false
+	}
+	edit := capabilities.Workspace.WorkspaceEdit
+	return edit.DocumentChanges && containsString(edit.ResourceOperations, "create")
+}
@@ -0,0 +1,142 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func TestHandleCodeActionCreatesMissingReferencedObject(t *testing.T) {
+	t.Run("new single-object file", func(t *testing.T) {
+		server, capture, root := initializeCreateFileServer(t, filepath.Join("..", "..", "examples", "full"))
+		path := filepath.Join(root, "data", "posts", "launch.yaml")
+		text := "id: post-1\ntitle: Launch Day\nstatus: PUBLISHED\nauthor: user-zed\n"
+
+		actions := openDocumentAndCodeActions(t, server, capture, path, text)
+		action := requireCodeAction(t, actions, `Create User "user-zed"`)
+		if action.Edit == nil || len(action.Edit.DocumentChanges) != 2 {
+			t.Fatalf("expected create and edit document changes, got %#v", action.Edit)
+		}
+
+		targetURI := string(uri.File(filepath.Join(root, "data", "users", "user-zed.yaml")))
+		create, ok := action.Edit.DocumentChanges[0].(map[string]interface{})
+		if !ok || create["kind"] != "create" || create["uri"] != targetURI {
+			t.Fatalf("expected create operation for %s, got %#v", targetURI, action.Edit.DocumentChanges[0])
+		}
+
+		edit, ok := action.Edit.DocumentChanges[1].(map[string]interface{})
+		if !ok {
+			t.Fatalf("expected text document edit, got %#v", action.Edit.DocumentChanges[1])
+		}
+		edits, _ := edit["edits"].([]interface{})
+		if len(edits) != 1 {
+			t.Fatalf("expected one text edit, got %#v", edit)
+		}
+		newText, _ := edits[0].(map[string]interface{})["newText"].(string)
+		if want := "id: user-zed\nname: \"\"\nemail: \"\"\n"; newText != want {
+			t.Fatalf("expected new object %q, got %q", want, newText)
+		}
+	})
+
+	t.Run("requires client file creation support", func(t *testing.T) {
+		server, capture, root := initializeCodeActionServer(t, filepath.Join("..", "..", "examples", "full"))
+		path := filepath.Join(root, "data", "posts", "launch.yaml")
+		text := "id: post-1\ntitle: Launch Day\nstatus: PUBLISHED\nauthor: user-zed\n"
+
+		actions := openDocumentAndCodeActions(t, server, capture, path, text)
+		for _, action := range actions {
+			if action.Title == `Create User "user-zed"` {
+				t.Fatalf("expected no create action without resource operation support, got %#v", action)
+			}
+		}
+	})
+
+	t.Run("append to multi-object file", func(t *testing.T) {
+		root := t.TempDir()
+		cfg := `mergeway:
+  version: 1
+
+entities:
+  Tag:
+    identifier: id
+    include:
+      - data/tags.yaml
+    fields:
+      id: string
+      label:
+        type: string
+        required: true
+      color:
+        type: string
+        default: gray
+  Post:
+    identifier: id
+    include:
+      - data/posts/*.yaml
+    fields:
+      id: string
+      tags:
+        type: Tag
+        repeated: true
+`
+		writeTestFiles(t, root, map[string]string{
+			"mergeway.yaml":       cfg,
+			"data/tags.yaml":      "type: Tag\nitems:\n  - id: tag-1\n    label: One\n",
+			"data/posts/one.yaml": "id: post-1\ntags: [tag-1]\n",
+		})
+
+		server, capture, absRoot := initializeCreateFileServer(t, root)
+		path := filepath.Join(absRoot, "data", "posts", "one.yaml")
+		actions := openDocumentAndCodeActions(t, server, capture, path, "id: post-1\ntags: [tag-1, tag-2]\n")
+		action := requireCodeAction(t, actions, `Create Tag "tag-2"`)
+		edit := requireSingleEdit(t, action, filepath.Join(absRoot, "data", "tags.yaml"))
+
+		if want := "  - id: tag-2\n    label: \"\"\n    color: gray\n"; edit.NewText != want {
+			t.Fatalf("expected appended item %q, got %q", want, edit.NewText)
+		}
+		if edit.Range.Start != (protocol.Position{Line: 4, Character: 0}) {
+			t.Fatalf("expected insertion after the last item, got %+v", edit.Range)
+		}
+	})
+}
+
+func initializeCreateFileServer(t *testing.T, root string) (*Server, *diagnosticCapture, string) {
+	t.Helper()
+
+	absRoot := absTestPath(t, root)
+	capture := &diagnosticCapture{}
+	server := NewServer(Options{
+		Logger:             testLogger(),
+		PublishDiagnostics: capture.PublishDiagnostics,
+	})
+	callServer(t, server, protocol.MethodInitialize, 1, map[string]any{
+		"rootUri": string(uri.File(absRoot)),
+		"capabilities": map[string]any{
+			"workspace": map[string]any{
+				"workspaceEdit": map[string]any{
+					"documentChanges":    true,
+					"resourceOperations": []string{"create", "rename", "delete"},
+				},
+			},
+		},
+	}, (*protocol.InitializeResult)(nil))
+	capture.Reset()
+	return server, capture, absRoot
+}
+
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+
+	for name, body := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+}
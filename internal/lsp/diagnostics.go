@@ -55,7 +55,7 @@ func (s *Server) publishWorkspaceDiagnostics(ctx context.Context) error {
 	}
 
 	snapshot := s.runtime.Snapshot()
-	paramsByPath := collectSnapshotDiagnostics(snapshot)
+	paramsByPath := s.snapshotDiagnostics(snapshot)
 
 	s.publishMu.Lock()
 	defer s.publishMu.Unlock()
@@ -86,6 +86,32 @@ func (s *Server) publishWorkspaceDiagnostics(ctx context.Context) error {
 	return nil
 }
 
+// cachedDiagnostics holds the diagnostics of the latest snapshot
+// generation, so pushes and pulls against an unchanged workspace share one
+// collection.
+type cachedDiagnostics struct {
+	generation   uint64
+	paramsByPath map[string]*protocol.PublishDiagnosticsParams
+}
+
+// snapshotDiagnostics returns the diagnostics for snapshot, collecting them
+// only when its generation differs from the cached one. The returned map is
+// shared and must not be modified.
+func (s *Server) snapshotDiagnostics(snapshot *workspace.Snapshot) map[string]*protocol.PublishDiagnosticsParams {
+	if snapshot == nil {
+		return nil
+	}
+
+	s.diagnosticsMu.Lock()
+	defer s.diagnosticsMu.Unlock()
+	if cached := s.diagnosticsCache; cached != nil && cached.generation == snapshot.Generation {
+		return cached.paramsByPath
+	}
+	paramsByPath := collectSnapshotDiagnostics(snapshot)
+	s.diagnosticsCache = &cachedDiagnostics{generation: snapshot.Generation, paramsByPath: paramsByPath}
+	return paramsByPath
+}
+
 func collectSnapshotDiagnostics(snapshot *workspace.Snapshot) map[string]*protocol.PublishDiagnosticsParams {
 	if snapshot == nil {
 		return nil
@@ -117,6 +143,8 @@ func collectSnapshotDiagnostics(snapshot *workspace.Snapshot) map[string]*protoc
 }
 
 func (c *diagnosticCollector) collectRootDiagnostics(grouped map[string][]protocol.Diagnostic, root *workspace.RootRuntime) {
+	c.collectIncludeDiagnostics(grouped, root)
+
 	if root.Validation != nil && root.Validation.Result != nil {
 		for _, errItem := range root.Validation.Result.Errors {
 			path, diagnostic, ok := c.validationDiagnostic(root, errItem)
@@ -468,6 +496,12 @@ func parseDocumentNode(content []byte) (*yaml.Node, bool) {
 		return nil, false
 	}
 
+	if looksLikeJSON(content) {
+		if doc, err := parseJSONDocument(content); err == nil {
+			return doc, true
+		}
+	}
+
 	var doc yaml.Node
 	if err := yaml.Unmarshal(content, &doc); err != nil {
 		return nil, false
@@ -562,6 +596,10 @@ func nodeRange(node *yaml.Node) protocol.Range {
 	if column < 0 {
 		column = 0
 	}
+	if isQuotedScalar(node) {
+		// Quoted scalars report the position of the opening quote.
+		column++
+	}
 
 	endColumn := column + 1
 	if node.Value != "" {
@@ -574,6 +612,21 @@ func nodeRange(node *yaml.Node) protocol.Range {
 	}
 }
 
+// scalarTokenRange returns the full range of a scalar token, including any
+// surrounding quotes that nodeRange leaves out.
+func scalarTokenRange(node *yaml.Node) protocol.Range {
+	rng := nodeRange(node)
+	if isQuotedScalar(node) && rng.Start.Character > 0 {
+		rng.Start.Character--
+		rng.End.Character++
+	}
+	return rng
+}
+
+func isQuotedScalar(node *yaml.Node) bool {
+	return node != nil && node.Kind == yaml.ScalarNode && node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0
+}
+
 func locateLineContaining(content []byte, needle string) (protocol.Range, bool) {
 	if strings.TrimSpace(needle) == "" {
 		return protocol.Range{}, false
@@ -31,7 +31,7 @@ func TestHandleInitializePublishesSavedWorkspaceDiagnosticsParity(t *testing.T)
 
 			initializeServerForDiagnostics(t, server, root)
 
-			got := capture.messagesByPath()
+			got := capture.messagesByPath(protocol.DiagnosticSeverityError)
 			want := expectedValidationMessagesByPath(t, root)
 			if !reflect.DeepEqual(got, want) {
 				t.Fatalf("unexpected diagnostics\nwant: %#v\ngot: %#v", want, got)
@@ -323,10 +323,13 @@ func (c *diagnosticCapture) latestByPath() map[string]*protocol.PublishDiagnosti
 	return result
 }
 
-func (c *diagnosticCapture) messagesByPath() map[string][]string {
+func (c *diagnosticCapture) messagesByPath(severity protocol.DiagnosticSeverity) map[string][]string {
 	grouped := make(map[string][]string)
 	for path, params := range c.latestByPath() {
 		for _, diag := range params.Diagnostics {
+			if diag.Severity != severity {
+				continue
+			}
 			grouped[path] = append(grouped[path], diag.Message)
 		}
 		sort.Strings(grouped[path])
@@ -0,0 +1,77 @@
+package lsp
+
+import (
+	"context"
+
+	"go.lsp.dev/protocol"
+	"gopkg.in/yaml.v3"
+)
+
+// methodTextDocumentInlayHint is the LSP 3.17 inlay hint request, which the
+// protocol package does not define yet.
+const methodTextDocumentInlayHint = "textDocument/inlayHint"
+
+type inlayHintParams struct {
+	TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+	Range        protocol.Range                  `json:"range"`
+}
+
+type inlayHint struct {
+	Position    protocol.Position       `json:"position"`
+	Label       string                  `json:"label"`
+	Tooltip     *protocol.MarkupContent `json:"tooltip,omitempty"`
+	PaddingLeft bool                    `json:"paddingLeft,omitempty"`
+}
+
+func (s *Server) inlayHints(ctx context.Context, params *inlayHintParams) ([]inlayHint, error) {
+	analysis, typeDef, err := s.dataDocument(params.TextDocument.URI.Filename())
+	if err != nil || typeDef == nil {
+		return nil, err
+	}
+
+	var hints []inlayHint
+	for _, objectNode := range documentObjectNodes(analysis.doc) {
+		if objectNode == nil || objectNode.Kind != yaml.MappingNode {
+			continue
+		}
+		for idx := 0; idx+1 < len(objectNode.Content); idx += 2 {
+			fieldDef := typeDef.Fields[objectNode.Content[idx].Value]
+			if !fieldDef.IsReference() {
+				continue
+			}
+
+			valueNode := objectNode.Content[idx+1]
+			valueNodes := []*yaml.Node{valueNode}
+			if valueNode.Kind == yaml.SequenceNode {
+				valueNodes = valueNode.Content
+			}
+			for _, node := range valueNodes {
+				if node == nil || node.Kind != yaml.ScalarNode || node.Value == "" {
+					continue
+				}
+				end := scalarTokenRange(node).End
+				if !positionWithinRange(end, params.Range) {
+					continue
+				}
+				targets := resolveReferenceObjects(analysis.root, fieldDef, node.Value)
+				if len(targets) != 1 {
+					continue
+				}
+				label := objectPrimaryText(targets[0])
+				if label == "" || label == targets[0].ID {
+					continue
+				}
+				hints = append(hints, inlayHint{
+					Position: end,
+					Label:    label,
+					Tooltip: &protocol.MarkupContent{
+						Kind:  protocol.Markdown,
+						Value: objectSummaryMarkdown(targets[0], analysis.root),
+					},
+					PaddingLeft: true,
+				})
+			}
+		}
+	}
+	return hints, nil
+}
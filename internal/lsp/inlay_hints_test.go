@@ -0,0 +1,53 @@
+package lsp
+
+import (
+	"path/filepath"
+	"testing"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func TestHandleInlayHintShowsReferencedObjectText(t *testing.T) {
+	server, root := initializeExampleFullServer(t)
+	postPath := filepath.Join(root, "data", "posts", "launch.yaml")
+	postContent := readFile(t, postPath)
+
+	var result []inlayHint
+	callServer(t, server, methodTextDocumentInlayHint, 2, &inlayHintParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(postPath))},
+		Range: protocol.Range{
+			Start: protocol.Position{Line: 0, Character: 0},
+			End:   protocol.Position{Line: 100, Character: 0},
+		},
+	}, &result)
+
+	if len(result) != 2 {
+		t.Fatalf("expected author and tag hints, got %#v", result)
+	}
+	authorRange := scalarFieldValueRange(t, postContent, "author")
+	if result[0].Label != "Alice Example" || result[0].Position != authorRange.End {
+		t.Fatalf("expected Alice Example hint after author value, got %#v", result[0])
+	}
+	if result[1].Label != "Product Launch" {
+		t.Fatalf("expected Product Launch hint for tag reference, got %#v", result[1])
+	}
+}
+
+func TestHandleInlayHintRespectsRequestedRange(t *testing.T) {
+	server, root := initializeExampleFullServer(t)
+	postPath := filepath.Join(root, "data", "posts", "launch.yaml")
+
+	var result []inlayHint
+	callServer(t, server, methodTextDocumentInlayHint, 2, &inlayHintParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(postPath))},
+		Range: protocol.Range{
+			Start: protocol.Position{Line: 0, Character: 0},
+			End:   protocol.Position{Line: 2, Character: 0},
+		},
+	}, &result)
+
+	if len(result) != 0 {
+		t.Fatalf("expected no hints outside the requested range, got %#v", result)
+	}
+}
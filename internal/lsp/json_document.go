@@ -0,0 +1,233 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+const utf8BOM = "\ufeff"
+
+// jsonDocumentParser builds a yaml.Node tree from JSON source so the YAML-based
+// helpers can serve JSON data files. Node positions follow yaml.v3: 1-based
+// lines and columns, with quoted scalars positioned at their opening quote.
+type jsonDocumentParser struct {
+	data   []byte
+	pos    int
+	line   int
+	column int
+}
+
+func parseJSONDocument(content []byte) (*yaml.Node, error) {
+	p := &jsonDocumentParser{data: content, line: 1, column: 1}
+	if strings.HasPrefix(string(content), utf8BOM) {
+		p.pos = len(utf8BOM)
+	}
+	p.skipSpace()
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.data) {
+		return nil, p.errorf("unexpected %q after top-level value", p.data[p.pos])
+	}
+	return &yaml.Node{Kind: yaml.DocumentNode, Line: 1, Column: 1, Content: []*yaml.Node{value}}, nil
+}
+
+// looksLikeJSON reports whether content starts with a JSON object or array.
+func looksLikeJSON(content []byte) bool {
+	trimmed := strings.TrimLeft(strings.TrimPrefix(string(content), utf8BOM), " \t\r\n")
+	return strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")
+}
+
+func (p *jsonDocumentParser) parseValue() (*yaml.Node, error) {
+	if p.pos >= len(p.data) {
+		return nil, p.errorf("unexpected end of input")
+	}
+
+	switch c := p.data[p.pos]; {
+	case c == '{':
+		return p.parseObject()
+	case c == '[':
+		return p.parseArray()
+	case c == '"':
+		return p.parseString()
+	case c == 't':
+		return p.parseLiteral("true", "!!bool")
+	case c == 'f':
+		return p.parseLiteral("false", "!!bool")
+	case c == 'n':
+		return p.parseLiteral("null", "!!null")
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.parseNumber()
+	default:
+		return nil, p.errorf("unexpected %q", c)
+	}
+}
+
+func (p *jsonDocumentParser) parseObject() (*yaml.Node, error) {
+	node := p.newNode(yaml.MappingNode, "!!map")
+	node.Style = yaml.FlowStyle
+	p.advance(1)
+
+	p.skipSpace()
+	if p.consume('}') {
+		return node, nil
+	}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) || p.data[p.pos] != '"' {
+			return nil, p.errorf("expected object key")
+		}
+		key, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if !p.consume(':') {
+			return nil, p.errorf("expected ':' after object key")
+		}
+		p.skipSpace()
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, key, value)
+
+		p.skipSpace()
+		if p.consume(',') {
+			continue
+		}
+		if p.consume('}') {
+			return node, nil
+		}
+		return nil, p.errorf("expected ',' or '}' in object")
+	}
+}
+
+func (p *jsonDocumentParser) parseArray() (*yaml.Node, error) {
+	node := p.newNode(yaml.SequenceNode, "!!seq")
+	node.Style = yaml.FlowStyle
+	p.advance(1)
+
+	p.skipSpace()
+	if p.consume(']') {
+		return node, nil
+	}
+	for {
+		p.skipSpace()
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, value)
+
+		p.skipSpace()
+		if p.consume(',') {
+			continue
+		}
+		if p.consume(']') {
+			return node, nil
+		}
+		return nil, p.errorf("expected ',' or ']' in array")
+	}
+}
+
+func (p *jsonDocumentParser) parseString() (*yaml.Node, error) {
+	node := p.newNode(yaml.ScalarNode, "!!str")
+	node.Style = yaml.DoubleQuotedStyle
+
+	end := p.pos + 1
+	for end < len(p.data) && p.data[end] != '"' {
+		switch p.data[end] {
+		case '\\':
+			end++
+		case '\n':
+			return nil, p.errorf("unterminated string")
+		}
+		end++
+	}
+	if end >= len(p.data) {
+		return nil, p.errorf("unterminated string")
+	}
+
+	if err := json.Unmarshal(p.data[p.pos:end+1], &node.Value); err != nil {
+		return nil, p.errorf("invalid string: %v", err)
+	}
+	p.advance(end + 1 - p.pos)
+	return node, nil
+}
+
+func (p *jsonDocumentParser) parseNumber() (*yaml.Node, error) {
+	node := p.newNode(yaml.ScalarNode, "!!int")
+
+	end := p.pos
+	for end < len(p.data) && strings.IndexByte("+-0123456789.eE", p.data[end]) >= 0 {
+		end++
+	}
+	raw := p.data[p.pos:end]
+	if !json.Valid(raw) {
+		return nil, p.errorf("invalid number %q", raw)
+	}
+	if strings.ContainsAny(string(raw), ".eE") {
+		node.Tag = "!!float"
+	}
+	node.Value = string(raw)
+	p.advance(end - p.pos)
+	return node, nil
+}
+
+func (p *jsonDocumentParser) parseLiteral(literal, tag string) (*yaml.Node, error) {
+	if !strings.HasPrefix(string(p.data[p.pos:]), literal) {
+		return nil, p.errorf("invalid literal")
+	}
+	node := p.newNode(yaml.ScalarNode, tag)
+	node.Value = literal
+	p.advance(len(literal))
+	return node, nil
+}
+
+func (p *jsonDocumentParser) newNode(kind yaml.Kind, tag string) *yaml.Node {
+	return &yaml.Node{Kind: kind, Tag: tag, Line: p.line, Column: p.column}
+}
+
+func (p *jsonDocumentParser) consume(c byte) bool {
+	if p.pos < len(p.data) && p.data[p.pos] == c {
+		p.advance(1)
+		return true
+	}
+	return false
+}
+
+func (p *jsonDocumentParser) skipSpace() {
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case ' ', '\t', '\r', '\n':
+			p.advance(1)
+		default:
+			return
+		}
+	}
+}
+
+// advance moves past n bytes, counting columns in characters like yaml.v3.
+func (p *jsonDocumentParser) advance(n int) {
+	for end := p.pos + n; p.pos < end && p.pos < len(p.data); p.pos++ {
+		c := p.data[p.pos]
+		switch {
+		case c == '\n':
+			p.line++
+			p.column = 1
+		case utf8.RuneStart(c):
+			p.column++
+		}
+	}
+}
+
+func (p *jsonDocumentParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("json: line %d column %d: %s", p.line, p.column, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,165 @@
+package lsp
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseDocumentNodeTracksJSONPositions(t *testing.T) {
+	content := "{\n\t\"id\": \"a\\/b\",\n\t\"count\": 2,\n\t\"tags\": [\"x\", true, null]\n}\n"
+
+	doc, ok := parseDocumentNode([]byte(content))
+	if !ok {
+		t.Fatalf("expected JSON with escaped slash and tab indentation to parse")
+	}
+
+	root := documentRoot(doc)
+	if root.Kind != yaml.MappingNode || root.Style&yaml.FlowStyle == 0 {
+		t.Fatalf("expected flow mapping root, got %#v", root)
+	}
+
+	keyNode, valueNode := mappingEntry(root, "id")
+	if valueNode == nil || valueNode.Value != "a/b" {
+		t.Fatalf("expected decoded id value, got %#v", valueNode)
+	}
+	if got := nodeRange(keyNode); got.Start != (protocol.Position{Line: 1, Character: 2}) || got.End.Character != 4 {
+		t.Fatalf("expected key range inside quotes, got %+v", got)
+	}
+	if got := scalarTokenRange(valueNode); got.Start.Character != 7 {
+		t.Fatalf("expected value token to start at its quote, got %+v", got)
+	}
+
+	_, countNode := mappingEntry(root, "count")
+	if countNode == nil || countNode.Tag != "!!int" || countNode.Value != "2" {
+		t.Fatalf("expected integer count node, got %#v", countNode)
+	}
+
+	_, tagsNode := mappingEntry(root, "tags")
+	if tagsNode == nil || tagsNode.Kind != yaml.SequenceNode || len(tagsNode.Content) != 3 {
+		t.Fatalf("expected three-item tags sequence, got %#v", tagsNode)
+	}
+	if tagsNode.Content[1].Tag != "!!bool" || tagsNode.Content[2].Tag != "!!null" {
+		t.Fatalf("expected bool and null literals, got %#v", tagsNode.Content)
+	}
+	if tagsNode.Content[2].Line != 4 || tagsNode.Content[2].Column != 22 {
+		t.Fatalf("expected null at 4:22, got %d:%d", tagsNode.Content[2].Line, tagsNode.Content[2].Column)
+	}
+}
+
+func TestParseDocumentNodeFallsBackToYAMLFlowMappings(t *testing.T) {
+	doc, ok := parseDocumentNode([]byte("{id: user-1, name: Example}\n"))
+	if !ok {
+		t.Fatalf("expected YAML flow mapping to parse")
+	}
+	if _, valueNode := mappingEntry(documentRoot(doc), "name"); valueNode == nil || valueNode.Value != "Example" {
+		t.Fatalf("expected name from YAML fallback, got %#v", valueNode)
+	}
+
+	if _, err := parseJSONDocument([]byte("{\"id\": \"a\",}")); err == nil {
+		t.Fatalf("expected trailing comma to be rejected")
+	}
+}
+
+func TestJSONDataFilesMatchYAMLFeatures(t *testing.T) {
+	t.Run("hover and definition on quoted reference", func(t *testing.T) {
+		server, root := initializeExampleFullServer(t)
+		path := filepath.Join(root, "data", "comments", "launch-comment.json")
+		content := readFile(t, path)
+		pos := positionInContent(t, content, `user-bob"`)
+		pos.Character += uint32(len("user-bob")) - 1
+		params := protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(path))},
+			Position:     pos,
+		}
+
+		var hover protocol.Hover
+		callServer(t, server, protocol.MethodTextDocumentHover, 2, &protocol.HoverParams{TextDocumentPositionParams: params}, &hover)
+		if !strings.Contains(hover.Contents.Value, "Bob Example") {
+			t.Fatalf("expected hover for user-bob, got %q", hover.Contents.Value)
+		}
+		if hover.Range == nil || *hover.Range != scalarFieldValueRange(t, content, "author") {
+			t.Fatalf("expected hover range on the unquoted author value, got %+v", hover.Range)
+		}
+
+		var locations []protocol.Location
+		callServer(t, server, protocol.MethodTextDocumentDefinition, 3, &protocol.DefinitionParams{TextDocumentPositionParams: params}, &locations)
+		bobPath := filepath.Join(root, "data", "users", "bob.json")
+		if len(locations) != 1 {
+			t.Fatalf("expected one definition, got %#v", locations)
+		}
+		expectLocation(t, locations[0], bobPath, scalarFieldValueRange(t, readFile(t, bobPath), "id"))
+	})
+
+	t.Run("completion inside quoted value", func(t *testing.T) {
+		server, root := initializeExampleFullServer(t)
+		path := filepath.Join(root, "data", "comments", "launch-comment.json")
+		text, pos := cursorContent("{\n  \"id\": \"comment-001\",\n  \"author\": \"user-|\"\n}\n")
+		openDocument(t, server, path, "json", 1, text)
+
+		var result protocol.CompletionList
+		callServer(t, server, protocol.MethodTextDocumentCompletion, 2, &protocol.CompletionParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(path))},
+				Position:     pos,
+			},
+		}, &result)
+
+		labels := completionLabels(result.Items)
+		if !contains(labels, "user-alice") || !contains(labels, "user-bob") {
+			t.Fatalf("expected user reference completions, got %v", labels)
+		}
+	})
+
+	t.Run("missing required field insertion", func(t *testing.T) {
+		server, capture, root := initializeCodeActionServer(t, filepath.Join("..", "..", "examples", "full"))
+		path := filepath.Join(root, "data", "comments", "launch-comment.json")
+		text := "{\n  \"id\": \"comment-001\",\n  \"author\": \"user-bob\",\n  \"content\": \"Congrats team!\",\n  \"created_at\": \"2024-05-01T09:00:00Z\"\n}\n"
+
+		actions := openDocumentAndCodeActions(t, server, capture, path, text)
+		action := requireCodeAction(t, actions, `Insert missing field "post"`)
+		edit := requireSingleEdit(t, action, path)
+
+		if edit.NewText != "\"post\": \"\",\n  " {
+			t.Fatalf("expected JSON entry before author, got %q", edit.NewText)
+		}
+		if edit.Range.Start != (protocol.Position{Line: 2, Character: 2}) {
+			t.Fatalf("expected insertion at the author key, got %+v", edit.Range)
+		}
+	})
+
+	t.Run("missing required field appended after last entry", func(t *testing.T) {
+		server, capture, root := initializeCodeActionServer(t, filepath.Join("..", "..", "examples", "full"))
+		path := filepath.Join(root, "data", "users", "bob.json")
+		text := "{\n  \"id\": \"user-bob\",\n  \"name\": \"Bob Example\"\n}\n"
+
+		actions := openDocumentAndCodeActions(t, server, capture, path, text)
+		action := requireCodeAction(t, actions, `Insert missing field "email"`)
+		edit := requireSingleEdit(t, action, path)
+
+		if edit.NewText != ",\n  \"email\": \"\"" {
+			t.Fatalf("expected email appended after name, got %q", edit.NewText)
+		}
+		if want := scalarTokenRange(mustMappingValue(t, text, "name")).End; edit.Range.Start != want {
+			t.Fatalf("expected insertion after the name value at %+v, got %+v", want, edit.Range)
+		}
+	})
+}
+
+func mustMappingValue(t *testing.T, content, field string) *yaml.Node {
+	t.Helper()
+
+	doc, ok := parseDocumentNode([]byte(content))
+	if !ok {
+		t.Fatalf("parse document: %q", content)
+	}
+	_, valueNode := mappingEntry(documentRoot(doc), field)
+	if valueNode == nil {
+		t.Fatalf("expected field %q in %q", field, content)
+	}
+	return valueNode
+}
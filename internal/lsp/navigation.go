@@ -43,50 +43,61 @@ func (s *Server) references(ctx context.Context, params *protocol.ReferenceParam
 
 func (s *Server) documentSymbols(ctx context.Context, params *protocol.DocumentSymbolParams) ([]protocol.DocumentSymbol, error) {
 	path := params.TextDocument.URI.Filename()
+	analysis, typeDef, err := s.dataDocument(path)
+	if err != nil || typeDef == nil {
+		return nil, err
+	}
+
+	objectNodes := documentObjectNodes(analysis.doc)
+	symbols := make([]protocol.DocumentSymbol, 0, len(objectNodes))
+	for index, objectNode := range objectNodes {
+		symbol := buildDocumentSymbol(analysis.root, typeDef, path, objectNode, index)
+		if symbol.Name == "" {
+			continue
+		}
+		symbols = append(symbols, symbol)
+	}
+
+	return symbols, nil
+}
+
+// dataDocument parses a data file owned by a detected root and resolves the
+// entity type its objects belong to.
+func (s *Server) dataDocument(path string) (*documentAnalysis, *config.TypeDefinition, error) {
 	if s.runtime == nil {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	root := s.runtime.RootByPath(path)
 	if root == nil || root.Index == nil {
-		return nil, nil
+		return nil, nil, nil
 	}
 	if len(root.Index.TypesForFile(path)) == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	content, err := s.documentContent(path)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	doc, ok := parseDocumentNode(content)
 	if !ok {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	analysis := &documentAnalysis{
 		path:    path,
 		content: content,
 		root:    root,
+		kind:    analysisKindData,
 		doc:     doc,
 		cfg:     validationConfig(root),
 	}
 	typeDef := resolveDataTypeDefinition(analysis)
 	if typeDef == nil {
-		return nil, nil
+		return nil, nil, nil
 	}
-
-	objectNodes := documentObjectNodes(doc)
-	symbols := make([]protocol.DocumentSymbol, 0, len(objectNodes))
-	for index, objectNode := range objectNodes {
-		symbol := buildDocumentSymbol(root, typeDef, path, objectNode, index)
-		if symbol.Name == "" {
-			continue
-		}
-		symbols = append(symbols, symbol)
-	}
-
-	return symbols, nil
+	return analysis, typeDef, nil
 }
 
 func (s *Server) workspaceSymbols(ctx context.Context, params *protocol.WorkspaceSymbolParams) ([]protocol.SymbolInformation, error) {
@@ -213,7 +224,24 @@ func (s *Server) referenceDeclarations(target *referenceTarget) []protocol.Locat
 	return locations
 }
 
+type referenceUsage struct {
+	path string
+	node *yaml.Node
+}
+
 func (s *Server) referenceUsages(target *referenceTarget) []protocol.Location {
+	usages := s.referenceUsageNodes(target)
+	locations := make([]protocol.Location, 0, len(usages))
+	for _, usage := range usages {
+		locations = append(locations, protocol.Location{
+			URI:   protocol.DocumentURI(uri.File(usage.path)),
+			Range: nodeRange(usage.node),
+		})
+	}
+	return locations
+}
+
+func (s *Server) referenceUsageNodes(target *referenceTarget) []referenceUsage {
 	if target == nil || target.root == nil || target.root.Workspace == nil {
 		return nil
 	}
@@ -228,7 +256,7 @@ func (s *Server) referenceUsages(target *referenceTarget) []protocol.Location {
 		typeSet[typeName] = struct{}{}
 	}
 
-	var locations []protocol.Location
+	var usages []referenceUsage
 	for _, sourceType := range sortedTypeNames(cfg.Types) {
 		typeDef := cfg.Types[sourceType]
 		if typeDef == nil {
@@ -259,12 +287,14 @@ func (s *Server) referenceUsages(target *referenceTarget) []protocol.Location {
 				if valueNode == nil {
 					continue
 				}
-				locations = append(locations, referenceNodeLocations(obj.File, valueNode, target.id)...)
+				for _, node := range referenceValueNodes(valueNode, target.id) {
+					usages = append(usages, referenceUsage{path: obj.File, node: node})
+				}
 			}
 		}
 	}
 
-	return locations
+	return usages
 }
 
 func buildDocumentSymbol(root *workspace.RootRuntime, typeDef *config.TypeDefinition, path string, objectNode *yaml.Node, index int) protocol.DocumentSymbol {
@@ -397,31 +427,25 @@ func referenceFieldMatchesTypes(fieldDef *config.FieldDefinition, targetTypes ma
 	return false
 }
 
-func referenceNodeLocations(path string, valueNode *yaml.Node, want string) []protocol.Location {
+func referenceValueNodes(valueNode *yaml.Node, want string) []*yaml.Node {
 	if valueNode == nil || want == "" {
 		return nil
 	}
 
-	var locations []protocol.Location
+	var nodes []*yaml.Node
 	switch valueNode.Kind {
 	case yaml.ScalarNode:
 		if valueNode.Value == want {
-			locations = append(locations, protocol.Location{
-				URI:   protocol.DocumentURI(uri.File(path)),
-				Range: nodeRange(valueNode),
-			})
+			nodes = append(nodes, valueNode)
 		}
 	case yaml.SequenceNode:
 		for _, child := range valueNode.Content {
 			if child != nil && child.Kind == yaml.ScalarNode && child.Value == want {
-				locations = append(locations, protocol.Location{
-					URI:   protocol.DocumentURI(uri.File(path)),
-					Range: nodeRange(child),
-				})
+				nodes = append(nodes, child)
 			}
 		}
 	}
-	return locations
+	return nodes
 }
 
 func objectNodeForObject(doc *yaml.Node, typeDef *config.TypeDefinition, obj *data.Object) *yaml.Node {
@@ -0,0 +1,182 @@
+package lsp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/mergewayhq/mergeway-cli/internal/workspace"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+// The protocol package predates pull diagnostics (LSP 3.17), so the request
+// and report shapes are declared locally.
+const (
+	methodTextDocumentDiagnostic     = "textDocument/diagnostic"
+	methodWorkspaceDiagnostic        = "workspace/diagnostic"
+	methodWorkspaceDiagnosticRefresh = "workspace/diagnostic/refresh"
+
+	diagnosticReportFull      = "full"
+	diagnosticReportUnchanged = "unchanged"
+)
+
+type diagnosticOptions struct {
+	Identifier            string `json:"identifier,omitempty"`
+	InterFileDependencies bool   `json:"interFileDependencies"`
+	WorkspaceDiagnostics  bool   `json:"workspaceDiagnostics"`
+}
+
+type documentDiagnosticParams struct {
+	TextDocument     protocol.TextDocumentIdentifier `json:"textDocument"`
+	Identifier       string                          `json:"identifier,omitempty"`
+	PreviousResultID string                          `json:"previousResultId,omitempty"`
+}
+
+type previousResultID struct {
+	URI   protocol.DocumentURI `json:"uri"`
+	Value string               `json:"value"`
+}
+
+type workspaceDiagnosticParams struct {
+	Identifier        string             `json:"identifier,omitempty"`
+	PreviousResultIDs []previousResultID `json:"previousResultIds"`
+}
+
+type fullDocumentDiagnosticReport struct {
+	Kind     string                `json:"kind"`
+	ResultID string                `json:"resultId,omitempty"`
+	Items    []protocol.Diagnostic `json:"items"`
+}
+
+type unchangedDocumentDiagnosticReport struct {
+	Kind     string `json:"kind"`
+	ResultID string `json:"resultId"`
+}
+
+type workspaceFullDocumentDiagnosticReport struct {
+	fullDocumentDiagnosticReport
+	URI     protocol.DocumentURI `json:"uri"`
+	Version *int32               `json:"version"`
+}
+
+type workspaceUnchangedDocumentDiagnosticReport struct {
+	unchangedDocumentDiagnosticReport
+	URI     protocol.DocumentURI `json:"uri"`
+	Version *int32               `json:"version"`
+}
+
+type workspaceDiagnosticReport struct {
+	Items []interface{} `json:"items"`
+}
+
+// documentDiagnostics answers a pull for one file. Result IDs are content
+// hashes of the diagnostics, so an unchanged file is reported as such without
+// the server tracking what each client has seen.
+func (s *Server) documentDiagnostics(_ context.Context, params *documentDiagnosticParams) (interface{}, error) {
+	if s.runtime == nil {
+		return fullDocumentDiagnosticReport{Kind: diagnosticReportFull, Items: []protocol.Diagnostic{}}, nil
+	}
+
+	path := params.TextDocument.URI.Filename()
+	items := diagnosticItems(s.snapshotDiagnostics(s.runtime.Snapshot())[path])
+	resultID := diagnosticsResultID(items)
+	if resultID != "" && params.PreviousResultID == resultID {
+		return unchangedDocumentDiagnosticReport{Kind: diagnosticReportUnchanged, ResultID: resultID}, nil
+	}
+	return fullDocumentDiagnosticReport{Kind: diagnosticReportFull, ResultID: resultID, Items: items}, nil
+}
+
+// workspaceDiagnostics reports every file with diagnostics plus every file the
+// client already holds a result for, so cleared files are reported empty.
+func (s *Server) workspaceDiagnostics(_ context.Context, params *workspaceDiagnosticParams) (*workspaceDiagnosticReport, error) {
+	report := &workspaceDiagnosticReport{Items: []interface{}{}}
+	if s.runtime == nil {
+		return report, nil
+	}
+
+	snapshot := s.runtime.Snapshot()
+	paramsByPath := s.snapshotDiagnostics(snapshot)
+
+	previous := make(map[string]string, len(params.PreviousResultIDs))
+	for _, entry := range params.PreviousResultIDs {
+		previous[entry.URI.Filename()] = entry.Value
+	}
+
+	paths := make([]string, 0, len(paramsByPath)+len(previous))
+	for path := range paramsByPath {
+		paths = append(paths, path)
+	}
+	for path := range previous {
+		if _, ok := paramsByPath[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		documentURI := protocol.DocumentURI(uri.File(path))
+		version := openDocumentVersion(snapshot.Documents[path])
+		items := diagnosticItems(paramsByPath[path])
+		resultID := diagnosticsResultID(items)
+		if resultID != "" && previous[path] == resultID {
+			report.Items = append(report.Items, workspaceUnchangedDocumentDiagnosticReport{
+				unchangedDocumentDiagnosticReport: unchangedDocumentDiagnosticReport{Kind: diagnosticReportUnchanged, ResultID: resultID},
+				URI:                               documentURI,
+				Version:                           version,
+			})
+			continue
+		}
+		report.Items = append(report.Items, workspaceFullDocumentDiagnosticReport{
+			fullDocumentDiagnosticReport: fullDocumentDiagnosticReport{Kind: diagnosticReportFull, ResultID: resultID, Items: items},
+			URI:                          documentURI,
+			Version:                      version,
+		})
+	}
+	return report, nil
+}
+
+// refreshPullDiagnostics asks the client to re-pull after a reload. Clients
+// without refresh support re-pull on their own schedule.
+func (s *Server) refreshPullDiagnostics(ctx context.Context) error {
+	s.mu.Lock()
+	ready := s.clientReady && s.diagnosticRefresh && s.callClient != nil
+	call := s.callClient
+	s.mu.Unlock()
+	if !ready {
+		return nil
+	}
+	return call(ctx, methodWorkspaceDiagnosticRefresh, nil, nil)
+}
+
+func (s *Server) usesPullDiagnostics() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pullDiagnostics
+}
+
+func diagnosticItems(params *protocol.PublishDiagnosticsParams) []protocol.Diagnostic {
+	if params == nil || params.Diagnostics == nil {
+		return []protocol.Diagnostic{}
+	}
+	return params.Diagnostics
+}
+
+func diagnosticsResultID(items []protocol.Diagnostic) string {
+	encoded, err := json.Marshal(items)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:8])
+}
+
+func openDocumentVersion(doc *workspace.OpenDocument) *int32 {
+	if doc == nil {
+		return nil
+	}
+	version := doc.Version
+	return &version
+}
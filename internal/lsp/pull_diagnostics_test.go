@@ -0,0 +1,177 @@
+package lsp
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+type pulledDiagnosticReport struct {
+	Kind     string                `json:"kind"`
+	ResultID string                `json:"resultId"`
+	Items    []protocol.Diagnostic `json:"items"`
+	URI      protocol.DocumentURI  `json:"uri"`
+}
+
+func TestHandleDocumentDiagnosticReturnsUnchangedForSameResult(t *testing.T) {
+	server, capture, calls, root := initializePullDiagnosticsServer(t)
+	postPath := filepath.Join(root, "data", "posts", "post.yaml")
+	postURI := protocol.DocumentURI(uri.File(postPath))
+
+	if got := capture.latestByPath(); len(got) != 0 {
+		t.Fatalf("expected no pushed diagnostics for a pull client, got %#v", got)
+	}
+
+	var first pulledDiagnosticReport
+	callServer(t, server, methodTextDocumentDiagnostic, 2, &documentDiagnosticParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: postURI},
+	}, &first)
+	if first.Kind != diagnosticReportFull || first.ResultID == "" || len(first.Items) != 1 {
+		t.Fatalf("expected full report with one diagnostic, got %#v", first)
+	}
+	if !strings.Contains(first.Items[0].Message, "missing-user") {
+		t.Fatalf("unexpected diagnostic: %s", first.Items[0].Message)
+	}
+
+	var second pulledDiagnosticReport
+	callServer(t, server, methodTextDocumentDiagnostic, 3, &documentDiagnosticParams{
+		TextDocument:     protocol.TextDocumentIdentifier{URI: postURI},
+		PreviousResultID: first.ResultID,
+	}, &second)
+	if second.Kind != diagnosticReportUnchanged || second.ResultID != first.ResultID || second.Items != nil {
+		t.Fatalf("expected unchanged report, got %#v", second)
+	}
+
+	openDocument(t, server, postPath, "yaml", 1, "id: post-1\nauthor: user-1\ntitle: Fixed\n")
+	if err := server.runtime.FlushReload(); err != nil {
+		t.Fatalf("FlushReload: %v", err)
+	}
+	if !calls.called(methodWorkspaceDiagnosticRefresh) {
+		t.Fatalf("expected a diagnostic refresh request after reload, got %v", calls.methods())
+	}
+
+	var third pulledDiagnosticReport
+	callServer(t, server, methodTextDocumentDiagnostic, 4, &documentDiagnosticParams{
+		TextDocument:     protocol.TextDocumentIdentifier{URI: postURI},
+		PreviousResultID: first.ResultID,
+	}, &third)
+	if third.Kind != diagnosticReportFull || third.Items == nil || len(third.Items) != 0 {
+		t.Fatalf("expected empty full report after the fix, got %#v", third)
+	}
+}
+
+func TestHandleWorkspaceDiagnosticSkipsUnchangedFiles(t *testing.T) {
+	server, _, _, root := initializePullDiagnosticsServer(t)
+	postPath := filepath.Join(root, "data", "posts", "post.yaml")
+
+	var first struct {
+		Items []pulledDiagnosticReport `json:"items"`
+	}
+	callServer(t, server, methodWorkspaceDiagnostic, 2, &workspaceDiagnosticParams{}, &first)
+	if len(first.Items) != 1 || first.Items[0].URI.Filename() != postPath || first.Items[0].Kind != diagnosticReportFull {
+		t.Fatalf("expected one full report for %s, got %#v", postPath, first.Items)
+	}
+
+	var second struct {
+		Items []pulledDiagnosticReport `json:"items"`
+	}
+	callServer(t, server, methodWorkspaceDiagnostic, 3, &workspaceDiagnosticParams{
+		PreviousResultIDs: []previousResultID{{URI: first.Items[0].URI, Value: first.Items[0].ResultID}},
+	}, &second)
+	if len(second.Items) != 1 || second.Items[0].Kind != diagnosticReportUnchanged {
+		t.Fatalf("expected unchanged report, got %#v", second.Items)
+	}
+
+	openDocument(t, server, postPath, "yaml", 1, "id: post-1\nauthor: user-1\ntitle: Fixed\n")
+	if err := server.runtime.FlushReload(); err != nil {
+		t.Fatalf("FlushReload: %v", err)
+	}
+
+	var third struct {
+		Items []pulledDiagnosticReport `json:"items"`
+	}
+	callServer(t, server, methodWorkspaceDiagnostic, 4, &workspaceDiagnosticParams{
+		PreviousResultIDs: []previousResultID{{URI: first.Items[0].URI, Value: first.Items[0].ResultID}},
+	}, &third)
+	if len(third.Items) != 1 || third.Items[0].Kind != diagnosticReportFull || len(third.Items[0].Items) != 0 {
+		t.Fatalf("expected cleared full report for the fixed file, got %#v", third.Items)
+	}
+}
+
+func TestSnapshotDiagnosticsAreCollectedOncePerGeneration(t *testing.T) {
+	server, _, _, root := initializePullDiagnosticsServer(t)
+	postPath := filepath.Join(root, "data", "posts", "post.yaml")
+
+	callServer(t, server, methodTextDocumentDiagnostic, 2, &documentDiagnosticParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(postPath))},
+	}, &pulledDiagnosticReport{})
+	cached := server.diagnosticsCache
+	if cached == nil || cached.generation != server.runtime.Snapshot().Generation {
+		t.Fatalf("expected the pull to cache the current generation, got %#v", cached)
+	}
+
+	callServer(t, server, methodWorkspaceDiagnostic, 3, &workspaceDiagnosticParams{}, &struct{}{})
+	if got := server.snapshotDiagnostics(server.runtime.Snapshot()); reflect.ValueOf(got).Pointer() != reflect.ValueOf(cached.paramsByPath).Pointer() {
+		t.Fatal("expected the workspace pull to reuse the cached diagnostics")
+	}
+
+	openDocument(t, server, postPath, "yaml", 1, "id: post-1\nauthor: user-1\ntitle: Fixed\n")
+	if err := server.runtime.FlushReload(); err != nil {
+		t.Fatalf("FlushReload: %v", err)
+	}
+	if got := server.snapshotDiagnostics(server.runtime.Snapshot()); len(diagnosticItems(got[postPath])) != 0 {
+		t.Fatalf("expected fresh diagnostics after the edit, got %#v", got[postPath])
+	}
+}
+
+func initializePullDiagnosticsServer(t *testing.T) (*Server, *diagnosticCapture, *clientCallCapture, string) {
+	t.Helper()
+
+	root := absTestPath(t, t.TempDir())
+	writeTestFiles(t, root, map[string]string{
+		"mergeway.yaml": `mergeway:
+  version: 1
+
+entities:
+  User:
+    identifier: id
+    include:
+      - data/users/*.yaml
+    fields:
+      id: string
+  Post:
+    identifier: id
+    include:
+      - data/posts/*.yaml
+    fields:
+      id: string
+      author:
+        type: User
+        required: true
+      title: string
+`,
+		"data/users/user-1.yaml": "id: user-1\n",
+		"data/posts/post.yaml":   "id: post-1\nauthor: missing-user\ntitle: Missing Author\n",
+	})
+
+	capture := &diagnosticCapture{}
+	calls := &clientCallCapture{}
+	server := NewServer(Options{
+		Logger:             testLogger(),
+		PublishDiagnostics: capture.PublishDiagnostics,
+		CallClient:         calls.CallClient,
+	})
+	callServer(t, server, protocol.MethodInitialize, 1, map[string]any{
+		"rootUri": string(uri.File(root)),
+		"capabilities": map[string]any{
+			"textDocument": map[string]any{"diagnostic": map[string]any{}},
+			"workspace":    map[string]any{"diagnostics": map[string]any{"refreshSupport": true}},
+		},
+	}, (*protocol.InitializeResult)(nil))
+	callServer(t, server, protocol.MethodInitialized, 2, &protocol.InitializedParams{}, (*struct{})(nil))
+	return server, capture, calls, root
+}
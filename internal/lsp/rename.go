@@ -0,0 +1,305 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mergewayhq/mergeway-cli/internal/config"
+	"github.com/mergewayhq/mergeway-cli/internal/workspace"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+	"gopkg.in/yaml.v3"
+)
+
+type prepareRenameResult struct {
+	Range       protocol.Range `json:"range"`
+	Placeholder string         `json:"placeholder"`
+}
+
+type renameKind int
+
+const (
+	renameKindIdentifier renameKind = iota + 1
+	renameKindField
+)
+
+type renameTarget struct {
+	kind        renameKind
+	rng         protocol.Range
+	placeholder string
+	root        *workspace.RootRuntime
+
+	reference *referenceTarget
+
+	typeDef   *config.TypeDefinition
+	fieldName string
+}
+
+type renameEdits map[string][]protocol.TextEdit
+
+func (s *Server) prepareRename(ctx context.Context, params *protocol.PrepareRenameParams) (*prepareRenameResult, error) {
+	target, err := s.resolveRenameTarget(params.TextDocument.URI.Filename(), params.Position)
+	if err != nil || target == nil {
+		return nil, err
+	}
+	return &prepareRenameResult{Range: target.rng, Placeholder: target.placeholder}, nil
+}
+
+func (s *Server) rename(ctx context.Context, params *protocol.RenameParams) (*protocol.WorkspaceEdit, error) {
+	target, err := s.resolveRenameTarget(params.TextDocument.URI.Filename(), params.Position)
+	if err != nil || target == nil {
+		return nil, err
+	}
+
+	newName := strings.TrimSpace(params.NewName)
+	if newName == "" {
+		return nil, fmt.Errorf("lsp: rename requires a non-empty name")
+	}
+	if newName == target.placeholder {
+		return nil, nil
+	}
+
+	var edits renameEdits
+	switch target.kind {
+	case renameKindIdentifier:
+		edits, err = s.identifierRenameEdits(target.reference, newName)
+	case renameKindField:
+		edits, err = s.fieldRenameEdits(target.root, target.typeDef, target.fieldName, newName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return edits.workspaceEdit(), nil
+}
+
+func (s *Server) resolveRenameTarget(path string, position protocol.Position) (*renameTarget, error) {
+	analysis, err := s.analyzePosition(path, position)
+	if err != nil || analysis == nil {
+		return nil, err
+	}
+
+	switch analysis.kind {
+	case analysisKindData:
+		return identifierRenameTarget(analysis), nil
+	case analysisKindConfig:
+		return fieldRenameTarget(analysis), nil
+	default:
+		return nil, nil
+	}
+}
+
+func identifierRenameTarget(analysis *documentAnalysis) *renameTarget {
+	if analysis.data == nil || !analysis.data.onFieldValue {
+		return nil
+	}
+
+	target := resolveReferenceTarget(analysis)
+	if target == nil || len(target.declarations) == 0 {
+		return nil
+	}
+	for _, obj := range target.declarations {
+		typeDef := analysis.cfg.Types[obj.Type]
+		if typeDef == nil || typeDef.Identifier.IsPath() || obj.Inline || obj.ReadOnly {
+			return nil
+		}
+	}
+
+	return &renameTarget{
+		kind:        renameKindIdentifier,
+		rng:         analysis.data.positionRange,
+		placeholder: target.id,
+		root:        analysis.root,
+		reference:   target,
+	}
+}
+
+func fieldRenameTarget(analysis *documentAnalysis) *renameTarget {
+	if analysis.cfg == nil || analysis.doc == nil {
+		return nil
+	}
+
+	entityName, keyNode := configFieldKeyAtPosition(analysis.doc, analysis.position)
+	if keyNode == nil {
+		return nil
+	}
+	typeDef := analysis.cfg.Types[entityName]
+	if typeDef == nil || typeDef.Fields[keyNode.Value] == nil {
+		return nil
+	}
+
+	return &renameTarget{
+		kind:        renameKindField,
+		rng:         nodeRange(keyNode),
+		placeholder: keyNode.Value,
+		root:        analysis.root,
+		typeDef:     typeDef,
+		fieldName:   keyNode.Value,
+	}
+}
+
+func (s *Server) identifierRenameEdits(target *referenceTarget, newID string) (renameEdits, error) {
+	if target.root.Workspace != nil {
+		for _, obj := range target.declarations {
+			if len(target.root.Workspace.Find(obj.Type, newID)) > 0 {
+				return nil, fmt.Errorf("lsp: %s %q already exists", obj.Type, newID)
+			}
+		}
+	}
+
+	edits := make(renameEdits)
+	for _, obj := range target.declarations {
+		if node := s.objectIdentifierNode(target.root, obj); node != nil {
+			edits.add(obj.File, nodeRange(node), newID)
+		}
+	}
+	for _, usage := range s.referenceUsageNodes(target) {
+		edits.add(usage.path, nodeRange(usage.node), newID)
+	}
+	return edits, nil
+}
+
+func (s *Server) fieldRenameEdits(root *workspace.RootRuntime, typeDef *config.TypeDefinition, oldName, newName string) (renameEdits, error) {
+	cfg := validationConfig(root)
+	typeNames := cfg.AssignableTypes(typeDef.Name)
+	for _, typeName := range typeNames {
+		if cfg.Types[typeName].Fields[newName] != nil {
+			return nil, fmt.Errorf("lsp: type %s already defines field %q", typeName, newName)
+		}
+	}
+
+	edits := make(renameEdits)
+	for _, typeName := range typeNames {
+		current := cfg.Types[typeName]
+		if current.Source == "" {
+			continue
+		}
+		content, err := s.documentContent(current.Source)
+		if err != nil {
+			continue
+		}
+		doc, ok := parseDocumentNode(content)
+		if !ok {
+			continue
+		}
+		entityNode := configEntityNode(doc, typeName)
+		if entityNode == nil {
+			continue
+		}
+
+		if typeName == typeDef.Name {
+			if _, fieldsNode := mappingEntry(entityNode, "fields"); fieldsNode != nil {
+				if keyNode, _ := mappingEntry(fieldsNode, oldName); keyNode != nil {
+					edits.add(current.Source, nodeRange(keyNode), newName)
+				}
+			}
+			if node := identifierFieldNode(entityNode); node != nil && node.Value == oldName {
+				edits.add(current.Source, nodeRange(node), newName)
+			}
+		}
+
+		if _, inlineNode := mappingEntry(entityNode, "data"); inlineNode != nil && inlineNode.Kind == yaml.SequenceNode {
+			for _, itemNode := range inlineNode.Content {
+				if keyNode, _ := mappingEntry(itemNode, oldName); keyNode != nil {
+					edits.add(current.Source, nodeRange(keyNode), newName)
+				}
+			}
+		}
+	}
+
+	if root.Workspace == nil {
+		return edits, nil
+	}
+	for _, typeName := range typeNames {
+		objectTypeDef := cfg.Types[typeName]
+		for _, obj := range root.Workspace.Objects(typeName) {
+			if obj.Type != typeName || obj.Inline {
+				continue
+			}
+			content, err := s.documentContent(obj.File)
+			if err != nil {
+				continue
+			}
+			doc, ok := parseDocumentNode(content)
+			if !ok {
+				continue
+			}
+			if keyNode, _ := mappingEntry(objectNodeForObject(doc, objectTypeDef, obj), oldName); keyNode != nil {
+				edits.add(obj.File, nodeRange(keyNode), newName)
+			}
+		}
+	}
+
+	return edits, nil
+}
+
+func configFieldKeyAtPosition(doc *yaml.Node, position protocol.Position) (string, *yaml.Node) {
+	_, entitiesNode := mappingEntry(documentRoot(doc), "entities")
+	if entitiesNode == nil || entitiesNode.Kind != yaml.MappingNode {
+		return "", nil
+	}
+
+	for idx := 0; idx+1 < len(entitiesNode.Content); idx += 2 {
+		entityName := entitiesNode.Content[idx].Value
+		_, fieldsNode := mappingEntry(entitiesNode.Content[idx+1], "fields")
+		if fieldsNode == nil || fieldsNode.Kind != yaml.MappingNode {
+			continue
+		}
+		for fieldIdx := 0; fieldIdx+1 < len(fieldsNode.Content); fieldIdx += 2 {
+			keyNode := fieldsNode.Content[fieldIdx]
+			if positionWithinRange(position, nodeRange(keyNode)) {
+				return entityName, keyNode
+			}
+		}
+	}
+	return "", nil
+}
+
+func configEntityNode(doc *yaml.Node, typeName string) *yaml.Node {
+	_, entitiesNode := mappingEntry(documentRoot(doc), "entities")
+	_, entityNode := mappingEntry(entitiesNode, typeName)
+	if entityNode == nil || entityNode.Kind != yaml.MappingNode {
+		return nil
+	}
+	return entityNode
+}
+
+func identifierFieldNode(entityNode *yaml.Node) *yaml.Node {
+	_, identifierNode := mappingEntry(entityNode, "identifier")
+	if identifierNode == nil {
+		return nil
+	}
+	if identifierNode.Kind == yaml.ScalarNode {
+		return identifierNode
+	}
+	_, fieldNode := mappingEntry(identifierNode, "field")
+	if fieldNode == nil || fieldNode.Kind != yaml.ScalarNode {
+		return nil
+	}
+	return fieldNode
+}
+
+func (e renameEdits) add(path string, rng protocol.Range, newText string) {
+	for _, existing := range e[path] {
+		if existing.Range == rng {
+			return
+		}
+	}
+	e[path] = append(e[path], protocol.TextEdit{Range: rng, NewText: newText})
+}
+
+func (e renameEdits) workspaceEdit() *protocol.WorkspaceEdit {
+	if len(e) == 0 {
+		return nil
+	}
+
+	changes := make(map[protocol.DocumentURI][]protocol.TextEdit, len(e))
+	for path, edits := range e {
+		sort.Slice(edits, func(i, j int) bool {
+			return compareRanges(edits[i].Range, edits[j].Range) < 0
+		})
+		changes[protocol.DocumentURI(uri.File(path))] = edits
+	}
+	return &protocol.WorkspaceEdit{Changes: changes}
+}
@@ -0,0 +1,130 @@
+package lsp
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func TestHandleRenameUpdatesIdentifierDeclarationsAndUsages(t *testing.T) {
+	server, root := initializeExampleFullServer(t)
+	userPath := filepath.Join(root, "data", "users", "alice.yaml")
+	userContent := readFile(t, userPath)
+	postPath := filepath.Join(root, "data", "posts", "launch.yaml")
+	postContent := readFile(t, postPath)
+
+	position := positionInContent(t, postContent, "user-alice")
+
+	var prepared prepareRenameResult
+	callServer(t, server, protocol.MethodTextDocumentPrepareRename, 2, &protocol.PrepareRenameParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(postPath))},
+			Position:     position,
+		},
+	}, &prepared)
+	if prepared.Placeholder != "user-alice" {
+		t.Fatalf("expected placeholder user-alice, got %q", prepared.Placeholder)
+	}
+
+	var edit protocol.WorkspaceEdit
+	callServer(t, server, protocol.MethodTextDocumentRename, 3, &protocol.RenameParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(postPath))},
+			Position:     position,
+		},
+		NewName: "user-alicia",
+	}, &edit)
+
+	if len(edit.Changes) != 2 {
+		t.Fatalf("expected edits in two files, got %#v", edit.Changes)
+	}
+	expectRenameEdit(t, edit, userPath, scalarFieldValueRange(t, userContent, "id"), "user-alicia")
+	expectRenameEdit(t, edit, postPath, scalarFieldValueRange(t, postContent, "author"), "user-alicia")
+}
+
+func TestHandleRenameRejectsExistingIdentifier(t *testing.T) {
+	server, root := initializeExampleFullServer(t)
+	userPath := filepath.Join(root, "data", "users", "alice.yaml")
+	userContent := readFile(t, userPath)
+
+	req := &protocol.RenameParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(userPath))},
+			Position:     positionInContent(t, userContent, "user-alice"),
+		},
+		NewName: "user-bob",
+	}
+
+	result, err := server.rename(t.Context(), req)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected duplicate identifier error, got result %#v err %v", result, err)
+	}
+}
+
+func TestHandleRenameUpdatesFieldNamesAcrossDataFiles(t *testing.T) {
+	t.Run("config and yaml/json data", func(t *testing.T) {
+		server, root := initializeExampleFullServer(t)
+		configPath := filepath.Join(root, "entities", "User.yaml")
+		configContent := readFile(t, configPath)
+		alicePath := filepath.Join(root, "data", "users", "alice.yaml")
+		bobPath := filepath.Join(root, "data", "users", "bob.json")
+
+		var edit protocol.WorkspaceEdit
+		callServer(t, server, protocol.MethodTextDocumentRename, 2, &protocol.RenameParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(configPath))},
+				Position:     positionInContent(t, configContent, "name:"),
+			},
+			NewName: "display_name",
+		}, &edit)
+
+		for _, path := range []string{configPath, alicePath, bobPath} {
+			edits := edit.Changes[protocol.DocumentURI(uri.File(path))]
+			if len(edits) != 1 || edits[0].NewText != "display_name" {
+				t.Fatalf("expected one display_name edit for %s, got %#v", path, edits)
+			}
+		}
+
+		bobEdit := edit.Changes[protocol.DocumentURI(uri.File(bobPath))][0]
+		bobPosition := positionInContent(t, readFile(t, bobPath), "name")
+		if bobEdit.Range.Start != bobPosition {
+			t.Fatalf("expected JSON key edit to skip the opening quote, got %+v", bobEdit.Range)
+		}
+	})
+
+	t.Run("inherited field", func(t *testing.T) {
+		server, root := initializeInheritanceServer(t)
+		configPath := filepath.Join(root, "mergeway.yaml")
+		configContent := readFile(t, configPath)
+		dogPath := filepath.Join(root, "data", "dogs", "dog.yaml")
+
+		var edit protocol.WorkspaceEdit
+		callServer(t, server, protocol.MethodTextDocumentRename, 2, &protocol.RenameParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(configPath))},
+				Position:     positionInContent(t, configContent, "name:"),
+			},
+			NewName: "label",
+		}, &edit)
+
+		dogEdits := edit.Changes[protocol.DocumentURI(uri.File(dogPath))]
+		if len(dogEdits) != 1 || dogEdits[0].Range.Start.Line != 1 {
+			t.Fatalf("expected descendant data edit on the name line, got %#v", dogEdits)
+		}
+	})
+}
+
+func expectRenameEdit(t *testing.T, edit protocol.WorkspaceEdit, path string, rng protocol.Range, newText string) {
+	t.Helper()
+
+	edits := edit.Changes[protocol.DocumentURI(uri.File(path))]
+	for _, candidate := range edits {
+		if candidate.Range == rng && candidate.NewText == newText {
+			return
+		}
+	}
+	t.Fatalf("expected edit %+v -> %q for %s, got %#v", rng, newText, path, edits)
+}
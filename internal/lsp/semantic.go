@@ -71,6 +71,9 @@ func (s *Server) completion(ctx context.Context, params *protocol.CompletionPara
 		return nil, err
 	}
 
+	if site := completionSiteForAnalysis(analysis); site != nil {
+		return &protocol.CompletionList{Items: site.completionItems(analysis.root, s.supportsSnippets())}, nil
+	}
 	items := completionItemsForAnalysis(analysis)
 	return &protocol.CompletionList{Items: items}, nil
 }
@@ -82,6 +85,13 @@ func (s *Server) hover(ctx context.Context, params *protocol.HoverParams) (*prot
 	}
 
 	content := hoverContentForAnalysis(analysis)
+	var hoverRange *protocol.Range
+	if content == "" {
+		content, hoverRange = s.configPathHover(analysis)
+	}
+	if content == "" {
+		content, hoverRange = configEntityHover(analysis)
+	}
 	if content == "" {
 		return nil, nil
 	}
@@ -91,6 +101,7 @@ func (s *Server) hover(ctx context.Context, params *protocol.HoverParams) (*prot
 			Kind:  protocol.Markdown,
 			Value: content,
 		},
+		Range: hoverRange,
 	}
 	if analysis.data != nil {
 		result.Range = &analysis.data.positionRange
@@ -350,10 +361,10 @@ func populateDataLineContext(analysis *documentAnalysis, result *dataAnalysis) {
 	if result.onFieldKey || result.onFieldValue {
 		if !result.onFieldValue && strings.Contains(trimmed, ":") {
 			parts := strings.SplitN(trimmed, ":", 2)
-			result.keyPrefix = strings.TrimSpace(parts[0])
+			result.keyPrefix = unquotedPrefix(parts[0])
 		}
 		if result.onFieldValue {
-			result.valuePrefix = trailingScalarPrefix(beforeCursor)
+			result.valuePrefix = unquotedPrefix(trailingScalarPrefix(beforeCursor))
 		}
 		return
 	}
@@ -366,7 +377,7 @@ func populateDataLineContext(analysis *documentAnalysis, result *dataAnalysis) {
 	trimmedLine := strings.TrimSpace(line)
 	if trimmedLine == "" || indentation(line) == objectIndent {
 		result.pendingField = true
-		result.keyPrefix = strings.TrimSpace(strings.TrimSuffix(trimmed, ":"))
+		result.keyPrefix = unquotedPrefix(strings.TrimSuffix(trimmed, ":"))
 		result.positionRange = singlePointRange(analysis.content, lineNo, int(analysis.position.Character))
 	}
 
@@ -401,7 +412,7 @@ func completionItemsForAnalysis(analysis *documentAnalysis) []protocol.Completio
 			return enumCompletionItems(analysis.data.fieldDef, analysis.data.valuePrefix)
 		}
 		if analysis.data.fieldDef.IsReference() {
-			return referenceCompletionItems(analysis.root, analysis.data.fieldDef, analysis.data.valuePrefix)
+			return referenceCompletionItems(analysis.root, analysis.data.fieldDef, analysis.data.valuePrefix, nil)
 		}
 	}
 	return nil
@@ -452,22 +463,6 @@ func enumCompletionItems(fieldDef *config.FieldDefinition, prefix string) []prot
 	return items
 }
 
-func referenceCompletionItems(root *workspace.RootRuntime, fieldDef *config.FieldDefinition, prefix string) []protocol.CompletionItem {
-	ids := referenceIDs(root, fieldDef)
-	items := make([]protocol.CompletionItem, 0, len(ids))
-	for _, id := range ids {
-		if prefix != "" && !strings.HasPrefix(id, prefix) {
-			continue
-		}
-		items = append(items, protocol.CompletionItem{
-			Label:         id,
-			Kind:          protocol.CompletionItemKindReference,
-			Documentation: referenceCompletionDocumentation(root, fieldDef, id),
-		})
-	}
-	return items
-}
-
 func hoverContentForAnalysis(analysis *documentAnalysis) string {
 	if analysis == nil || analysis.data == nil {
 		return ""
@@ -555,14 +550,6 @@ func referenceIDs(root *workspace.RootRuntime, fieldDef *config.FieldDefinition)
 	return ids
 }
 
-func referenceCompletionDocumentation(root *workspace.RootRuntime, fieldDef *config.FieldDefinition, id string) string {
-	matches := resolveReferenceObjects(root, fieldDef, id)
-	if len(matches) != 1 {
-		return fieldDocumentation(fieldDef)
-	}
-	return objectSummaryMarkdown(matches[0], root)
-}
-
 func fieldDocumentation(fieldDef *config.FieldDefinition) string {
 	if fieldDef == nil {
 		return ""
@@ -617,45 +604,53 @@ func typeDocumentation(typeDef *config.TypeDefinition) string {
 }
 
 func (s *Server) objectIdentifierRange(root *workspace.RootRuntime, obj *data.Object) (protocol.Range, bool) {
-	if obj == nil {
+	valueNode := s.objectIdentifierNode(root, obj)
+	if valueNode == nil {
 		return protocol.Range{}, false
 	}
+	return nodeRange(valueNode), true
+}
+
+func (s *Server) objectIdentifierNode(root *workspace.RootRuntime, obj *data.Object) *yaml.Node {
+	if obj == nil {
+		return nil
+	}
 
 	content, err := s.documentContent(obj.File)
 	if err != nil {
-		return protocol.Range{}, false
+		return nil
 	}
 
 	doc, ok := parseDocumentNode(content)
 	if !ok {
-		return protocol.Range{}, false
+		return nil
 	}
 
 	rootNode := documentRoot(doc)
 	cfg := validationConfig(root)
 	if cfg == nil {
-		return protocol.Range{}, false
+		return nil
 	}
 	typeDef := cfg.Types[obj.Type]
 	if typeDef == nil {
-		return protocol.Range{}, false
+		return nil
 	}
 
 	if _, itemsNode := mappingEntry(rootNode, "items"); itemsNode != nil && itemsNode.Kind == yaml.SequenceNode {
 		for _, itemNode := range itemsNode.Content {
 			if itemMatchesIdentifier(itemNode, typeDef.Identifier.Field, obj.ID) {
 				if _, valueNode := mappingEntry(itemNode, typeDef.Identifier.Field); valueNode != nil {
-					return nodeRange(valueNode), true
+					return valueNode
 				}
 			}
 		}
 	}
 
 	if _, valueNode := mappingEntry(rootNode, typeDef.Identifier.Field); valueNode != nil && valueNode.Value == obj.ID {
-		return nodeRange(valueNode), true
+		return valueNode
 	}
 
-	return protocol.Range{}, false
+	return nil
 }
 
 func itemMatchesIdentifier(node *yaml.Node, idField, want string) bool {
@@ -769,6 +764,15 @@ func trailingScalarPrefix(line string) string {
 	return strings.TrimSpace(strings.TrimPrefix(line, "-"))
 }
 
+// unquotedPrefix reduces a partially typed key or value to its last flow item
+// without quotes, as found in JSON files and flow-style or quoted YAML.
+func unquotedPrefix(value string) string {
+	if idx := strings.LastIndexAny(value, "[,"); idx >= 0 {
+		value = value[idx+1:]
+	}
+	return strings.Trim(strings.TrimSpace(value), `"'`)
+}
+
 func enclosingSequenceField(objectNode *yaml.Node, line int) string {
 	if objectNode == nil || objectNode.Kind != yaml.MappingNode {
 		return ""
@@ -0,0 +1,409 @@
+package lsp
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mergewayhq/mergeway-cli/internal/config"
+	"github.com/mergewayhq/mergeway-cli/internal/workspace"
+	"go.lsp.dev/protocol"
+	"gopkg.in/yaml.v3"
+)
+
+// Token type indices into semanticTokenLegend.TokenTypes.
+const (
+	semanticTypeClass uint32 = iota
+	semanticTypeType
+	semanticTypeProperty
+	semanticTypeVariable
+	semanticTypeEnumMember
+)
+
+// Token modifier bits matching semanticTokenLegend.TokenModifiers.
+const (
+	semanticModifierDeclaration uint32 = 1 << iota
+	semanticModifierReadonly
+	semanticModifierDangling
+)
+
+var semanticTokenLegend = protocol.SemanticTokensLegend{
+	TokenTypes: []protocol.SemanticTokenTypes{
+		protocol.SemanticTokenClass,
+		protocol.SemanticTokenType,
+		protocol.SemanticTokenProperty,
+		protocol.SemanticTokenVariable,
+		protocol.SemanticTokenEnumMember,
+	},
+	TokenModifiers: []protocol.SemanticTokenModifiers{
+		protocol.SemanticTokenModifierDeclaration,
+		protocol.SemanticTokenModifierReadonly,
+		"dangling",
+	},
+}
+
+type semanticTokensOptions struct {
+	Legend protocol.SemanticTokensLegend `json:"legend"`
+	Full   semanticTokensFullOptions     `json:"full"`
+}
+
+type semanticTokensFullOptions struct {
+	Delta bool `json:"delta"`
+}
+
+type semanticToken struct {
+	line      uint32
+	start     uint32
+	length    uint32
+	tokenType uint32
+	modifiers uint32
+}
+
+type semanticTokensResult struct {
+	resultID string
+	data     []uint32
+}
+
+func (s *Server) semanticTokensFull(ctx context.Context, params *protocol.SemanticTokensParams) (*protocol.SemanticTokens, error) {
+	path := params.TextDocument.URI.Filename()
+	data, err := s.documentSemanticTokens(path)
+	if err != nil {
+		return nil, err
+	}
+
+	result := s.storeSemanticTokens(path, data)
+	return &protocol.SemanticTokens{ResultID: result.resultID, Data: result.data}, nil
+}
+
+func (s *Server) semanticTokensDelta(ctx context.Context, params *protocol.SemanticTokensDeltaParams) (interface{}, error) {
+	path := params.TextDocument.URI.Filename()
+	data, err := s.documentSemanticTokens(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.semanticMu.Lock()
+	previous, ok := s.semanticResults[path]
+	s.semanticMu.Unlock()
+
+	result := s.storeSemanticTokens(path, data)
+	if !ok || previous.resultID != params.PreviousResultID {
+		return &protocol.SemanticTokens{ResultID: result.resultID, Data: result.data}, nil
+	}
+
+	return &protocol.SemanticTokensDelta{
+		ResultID: result.resultID,
+		Edits:    semanticTokensEdits(previous.data, result.data),
+	}, nil
+}
+
+func (s *Server) storeSemanticTokens(path string, data []uint32) semanticTokensResult {
+	s.semanticMu.Lock()
+	defer s.semanticMu.Unlock()
+
+	s.semanticResultSeq++
+	result := semanticTokensResult{
+		resultID: strconv.FormatUint(s.semanticResultSeq, 10),
+		data:     data,
+	}
+	s.semanticResults[path] = result
+	return result
+}
+
+// forgetSemanticTokens drops the result kept for path, which no later delta
+// request can refer to.
+func (s *Server) forgetSemanticTokens(path string) {
+	s.semanticMu.Lock()
+	defer s.semanticMu.Unlock()
+	delete(s.semanticResults, path)
+}
+
+// pruneSemanticTokens drops the results kept for files that are neither open
+// nor indexed by a root, such as files deleted or excluded since they were
+// last tokenized.
+func (s *Server) pruneSemanticTokens(snapshot *workspace.Snapshot) {
+	s.semanticMu.Lock()
+	defer s.semanticMu.Unlock()
+	for path := range s.semanticResults {
+		if _, ok := snapshot.Documents[path]; ok {
+			continue
+		}
+		if !snapshotIndexesPath(snapshot, path) {
+			delete(s.semanticResults, path)
+		}
+	}
+}
+
+func snapshotIndexesPath(snapshot *workspace.Snapshot, path string) bool {
+	for _, root := range snapshot.Roots {
+		if root.Index == nil {
+			continue
+		}
+		if _, ok := root.Index.DataFiles[path]; ok {
+			return true
+		}
+		if _, ok := root.Index.ConfigFiles[path]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) documentSemanticTokens(path string) ([]uint32, error) {
+	analysis, typeDef, err := s.dataDocument(path)
+	if err != nil {
+		return nil, err
+	}
+	if typeDef != nil {
+		return encodeSemanticTokens(dataSemanticTokens(analysis, typeDef)), nil
+	}
+
+	if s.runtime == nil {
+		return []uint32{}, nil
+	}
+	root := s.runtime.RootByPath(path)
+	if root == nil || root.Index == nil {
+		return []uint32{}, nil
+	}
+	if _, ok := root.Index.ConfigFiles[path]; !ok {
+		return []uint32{}, nil
+	}
+
+	content, err := s.documentContent(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := parseDocumentNode(content)
+	if !ok {
+		return []uint32{}, nil
+	}
+	return encodeSemanticTokens(configSemanticTokens(doc, validationConfig(root))), nil
+}
+
+func dataSemanticTokens(analysis *documentAnalysis, typeDef *config.TypeDefinition) []semanticToken {
+	var tokens []semanticToken
+
+	root := documentRoot(analysis.doc)
+	if _, typeNode := mappingEntry(root, "type"); typeNode != nil && analysis.cfg.Types[typeNode.Value] != nil {
+		tokens = appendScalarToken(tokens, typeNode, semanticTypeClass, 0)
+	}
+
+	for _, objectNode := range documentObjectNodes(analysis.doc) {
+		if objectNode == nil || objectNode.Kind != yaml.MappingNode {
+			continue
+		}
+		for idx := 0; idx+1 < len(objectNode.Content); idx += 2 {
+			keyNode := objectNode.Content[idx]
+			valueNode := objectNode.Content[idx+1]
+			fieldDef := typeDef.Fields[keyNode.Value]
+			if fieldDef == nil {
+				continue
+			}
+
+			var keyModifiers uint32
+			if fieldDef.Source.IsPathDerived() {
+				keyModifiers |= semanticModifierReadonly
+			}
+			tokens = appendScalarToken(tokens, keyNode, semanticTypeProperty, keyModifiers)
+
+			for _, node := range scalarValueNodes(valueNode) {
+				switch {
+				case keyNode.Value == typeDef.Identifier.Field:
+					tokens = appendScalarToken(tokens, node, semanticTypeVariable, semanticModifierDeclaration)
+				case fieldDef.IsReference():
+					var modifiers uint32
+					if len(resolveReferenceObjects(analysis.root, fieldDef, node.Value)) == 0 {
+						modifiers |= semanticModifierDangling
+					}
+					tokens = appendScalarToken(tokens, node, semanticTypeVariable, modifiers)
+				case len(fieldDef.Enum) > 0:
+					tokens = appendScalarToken(tokens, node, semanticTypeEnumMember, 0)
+				}
+			}
+		}
+	}
+	return tokens
+}
+
+func configSemanticTokens(doc *yaml.Node, cfg *config.Config) []semanticToken {
+	_, entitiesNode := mappingEntry(documentRoot(doc), "entities")
+	if entitiesNode == nil || entitiesNode.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var tokens []semanticToken
+	for idx := 0; idx+1 < len(entitiesNode.Content); idx += 2 {
+		tokens = appendScalarToken(tokens, entitiesNode.Content[idx], semanticTypeClass, semanticModifierDeclaration)
+
+		entityNode := entitiesNode.Content[idx+1]
+		if _, extendsNode := mappingEntry(entityNode, "extends"); extendsNode != nil {
+			tokens = appendScalarToken(tokens, extendsNode, semanticTypeClass, 0)
+		}
+		if identifierNode := identifierFieldNode(entityNode); identifierNode != nil && identifierNode.Value != config.PathIdentifierField {
+			tokens = appendScalarToken(tokens, identifierNode, semanticTypeProperty, 0)
+		}
+		if _, fieldsNode := mappingEntry(entityNode, "fields"); fieldsNode != nil {
+			tokens = append(tokens, configFieldTokens(cfg, fieldsNode)...)
+		}
+	}
+	return tokens
+}
+
+func configFieldTokens(cfg *config.Config, fieldsNode *yaml.Node) []semanticToken {
+	if fieldsNode == nil || fieldsNode.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var tokens []semanticToken
+	for idx := 0; idx+1 < len(fieldsNode.Content); idx += 2 {
+		keyNode := fieldsNode.Content[idx]
+		valueNode := fieldsNode.Content[idx+1]
+
+		modifiers := semanticModifierDeclaration
+		if _, sourceNode := mappingEntry(valueNode, "source"); sourceNode != nil {
+			modifiers |= semanticModifierReadonly
+		}
+		tokens = appendScalarToken(tokens, keyNode, semanticTypeProperty, modifiers)
+
+		switch valueNode.Kind {
+		case yaml.ScalarNode:
+			tokens = append(tokens, fieldTypeTokens(cfg, valueNode)...)
+		case yaml.MappingNode:
+			if _, typeNode := mappingEntry(valueNode, "type"); typeNode != nil {
+				tokens = append(tokens, fieldTypeTokens(cfg, typeNode)...)
+			}
+			if _, enumNode := mappingEntry(valueNode, "enum"); enumNode != nil {
+				for _, node := range scalarValueNodes(enumNode) {
+					tokens = appendScalarToken(tokens, node, semanticTypeEnumMember, 0)
+				}
+			}
+			for _, nestedKey := range []string{"properties", "fields"} {
+				if _, nestedNode := mappingEntry(valueNode, nestedKey); nestedNode != nil {
+					tokens = append(tokens, configFieldTokens(cfg, nestedNode)...)
+				}
+			}
+		}
+	}
+	return tokens
+}
+
+// fieldTypeTokens classifies each member of a field type expression, which may
+// be a primitive, an entity name, or a reference union such as "User | Team".
+func fieldTypeTokens(cfg *config.Config, node *yaml.Node) []semanticToken {
+	if node == nil || node.Kind != yaml.ScalarNode || strings.Contains(node.Value, "\n") {
+		return nil
+	}
+
+	start := nodeRange(node).Start
+	var tokens []semanticToken
+	offset := 0
+	for _, part := range strings.Split(node.Value, "|") {
+		name := strings.TrimSpace(part)
+		column := offset + strings.Index(part, name)
+		offset += len(part) + 1
+		if name == "" {
+			continue
+		}
+
+		tokenType := semanticTypeClass
+		switch {
+		case containsString(primitiveFieldTypes, name):
+			tokenType = semanticTypeType
+		case cfg == nil || cfg.Types[name] == nil:
+			continue
+		}
+		tokens = append(tokens, semanticToken{
+			line:      start.Line,
+			start:     start.Character + uint32(column),
+			length:    uint32(len(name)),
+			tokenType: tokenType,
+		})
+	}
+	return tokens
+}
+
+func scalarValueNodes(node *yaml.Node) []*yaml.Node {
+	if node == nil {
+		return nil
+	}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return []*yaml.Node{node}
+	case yaml.SequenceNode:
+		var nodes []*yaml.Node
+		for _, child := range node.Content {
+			if child != nil && child.Kind == yaml.ScalarNode {
+				nodes = append(nodes, child)
+			}
+		}
+		return nodes
+	default:
+		return nil
+	}
+}
+
+func appendScalarToken(tokens []semanticToken, node *yaml.Node, tokenType, modifiers uint32) []semanticToken {
+	if node == nil || node.Kind != yaml.ScalarNode || node.Value == "" || strings.Contains(node.Value, "\n") {
+		return tokens
+	}
+	rng := nodeRange(node)
+	return append(tokens, semanticToken{
+		line:      rng.Start.Line,
+		start:     rng.Start.Character,
+		length:    rng.End.Character - rng.Start.Character,
+		tokenType: tokenType,
+		modifiers: modifiers,
+	})
+}
+
+// encodeSemanticTokens sorts tokens by position and applies the LSP relative
+// encoding of five integers per token.
+func encodeSemanticTokens(tokens []semanticToken) []uint32 {
+	sort.Slice(tokens, func(i, j int) bool {
+		if tokens[i].line != tokens[j].line {
+			return tokens[i].line < tokens[j].line
+		}
+		return tokens[i].start < tokens[j].start
+	})
+
+	data := make([]uint32, 0, len(tokens)*5)
+	var prevLine, prevStart uint32
+	for idx, token := range tokens {
+		if idx > 0 && token.line == prevLine && token.start < prevStart+data[len(data)-3] {
+			continue
+		}
+		deltaStart := token.start
+		if token.line == prevLine {
+			deltaStart = token.start - prevStart
+		}
+		data = append(data, token.line-prevLine, deltaStart, token.length, token.tokenType, token.modifiers)
+		prevLine = token.line
+		prevStart = token.start
+	}
+	return data
+}
+
+// semanticTokensEdits returns a single edit replacing the differing middle of
+// two encoded token arrays, or no edits when they are identical.
+func semanticTokensEdits(previous, current []uint32) []protocol.SemanticTokensEdit {
+	prefix := 0
+	for prefix < len(previous) && prefix < len(current) && previous[prefix] == current[prefix] {
+		prefix++
+	}
+	if prefix == len(previous) && prefix == len(current) {
+		return []protocol.SemanticTokensEdit{}
+	}
+
+	suffix := 0
+	for suffix < len(previous)-prefix && suffix < len(current)-prefix &&
+		previous[len(previous)-1-suffix] == current[len(current)-1-suffix] {
+		suffix++
+	}
+
+	return []protocol.SemanticTokensEdit{{
+		Start:       uint32(prefix),
+		DeleteCount: uint32(len(previous) - prefix - suffix),
+		Data:        append([]uint32{}, current[prefix:len(current)-suffix]...),
+	}}
+}
@@ -0,0 +1,223 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+type decodedSemanticToken struct {
+	position  protocol.Position
+	text      string
+	tokenType protocol.SemanticTokenTypes
+	modifiers []protocol.SemanticTokenModifiers
+}
+
+func TestHandleSemanticTokensFullClassifiesDataFile(t *testing.T) {
+	server, root := initializeExampleFullServer(t)
+	postPath := filepath.Join(root, "data", "posts", "launch.yaml")
+	postContent := readFile(t, postPath)
+
+	tokens := requestSemanticTokens(t, server, postPath, postContent)
+
+	expectSemanticToken(t, tokens, "id", protocol.SemanticTokenProperty)
+	expectSemanticToken(t, tokens, "post-001", protocol.SemanticTokenVariable, protocol.SemanticTokenModifierDeclaration)
+	expectSemanticToken(t, tokens, "PUBLISHED", protocol.SemanticTokenEnumMember)
+	expectSemanticToken(t, tokens, "user-alice", protocol.SemanticTokenVariable)
+	expectSemanticToken(t, tokens, "tag-product", protocol.SemanticTokenVariable)
+	if findSemanticToken(tokens, "Launch Day") != nil {
+		t.Fatalf("expected plain string values to stay unclassified, got %#v", tokens)
+	}
+}
+
+func TestHandleSemanticTokensFullMarksDanglingReferences(t *testing.T) {
+	server, root := initializeExampleFullServer(t)
+	postPath := filepath.Join(root, "data", "posts", "launch.yaml")
+	postContent := strings.Replace(readFile(t, postPath), "author: user-alice", "author: user-missing", 1)
+	openDocument(t, server, postPath, "yaml", 1, postContent)
+
+	tokens := requestSemanticTokens(t, server, postPath, postContent)
+
+	expectSemanticToken(t, tokens, "user-missing", protocol.SemanticTokenVariable, "dangling")
+	expectSemanticToken(t, tokens, "tag-product", protocol.SemanticTokenVariable)
+}
+
+func TestHandleSemanticTokensFullClassifiesConfigFile(t *testing.T) {
+	server, root := initializeExampleFullServer(t)
+	configPath := filepath.Join(root, "entities", "Post.yaml")
+	configContent := readFile(t, configPath)
+
+	tokens := requestSemanticTokens(t, server, configPath, configContent)
+
+	expectSemanticToken(t, tokens, "Post", protocol.SemanticTokenClass, protocol.SemanticTokenModifierDeclaration)
+	expectSemanticToken(t, tokens, "author", protocol.SemanticTokenProperty, protocol.SemanticTokenModifierDeclaration)
+	expectSemanticToken(t, tokens, "User", protocol.SemanticTokenClass)
+	expectSemanticToken(t, tokens, "string", protocol.SemanticTokenType)
+	expectSemanticToken(t, tokens, "DRAFT", protocol.SemanticTokenEnumMember)
+}
+
+func TestHandleSemanticTokensDeltaReturnsEdits(t *testing.T) {
+	server, root := initializeExampleFullServer(t)
+	postPath := filepath.Join(root, "data", "posts", "launch.yaml")
+	postContent := readFile(t, postPath)
+	documentURI := protocol.DocumentURI(uri.File(postPath))
+
+	var full protocol.SemanticTokens
+	callServer(t, server, protocol.MethodSemanticTokensFull, 2, &protocol.SemanticTokensParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: documentURI},
+	}, &full)
+	if full.ResultID == "" {
+		t.Fatalf("expected result id on full response")
+	}
+
+	var unchanged protocol.SemanticTokensDelta
+	callServer(t, server, protocol.MethodSemanticTokensFullDelta, 3, &protocol.SemanticTokensDeltaParams{
+		TextDocument:     protocol.TextDocumentIdentifier{URI: documentURI},
+		PreviousResultID: full.ResultID,
+	}, &unchanged)
+	if unchanged.ResultID == "" || unchanged.ResultID == full.ResultID || len(unchanged.Edits) != 0 {
+		t.Fatalf("expected empty delta with new result id, got %#v", unchanged)
+	}
+
+	openDocument(t, server, postPath, "yaml", 2, strings.Replace(postContent, "status: PUBLISHED\n", "", 1))
+
+	var delta protocol.SemanticTokensDelta
+	callServer(t, server, protocol.MethodSemanticTokensFullDelta, 4, &protocol.SemanticTokensDeltaParams{
+		TextDocument:     protocol.TextDocumentIdentifier{URI: documentURI},
+		PreviousResultID: unchanged.ResultID,
+	}, &delta)
+	if len(delta.Edits) != 1 {
+		t.Fatalf("expected a single edit, got %#v", delta)
+	}
+
+	patched := append([]uint32{}, full.Data[:delta.Edits[0].Start]...)
+	patched = append(patched, delta.Edits[0].Data...)
+	patched = append(patched, full.Data[delta.Edits[0].Start+delta.Edits[0].DeleteCount:]...)
+	if len(patched) != len(full.Data)-10 {
+		t.Fatalf("expected removal of the status key and value tokens, got %v from %v", patched, full.Data)
+	}
+}
+
+func TestSemanticTokensResultsAreDroppedWithTheirFiles(t *testing.T) {
+	server, _, root := initializeCommandServer(t)
+	userPath := filepath.Join(root, "data", "users", "user-1.yaml")
+	postPath := filepath.Join(root, "data", "posts", "post-1.yaml")
+	openDocument(t, server, userPath, "yaml", 1, readFile(t, userPath))
+	requestSemanticTokens(t, server, userPath, readFile(t, userPath))
+	requestSemanticTokens(t, server, postPath, readFile(t, postPath))
+
+	callServer(t, server, protocol.MethodTextDocumentDidClose, 3, &protocol.DidCloseTextDocumentParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(userPath))},
+	}, (*struct{})(nil))
+	if got := semanticResultPaths(server); !reflect.DeepEqual(got, []string{postPath}) {
+		t.Fatalf("expected didClose to drop the closed file's tokens, got %v", got)
+	}
+
+	requestSemanticTokens(t, server, userPath, readFile(t, userPath))
+	if err := os.Remove(postPath); err != nil {
+		t.Fatalf("remove post: %v", err)
+	}
+	if err := server.runtime.Rescan(); err != nil {
+		t.Fatalf("Rescan: %v", err)
+	}
+	if err := server.runtime.FlushReload(); err != nil {
+		t.Fatalf("FlushReload: %v", err)
+	}
+	if got := semanticResultPaths(server); !reflect.DeepEqual(got, []string{userPath}) {
+		t.Fatalf("expected the reload to drop only the deleted file's tokens, got %v", got)
+	}
+}
+
+func TestSemanticTokensEdits(t *testing.T) {
+	edits := semanticTokensEdits([]uint32{1, 2, 3, 4, 5}, []uint32{1, 2, 9, 4, 5})
+	if len(edits) != 1 || edits[0].Start != 2 || edits[0].DeleteCount != 1 || len(edits[0].Data) != 1 || edits[0].Data[0] != 9 {
+		t.Fatalf("unexpected edits %#v", edits)
+	}
+
+	if edits := semanticTokensEdits([]uint32{1, 2}, []uint32{1, 2}); len(edits) != 0 {
+		t.Fatalf("expected no edits for identical data, got %#v", edits)
+	}
+}
+
+func requestSemanticTokens(t *testing.T, server *Server, path, content string) []decodedSemanticToken {
+	t.Helper()
+
+	var result protocol.SemanticTokens
+	callServer(t, server, protocol.MethodSemanticTokensFull, 2, &protocol.SemanticTokensParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(path))},
+	}, &result)
+	if len(result.Data)%5 != 0 {
+		t.Fatalf("expected token data in groups of five, got %d integers", len(result.Data))
+	}
+
+	lines := strings.Split(content, "\n")
+	var tokens []decodedSemanticToken
+	var line, start uint32
+	for idx := 0; idx < len(result.Data); idx += 5 {
+		if result.Data[idx] > 0 {
+			start = 0
+		}
+		line += result.Data[idx]
+		start += result.Data[idx+1]
+		length := result.Data[idx+2]
+
+		token := decodedSemanticToken{
+			position:  protocol.Position{Line: line, Character: start},
+			text:      lines[line][start : start+length],
+			tokenType: semanticTokenLegend.TokenTypes[result.Data[idx+3]],
+		}
+		for bit, modifier := range semanticTokenLegend.TokenModifiers {
+			if result.Data[idx+4]&(1<<bit) != 0 {
+				token.modifiers = append(token.modifiers, modifier)
+			}
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+func findSemanticToken(tokens []decodedSemanticToken, text string) *decodedSemanticToken {
+	for idx := range tokens {
+		if tokens[idx].text == text {
+			return &tokens[idx]
+		}
+	}
+	return nil
+}
+
+func expectSemanticToken(t *testing.T, tokens []decodedSemanticToken, text string, tokenType protocol.SemanticTokenTypes, modifiers ...protocol.SemanticTokenModifiers) {
+	t.Helper()
+
+	token := findSemanticToken(tokens, text)
+	if token == nil {
+		t.Fatalf("expected token for %q, got %#v", text, tokens)
+	}
+	if token.tokenType != tokenType {
+		t.Fatalf("expected %q to be %s, got %s", text, tokenType, token.tokenType)
+	}
+	if len(token.modifiers) != len(modifiers) {
+		t.Fatalf("expected %q modifiers %v, got %v", text, modifiers, token.modifiers)
+	}
+	for idx := range modifiers {
+		if token.modifiers[idx] != modifiers[idx] {
+			t.Fatalf("expected %q modifiers %v, got %v", text, modifiers, token.modifiers)
+		}
+	}
+}
+
+func semanticResultPaths(server *Server) []string {
+	server.semanticMu.Lock()
+	defer server.semanticMu.Unlock()
+	paths := make([]string, 0, len(server.semanticResults))
+	for path := range server.semanticResults {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 
 	"github.com/mergewayhq/mergeway-cli/internal/version"
 	"github.com/mergewayhq/mergeway-cli/internal/workspace"
@@ -23,6 +24,11 @@ type Options struct {
 	// PublishDiagnostics optionally overrides outbound diagnostics publishing.
 	// It is primarily intended for tests.
 	PublishDiagnostics func(context.Context, *protocol.PublishDiagnosticsParams) error
+
+	// CallClient optionally overrides outbound server-to-client requests such
+	// as capability registration. When result is non-nil the response is
+	// decoded into it. It is primarily intended for tests.
+	CallClient func(ctx context.Context, method string, params, result interface{}) error
 }
 
 // Server handles the minimal LSP lifecycle required for phase 3.
@@ -41,6 +47,41 @@ type Server struct {
 	publishDiagnostics diagnosticPublisher
 	publishMu          sync.Mutex
 	publishedPaths     map[string]struct{}
+	createFileEdits    bool
+	applyEdits         bool
+	showDocuments      bool
+	completionSnippets bool
+	pullDiagnostics    bool
+	diagnosticRefresh  bool
+	watchRegistration  bool
+	relativePatterns   bool
+	showReferences     bool
+	clientReady        bool
+	callClient         clientCaller
+	watchMu            sync.Mutex
+	watchKey           string
+	semanticMu         sync.Mutex
+	semanticResultSeq  uint64
+	semanticResults    map[string]semanticTokensResult
+	reportMu           sync.Mutex
+	reportDir          string
+	diagnosticsMu      sync.Mutex
+	diagnosticsCache   *cachedDiagnostics
+}
+
+// serverCapabilities extends the protocol package capabilities with providers
+// introduced after the LSP revision it models.
+type serverCapabilities struct {
+	protocol.ServerCapabilities
+
+	InlayHintProvider     bool               `json:"inlayHintProvider,omitempty"`
+	DiagnosticProvider    *diagnosticOptions `json:"diagnosticProvider,omitempty"`
+	TypeHierarchyProvider bool               `json:"typeHierarchyProvider,omitempty"`
+}
+
+type initializeResult struct {
+	Capabilities serverCapabilities   `json:"capabilities"`
+	ServerInfo   *protocol.ServerInfo `json:"serverInfo,omitempty"`
 }
 
 // Run serves LSP traffic over a stdio-compatible connection until the client
@@ -51,15 +92,27 @@ func Run(ctx context.Context, conn io.ReadWriteCloser, opts Options) (int, error
 	}
 
 	server := NewServer(opts)
+	defer server.removeReports()
 	stream := jsonrpc2.NewStream(conn)
 	writeMu := &sync.Mutex{}
 	if server.publishDiagnostics == nil {
 		server.publishDiagnostics = streamDiagnosticPublisher(stream, writeMu)
 	}
+	calls := newPendingCalls()
+	if server.callClient == nil {
+		server.callClient = streamClientCaller(stream, writeMu, calls)
+	}
 	handler := protocol.CancelHandler(jsonrpc2.ReplyHandler(server.Handle))
 
+	// Messages are read on their own goroutine so that a handler awaiting a
+	// client response does not block the read of that response.
+	inbox := newMessageInbox()
+	done := make(chan struct{})
+	defer close(done)
+	go readMessages(ctx, stream, calls, inbox, done)
+
 	for {
-		msg, _, err := stream.Read(ctx)
+		req, err := inbox.next()
 		if err != nil {
 			switch {
 			case errors.Is(err, io.EOF), isClosedConnError(err), errors.Is(err, context.Canceled):
@@ -69,11 +122,6 @@ func Run(ctx context.Context, conn io.ReadWriteCloser, opts Options) (int, error
 			}
 		}
 
-		req, ok := msg.(jsonrpc2.Request)
-		if !ok {
-			continue
-		}
-
 		err = handler(ctx, replier(stream, writeMu, req), req)
 		switch {
 		case err == nil:
@@ -86,6 +134,88 @@ func Run(ctx context.Context, conn io.ReadWriteCloser, opts Options) (int, error
 	}
 }
 
+// readMessages reads stream until it fails. Responses are handed to the
+// pending call they answer; requests and notifications are queued in inbox
+// in the order they arrived.
+func readMessages(ctx context.Context, stream jsonrpc2.Stream, calls *pendingCalls, inbox *messageInbox, done <-chan struct{}) {
+	for {
+		msg, _, err := stream.Read(ctx)
+		if err != nil {
+			calls.fail(err)
+			inbox.fail(err)
+			return
+		}
+
+		switch msg := msg.(type) {
+		case *jsonrpc2.Response:
+			calls.deliver(msg)
+		case jsonrpc2.Request:
+			inbox.push(msg)
+		}
+
+		select {
+		case <-done:
+			return
+		default:
+		}
+	}
+}
+
+// messageInbox is an unbounded queue of inbound requests. It never blocks the
+// reader, so responses queued behind a request are still delivered.
+type messageInbox struct {
+	mu       sync.Mutex
+	requests []jsonrpc2.Request
+	err      error
+	ready    chan struct{}
+}
+
+func newMessageInbox() *messageInbox {
+	return &messageInbox{ready: make(chan struct{}, 1)}
+}
+
+func (q *messageInbox) push(req jsonrpc2.Request) {
+	q.mu.Lock()
+	q.requests = append(q.requests, req)
+	q.mu.Unlock()
+	q.signal()
+}
+
+func (q *messageInbox) fail(err error) {
+	q.mu.Lock()
+	q.err = err
+	q.mu.Unlock()
+	q.signal()
+}
+
+func (q *messageInbox) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// next returns the oldest queued request, waiting for one to arrive. Once the
+// queue is drained after a read failure, it returns that failure.
+func (q *messageInbox) next() (jsonrpc2.Request, error) {
+	for {
+		q.mu.Lock()
+		if len(q.requests) > 0 {
+			req := q.requests[0]
+			q.requests[0] = nil
+			q.requests = q.requests[1:]
+			q.mu.Unlock()
+			return req, nil
+		}
+		err := q.err
+		q.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		<-q.ready
+	}
+}
+
 // NewServer constructs a minimal mergeway LSP lifecycle server.
 func NewServer(opts Options) *Server {
 	logger := opts.Logger
@@ -98,7 +228,9 @@ func NewServer(opts Options) *Server {
 		exitCode:           1,
 		trace:              protocol.TraceOff,
 		publishDiagnostics: opts.PublishDiagnostics,
+		callClient:         opts.CallClient,
 		publishedPaths:     make(map[string]struct{}),
+		semanticResults:    make(map[string]semanticTokensResult),
 	}
 }
 
@@ -128,6 +260,8 @@ func (s *Server) Handle(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc
 		return s.handleDidChange(ctx, reply, req)
 	case protocol.MethodTextDocumentDidClose:
 		return s.handleDidClose(ctx, reply, req)
+	case protocol.MethodWorkspaceDidChangeWatchedFiles:
+		return s.handleDidChangeWatchedFiles(ctx, reply, req)
 	case protocol.MethodTextDocumentCompletion:
 		return s.handleCompletion(ctx, reply, req)
 	case protocol.MethodTextDocumentHover:
@@ -142,6 +276,32 @@ func (s *Server) Handle(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc
 		return s.handleWorkspaceSymbol(ctx, reply, req)
 	case protocol.MethodTextDocumentCodeAction:
 		return s.handleCodeAction(ctx, reply, req)
+	case protocol.MethodTextDocumentPrepareRename:
+		return s.handlePrepareRename(ctx, reply, req)
+	case protocol.MethodTextDocumentRename:
+		return s.handleRename(ctx, reply, req)
+	case methodTextDocumentInlayHint:
+		return s.handleInlayHint(ctx, reply, req)
+	case protocol.MethodTextDocumentCodeLens:
+		return s.handleCodeLens(ctx, reply, req)
+	case protocol.MethodTextDocumentDocumentLink:
+		return s.handleDocumentLink(ctx, reply, req)
+	case protocol.MethodSemanticTokensFull:
+		return s.handleSemanticTokensFull(ctx, reply, req)
+	case protocol.MethodSemanticTokensFullDelta:
+		return s.handleSemanticTokensDelta(ctx, reply, req)
+	case methodTextDocumentPrepareTypeHierarchy:
+		return s.handlePrepareTypeHierarchy(ctx, reply, req)
+	case methodTypeHierarchySupertypes:
+		return s.handleTypeHierarchySupertypes(ctx, reply, req)
+	case methodTypeHierarchySubtypes:
+		return s.handleTypeHierarchySubtypes(ctx, reply, req)
+	case protocol.MethodWorkspaceExecuteCommand:
+		return s.handleExecuteCommand(ctx, reply, req)
+	case methodTextDocumentDiagnostic:
+		return s.handleDocumentDiagnostic(ctx, reply, req)
+	case methodWorkspaceDiagnostic:
+		return s.handleWorkspaceDiagnostic(ctx, reply, req)
 	default:
 		if !s.isInitialized() {
 			return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.ServerNotInitialized, "server not initialized"))
@@ -172,6 +332,15 @@ func (s *Server) handleInitialize(ctx context.Context, reply jsonrpc2.Replier, r
 	s.trace = params.Trace
 	s.rootURI = resolveRootURI(&params, legacy)
 	s.workspaceFolders = resolveWorkspaceFolders(&params)
+	s.createFileEdits = supportsCreateFileEdits(params.Capabilities)
+	s.applyEdits = supportsApplyEdit(params.Capabilities)
+	s.showDocuments = supportsShowDocument(params.Capabilities)
+	s.completionSnippets = supportsCompletionSnippets(params.Capabilities)
+	s.pullDiagnostics = legacy.Capabilities.TextDocument.Diagnostic != nil
+	s.diagnosticRefresh = legacy.Capabilities.Workspace.Diagnostics.RefreshSupport
+	s.watchRegistration = supportsWatchedFileRegistration(params.Capabilities)
+	s.relativePatterns = legacy.Capabilities.Workspace.DidChangeWatchedFiles.RelativePatternSupport
+	s.showReferences = supportsShowReferencesCommand(legacy.InitializationOptions)
 	roots, err := workspace.OpenRoots(resolveRootCandidates(s.rootURI, s.workspaceFolders))
 	if err != nil {
 		s.mu.Unlock()
@@ -180,9 +349,7 @@ func (s *Server) handleInitialize(ctx context.Context, reply jsonrpc2.Replier, r
 	s.roots = roots
 	s.runtime = workspace.NewRuntime(roots)
 	s.runtime.SetReloadHook(func() {
-		if err := s.publishWorkspaceDiagnostics(context.Background()); err != nil {
-			s.logger.Error("publish_diagnostics", slog.Any("error", err))
-		}
+		s.afterReload(context.Background())
 	})
 	s.initialized = true
 	s.shutdownRequested = false
@@ -201,8 +368,8 @@ func (s *Server) handleInitialize(ctx context.Context, reply jsonrpc2.Replier, r
 		slog.String("trace", string(s.trace)),
 	)
 
-	result := &protocol.InitializeResult{
-		Capabilities: protocol.ServerCapabilities{
+	result := &initializeResult{
+		Capabilities: serverCapabilities{ServerCapabilities: protocol.ServerCapabilities{
 			TextDocumentSync: &protocol.TextDocumentSyncOptions{
 				OpenClose: true,
 				Change:    protocol.TextDocumentSyncKindFull,
@@ -214,12 +381,28 @@ func (s *Server) handleInitialize(ctx context.Context, reply jsonrpc2.Replier, r
 			DocumentSymbolProvider:  true,
 			WorkspaceSymbolProvider: true,
 			CodeActionProvider:      true,
+			RenameProvider:          &protocol.RenameOptions{PrepareProvider: true},
+			CodeLensProvider:        &protocol.CodeLensOptions{},
+			DocumentLinkProvider:    &protocol.DocumentLinkOptions{},
+			ExecuteCommandProvider:  &protocol.ExecuteCommandOptions{Commands: workspaceCommands},
+			SemanticTokensProvider: &semanticTokensOptions{
+				Legend: semanticTokenLegend,
+				Full:   semanticTokensFullOptions{Delta: true},
+			},
 			Workspace: &protocol.ServerCapabilitiesWorkspace{
 				WorkspaceFolders: &protocol.ServerCapabilitiesWorkspaceFolders{
 					Supported:           true,
 					ChangeNotifications: false,
 				},
 			},
+		},
+			InlayHintProvider:     true,
+			TypeHierarchyProvider: true,
+			DiagnosticProvider: &diagnosticOptions{
+				Identifier:            diagnosticSource,
+				InterFileDependencies: true,
+				WorkspaceDiagnostics:  true,
+			},
 		},
 		ServerInfo: &protocol.ServerInfo{
 			Name:    "mergeway-lsp",
@@ -235,9 +418,32 @@ func (s *Server) handleInitialized(ctx context.Context, reply jsonrpc2.Replier,
 		return reply(ctx, nil, fmt.Errorf("%s: %w", jsonrpc2.ErrParse, err))
 	}
 	s.logger.Debug("initialized")
+
+	s.mu.Lock()
+	s.clientReady = s.initialized
+	s.mu.Unlock()
+	if err := s.registerWatchedFiles(ctx); err != nil {
+		s.logger.Error("register_watched_files", slog.Any("error", err))
+	}
 	return reply(ctx, nil, nil)
 }
 
+// afterReload pushes diagnostics, or asks pull-model clients to re-pull them,
+// and keeps the watched-file registration in step with the include globs.
+func (s *Server) afterReload(ctx context.Context) {
+	s.pruneSemanticTokens(s.runtime.Snapshot())
+	if s.usesPullDiagnostics() {
+		if err := s.refreshPullDiagnostics(ctx); err != nil {
+			s.logger.Error("refresh_diagnostics", slog.Any("error", err))
+		}
+	} else if err := s.publishWorkspaceDiagnostics(ctx); err != nil {
+		s.logger.Error("publish_diagnostics", slog.Any("error", err))
+	}
+	if err := s.registerWatchedFiles(ctx); err != nil {
+		s.logger.Error("register_watched_files", slog.Any("error", err))
+	}
+}
+
 func (s *Server) handleShutdown(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
 	if len(req.Params()) > 0 {
 		return reply(ctx, nil, fmt.Errorf("expected no params: %w", jsonrpc2.ErrInvalidParams))
@@ -249,6 +455,7 @@ func (s *Server) handleShutdown(ctx context.Context, reply jsonrpc2.Replier, req
 	s.mu.Lock()
 	s.shutdownRequested = true
 	s.mu.Unlock()
+	s.removeReports()
 
 	s.logger.Debug("shutdown")
 	return reply(ctx, nil, nil)
@@ -346,7 +553,9 @@ func (s *Server) handleDidClose(ctx context.Context, reply jsonrpc2.Replier, req
 		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.ServerNotInitialized, "server not initialized"))
 	}
 
-	s.runtime.DidClose(params.TextDocument.URI.Filename())
+	path := params.TextDocument.URI.Filename()
+	s.runtime.DidClose(path)
+	s.forgetSemanticTokens(path)
 	return reply(ctx, nil, nil)
 }
 
@@ -462,6 +671,243 @@ func (s *Server) handleCodeAction(ctx context.Context, reply jsonrpc2.Replier, r
 	return reply(ctx, result, err)
 }
 
+func (s *Server) handlePrepareRename(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params protocol.PrepareRenameParams
+	if err := decodeParams(req.Params(), &params); err != nil {
+		return reply(ctx, nil, fmt.Errorf("%s: %w", jsonrpc2.ErrParse, err))
+	}
+	if !s.isInitialized() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.ServerNotInitialized, "server not initialized"))
+	}
+	if s.isShuttingDown() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.InvalidRequest, "server is shutting down"))
+	}
+
+	result, err := s.prepareRename(ctx, &params)
+	return reply(ctx, result, err)
+}
+
+func (s *Server) handleRename(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params protocol.RenameParams
+	if err := decodeParams(req.Params(), &params); err != nil {
+		return reply(ctx, nil, fmt.Errorf("%s: %w", jsonrpc2.ErrParse, err))
+	}
+	if !s.isInitialized() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.ServerNotInitialized, "server not initialized"))
+	}
+	if s.isShuttingDown() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.InvalidRequest, "server is shutting down"))
+	}
+
+	result, err := s.rename(ctx, &params)
+	return reply(ctx, result, err)
+}
+
+func (s *Server) handleInlayHint(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params inlayHintParams
+	if err := decodeParams(req.Params(), &params); err != nil {
+		return reply(ctx, nil, fmt.Errorf("%s: %w", jsonrpc2.ErrParse, err))
+	}
+	if !s.isInitialized() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.ServerNotInitialized, "server not initialized"))
+	}
+	if s.isShuttingDown() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.InvalidRequest, "server is shutting down"))
+	}
+
+	result, err := s.inlayHints(ctx, &params)
+	return reply(ctx, result, err)
+}
+
+func (s *Server) handleCodeLens(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params protocol.CodeLensParams
+	if err := decodeParams(req.Params(), &params); err != nil {
+		return reply(ctx, nil, fmt.Errorf("%s: %w", jsonrpc2.ErrParse, err))
+	}
+	if !s.isInitialized() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.ServerNotInitialized, "server not initialized"))
+	}
+	if s.isShuttingDown() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.InvalidRequest, "server is shutting down"))
+	}
+
+	result, err := s.codeLenses(ctx, &params)
+	return reply(ctx, result, err)
+}
+
+func (s *Server) handleDocumentLink(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params protocol.DocumentLinkParams
+	if err := decodeParams(req.Params(), &params); err != nil {
+		return reply(ctx, nil, fmt.Errorf("%s: %w", jsonrpc2.ErrParse, err))
+	}
+	if !s.isInitialized() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.ServerNotInitialized, "server not initialized"))
+	}
+	if s.isShuttingDown() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.InvalidRequest, "server is shutting down"))
+	}
+
+	result, err := s.documentLinks(ctx, &params)
+	return reply(ctx, result, err)
+}
+
+func (s *Server) handleSemanticTokensFull(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params protocol.SemanticTokensParams
+	if err := decodeParams(req.Params(), &params); err != nil {
+		return reply(ctx, nil, fmt.Errorf("%s: %w", jsonrpc2.ErrParse, err))
+	}
+	if !s.isInitialized() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.ServerNotInitialized, "server not initialized"))
+	}
+	if s.isShuttingDown() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.InvalidRequest, "server is shutting down"))
+	}
+
+	result, err := s.semanticTokensFull(ctx, &params)
+	return reply(ctx, result, err)
+}
+
+func (s *Server) handleSemanticTokensDelta(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params protocol.SemanticTokensDeltaParams
+	if err := decodeParams(req.Params(), &params); err != nil {
+		return reply(ctx, nil, fmt.Errorf("%s: %w", jsonrpc2.ErrParse, err))
+	}
+	if !s.isInitialized() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.ServerNotInitialized, "server not initialized"))
+	}
+	if s.isShuttingDown() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.InvalidRequest, "server is shutting down"))
+	}
+
+	result, err := s.semanticTokensDelta(ctx, &params)
+	return reply(ctx, result, err)
+}
+
+func (s *Server) handleDidChangeWatchedFiles(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params protocol.DidChangeWatchedFilesParams
+	if err := decodeParams(req.Params(), &params); err != nil {
+		return reply(ctx, nil, fmt.Errorf("%s: %w", jsonrpc2.ErrParse, err))
+	}
+
+	if s.runtime == nil {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.ServerNotInitialized, "server not initialized"))
+	}
+
+	s.logger.Debug("did_change_watched_files", slog.Int("changes", len(params.Changes)))
+	if err := s.runtime.Rescan(); err != nil {
+		return reply(ctx, nil, err)
+	}
+	return reply(ctx, nil, nil)
+}
+
+func (s *Server) handlePrepareTypeHierarchy(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params typeHierarchyPrepareParams
+	if err := decodeParams(req.Params(), &params); err != nil {
+		return reply(ctx, nil, fmt.Errorf("%s: %w", jsonrpc2.ErrParse, err))
+	}
+	if !s.isInitialized() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.ServerNotInitialized, "server not initialized"))
+	}
+	if s.isShuttingDown() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.InvalidRequest, "server is shutting down"))
+	}
+
+	result, err := s.prepareTypeHierarchy(ctx, &params)
+	return reply(ctx, result, err)
+}
+
+func (s *Server) handleTypeHierarchySupertypes(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params typeHierarchyItemParams
+	if err := decodeParams(req.Params(), &params); err != nil {
+		return reply(ctx, nil, fmt.Errorf("%s: %w", jsonrpc2.ErrParse, err))
+	}
+	if !s.isInitialized() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.ServerNotInitialized, "server not initialized"))
+	}
+	if s.isShuttingDown() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.InvalidRequest, "server is shutting down"))
+	}
+
+	result, err := s.typeHierarchySupertypes(ctx, &params)
+	return reply(ctx, result, err)
+}
+
+func (s *Server) handleTypeHierarchySubtypes(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params typeHierarchyItemParams
+	if err := decodeParams(req.Params(), &params); err != nil {
+		return reply(ctx, nil, fmt.Errorf("%s: %w", jsonrpc2.ErrParse, err))
+	}
+	if !s.isInitialized() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.ServerNotInitialized, "server not initialized"))
+	}
+	if s.isShuttingDown() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.InvalidRequest, "server is shutting down"))
+	}
+
+	result, err := s.typeHierarchySubtypes(ctx, &params)
+	return reply(ctx, result, err)
+}
+
+func (s *Server) handleExecuteCommand(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params protocol.ExecuteCommandParams
+	if err := decodeParams(req.Params(), &params); err != nil {
+		return reply(ctx, nil, fmt.Errorf("%s: %w", jsonrpc2.ErrParse, err))
+	}
+	if !s.isInitialized() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.ServerNotInitialized, "server not initialized"))
+	}
+	if s.isShuttingDown() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.InvalidRequest, "server is shutting down"))
+	}
+
+	result, err := s.executeCommand(ctx, &params)
+	return reply(ctx, result, err)
+}
+
+func (s *Server) handleDocumentDiagnostic(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params documentDiagnosticParams
+	if err := decodeParams(req.Params(), &params); err != nil {
+		return reply(ctx, nil, fmt.Errorf("%s: %w", jsonrpc2.ErrParse, err))
+	}
+	if !s.isInitialized() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.ServerNotInitialized, "server not initialized"))
+	}
+	if s.isShuttingDown() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.InvalidRequest, "server is shutting down"))
+	}
+
+	result, err := s.documentDiagnostics(ctx, &params)
+	return reply(ctx, result, err)
+}
+
+func (s *Server) handleWorkspaceDiagnostic(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params workspaceDiagnosticParams
+	if err := decodeParams(req.Params(), &params); err != nil {
+		return reply(ctx, nil, fmt.Errorf("%s: %w", jsonrpc2.ErrParse, err))
+	}
+	if !s.isInitialized() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.ServerNotInitialized, "server not initialized"))
+	}
+	if s.isShuttingDown() {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.InvalidRequest, "server is shutting down"))
+	}
+
+	result, err := s.workspaceDiagnostics(ctx, &params)
+	return reply(ctx, result, err)
+}
+
+func (s *Server) canCreateFiles() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createFileEdits
+}
+
+func (s *Server) supportsSnippets() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.completionSnippets
+}
+
 func (s *Server) isInitialized() bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -533,8 +979,26 @@ func decodeParams(raw json.RawMessage, dst interface{}) error {
 }
 
 type legacyInitializeParams struct {
-	RootURI  protocol.DocumentURI `json:"rootUri,omitempty"`
-	RootPath string               `json:"rootPath,omitempty"`
+	RootURI               protocol.DocumentURI     `json:"rootUri,omitempty"`
+	RootPath              string                   `json:"rootPath,omitempty"`
+	Capabilities          legacyClientCapabilities `json:"capabilities"`
+	InitializationOptions json.RawMessage          `json:"initializationOptions,omitempty"`
+}
+
+// legacyClientCapabilities decodes the client capabilities introduced after
+// the LSP revision modeled by the protocol package.
+type legacyClientCapabilities struct {
+	TextDocument struct {
+		Diagnostic *json.RawMessage `json:"diagnostic,omitempty"`
+	} `json:"textDocument"`
+	Workspace struct {
+		Diagnostics struct {
+			RefreshSupport bool `json:"refreshSupport,omitempty"`
+		} `json:"diagnostics"`
+		DidChangeWatchedFiles struct {
+			RelativePatternSupport bool `json:"relativePatternSupport,omitempty"`
+		} `json:"didChangeWatchedFiles"`
+	} `json:"workspace"`
 }
 
 func decodeLegacyInitialize(raw json.RawMessage) (legacyInitializeParams, error) {
@@ -572,6 +1036,107 @@ func replier(stream jsonrpc2StreamWriter, writeMu locker, req jsonrpc2.Request)
 	}
 }
 
+// clientCaller sends a server-to-client request. When result is non-nil the
+// call waits for the client's response and decodes its result into it;
+// otherwise the request is fire-and-forget.
+type clientCaller func(ctx context.Context, method string, params, result interface{}) error
+
+func streamClientCaller(stream jsonrpc2StreamWriter, writeMu locker, calls *pendingCalls) clientCaller {
+	var seq atomic.Int64
+	return func(ctx context.Context, method string, params, result interface{}) error {
+		id := jsonrpc2.NewStringID(fmt.Sprintf("mergeway-%d", seq.Add(1)))
+		call, err := jsonrpc2.NewCall(id, method, params)
+		if err != nil {
+			return fmt.Errorf("%s: %w", method, err)
+		}
+		if result == nil {
+			return writeJSONRPCMessage(ctx, stream, writeMu, call)
+		}
+
+		responses, err := calls.add(id)
+		if err != nil {
+			return fmt.Errorf("%s: %w", method, err)
+		}
+		defer calls.remove(id)
+		if err := writeJSONRPCMessage(ctx, stream, writeMu, call); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case response, ok := <-responses:
+			if !ok {
+				return fmt.Errorf("%s: %w", method, calls.err())
+			}
+			if err := response.Err(); err != nil {
+				return fmt.Errorf("%s: %w", method, err)
+			}
+			if err := decodeParams(response.Result(), result); err != nil {
+				return fmt.Errorf("%s: decode response: %w", method, err)
+			}
+			return nil
+		}
+	}
+}
+
+// pendingCalls tracks the server-to-client requests awaiting a response.
+type pendingCalls struct {
+	mu      sync.Mutex
+	waiting map[jsonrpc2.ID]chan *jsonrpc2.Response
+	closed  error
+}
+
+func newPendingCalls() *pendingCalls {
+	return &pendingCalls{waiting: make(map[jsonrpc2.ID]chan *jsonrpc2.Response)}
+}
+
+func (c *pendingCalls) add(id jsonrpc2.ID) (<-chan *jsonrpc2.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed != nil {
+		return nil, c.closed
+	}
+	responses := make(chan *jsonrpc2.Response, 1)
+	c.waiting[id] = responses
+	return responses, nil
+}
+
+func (c *pendingCalls) remove(id jsonrpc2.ID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.waiting, id)
+}
+
+// deliver hands response to the call it answers. Responses to unknown or
+// fire-and-forget calls are dropped.
+func (c *pendingCalls) deliver(response *jsonrpc2.Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if responses, ok := c.waiting[response.ID()]; ok {
+		responses <- response
+		delete(c.waiting, response.ID())
+	}
+}
+
+// fail ends every pending call once the connection can no longer deliver
+// responses.
+func (c *pendingCalls) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = fmt.Errorf("connection closed: %w", err)
+	for id, responses := range c.waiting {
+		close(responses)
+		delete(c.waiting, id)
+	}
+}
+
+func (c *pendingCalls) err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
 func newNotification(method string, params interface{}) (*jsonrpc2.Notification, error) {
 	return jsonrpc2.NewNotification(method, params)
 }
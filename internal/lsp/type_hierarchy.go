@@ -0,0 +1,199 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mergewayhq/mergeway-cli/internal/config"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+	"gopkg.in/yaml.v3"
+)
+
+// The LSP 3.17 type hierarchy requests are not defined by the protocol package.
+const (
+	methodTextDocumentPrepareTypeHierarchy = "textDocument/prepareTypeHierarchy"
+	methodTypeHierarchySupertypes          = "typeHierarchy/supertypes"
+	methodTypeHierarchySubtypes            = "typeHierarchy/subtypes"
+)
+
+type typeHierarchyPrepareParams struct {
+	protocol.TextDocumentPositionParams
+}
+
+type typeHierarchyItemParams struct {
+	Item typeHierarchyItem `json:"item"`
+}
+
+type typeHierarchyItem struct {
+	Name           string               `json:"name"`
+	Kind           protocol.SymbolKind  `json:"kind"`
+	Detail         string               `json:"detail,omitempty"`
+	URI            protocol.DocumentURI `json:"uri"`
+	Range          protocol.Range       `json:"range"`
+	SelectionRange protocol.Range       `json:"selectionRange"`
+}
+
+func (s *Server) prepareTypeHierarchy(ctx context.Context, params *typeHierarchyPrepareParams) ([]typeHierarchyItem, error) {
+	analysis, err := s.analyzePosition(params.TextDocument.URI.Filename(), params.Position)
+	if err != nil || analysis == nil || analysis.kind != analysisKindConfig || analysis.doc == nil || analysis.cfg == nil {
+		return nil, err
+	}
+
+	typeName, _ := configEntityNameAtPosition(analysis.doc, params.Position)
+	item, ok := s.typeHierarchyItem(analysis.cfg, typeName)
+	if !ok {
+		return nil, nil
+	}
+	return []typeHierarchyItem{item}, nil
+}
+
+func (s *Server) typeHierarchySupertypes(ctx context.Context, params *typeHierarchyItemParams) ([]typeHierarchyItem, error) {
+	cfg := s.typeHierarchyConfig(params.Item)
+	if cfg == nil || cfg.Types[params.Item.Name] == nil {
+		return nil, nil
+	}
+
+	item, ok := s.typeHierarchyItem(cfg, cfg.Types[params.Item.Name].Extends)
+	if !ok {
+		return []typeHierarchyItem{}, nil
+	}
+	return []typeHierarchyItem{item}, nil
+}
+
+func (s *Server) typeHierarchySubtypes(ctx context.Context, params *typeHierarchyItemParams) ([]typeHierarchyItem, error) {
+	cfg := s.typeHierarchyConfig(params.Item)
+	if cfg == nil || cfg.Types[params.Item.Name] == nil {
+		return nil, nil
+	}
+
+	items := []typeHierarchyItem{}
+	for _, name := range sortedTypeNames(cfg.Types) {
+		if cfg.Types[name].Extends != params.Item.Name {
+			continue
+		}
+		if item, ok := s.typeHierarchyItem(cfg, name); ok {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (s *Server) typeHierarchyConfig(item typeHierarchyItem) *config.Config {
+	if s.runtime == nil {
+		return nil
+	}
+	return validationConfig(s.runtime.RootByPath(item.URI.Filename()))
+}
+
+// typeHierarchyItem locates typeName's entry under `entities:` in the config
+// file that defines it.
+func (s *Server) typeHierarchyItem(cfg *config.Config, typeName string) (typeHierarchyItem, bool) {
+	typeDef := cfg.Types[typeName]
+	if typeDef == nil || typeDef.Source == "" {
+		return typeHierarchyItem{}, false
+	}
+
+	content, err := s.documentContent(typeDef.Source)
+	if err != nil {
+		return typeHierarchyItem{}, false
+	}
+	doc, ok := parseDocumentNode(content)
+	if !ok {
+		return typeHierarchyItem{}, false
+	}
+	_, entitiesNode := mappingEntry(documentRoot(doc), "entities")
+	keyNode, entityNode := mappingEntry(entitiesNode, typeName)
+	if keyNode == nil {
+		return typeHierarchyItem{}, false
+	}
+
+	item := typeHierarchyItem{
+		Name:           typeName,
+		Kind:           protocol.SymbolKindClass,
+		URI:            protocol.DocumentURI(uri.File(typeDef.Source)),
+		Range:          structuralRangeIncludingKey(keyNode, entityNode),
+		SelectionRange: nodeRange(keyNode),
+	}
+	if typeDef.Extends != "" {
+		item.Detail = "extends " + typeDef.Extends
+	}
+	return item, true
+}
+
+// configEntityHover describes the entity named under the cursor, including its
+// ancestry and the fields it inherits with the entity each one comes from.
+func configEntityHover(analysis *documentAnalysis) (string, *protocol.Range) {
+	if analysis == nil || analysis.kind != analysisKindConfig || analysis.doc == nil || analysis.cfg == nil {
+		return "", nil
+	}
+
+	typeName, node := configEntityNameAtPosition(analysis.doc, analysis.position)
+	typeDef := analysis.cfg.Types[typeName]
+	if typeDef == nil {
+		return "", nil
+	}
+
+	var b strings.Builder
+	b.WriteString(typeDocumentation(typeDef))
+	if len(typeDef.Ancestors) > 0 {
+		ancestors := make([]string, 0, len(typeDef.Ancestors))
+		for idx := len(typeDef.Ancestors) - 1; idx >= 0; idx-- {
+			ancestors = append(ancestors, "`"+typeDef.Ancestors[idx]+"`")
+		}
+		fmt.Fprintf(&b, "\n\nExtends %s", strings.Join(ancestors, " → "))
+	}
+	if len(typeDef.Descendants) > 0 {
+		fmt.Fprintf(&b, "\n\nSubtypes: `%s`", strings.Join(typeDef.Descendants, "`, `"))
+	}
+
+	if parentDef := analysis.cfg.Types[typeDef.Extends]; parentDef != nil && len(parentDef.FieldOrder) > 0 {
+		b.WriteString("\n\n**Inherited fields**\n")
+		for _, fieldName := range parentDef.FieldOrder {
+			fieldDef := parentDef.Fields[fieldName]
+			if fieldDef == nil {
+				continue
+			}
+			typeLabel := fieldDef.Type
+			if fieldDef.IsReference() {
+				typeLabel = fieldDef.ReferenceLabel()
+			}
+			fmt.Fprintf(&b, "\n- `%s`: `%s` (from `%s`)", fieldName, typeLabel, fieldOrigin(analysis.cfg, typeDef, fieldName))
+		}
+	}
+
+	rng := nodeRange(node)
+	return b.String(), &rng
+}
+
+// fieldOrigin returns the most distant ancestor that declares fieldName, which
+// is the entity that introduced it.
+func fieldOrigin(cfg *config.Config, typeDef *config.TypeDefinition, fieldName string) string {
+	for _, ancestor := range typeDef.Ancestors {
+		if ancestorDef := cfg.Types[ancestor]; ancestorDef != nil && ancestorDef.Fields[fieldName] != nil {
+			return ancestor
+		}
+	}
+	return typeDef.Name
+}
+
+// configEntityNameAtPosition returns the entity named by an `entities:` key or
+// an `extends:` value under the cursor.
+func configEntityNameAtPosition(doc *yaml.Node, position protocol.Position) (string, *yaml.Node) {
+	_, entitiesNode := mappingEntry(documentRoot(doc), "entities")
+	if entitiesNode == nil || entitiesNode.Kind != yaml.MappingNode {
+		return "", nil
+	}
+
+	for idx := 0; idx+1 < len(entitiesNode.Content); idx += 2 {
+		keyNode := entitiesNode.Content[idx]
+		if positionWithinNode(position, keyNode) {
+			return keyNode.Value, keyNode
+		}
+		if _, extendsNode := mappingEntry(entitiesNode.Content[idx+1], "extends"); extendsNode != nil && extendsNode.Kind == yaml.ScalarNode && positionWithinNode(position, extendsNode) {
+			return extendsNode.Value, extendsNode
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,135 @@
+package lsp
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func TestTypeHierarchyNavigatesExtends(t *testing.T) {
+	server, root := initializeHierarchyServer(t)
+	dogPath := filepath.Join(root, "entities", "Dog.yaml")
+	animalPath := filepath.Join(root, "entities", "Animal.yaml")
+	dogContent := readFile(t, dogPath)
+
+	var prepared []typeHierarchyItem
+	callServer(t, server, methodTextDocumentPrepareTypeHierarchy, 2, &typeHierarchyPrepareParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(dogPath))},
+			Position:     positionInContent(t, dogContent, "Dog:"),
+		},
+	}, &prepared)
+	if len(prepared) != 1 || prepared[0].Name != "Dog" || prepared[0].Detail != "extends Animal" {
+		t.Fatalf("expected Dog item, got %#v", prepared)
+	}
+	if prepared[0].SelectionRange.Start != positionInContent(t, dogContent, "Dog:") {
+		t.Fatalf("expected selection on the entity key, got %+v", prepared[0].SelectionRange)
+	}
+
+	var supertypes []typeHierarchyItem
+	callServer(t, server, methodTypeHierarchySupertypes, 3, &typeHierarchyItemParams{Item: prepared[0]}, &supertypes)
+	if len(supertypes) != 1 || supertypes[0].Name != "Animal" || supertypes[0].URI.Filename() != animalPath {
+		t.Fatalf("expected Animal supertype in %s, got %#v", animalPath, supertypes)
+	}
+
+	var subtypes []typeHierarchyItem
+	callServer(t, server, methodTypeHierarchySubtypes, 4, &typeHierarchyItemParams{Item: supertypes[0]}, &subtypes)
+	if len(subtypes) != 2 || subtypes[0].Name != "Cat" || subtypes[1].Name != "Dog" {
+		t.Fatalf("expected direct Cat and Dog subtypes, got %#v", subtypes)
+	}
+
+	var leaves []typeHierarchyItem
+	callServer(t, server, methodTypeHierarchySubtypes, 5, &typeHierarchyItemParams{Item: prepared[0]}, &leaves)
+	if len(leaves) != 1 || leaves[0].Name != "Puppy" {
+		t.Fatalf("expected Puppy under Dog, got %#v", leaves)
+	}
+
+	var fromExtends []typeHierarchyItem
+	callServer(t, server, methodTextDocumentPrepareTypeHierarchy, 6, &typeHierarchyPrepareParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(dogPath))},
+			Position:     positionInContent(t, dogContent, "Animal"),
+		},
+	}, &fromExtends)
+	if len(fromExtends) != 1 || fromExtends[0].Name != "Animal" {
+		t.Fatalf("expected Animal from the extends value, got %#v", fromExtends)
+	}
+}
+
+func TestHandleHoverListsInheritedFieldsWithOrigin(t *testing.T) {
+	server, root := initializeHierarchyServer(t)
+	dogPath := filepath.Join(root, "entities", "Dog.yaml")
+	dogContent := readFile(t, dogPath)
+
+	var hover protocol.Hover
+	callServer(t, server, protocol.MethodTextDocumentHover, 2, &protocol.HoverParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(dogPath))},
+			Position:     positionInContent(t, dogContent, "Puppy:"),
+		},
+	}, &hover)
+
+	for _, want := range []string{
+		"Extends `Dog` → `Animal`",
+		"- `id`: `string` (from `Animal`)",
+		"- `name`: `string` (from `Animal`)",
+		"- `breed`: `string` (from `Dog`)",
+	} {
+		if !strings.Contains(hover.Contents.Value, want) {
+			t.Fatalf("expected %q in hover, got %q", want, hover.Contents.Value)
+		}
+	}
+	if strings.Contains(hover.Contents.Value, "`age`") {
+		t.Fatalf("expected Puppy's own field not to be listed as inherited, got %q", hover.Contents.Value)
+	}
+}
+
+func initializeHierarchyServer(t *testing.T) (*Server, string) {
+	t.Helper()
+
+	root := absTestPath(t, t.TempDir())
+	writeTestFiles(t, root, map[string]string{
+		"mergeway.yaml": "mergeway:\n  version: 1\n\ninclude:\n  - entities/*.yaml\n",
+		"entities/Animal.yaml": `mergeway:
+  version: 1
+
+entities:
+  Animal:
+    identifier: id
+    include:
+      - data/animals/*.yaml
+    fields:
+      id: string
+      name: string
+  Cat:
+    extends: Animal
+    include:
+      - data/cats/*.yaml
+    fields:
+      indoor: boolean
+`,
+		"entities/Dog.yaml": `mergeway:
+  version: 1
+
+entities:
+  Dog:
+    extends: Animal
+    include:
+      - data/dogs/*.yaml
+    fields:
+      breed: string
+  Puppy:
+    extends: Dog
+    include:
+      - data/puppies/*.yaml
+    fields:
+      age: integer
+`,
+	})
+
+	server, _, absRoot := initializeCodeActionServer(t, root)
+	return server, absRoot
+}
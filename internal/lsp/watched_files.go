@@ -0,0 +1,141 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+const watchedFilesRegistrationID = "mergeway-watched-files"
+
+// fileSystemWatcher mirrors the LSP 3.17 watcher, whose glob may be relative
+// to a base URI; the protocol package only models plain string globs.
+type fileSystemWatcher struct {
+	GlobPattern interface{} `json:"globPattern"`
+}
+
+type relativePattern struct {
+	BaseURI protocol.URI `json:"baseUri"`
+	Pattern string       `json:"pattern"`
+}
+
+type didChangeWatchedFilesRegistrationOptions struct {
+	Watchers []fileSystemWatcher `json:"watchers"`
+}
+
+// registerWatchedFiles asks the client to report changes to config files and
+// include globs. The registration is replaced whenever the watched set
+// changes, for example after an include glob is edited.
+func (s *Server) registerWatchedFiles(ctx context.Context) error {
+	s.mu.Lock()
+	ready := s.clientReady && s.watchRegistration && s.callClient != nil && s.runtime != nil
+	call := s.callClient
+	s.mu.Unlock()
+	if !ready {
+		return nil
+	}
+
+	watchers := s.fileWatchers()
+	encoded, err := json.Marshal(watchers)
+	if err != nil {
+		return err
+	}
+
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	if string(encoded) == s.watchKey {
+		return nil
+	}
+
+	if s.watchKey != "" {
+		err := call(ctx, protocol.MethodClientUnregisterCapability, &protocol.UnregistrationParams{
+			Unregisterations: []protocol.Unregistration{{
+				ID:     watchedFilesRegistrationID,
+				Method: protocol.MethodWorkspaceDidChangeWatchedFiles,
+			}},
+		}, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = call(ctx, protocol.MethodClientRegisterCapability, &protocol.RegistrationParams{
+		Registrations: []protocol.Registration{{
+			ID:              watchedFilesRegistrationID,
+			Method:          protocol.MethodWorkspaceDidChangeWatchedFiles,
+			RegisterOptions: didChangeWatchedFilesRegistrationOptions{Watchers: watchers},
+		}},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	s.watchKey = string(encoded)
+	return nil
+}
+
+// fileWatchers lists the entry config names, every loaded config source, and
+// every include glob for each detected root.
+func (s *Server) fileWatchers() []fileSystemWatcher {
+	s.mu.Lock()
+	relative := s.relativePatterns
+	s.mu.Unlock()
+
+	snapshot := s.runtime.Snapshot()
+	rootPaths := make([]string, 0, len(snapshot.Roots))
+	for rootPath := range snapshot.Roots {
+		rootPaths = append(rootPaths, rootPath)
+	}
+	sort.Strings(rootPaths)
+
+	var watchers []fileSystemWatcher
+	for _, rootPath := range rootPaths {
+		index := snapshot.Roots[rootPath].Index
+		if index == nil {
+			continue
+		}
+
+		patterns := []string{filepath.Join(index.Root, "mergeway.{yaml,yml}")}
+		configFiles := make([]string, 0, len(index.ConfigFiles))
+		for path := range index.ConfigFiles {
+			configFiles = append(configFiles, path)
+		}
+		sort.Strings(configFiles)
+		patterns = append(patterns, configFiles...)
+		patterns = append(patterns, index.IncludePatterns...)
+
+		seen := make(map[string]struct{}, len(patterns))
+		for _, pattern := range patterns {
+			if _, ok := seen[pattern]; ok {
+				continue
+			}
+			seen[pattern] = struct{}{}
+			watchers = append(watchers, watcherForPattern(index.Root, pattern, relative))
+		}
+	}
+	return watchers
+}
+
+// watcherForPattern expresses pattern relative to root when it lies inside it
+// and the client accepts relative patterns, which lets clients scope the
+// watch, and as an absolute glob otherwise.
+func watcherForPattern(root, pattern string, relative bool) fileSystemWatcher {
+	rel, err := filepath.Rel(root, pattern)
+	if !relative || err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fileSystemWatcher{GlobPattern: filepath.ToSlash(pattern)}
+	}
+	return fileSystemWatcher{GlobPattern: relativePattern{
+		BaseURI: protocol.URI(uri.File(root)),
+		Pattern: filepath.ToSlash(rel),
+	}}
+}
+
+func supportsWatchedFileRegistration(capabilities protocol.ClientCapabilities) bool {
+	return capabilities.Workspace != nil &&
+		capabilities.Workspace.DidChangeWatchedFiles != nil &&
+		capabilities.Workspace.DidChangeWatchedFiles.DynamicRegistration
+}
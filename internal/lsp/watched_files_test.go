@@ -0,0 +1,259 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+type clientCall struct {
+	method string
+	params json.RawMessage
+}
+
+type clientCallCapture struct {
+	mu    sync.Mutex
+	calls []clientCall
+	// rejectEdits makes workspace/applyEdit report the edit as not applied.
+	rejectEdits bool
+}
+
+func TestHandleInitializedRegistersWatchedFiles(t *testing.T) {
+	server, calls, root := initializeWatchedFilesServer(t, true)
+
+	registrations := calls.registrations(t)
+	if len(registrations) != 1 {
+		t.Fatalf("expected one registration, got %#v", registrations)
+	}
+	globs := watchedGlobs(t, registrations[0])
+	for _, want := range []string{"mergeway.{yaml,yml}", "mergeway.yaml", "data/users/*.yaml", "data/posts/*.yaml"} {
+		if !contains(globs, want) {
+			t.Fatalf("expected watcher %q relative to %s, got %v", want, root, globs)
+		}
+	}
+
+	if err := server.registerWatchedFiles(context.Background()); err != nil {
+		t.Fatalf("registerWatchedFiles: %v", err)
+	}
+	if got := len(calls.registrations(t)); got != 1 {
+		t.Fatalf("expected unchanged watchers not to re-register, got %d registrations", got)
+	}
+}
+
+func TestHandleInitializedRegistersAbsoluteGlobsWithoutRelativePatternSupport(t *testing.T) {
+	_, calls, root := initializeWatchedFilesServer(t, false)
+
+	registrations := calls.registrations(t)
+	if len(registrations) != 1 {
+		t.Fatalf("expected one registration, got %#v", registrations)
+	}
+	globs := watchedGlobs(t, registrations[0])
+	for _, rel := range []string{"mergeway.{yaml,yml}", "data/users/*.yaml", "data/posts/*.yaml"} {
+		if want := filepath.ToSlash(filepath.Join(root, rel)); !contains(globs, want) {
+			t.Fatalf("expected absolute watcher %q, got %v", want, globs)
+		}
+	}
+}
+
+func TestHandleDidChangeWatchedFilesRescansDisk(t *testing.T) {
+	server, calls, root := initializeWatchedFilesServer(t, true)
+	capture := &diagnosticCapture{}
+	server.publishDiagnostics = capture.PublishDiagnostics
+	postPath := filepath.Join(root, "data", "posts", "post.yaml")
+
+	writeTestFiles(t, root, map[string]string{
+		"data/users/missing-user.yaml": "id: missing-user\n",
+		"mergeway.yaml": `mergeway:
+  version: 1
+
+entities:
+  User:
+    identifier: id
+    include:
+      - data/users/*.yaml
+      - data/people/*.yaml
+    fields:
+      id: string
+  Post:
+    identifier: id
+    include:
+      - data/posts/*.yaml
+    fields:
+      id: string
+      author:
+        type: User
+        required: true
+      title: string
+`,
+	})
+	callServer(t, server, protocol.MethodWorkspaceDidChangeWatchedFiles, 3, &protocol.DidChangeWatchedFilesParams{
+		Changes: []*protocol.FileEvent{
+			{URI: uri.File(filepath.Join(root, "data", "users", "missing-user.yaml")), Type: protocol.FileChangeTypeCreated},
+			{URI: uri.File(filepath.Join(root, "mergeway.yaml")), Type: protocol.FileChangeTypeChanged},
+		},
+	}, (*struct{})(nil))
+	if err := server.runtime.FlushReload(); err != nil {
+		t.Fatalf("FlushReload: %v", err)
+	}
+
+	cleared := capture.latestByPath()[postPath]
+	if cleared == nil || len(cleared.Diagnostics) != 0 {
+		t.Fatalf("expected the dangling reference diagnostic to clear, got %#v", cleared)
+	}
+
+	registrations := calls.registrations(t)
+	if len(registrations) != 2 || !contains(watchedGlobs(t, registrations[1]), "data/people/*.yaml") {
+		t.Fatalf("expected re-registration with the new include glob, got %#v", registrations)
+	}
+	if !calls.called(protocol.MethodClientUnregisterCapability) {
+		t.Fatalf("expected the previous registration to be dropped, got %v", calls.methods())
+	}
+}
+
+func TestWatcherForPatternOutsideRoot(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "repo", "root")
+	outside := filepath.Join(string(filepath.Separator), "repo", "shared", "*.yaml")
+
+	watcher := watcherForPattern(root, outside, true)
+	if watcher.GlobPattern != filepath.ToSlash(outside) {
+		t.Fatalf("expected absolute glob for %s, got %#v", outside, watcher.GlobPattern)
+	}
+}
+
+func initializeWatchedFilesServer(t *testing.T, relativePatterns bool) (*Server, *clientCallCapture, string) {
+	t.Helper()
+
+	root := absTestPath(t, t.TempDir())
+	writeTestFiles(t, root, map[string]string{
+		"mergeway.yaml": `mergeway:
+  version: 1
+
+entities:
+  User:
+    identifier: id
+    include:
+      - data/users/*.yaml
+    fields:
+      id: string
+  Post:
+    identifier: id
+    include:
+      - data/posts/*.yaml
+    fields:
+      id: string
+      author:
+        type: User
+        required: true
+      title: string
+`,
+		"data/posts/post.yaml": "id: post-1\nauthor: missing-user\ntitle: Missing Author\n",
+	})
+
+	calls := &clientCallCapture{}
+	server := NewServer(Options{
+		Logger:             testLogger(),
+		PublishDiagnostics: (&diagnosticCapture{}).PublishDiagnostics,
+		CallClient:         calls.CallClient,
+	})
+	callServer(t, server, protocol.MethodInitialize, 1, map[string]any{
+		"rootUri": string(uri.File(root)),
+		"capabilities": map[string]any{
+			"workspace": map[string]any{"didChangeWatchedFiles": map[string]any{
+				"dynamicRegistration":    true,
+				"relativePatternSupport": relativePatterns,
+			}},
+		},
+	}, (*protocol.InitializeResult)(nil))
+	callServer(t, server, protocol.MethodInitialized, 2, &protocol.InitializedParams{}, (*struct{})(nil))
+	return server, calls, root
+}
+
+func watchedGlobs(t *testing.T, registration protocol.Registration) []string {
+	t.Helper()
+
+	body, err := json.Marshal(registration.RegisterOptions)
+	if err != nil {
+		t.Fatalf("marshal register options: %v", err)
+	}
+	var options struct {
+		Watchers []struct {
+			GlobPattern json.RawMessage `json:"globPattern"`
+		} `json:"watchers"`
+	}
+	if err := json.Unmarshal(body, &options); err != nil {
+		t.Fatalf("unmarshal register options: %v", err)
+	}
+
+	globs := make([]string, 0, len(options.Watchers))
+	for _, watcher := range options.Watchers {
+		var pattern relativePattern
+		if err := json.Unmarshal(watcher.GlobPattern, &pattern); err == nil {
+			globs = append(globs, pattern.Pattern)
+			continue
+		}
+		var glob string
+		if err := json.Unmarshal(watcher.GlobPattern, &glob); err != nil {
+			t.Fatalf("unexpected glob pattern %s", watcher.GlobPattern)
+		}
+		globs = append(globs, glob)
+	}
+	return globs
+}
+
+func (c *clientCallCapture) CallClient(_ context.Context, method string, params, result interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, clientCall{method: method, params: body})
+	if response, ok := result.(*protocol.ApplyWorkspaceEditResponse); ok {
+		response.Applied = !c.rejectEdits
+		if c.rejectEdits {
+			response.FailureReason = "file is read-only"
+		}
+	}
+	return nil
+}
+
+func (c *clientCallCapture) methods() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	methods := make([]string, 0, len(c.calls))
+	for _, call := range c.calls {
+		methods = append(methods, call.method)
+	}
+	return methods
+}
+
+func (c *clientCallCapture) called(method string) bool {
+	return contains(c.methods(), method)
+}
+
+func (c *clientCallCapture) registrations(t *testing.T) []protocol.Registration {
+	t.Helper()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var registrations []protocol.Registration
+	for _, call := range c.calls {
+		if call.method != protocol.MethodClientRegisterCapability {
+			continue
+		}
+		var params protocol.RegistrationParams
+		if err := json.Unmarshal(call.params, &params); err != nil {
+			t.Fatalf("unmarshal registration: %v", err)
+		}
+		registrations = append(registrations, params.Registrations...)
+	}
+	return registrations
+}
@@ -0,0 +1,151 @@
+package mergecmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	diffpkg "github.com/mergewayhq/mergeway-cli/internal/diff"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes follow git merge-file: conflicts and failures are distinguishable.
+const (
+	exitConflicts = 1
+	exitFailure   = 2
+)
+
+type context struct {
+	Root   string
+	Config string
+	Format string
+	DryRun bool
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Run executes the mergeway-merge CLI. It returns an exit code.
+func Run(args []string, stdout, stderr io.Writer) int {
+	cmd := newRootCommand(stdout, stderr)
+	cmd.SetArgs(args)
+
+	if err := cmd.Execute(); err != nil {
+		var exitErr exitError
+		if errors.As(err, &exitErr) {
+			return exitErr.Code()
+		}
+		_, _ = fmt.Fprintln(stderr, err.Error())
+		return exitFailure
+	}
+	return 0
+}
+
+type exitError struct {
+	code int
+}
+
+func (e exitError) Error() string {
+	return fmt.Sprintf("exit code %d", e.code)
+}
+
+func (e exitError) Code() int {
+	if e.code == 0 {
+		return exitFailure
+	}
+	return e.code
+}
+
+func newExitError(code int) error {
+	return exitError{code: code}
+}
+
+func newRootCommand(stdout, stderr io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "mergeway-merge <base> <ours> <theirs>",
+		Short:         "Merge Mergeway-managed data from two revisions",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Long: `Merge Mergeway-managed data from two revisions against their common base.
+
+This command is a data-only, three-way semantic merge. Objects are matched by their Mergeway identity, not by file path, and configuration files are excluded entirely.
+
+Edits to different fields of the same object, repeated values added or removed on either side, and an object moved on one side while edited on the other all merge automatically. The same field changed differently, an object deleted on one side and modified on the other, and an object moved to different files on both sides are conflicts.
+
+Merged data files are written to the working tree under --root. Files that cannot be resolved carry <<<<<<< ours / ======= / >>>>>>> theirs markers. Files with local changes that differ from <ours> are never overwritten.
+
+Use --format json to emit the structured conflict report. The exit code is 0 for a clean merge, 1 when conflicts remain, and 2 on errors.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := contextFromCommand(cmd)
+			if err != nil {
+				return err
+			}
+
+			output, err := diffpkg.Merge(diffpkg.MergeOptions{
+				Root:   ctx.Root,
+				Config: ctx.Config,
+				Args:   args,
+				JSON:   ctx.Format == "json",
+				DryRun: ctx.DryRun,
+			})
+			if err != nil {
+				if errors.Is(err, diffpkg.ErrMergeArgs) {
+					_ = cmd.Help()
+				}
+				_, _ = fmt.Fprintln(ctx.Stderr, diffpkg.FormatMergeCommandError(err))
+				return newExitError(exitFailure)
+			}
+
+			_, _ = fmt.Fprint(ctx.Stdout, output.Report)
+			if output.Conflicted {
+				return newExitError(exitConflicts)
+			}
+			return nil
+		},
+	}
+	cmd.SetOut(stdout)
+	cmd.SetErr(stderr)
+
+	flags := cmd.Flags()
+	flags.String("root", ".", "Repository root containing config and data directories")
+	flags.String("config", "", "Path to configuration entry file")
+	flags.String("format", "yaml", "Output format (yaml|json)")
+	flags.Bool("dry-run", false, "Report the merge without writing files")
+
+	return cmd
+}
+
+func contextFromCommand(cmd *cobra.Command) (*context, error) {
+	root, err := cmd.Flags().GetString("root")
+	if err != nil {
+		return nil, err
+	}
+	configPath, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return nil, err
+	}
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return nil, err
+	}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := &context{
+		Root:   root,
+		Config: configPath,
+		Format: strings.ToLower(format),
+		DryRun: dryRun,
+		Stdout: cmd.OutOrStdout(),
+		Stderr: cmd.ErrOrStderr(),
+	}
+
+	if ctx.Config == "" {
+		ctx.Config = filepath.Join(ctx.Root, "mergeway.yaml")
+	}
+
+	return ctx, nil
+}
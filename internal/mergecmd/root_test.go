@@ -0,0 +1,83 @@
+package mergecmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mergewayhq/mergeway-cli/internal/testutil"
+)
+
+func TestMergeExitsZeroOnCleanMerge(t *testing.T) {
+	repo := testutil.NewGitRepo(t)
+	base := repo.Revision(t, "HEAD")
+	theirs := repo.CommitOnBranch(t, base, "theirs", map[string]string{
+		"data/users/user-bob.yaml": "id: User-Bob\nname: Bob Example\nemail: bob@theirs.example.com\nrole: editor\n",
+	})
+	ours := repo.CommitDataChange(t, "data/users/user-bob.yaml", "id: User-Bob\nname: Bob Ours\nemail: bob@example.com\nrole: editor\n")
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	code := Run([]string{"--root", repo.Root, base, ours, theirs}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("expected clean merge to succeed, exit %d stderr %s", code, stderr.String())
+	}
+	if stdout.String() != "UPDATED data/users/user-bob.yaml\n" {
+		t.Fatalf("expected updated file in report, got %q", stdout.String())
+	}
+	if content := testutil.ReadFile(t, repo.Root, "data/users/user-bob.yaml"); content != "id: User-Bob\nname: Bob Ours\nemail: bob@theirs.example.com\nrole: editor\n" {
+		t.Fatalf("expected both edits in the merged file, got %q", content)
+	}
+}
+
+func TestMergeExitsOneOnConflicts(t *testing.T) {
+	repo := testutil.NewGitRepo(t)
+	base := repo.Revision(t, "HEAD")
+	theirs := repo.CommitOnBranch(t, base, "theirs", map[string]string{
+		"data/users/user-bob.yaml": "id: User-Bob\nname: Bob Theirs\nemail: bob@example.com\nrole: editor\n",
+	})
+	ours := repo.CommitDataChange(t, "data/users/user-bob.yaml", "id: User-Bob\nname: Bob Ours\nemail: bob@example.com\nrole: editor\n")
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	code := Run([]string{"--root", repo.Root, "--dry-run", base, ours, theirs}, stdout, stderr)
+	if code != 1 {
+		t.Fatalf("expected exit 1 for conflicts, got %d stderr %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "CONFLICTED data/users/user-bob.yaml") || !strings.Contains(stdout.String(), "CONFLICT (field) User[User-Bob] name") {
+		t.Fatalf("expected the conflict in the report, got %s", stdout.String())
+	}
+	if status := repo.StatusShort(t); status != "" {
+		t.Fatalf("expected --dry-run to leave the working tree untouched, got %s", status)
+	}
+}
+
+func TestMergeRequiresThreeArgs(t *testing.T) {
+	repo := testutil.NewGitRepo(t)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	code := Run([]string{"--root", repo.Root, "HEAD", "HEAD"}, stdout, stderr)
+	if code != 2 {
+		t.Fatalf("expected exit 2 for a usage error, got %d", code)
+	}
+	if !strings.Contains(stdout.String(), "Usage:") {
+		t.Fatalf("expected usage text in stdout, got %s", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "merge: input error: merge requires exactly 3 snapshot arguments") {
+		t.Fatalf("expected arity error in stderr, got %s", stderr.String())
+	}
+}
+
+func TestMergeHelpMentionsThreeWaySemanticMerge(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	code := Run([]string{"--help"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("expected merge help to succeed, exit %d stderr %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "three-way semantic merge") {
+		t.Fatalf("expected help text to describe the merge, got %s", stdout.String())
+	}
+}
@@ -0,0 +1,179 @@
+// Package testutil provides the repository fixtures shared by package tests.
+package testutil
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// FixtureRepo returns the path of the shared fixture repository, which
+// declares User, Tag, and Post types with a few objects of each.
+func FixtureRepo() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "data", "testdata", "repo")
+}
+
+// CopyFixture copies the shared fixture repository into a temporary directory
+// and returns its path.
+func CopyFixture(t testing.TB) string {
+	t.Helper()
+	return CopyDir(t, FixtureRepo())
+}
+
+// CopyDir copies src into a temporary directory and returns its path.
+func CopyDir(t testing.TB, src string) string {
+	t.Helper()
+	dest := t.TempDir()
+
+	if err := filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0o644)
+	}); err != nil {
+		t.Fatalf("copy fixture: %v", err)
+	}
+
+	return dest
+}
+
+// WriteFile writes content to the slash-separated path under root, creating
+// parent directories as needed.
+func WriteFile(t testing.TB, root, path, content string) {
+	t.Helper()
+	target := filepath.Join(root, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		t.Fatalf("create parent dir: %v", err)
+	}
+	if err := os.WriteFile(target, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+// ReadFile returns the content of the slash-separated path under root.
+func ReadFile(t testing.TB, root, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(path)))
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return string(content)
+}
+
+// RunGit runs git in root and returns its combined output, failing the test
+// when the command fails.
+func RunGit(t testing.TB, root string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", append([]string{"-C", root}, args...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, string(output))
+	}
+	return string(output)
+}
+
+// GitRepo is a git repository in a temporary directory.
+type GitRepo struct {
+	Root string
+}
+
+// NewGitRepo copies the shared fixture repository and commits it.
+func NewGitRepo(t testing.TB) GitRepo {
+	t.Helper()
+	return InitGitRepo(t, CopyFixture(t))
+}
+
+// NewGitRepoWithFiles writes files to an empty directory and commits them.
+func NewGitRepoWithFiles(t testing.TB, files map[string]string) GitRepo {
+	t.Helper()
+	root := t.TempDir()
+	for path, content := range files {
+		WriteFile(t, root, path, content)
+	}
+	return InitGitRepo(t, root)
+}
+
+// InitGitRepo initializes a repository in root and commits its content.
+func InitGitRepo(t testing.TB, root string) GitRepo {
+	t.Helper()
+	RunGit(t, root, "init")
+	RunGit(t, root, "config", "user.name", "Mergeway Tests")
+	RunGit(t, root, "config", "user.email", "mergeway-tests@example.com")
+	RunGit(t, root, "add", ".")
+	RunGit(t, root, "commit", "-m", "initial fixture")
+
+	return GitRepo{Root: root}
+}
+
+// Git runs git in the repository and returns its combined output.
+func (r GitRepo) Git(t testing.TB, args ...string) string {
+	t.Helper()
+	return RunGit(t, r.Root, args...)
+}
+
+// Revision resolves spec to a commit hash.
+func (r GitRepo) Revision(t testing.TB, spec string) string {
+	t.Helper()
+	return strings.TrimSpace(r.Git(t, "rev-parse", spec))
+}
+
+// WriteDataChange writes content to path in the working tree.
+func (r GitRepo) WriteDataChange(t testing.TB, path, content string) {
+	t.Helper()
+	WriteFile(t, r.Root, path, content)
+}
+
+// StageDataChange writes content to path and stages it.
+func (r GitRepo) StageDataChange(t testing.TB, path, content string) {
+	t.Helper()
+	r.WriteDataChange(t, path, content)
+	r.Git(t, "add", path)
+}
+
+// CommitDataChange writes content to path, commits it, and returns the new
+// revision.
+func (r GitRepo) CommitDataChange(t testing.TB, path, content string) string {
+	t.Helper()
+	r.StageDataChange(t, path, content)
+	r.Git(t, "commit", "-m", "update "+path)
+	return r.Revision(t, "HEAD")
+}
+
+// CommitOnBranch commits files on a new branch started at start and switches
+// back, so the working tree stays on the original branch. It returns the
+// revision of the branch.
+func (r GitRepo) CommitOnBranch(t testing.TB, start, branch string, files map[string]string) string {
+	t.Helper()
+	r.Git(t, "checkout", "-q", "-b", branch, start)
+	for path, content := range files {
+		r.StageDataChange(t, path, content)
+	}
+	r.Git(t, "commit", "-q", "-m", "update on "+branch)
+	revision := r.Revision(t, "HEAD")
+	r.Git(t, "checkout", "-q", "-")
+	return revision
+}
+
+// StatusShort returns the output of git status --short without the trailing
+// newline.
+func (r GitRepo) StatusShort(t testing.TB) string {
+	t.Helper()
+	return strings.TrimRight(r.Git(t, "status", "--short"), "\n")
+}
@@ -42,6 +42,8 @@ type Runtime struct {
 	documents map[string]*OpenDocument
 	timer     *time.Timer
 	onReload  func()
+	// generation counts changes to roots and documents.
+	generation uint64
 }
 
 // Snapshot captures a read-only copy of the runtime state used by callers that
@@ -49,6 +51,9 @@ type Runtime struct {
 type Snapshot struct {
 	Roots     map[string]*RootRuntime
 	Documents map[string]*OpenDocument
+	// Generation identifies the runtime state the snapshot was taken from.
+	// Snapshots with the same generation hold the same roots and documents.
+	Generation uint64
 }
 
 // NewRuntime constructs a runtime around a detected root set.
@@ -77,6 +82,7 @@ func (r *Runtime) DidOpen(doc *OpenDocument) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.documents[doc.Path] = cloneDocument(doc)
+	r.generation++
 	r.scheduleReloadLocked()
 	return nil
 }
@@ -91,6 +97,7 @@ func (r *Runtime) DidChange(path string, version int32, text string) error {
 	}
 	doc.Version = version
 	doc.Text = text
+	r.generation++
 	r.scheduleReloadLocked()
 	return nil
 }
@@ -100,6 +107,7 @@ func (r *Runtime) DidClose(path string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	delete(r.documents, path)
+	r.generation++
 	r.scheduleReloadLocked()
 }
 
@@ -114,6 +122,43 @@ func (r *Runtime) FlushReload() error {
 	return r.reload()
 }
 
+// Rescan re-detects the runtime's roots from disk so config edits and data
+// files created or deleted outside the editor are picked up, then schedules a
+// debounced recompute.
+func (r *Runtime) Rescan() error {
+	r.mu.Lock()
+	var candidates []string
+	if r.base != nil {
+		for _, root := range r.base.Roots {
+			candidates = append(candidates, root.Root)
+		}
+		candidates = append(candidates, r.base.MissingRoots...)
+	}
+	r.mu.Unlock()
+
+	set, err := OpenRoots(candidates)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	roots := make(map[string]*RootRuntime, len(set.Roots))
+	for _, root := range set.Roots {
+		state := &RootRuntime{Index: root, Workspace: root.Workspace}
+		if previous := r.roots[root.Root]; previous != nil {
+			state = cloneRuntime(previous)
+			state.Index = root
+		}
+		roots[root.Root] = state
+	}
+	r.base = set
+	r.roots = roots
+	r.generation++
+	r.scheduleReloadLocked()
+	return nil
+}
+
 // Document returns the currently open in-memory buffer, if any.
 func (r *Runtime) Document(path string) *OpenDocument {
 	r.mu.Lock()
@@ -143,8 +188,9 @@ func (r *Runtime) Snapshot() *Snapshot {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	return &Snapshot{
-		Roots:     cloneRuntimes(r.roots),
-		Documents: cloneDocuments(r.documents),
+		Roots:      cloneRuntimes(r.roots),
+		Documents:  cloneDocuments(r.documents),
+		Generation: r.generation,
 	}
 }
 
@@ -201,6 +247,7 @@ func (r *Runtime) reload() error {
 	for root, state := range next {
 		r.roots[root] = state
 	}
+	r.generation++
 	r.mu.Unlock()
 	if hook != nil {
 		hook()
@@ -21,6 +21,9 @@ type RootIndex struct {
 	ConfigFiles map[string]struct{}
 	DataFiles   map[string][]string
 	Workspace   *Workspace
+
+	// IncludePatterns lists the absolute include globs that populate DataFiles.
+	IncludePatterns []string
 }
 
 // RootSet captures all roots detected during one initialization pass.
@@ -150,11 +153,12 @@ func buildRootIndex(root, configPath string, cfg *config.Config, ws *Workspace)
 	}
 
 	return &RootIndex{
-		Root:        root,
-		ConfigPath:  configPath,
-		ConfigFiles: configFiles,
-		DataFiles:   dataFiles,
-		Workspace:   ws,
+		Root:            root,
+		ConfigPath:      configPath,
+		ConfigFiles:     configFiles,
+		DataFiles:       dataFiles,
+		Workspace:       ws,
+		IncludePatterns: includePatterns(root, cfg),
 	}, nil
 }
 
@@ -172,6 +176,40 @@ func buildFallbackRootIndex(root, configPath string) *RootIndex {
 	}
 }
 
+func includePatterns(root string, cfg *config.Config) []string {
+	if cfg == nil {
+		return nil
+	}
+
+	var patterns []string
+	seen := make(map[string]struct{})
+	for _, typeName := range sortedTypeNames(cfg.Types) {
+		typeDef := cfg.Types[typeName]
+		if typeDef == nil {
+			continue
+		}
+		for _, include := range typeDef.Include {
+			if include.Path == "" {
+				continue
+			}
+			pattern := includePattern(root, include.Path)
+			if _, ok := seen[pattern]; ok {
+				continue
+			}
+			seen[pattern] = struct{}{}
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+func includePattern(root, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(root, filepath.Clean(path))
+}
+
 func collectOwnedDataFiles(root string, cfg *config.Config) (map[string][]string, error) {
 	files := make(map[string][]string)
 	if cfg == nil {
@@ -189,11 +227,7 @@ func collectOwnedDataFiles(root string, cfg *config.Config) (map[string][]string
 				continue
 			}
 
-			pattern := include.Path
-			if !filepath.IsAbs(pattern) {
-				pattern = filepath.Join(root, filepath.Clean(pattern))
-			}
-
+			pattern := includePattern(root, include.Path)
 			matches, err := filepath.Glob(pattern)
 			if err != nil {
 				return nil, fmt.Errorf("workspace: glob %s: %w", include.Path, err)
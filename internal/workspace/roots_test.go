@@ -1,6 +1,7 @@
 package workspace
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
 )
@@ -129,3 +130,40 @@ func rootByBase(t *testing.T, set *RootSet, base string) *RootIndex {
 	t.Fatalf("root %q not found", base)
 	return nil
 }
+
+func TestRuntimeRescanPicksUpNewDataFiles(t *testing.T) {
+	root := workspaceInheritanceRepo(t)
+	set, err := OpenRoots([]string{root})
+	if err != nil {
+		t.Fatalf("OpenRoots: %v", err)
+	}
+	index := rootByBase(t, set, filepath.Base(root))
+	wantPattern := filepath.Join(index.Root, "data", "animals", "*.yaml")
+	if len(index.IncludePatterns) != 2 || index.IncludePatterns[0] != wantPattern {
+		t.Fatalf("expected include patterns starting with %s, got %v", wantPattern, index.IncludePatterns)
+	}
+
+	rt := NewRuntime(set)
+	newPath := filepath.Join(index.Root, "data", "animals", "cat.yaml")
+	if err := os.WriteFile(newPath, []byte("id: animal-2\nname: Cat\n"), 0o644); err != nil {
+		t.Fatalf("write cat: %v", err)
+	}
+	if rt.RootByPath(newPath) != nil {
+		t.Fatalf("expected new file to be unknown before rescan")
+	}
+
+	if err := rt.Rescan(); err != nil {
+		t.Fatalf("Rescan: %v", err)
+	}
+	if err := rt.FlushReload(); err != nil {
+		t.Fatalf("FlushReload: %v", err)
+	}
+
+	state := rt.RootByPath(newPath)
+	if state == nil {
+		t.Fatalf("expected rescan to index %s", newPath)
+	}
+	if got := len(state.Workspace.Find("Animal", "animal-2")); got != 1 {
+		t.Fatalf("expected animal-2 after rescan, got %d", got)
+	}
+}
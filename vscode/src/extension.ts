@@ -4,6 +4,8 @@ import * as vscode from "vscode";
 import {
   LanguageClient,
   LanguageClientOptions,
+  Location as ProtocolLocation,
+  Position as ProtocolPosition,
   ServerOptions,
   Trace,
 } from "vscode-languageclient/node";
@@ -27,6 +29,25 @@ export async function activate(context: vscode.ExtensionContext): Promise<void>
     }),
   );
 
+  context.subscriptions.push(
+    vscode.commands.registerCommand(
+      "mergeway.showReferences",
+      async (uri: string, position: ProtocolPosition, locations: ProtocolLocation[]) => {
+        if (!client) {
+          return;
+        }
+
+        const converter = client.protocol2CodeConverter;
+        await vscode.commands.executeCommand(
+          "editor.action.showReferences",
+          vscode.Uri.parse(uri),
+          converter.asPosition(position),
+          locations.map((location) => converter.asLocation(location)),
+        );
+      },
+    ),
+  );
+
   context.subscriptions.push(
     vscode.workspace.onDidChangeConfiguration(async (event) => {
       if (event.affectsConfiguration("mergeway.lsp.trace.server")) {
@@ -107,6 +128,7 @@ async function startLanguageServer(): Promise<void> {
     traceOutputChannel: channel,
     initializationOptions: {
       configFiles: ["mergeway.yaml", "mergeway.yml"],
+      showReferencesCommand: true,
     },
   };
 